@@ -2,6 +2,7 @@ package cfg
 
 import (
 	"log"
+	"time"
 
 	"github.com/spf13/viper"
 )
@@ -12,9 +13,63 @@ type Config struct {
 	API_HOST     string `mapstructure:"API_HOST"`
 	API_PORT     string `mapstructure:"API_PORT"`
 	REPORTS_HOST string `mapstructure:"REPORTS_HOST"`
+	// Порт gRPC сервера, хост совпадает с API_HOST
+	GRPC_PORT string `mapstructure:"GRPC_PORT"`
+	// Сколько /readyz отвечает 503 перед остановкой сервера, чтобы балансировщик успел убрать его
+	SHUTDOWN_DELAY time.Duration `mapstructure:"SHUTDOWN_DELAY"`
+	// Ключ администратора со всеми правами, используется для выпуска первых API ключей.
+	// Если пустой, ключи выпускаются только ключами с правом keys:admin
+	ADMIN_API_KEY string `mapstructure:"ADMIN_API_KEY"`
+
+	// Путь к файлу или URL с JWKS для проверки Bearer токенов. Если пустой, токены не принимаются
+	JWT_JWKS string `mapstructure:"JWT_JWKS"`
+	// Ожидаемые iss и aud токена, пустое значение не проверяется
+	JWT_ISSUER   string `mapstructure:"JWT_ISSUER"`
+	JWT_AUDIENCE string `mapstructure:"JWT_AUDIENCE"`
+	// Claim с правами токена
+	JWT_SCOPES_CLAIM string `mapstructure:"JWT_SCOPES_CLAIM"`
+	// Claim с пространствами имен токена
+	JWT_NAMESPACES_CLAIM string `mapstructure:"JWT_NAMESPACES_CLAIM"`
 
 	REDIS_HOST string `mapstructure:"REDIS_HOST"`
 	REDIS_PORT string `mapstructure:"REDIS_PORT"`
+
+	// Хранилище лимитов запросов: memory, redis (общие лимиты для всех экземпляров) или off
+	RATE_LIMIT_MODE string `mapstructure:"RATE_LIMIT_MODE"`
+	// Лимит клиента на маршрут по умолчанию, например 100/m
+	RATE_LIMIT_DEFAULT string `mapstructure:"RATE_LIMIT_DEFAULT"`
+	// Лимиты отдельных маршрутов: "POST /segment/user=10/s; GET /segment/user/{userId}=off"
+	RATE_LIMIT_ROUTES string `mapstructure:"RATE_LIMIT_ROUTES"`
+	// Лимит адреса на маршрут до проверки ключа, ограничивает перебор ключей и запросы с неверными токенами
+	RATE_LIMIT_IP string `mapstructure:"RATE_LIMIT_IP"`
+
+	// Кеш сегментов пользователя: memory, redis (память процесса и общий кеш в Redis) или off
+	CACHE_MODE string `mapstructure:"CACHE_MODE"`
+	// Сколько пользователей хранится в памяти процесса
+	CACHE_SIZE int `mapstructure:"CACHE_SIZE"`
+	// Время жизни записи, ограничивает устаревание данных после изменений на других экземплярах
+	CACHE_TTL time.Duration `mapstructure:"CACHE_TTL"`
+
+	// Куда отправлять трейсы: none, stdout или otlp
+	TRACING_EXPORTER string `mapstructure:"TRACING_EXPORTER"`
+	// Адрес OTLP/HTTP коллектора в формате host:port
+	TRACING_OTLP_ENDPOINT string `mapstructure:"TRACING_OTLP_ENDPOINT"`
+	// Отправлять трейсы в коллектор без TLS
+	TRACING_OTLP_INSECURE bool `mapstructure:"TRACING_OTLP_INSECURE"`
+	// Доля запросов, для которых пишется трейс, от 0 до 1
+	TRACING_SAMPLE_RATIO float64 `mapstructure:"TRACING_SAMPLE_RATIO"`
+
+	// Расписание отправки событий outbox в задачи доставки вебхуков
+	WEBHOOK_DISPATCH_CRON string `mapstructure:"WEBHOOK_DISPATCH_CRON"`
+	// Сколько раз повторять неудачную доставку вебхука
+	WEBHOOK_MAX_RETRIES int `mapstructure:"WEBHOOK_MAX_RETRIES"`
+	// Время ожидания ответа получателя вебхука
+	WEBHOOK_TIMEOUT time.Duration `mapstructure:"WEBHOOK_TIMEOUT"`
+	// Разрешить вебхуки на loopback, частные и link-local адреса
+	WEBHOOK_ALLOW_PRIVATE bool `mapstructure:"WEBHOOK_ALLOW_PRIVATE"`
+
+	// Расписание пересчета посуточной сводки по сегментам (cron или @every)
+	ANALYTICS_REFRESH_CRON string `mapstructure:"ANALYTICS_REFRESH_CRON"`
 }
 
 var cfg Config
@@ -29,6 +84,30 @@ func Load(path string) error {
 
 	viper.AutomaticEnv()
 
+	viper.SetDefault("SHUTDOWN_DELAY", "5s")
+	viper.SetDefault("GRPC_PORT", "9090")
+	viper.SetDefault("ANALYTICS_REFRESH_CRON", "@every 15m")
+	viper.SetDefault("WEBHOOK_DISPATCH_CRON", "@every 5s")
+	viper.SetDefault("WEBHOOK_MAX_RETRIES", 10)
+	viper.SetDefault("WEBHOOK_TIMEOUT", "10s")
+	viper.SetDefault("WEBHOOK_ALLOW_PRIVATE", false)
+	viper.SetDefault("RATE_LIMIT_MODE", "memory")
+	viper.SetDefault("RATE_LIMIT_DEFAULT", "50/s")
+	viper.SetDefault("RATE_LIMIT_ROUTES", "")
+	viper.SetDefault("RATE_LIMIT_IP", "100/s")
+	viper.SetDefault("CACHE_MODE", "memory")
+	viper.SetDefault("CACHE_SIZE", 10000)
+	viper.SetDefault("CACHE_TTL", "30s")
+	viper.SetDefault("TRACING_EXPORTER", "none")
+	viper.SetDefault("TRACING_OTLP_ENDPOINT", "localhost:4318")
+	viper.SetDefault("TRACING_OTLP_INSECURE", false)
+	viper.SetDefault("TRACING_SAMPLE_RATIO", 1.0)
+	viper.SetDefault("JWT_JWKS", "")
+	viper.SetDefault("JWT_ISSUER", "")
+	viper.SetDefault("JWT_AUDIENCE", "")
+	viper.SetDefault("JWT_SCOPES_CLAIM", "scope")
+	viper.SetDefault("JWT_NAMESPACES_CLAIM", "namespaces")
+
 	err := viper.Unmarshal(&cfg)
 	if err != nil {
 		log.Fatalf("unable to unmarshall into struct, %v", err)
@@ -1,14 +1,30 @@
 package main
 
 import (
+	"context"
 	"fmt"
+	"time"
 
 	"github.com/dezzerlol/avitotech-test-2023/cfg"
+	"github.com/dezzerlol/avitotech-test-2023/internal/auth"
+	"github.com/dezzerlol/avitotech-test-2023/internal/cache"
 	"github.com/dezzerlol/avitotech-test-2023/internal/db"
+	"github.com/dezzerlol/avitotech-test-2023/internal/events"
+	"github.com/dezzerlol/avitotech-test-2023/internal/grpc"
+	"github.com/dezzerlol/avitotech-test-2023/internal/health"
 	"github.com/dezzerlol/avitotech-test-2023/internal/http"
+	"github.com/dezzerlol/avitotech-test-2023/internal/metrics"
+	"github.com/dezzerlol/avitotech-test-2023/internal/ratelimit"
+	"github.com/dezzerlol/avitotech-test-2023/internal/repo"
+	"github.com/dezzerlol/avitotech-test-2023/internal/report"
+	"github.com/dezzerlol/avitotech-test-2023/internal/service"
+	"github.com/dezzerlol/avitotech-test-2023/internal/tracing"
 	"github.com/dezzerlol/avitotech-test-2023/internal/worker"
 	"github.com/dezzerlol/avitotech-test-2023/pkg/logger"
 	"github.com/hibiken/asynq"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
 )
 
 // @title          Avitotech Test 2023 API
@@ -17,6 +33,15 @@ import (
 
 // @host      localhost:8080
 // @BasePath  /
+
+// @securityDefinitions.apikey ApiKeyAuth
+// @in header
+// @name X-Api-Key
+
+// @securityDefinitions.apikey BearerAuth
+// @in header
+// @name Authorization
+// @description JWT в формате "Bearer <token>"
 func main() {
 	logger := logger.New()
 	err := cfg.Load(".")
@@ -25,6 +50,27 @@ func main() {
 		logger.Fatalf("Error reading config: %s", err)
 	}
 
+	shutdownTracing, err := tracing.Setup(context.Background(), tracing.Config{
+		Exporter:     cfg.Get().TRACING_EXPORTER,
+		OTLPEndpoint: cfg.Get().TRACING_OTLP_ENDPOINT,
+		OTLPInsecure: cfg.Get().TRACING_OTLP_INSECURE,
+		SampleRatio:  cfg.Get().TRACING_SAMPLE_RATIO,
+	})
+
+	if err != nil {
+		logger.Fatalf("Error setting up tracing: %s", err)
+	}
+
+	// Отправляем оставшиеся спаны после остановки сервера
+	defer func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		if err := shutdownTracing(ctx); err != nil {
+			logger.Errorf("Error shutting down tracing: %s", err)
+		}
+	}()
+
 	db, err := db.New(cfg.Get().DB_DSN)
 
 	if err != nil {
@@ -37,8 +83,36 @@ func main() {
 		Addr: fmt.Sprintf("%s:%s", cfg.Get().REDIS_HOST, cfg.Get().REDIS_PORT),
 	}
 
+	// Клиент Redis для лимитов запросов и проверки готовности
+	redisClient := redisOpts.MakeRedisClient().(redis.UniversalClient)
+	defer redisClient.Close()
+
+	inspector := asynq.NewInspector(redisOpts)
+
+	// Статистика пула соединений и очередей снимается в момент сбора метрик
+	prometheus.MustRegister(
+		metrics.NewPoolCollector(db),
+		metrics.NewQueueCollector(inspector, logger),
+	)
+
+	checker := health.NewChecker()
+	checker.Add("db", health.PingDB(db))
+	checker.Add("redis", health.PingRedis(redisClient))
+	checker.Add("worker", health.WorkerHeartbeat(inspector))
+	checker.Add("reports_dir", health.WritableDir(report.Dir))
+
+	segmentsCache, err := newSegmentsCache(logger, redisClient)
+
+	if err != nil {
+		logger.Fatalf("Error configuring cache: %s", err)
+	}
+
 	distributor := worker.NewTaskDistributor(redisOpts, logger)
-	processor := worker.NewTaskProcessor(redisOpts, logger, db)
+	processor := worker.NewTaskProcessor(redisOpts, logger, db, segmentsCache, worker.WebhookConfig{
+		MaxRetries:   cfg.Get().WEBHOOK_MAX_RETRIES,
+		Timeout:      cfg.Get().WEBHOOK_TIMEOUT,
+		AllowPrivate: cfg.Get().WEBHOOK_ALLOW_PRIVATE,
+	})
 
 	// Запускаем обработчик задач в отдельной горутине
 	go func() {
@@ -49,6 +123,106 @@ func main() {
 		}
 	}()
 
-	server := http.New(logger, db, distributor)
+	scheduler, err := worker.NewScheduler(redisOpts, logger, cfg.Get().ANALYTICS_REFRESH_CRON, cfg.Get().WEBHOOK_DISPATCH_CRON)
+
+	if err != nil {
+		logger.Fatalf("Error creating scheduler: %s", err)
+	}
+
+	// Запускаем планировщик периодических задач в отдельной горутине
+	go func() {
+		err := scheduler.Run()
+
+		if err != nil {
+			logger.Fatalf("Error starting scheduler: %s", err)
+		}
+	}()
+
+	// Проверка JWT включается, только если задан JWKS
+	var jwtVerifier *auth.JWTVerifier
+
+	if cfg.Get().JWT_JWKS != "" {
+		jwks, err := auth.NewJWKS(context.Background(), cfg.Get().JWT_JWKS)
+
+		if err != nil {
+			logger.Fatalf("Error loading jwks: %s", err)
+		}
+
+		jwtVerifier = auth.NewJWTVerifier(jwks, auth.JWTConfig{
+			Issuer:          cfg.Get().JWT_ISSUER,
+			Audience:        cfg.Get().JWT_AUDIENCE,
+			ScopesClaim:     cfg.Get().JWT_SCOPES_CLAIM,
+			NamespacesClaim: cfg.Get().JWT_NAMESPACES_CLAIM,
+		})
+	}
+
+	limiter, err := newRateLimiter(redisClient)
+
+	if err != nil {
+		logger.Fatalf("Error configuring rate limit: %s", err)
+	}
+
+	// gRPC сервер работает на тех же сервисах, что и HTTP API
+	segmentService := service.NewSegmentSvc(distributor, repo.NewSegmentRepo(db), repo.NewUserRepo(db), segmentsCache)
+	apiKeyService := service.NewAPIKeySvc(repo.NewAPIKeyRepo(db), cfg.Get().ADMIN_API_KEY)
+
+	grpcServer := grpc.New(logger, segmentService, apiKeyService, jwtVerifier, limiter, checker)
+	go grpcServer.Run(cfg.Get().API_HOST, cfg.Get().GRPC_PORT)
+
+	// Одно соединение с LISTEN на процесс раздает изменения членства всем потокам событий
+	eventsCtx, stopEvents := context.WithCancel(context.Background())
+	defer stopEvents()
+
+	eventsHub := events.NewHub(db, repo.NewSegmentRepo(db), logger)
+	go eventsHub.Run(eventsCtx)
+
+	server := http.New(logger, db, distributor, jwtVerifier, limiter, segmentsCache, eventsHub, checker)
 	server.Run(cfg.Get().API_HOST, cfg.Get().API_PORT)
+
+	// HTTP сервер останавливается по сигналу, после него останавливаем gRPC
+	grpcServer.Stop(20 * time.Second)
+}
+
+// newRateLimiter возвращает ограничитель запросов по конфигу или nil, если он выключен.
+// В режиме redis используется тот же Redis, что и для очереди задач.
+func newRateLimiter(redisClient redis.UniversalClient) (*ratelimit.Limiter, error) {
+	rules, err := ratelimit.ParseRules(cfg.Get().RATE_LIMIT_DEFAULT, cfg.Get().RATE_LIMIT_ROUTES)
+
+	if err != nil {
+		return nil, err
+	}
+
+	rules.IP, err = ratelimit.ParseLimit(cfg.Get().RATE_LIMIT_IP)
+
+	if err != nil {
+		return nil, err
+	}
+
+	switch cfg.Get().RATE_LIMIT_MODE {
+	case ratelimit.ModeOff:
+		return nil, nil
+	case ratelimit.ModeMemory:
+		return ratelimit.NewLimiter(ratelimit.NewMemoryStore(), rules), nil
+	case ratelimit.ModeRedis:
+		return ratelimit.NewLimiter(ratelimit.NewRedisStore(redisClient), rules), nil
+	}
+
+	return nil, fmt.Errorf("%w: %s", ratelimit.ErrUnknownMode, cfg.Get().RATE_LIMIT_MODE)
+}
+
+// newSegmentsCache возвращает кеш сегментов пользователя по конфигу или nil, если он выключен.
+// В режиме redis перед общим кешем в Redis остается кеш в памяти процесса.
+func newSegmentsCache(logger *zap.SugaredLogger, redisClient redis.UniversalClient) (*cache.UserSegments, error) {
+	memory := cache.NewMemoryStore(cfg.Get().CACHE_SIZE, cfg.Get().CACHE_TTL)
+
+	switch cfg.Get().CACHE_MODE {
+	case cache.ModeOff:
+		return nil, nil
+	case cache.ModeMemory:
+		return cache.New(logger, memory), nil
+	case cache.ModeRedis:
+		return cache.New(logger, memory, cache.NewRedisStore(redisClient, cfg.Get().CACHE_TTL)), nil
+	}
+
+	return nil, fmt.Errorf("%w: %s", cache.ErrUnknownMode, cfg.Get().CACHE_MODE)
 }
@@ -0,0 +1,24 @@
+package accesslog
+
+import "context"
+
+// Entry собирает поля строки access log, которые известны только обработчику запроса.
+type Entry struct {
+	UserID int64
+}
+
+type entryKey struct{}
+
+// WithEntry добавляет в контекст пустую запись, которую заполняют обработчики.
+func WithEntry(ctx context.Context) (context.Context, *Entry) {
+	entry := &Entry{}
+	return context.WithValue(ctx, entryKey{}, entry), entry
+}
+
+// SetUserID сохраняет id пользователя, с которым работает запрос.
+// Без записи в контексте ничего не делает.
+func SetUserID(ctx context.Context, userId int64) {
+	if entry, ok := ctx.Value(entryKey{}).(*Entry); ok {
+		entry.UserID = userId
+	}
+}
@@ -0,0 +1,232 @@
+package apierror
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/dezzerlol/avitotech-test-2023/internal/auth"
+	"github.com/dezzerlol/avitotech-test-2023/internal/events"
+	"github.com/dezzerlol/avitotech-test-2023/internal/repo"
+	"github.com/dezzerlol/avitotech-test-2023/internal/webhook"
+	"github.com/dezzerlol/avitotech-test-2023/pkg/payload"
+	"github.com/go-chi/chi/v5/middleware"
+)
+
+// Code - стабильный машиночитаемый код ошибки. Коды не меняются между версиями API,
+// в отличие от текста сообщения.
+type Code string
+
+const (
+	CodeInvalidRequest   Code = "INVALID_REQUEST"
+	CodeValidationFailed Code = "VALIDATION_FAILED"
+	CodeRouteNotFound    Code = "ROUTE_NOT_FOUND"
+	CodeMethodNotAllowed Code = "METHOD_NOT_ALLOWED"
+
+	CodeUnauthorized       Code = "UNAUTHORIZED"
+	CodeInvalidAPIKey      Code = "INVALID_API_KEY"
+	CodeInvalidToken       Code = "INVALID_TOKEN"
+	CodeForbidden          Code = "FORBIDDEN"
+	CodeNamespaceForbidden Code = "NAMESPACE_FORBIDDEN"
+	CodeRateLimitExceeded  Code = "RATE_LIMIT_EXCEEDED"
+	CodeSegmentNotFound    Code = "SEGMENT_NOT_FOUND"
+	CodeSegmentExists      Code = "SEGMENT_ALREADY_EXISTS"
+	CodeUserNotFound       Code = "USER_NOT_FOUND"
+	CodeNamespaceNotFound  Code = "NAMESPACE_NOT_FOUND"
+	CodeNamespaceExists    Code = "NAMESPACE_ALREADY_EXISTS"
+	CodeAPIKeyNotFound     Code = "API_KEY_NOT_FOUND"
+	CodeWebhookNotFound    Code = "WEBHOOK_NOT_FOUND"
+	CodeWebhookForbidden   Code = "WEBHOOK_URL_FORBIDDEN"
+	CodeLayerNotFound      Code = "LAYER_NOT_FOUND"
+	CodeLayerExists        Code = "LAYER_ALREADY_EXISTS"
+	CodeLayerFull          Code = "LAYER_FULL"
+	CodeLayerConflict      Code = "LAYER_CONFLICT"
+	CodeVariantNotFound    Code = "VARIANT_NOT_FOUND"
+	CodeVariantRemoved     Code = "VARIANT_REMOVED"
+	CodeNoVariants         Code = "SEGMENT_HAS_NO_VARIANTS"
+	CodeUserNotInSegment   Code = "USER_NOT_IN_SEGMENT"
+	CodeReportNotFound     Code = "REPORT_NOT_FOUND"
+	CodeNotFound           Code = "NOT_FOUND"
+	CodeAlreadyExists      Code = "ALREADY_EXISTS"
+	CodeTimeout            Code = "TIMEOUT"
+	CodeCanceled           Code = "CANCELED"
+	CodeUnavailable        Code = "UNAVAILABLE"
+	CodeInternal           Code = "INTERNAL"
+)
+
+// Error - ошибка API с HTTP статусом и кодом.
+type Error struct {
+	Status  int
+	Code    Code
+	Message string
+	// Ошибки отдельных полей для VALIDATION_FAILED
+	Details []payload.ValidationError
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+func New(status int, code Code, message string) *Error {
+	return &Error{Status: status, Code: code, Message: message}
+}
+
+// BadRequest - некорректное тело, параметр пути или query параметр.
+func BadRequest(err error) *Error {
+	return New(http.StatusBadRequest, CodeInvalidRequest, err.Error())
+}
+
+// Validation - тело запроса не прошло валидацию.
+func Validation(errs []payload.ValidationError) *Error {
+	return &Error{
+		Status:  http.StatusBadRequest,
+		Code:    CodeValidationFailed,
+		Message: "request validation failed",
+		Details: errs,
+	}
+}
+
+// Соответствие ошибок repo и auth кодам API. Все обработчики получают статус и код отсюда.
+var known = []struct {
+	err    error
+	status int
+	code   Code
+}{
+	{repo.ErrSegmentNotFound, http.StatusNotFound, CodeSegmentNotFound},
+	{repo.ErrSegmentAlreadyExists, http.StatusConflict, CodeSegmentExists},
+	{repo.ErrUserNotFound, http.StatusNotFound, CodeUserNotFound},
+	{repo.ErrNamespaceNotFound, http.StatusNotFound, CodeNamespaceNotFound},
+	{repo.ErrNamespaceAlreadyExists, http.StatusConflict, CodeNamespaceExists},
+	{repo.ErrAPIKeyNotFound, http.StatusNotFound, CodeAPIKeyNotFound},
+	{repo.ErrWebhookNotFound, http.StatusNotFound, CodeWebhookNotFound},
+	{webhook.ErrForbiddenAddress, http.StatusBadRequest, CodeWebhookForbidden},
+	{repo.ErrLayerNotFound, http.StatusNotFound, CodeLayerNotFound},
+	{repo.ErrLayerAlreadyExists, http.StatusConflict, CodeLayerExists},
+	{repo.ErrLayerFull, http.StatusConflict, CodeLayerFull},
+	{repo.ErrLayerConflict, http.StatusConflict, CodeLayerConflict},
+	{repo.ErrVariantNotFound, http.StatusNotFound, CodeVariantNotFound},
+	{repo.ErrVariantRemoved, http.StatusBadRequest, CodeVariantRemoved},
+	{repo.ErrSegmentHasNoVariants, http.StatusBadRequest, CodeNoVariants},
+	{repo.ErrUserNotInSegment, http.StatusNotFound, CodeUserNotInSegment},
+	{repo.ErrNotFound, http.StatusNotFound, CodeNotFound},
+	{repo.ErrAlreadyExists, http.StatusConflict, CodeAlreadyExists},
+	{auth.ErrInvalidToken, http.StatusUnauthorized, CodeInvalidToken},
+	{context.DeadlineExceeded, http.StatusGatewayTimeout, CodeTimeout},
+	{context.Canceled, StatusClientClosedRequest, CodeCanceled},
+	{events.ErrUnavailable, http.StatusServiceUnavailable, CodeUnavailable},
+}
+
+// FromError возвращает ошибку API для err. Неизвестные ошибки становятся INTERNAL без текста исходной ошибки.
+func FromError(err error) *Error {
+	var apiErr *Error
+
+	if errors.As(err, &apiErr) {
+		return apiErr
+	}
+
+	// Клиенту нужно знать, с каким сегментом конфликт
+	var conflictErr *repo.LayerConflictError
+
+	if errors.As(err, &conflictErr) {
+		return New(http.StatusConflict, CodeLayerConflict, conflictErr.Error())
+	}
+
+	for _, k := range known {
+		if errors.Is(err, k.err) {
+			return New(k.status, k.code, k.err.Error())
+		}
+	}
+
+	return New(http.StatusInternalServerError, CodeInternal, "internal server error")
+}
+
+// Нестандартный статус nginx для запроса, который клиент прервал, не дождавшись ответа.
+// Ответ клиент уже не прочитает, статус нужен для лога и метрик
+const StatusClientClosedRequest = 499
+
+func statusText(status int) string {
+	if status == StatusClientClosedRequest {
+		return "Client Closed Request"
+	}
+
+	return http.StatusText(status)
+}
+
+// Тип проблемы RFC 7807 строится из кода ошибки
+const problemTypePrefix = "urn:avitotech-segments:error:"
+
+const ProblemContentType = "application/problem+json"
+
+// Write пишет ошибку в едином формате:
+//
+//	{"error": {"code": "SEGMENT_NOT_FOUND", "message": "segment not found", "request_id": "..."}}
+//
+// Если клиент указал в Accept application/problem+json, ошибка пишется в формате RFC 7807
+// с кодом в расширении code.
+func Write(w http.ResponseWriter, r *http.Request, err error) {
+	apiErr := FromError(err)
+	requestId := middleware.GetReqID(r.Context())
+
+	if strings.Contains(r.Header.Get("Accept"), ProblemContentType) {
+		writeProblem(w, r, apiErr, requestId)
+		return
+	}
+
+	body := Body{
+		Code:      apiErr.Code,
+		Message:   apiErr.Message,
+		Details:   apiErr.Details,
+		RequestID: requestId,
+	}
+
+	payload.WriteJSON(w, apiErr.Status, payload.Data{"error": body}, nil)
+}
+
+// Body описывает ошибку в ответе. Для swagger: ответ с ошибкой - Response.
+type Body struct {
+	Code      Code                      `json:"code" example:"SEGMENT_NOT_FOUND"`
+	Message   string                    `json:"message" example:"segment not found"`
+	Details   []payload.ValidationError `json:"details,omitempty"`
+	RequestID string                    `json:"request_id,omitempty"`
+}
+
+type Response struct {
+	Error Body `json:"error"`
+}
+
+// Problem - ошибка в формате RFC 7807.
+type Problem struct {
+	Type      string                    `json:"type"`
+	Title     string                    `json:"title"`
+	Status    int                       `json:"status"`
+	Detail    string                    `json:"detail"`
+	Instance  string                    `json:"instance"`
+	Code      Code                      `json:"code"`
+	Errors    []payload.ValidationError `json:"errors,omitempty"`
+	RequestID string                    `json:"request_id,omitempty"`
+}
+
+func writeProblem(w http.ResponseWriter, r *http.Request, apiErr *Error, requestId string) {
+	problem := Problem{
+		Type:      problemTypePrefix + string(apiErr.Code),
+		Title:     statusText(apiErr.Status),
+		Status:    apiErr.Status,
+		Detail:    apiErr.Message,
+		Instance:  r.URL.Path,
+		Code:      apiErr.Code,
+		Errors:    apiErr.Details,
+		RequestID: requestId,
+	}
+
+	res, err := json.Marshal(problem)
+
+	if err != nil {
+		return
+	}
+
+	w.Header().Set("Content-Type", ProblemContentType)
+	w.WriteHeader(apiErr.Status)
+	w.Write(res)
+}
@@ -0,0 +1,103 @@
+package apierror
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/dezzerlol/avitotech-test-2023/internal/repo"
+	"github.com/dezzerlol/avitotech-test-2023/pkg/payload"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_FromError(t *testing.T) {
+	tests := []struct {
+		name   string
+		err    error
+		status int
+		code   Code
+	}{
+		{"segment not found", repo.ErrSegmentNotFound, http.StatusNotFound, CodeSegmentNotFound},
+		{"wrapped user not found", fmt.Errorf("get user: %w", repo.ErrUserNotFound), http.StatusNotFound, CodeUserNotFound},
+		{"segment exists", repo.ErrSegmentAlreadyExists, http.StatusConflict, CodeSegmentExists},
+		{"namespace exists", repo.ErrNamespaceAlreadyExists, http.StatusConflict, CodeNamespaceExists},
+		{"timeout", context.DeadlineExceeded, http.StatusGatewayTimeout, CodeTimeout},
+		{"canceled", fmt.Errorf("query: %w", context.Canceled), StatusClientClosedRequest, CodeCanceled},
+		{"layer full", repo.ErrLayerFull, http.StatusConflict, CodeLayerFull},
+		{"layer conflict", &repo.LayerConflictError{Layer: "checkout", Segment: "B", Conflicting: "A"}, http.StatusConflict, CodeLayerConflict},
+		{"variant removed", repo.ErrVariantRemoved, http.StatusBadRequest, CodeVariantRemoved},
+		{"user not in segment", repo.ErrUserNotInSegment, http.StatusNotFound, CodeUserNotInSegment},
+		{"api error", New(http.StatusTooManyRequests, CodeRateLimitExceeded, "rate limit exceeded"), http.StatusTooManyRequests, CodeRateLimitExceeded},
+		{"unknown", errors.New("connection refused"), http.StatusInternalServerError, CodeInternal},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			apiErr := FromError(tc.err)
+
+			require.Equal(t, tc.status, apiErr.Status)
+			require.Equal(t, tc.code, apiErr.Code)
+		})
+	}
+
+	// Конфликт слоя сообщает, с каким сегментом конфликт
+	require.Equal(t, "segment B conflicts with segment A in layer checkout", FromError(&repo.LayerConflictError{Layer: "checkout", Segment: "B", Conflicting: "A"}).Message)
+
+	// Текст неизвестных ошибок не отдается клиенту
+	require.Equal(t, "internal server error", FromError(errors.New("connection refused")).Message)
+}
+
+func Test_Write(t *testing.T) {
+	newRequest := func(accept string) *http.Request {
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/segment/user/1", nil)
+		req.Header.Set("Accept", accept)
+
+		return req.WithContext(context.WithValue(req.Context(), middleware.RequestIDKey, "req-1"))
+	}
+
+	t.Run("Should write error envelope", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		Write(w, newRequest("application/json"), repo.ErrSegmentNotFound)
+
+		require.Equal(t, http.StatusNotFound, w.Code)
+
+		var res Response
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &res))
+		require.Equal(t, CodeSegmentNotFound, res.Error.Code)
+		require.Equal(t, repo.ErrSegmentNotFound.Error(), res.Error.Message)
+		require.Equal(t, "req-1", res.Error.RequestID)
+	})
+
+	t.Run("Should write validation details", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		Write(w, newRequest(""), Validation([]payload.ValidationError{{Field: "slug", Message: "min"}}))
+
+		require.Equal(t, http.StatusBadRequest, w.Code)
+
+		var res Response
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &res))
+		require.Equal(t, CodeValidationFailed, res.Error.Code)
+		require.Len(t, res.Error.Details, 1)
+	})
+
+	t.Run("Should write problem+json", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		Write(w, newRequest(ProblemContentType), repo.ErrUserNotFound)
+
+		require.Equal(t, http.StatusNotFound, w.Code)
+		require.Equal(t, ProblemContentType, w.Header().Get("Content-Type"))
+
+		var problem Problem
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &problem))
+		require.Equal(t, "urn:avitotech-segments:error:USER_NOT_FOUND", problem.Type)
+		require.Equal(t, http.StatusNotFound, problem.Status)
+		require.Equal(t, CodeUserNotFound, problem.Code)
+		require.Equal(t, "/api/v1/segment/user/1", problem.Instance)
+		require.Equal(t, "req-1", problem.RequestID)
+	})
+}
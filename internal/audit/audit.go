@@ -0,0 +1,38 @@
+package audit
+
+import "context"
+
+// Источник изменения членства пользователя в сегменте
+const (
+	SourceAPI           = "api"            // ручное добавление/удаление через API
+	SourceTTL           = "ttl"            // удаление по истечении ttl
+	SourceSegmentDelete = "segment_delete" // удаление сегмента целиком
+	SourceRollout       = "rollout"        // добавление случайному проценту пользователей
+)
+
+// Meta описывает, кто и почему изменил членство в сегменте.
+// Сохраняется в user_segment_history вместе с операцией.
+type Meta struct {
+	Source    string
+	Actor     string
+	RequestID string
+}
+
+type metaKey struct{}
+
+func WithMeta(ctx context.Context, meta Meta) context.Context {
+	return context.WithValue(ctx, metaKey{}, meta)
+}
+
+func FromContext(ctx context.Context) Meta {
+	meta, _ := ctx.Value(metaKey{}).(Meta)
+	return meta
+}
+
+// WithSource задает источник изменения, сохраняя actor и request_id.
+func WithSource(ctx context.Context, source string) context.Context {
+	meta := FromContext(ctx)
+	meta.Source = source
+
+	return WithMeta(ctx, meta)
+}
@@ -0,0 +1,49 @@
+package audit
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"strings"
+)
+
+// Ограничения совпадают с размерами колонок в user_segment_history
+const (
+	MaxActorLen     = 255
+	MaxRequestIDLen = 64
+)
+
+// RequestID возвращает id запроса клиента или новый id, если клиент его не передал.
+// id клиента принимается, только если он помещается в колонку истории и не содержит управляющих символов.
+func RequestID(clientID string) string {
+	if validRequestID(clientID) {
+		return clientID
+	}
+
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+
+	return hex.EncodeToString(b)
+}
+
+func validRequestID(id string) bool {
+	if id == "" || len(id) > MaxRequestIDLen {
+		return false
+	}
+
+	for _, c := range id {
+		if c < 0x21 || c > 0x7e {
+			return false
+		}
+	}
+
+	return true
+}
+
+// TruncateActor обрезает actor до размера колонки истории.
+func TruncateActor(actor string) string {
+	if len(actor) > MaxActorLen {
+		return strings.ToValidUTF8(actor[:MaxActorLen], "")
+	}
+
+	return actor
+}
@@ -0,0 +1,92 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"slices"
+)
+
+// Права API ключей
+const (
+	ScopeSegmentsRead  = "segments:read"  // чтение сегментов пользователя, истории и аналитики
+	ScopeSegmentsWrite = "segments:write" // создание и удаление сегментов
+	ScopeUsersWrite    = "users:write"    // создание пользователей и изменение их сегментов
+	ScopeReportsRead   = "reports:read"   // создание, выгрузка и скачивание отчетов
+	ScopeKeysAdmin     = "keys:admin"     // выпуск, ротация и отзыв API ключей
+	ScopeWebhooksAdmin = "webhooks:admin" // регистрация, проверка и удаление вебхуков
+)
+
+var Scopes = []string{
+	ScopeSegmentsRead,
+	ScopeSegmentsWrite,
+	ScopeUsersWrite,
+	ScopeReportsRead,
+	ScopeKeysAdmin,
+	ScopeWebhooksAdmin,
+}
+
+// Префикс ключа, чтобы его было легко найти в логах и конфигах
+const keyPrefix = "seg_"
+
+// Способ аутентификации запроса
+const (
+	MethodAPIKey = "api_key"
+	MethodJWT    = "jwt"
+)
+
+// Identity описывает API ключ или JWT, с которым выполняется запрос.
+type Identity struct {
+	Method string
+	// id API ключа, для JWT не заполняется
+	KeyID int64
+	// Имя API ключа или sub токена
+	Name   string
+	Scopes []string
+	// Пространства имен, к которым есть доступ. Пустой список - доступ ко всем
+	Namespaces []string
+}
+
+func (i Identity) HasScope(scope string) bool {
+	return slices.Contains(i.Scopes, scope)
+}
+
+func (i Identity) HasNamespace(ns string) bool {
+	return len(i.Namespaces) == 0 || slices.Contains(i.Namespaces, ns)
+}
+
+type identityKey struct{}
+
+func WithIdentity(ctx context.Context, identity Identity) context.Context {
+	return context.WithValue(ctx, identityKey{}, identity)
+}
+
+// FromContext возвращает ключ запроса. ok равен false, если запрос выполняется без ключа (например, воркером).
+func FromContext(ctx context.Context) (Identity, bool) {
+	identity, ok := ctx.Value(identityKey{}).(Identity)
+	return identity, ok
+}
+
+func ValidScope(scope string) bool {
+	return slices.Contains(Scopes, scope)
+}
+
+// GenerateKey создает новый ключ. В базе хранится только его хэш.
+func GenerateKey() (string, error) {
+	b := make([]byte, 32)
+
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+
+	return keyPrefix + base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// HashKey возвращает sha256 ключа. Ключи случайные и длинные, поэтому медленный хэш не нужен,
+// а детерминированный хэш позволяет искать ключ по индексу.
+func HashKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
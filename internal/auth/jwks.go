@@ -0,0 +1,255 @@
+package auth
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Не чаще этого интервала JWKS по URL перечитывается при неизвестном kid, в том числе после неудачной попытки
+const jwksRefreshInterval = time.Minute
+
+var (
+	ErrUnknownKey = errors.New("unknown signing key")
+	ErrNoKeys     = errors.New("jwks has no usable keys")
+)
+
+type jwk struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	// RSA
+	N string `json:"n"`
+	E string `json:"e"`
+	// EC
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+// JWKS хранит открытые ключи для проверки JWT. Источник - путь к файлу или http(s) URL.
+// Ключи по URL перечитываются, когда приходит токен с неизвестным kid.
+type JWKS struct {
+	source string
+	client *http.Client
+
+	mu   sync.RWMutex
+	keys map[string]crypto.PublicKey
+	// Время последней попытки перечитать ключи, успешной или нет
+	refreshedAt time.Time
+}
+
+func NewJWKS(ctx context.Context, source string) (*JWKS, error) {
+	jwks := &JWKS{
+		source:      source,
+		client:      &http.Client{Timeout: 10 * time.Second},
+		refreshedAt: time.Now(),
+	}
+
+	if err := jwks.load(ctx); err != nil {
+		return nil, fmt.Errorf("load jwks %s: %w", source, err)
+	}
+
+	return jwks, nil
+}
+
+// Key возвращает ключ по kid. Если kid пустой и ключ в наборе один, возвращается он.
+func (j *JWKS) Key(ctx context.Context, kid string) (crypto.PublicKey, error) {
+	if key, ok := j.lookup(kid); ok {
+		return key, nil
+	}
+
+	if !j.isURL() {
+		return nil, ErrUnknownKey
+	}
+
+	// Иначе токены с произвольным kid заставляли бы перечитывать JWKS на каждый запрос
+	if !j.startRefresh() {
+		return nil, ErrUnknownKey
+	}
+
+	if err := j.load(ctx); err != nil {
+		return nil, err
+	}
+
+	if key, ok := j.lookup(kid); ok {
+		return key, nil
+	}
+
+	return nil, ErrUnknownKey
+}
+
+// startRefresh отмечает попытку перечитать ключи. Возвращает false, если прошлая попытка была меньше jwksRefreshInterval назад.
+func (j *JWKS) startRefresh() bool {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	if time.Since(j.refreshedAt) < jwksRefreshInterval {
+		return false
+	}
+
+	j.refreshedAt = time.Now()
+
+	return true
+}
+
+func (j *JWKS) lookup(kid string) (crypto.PublicKey, bool) {
+	j.mu.RLock()
+	defer j.mu.RUnlock()
+
+	if kid == "" && len(j.keys) == 1 {
+		for _, key := range j.keys {
+			return key, true
+		}
+	}
+
+	key, ok := j.keys[kid]
+	return key, ok
+}
+
+func (j *JWKS) isURL() bool {
+	return strings.HasPrefix(j.source, "http://") || strings.HasPrefix(j.source, "https://")
+}
+
+func (j *JWKS) load(ctx context.Context) error {
+	data, err := j.read(ctx)
+
+	if err != nil {
+		return err
+	}
+
+	var set struct {
+		Keys []jwk `json:"keys"`
+	}
+
+	if err := json.Unmarshal(data, &set); err != nil {
+		return err
+	}
+
+	keys := make(map[string]crypto.PublicKey, len(set.Keys))
+
+	for _, k := range set.Keys {
+		// Ключи шифрования для проверки подписи не подходят
+		if k.Use != "" && k.Use != "sig" {
+			continue
+		}
+
+		// Ключи неподдерживаемых типов (например OKP) и некорректные ключи пропускаются,
+		// остальные ключи набора остаются рабочими
+		key, err := k.publicKey()
+
+		if err != nil {
+			continue
+		}
+
+		keys[k.Kid] = key
+	}
+
+	if len(keys) == 0 {
+		return ErrNoKeys
+	}
+
+	j.mu.Lock()
+	j.keys = keys
+	j.mu.Unlock()
+
+	return nil
+}
+
+func (j *JWKS) read(ctx context.Context) ([]byte, error) {
+	if !j.isURL() {
+		return os.ReadFile(j.source)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, j.source, nil)
+
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := j.client.Do(req)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+
+	return io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+}
+
+func (k jwk) publicKey() (crypto.PublicKey, error) {
+	switch k.Kty {
+	case "RSA":
+		n, err := decodeBigInt(k.N)
+		if err != nil {
+			return nil, err
+		}
+
+		e, err := decodeBigInt(k.E)
+		if err != nil {
+			return nil, err
+		}
+
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+	case "EC":
+		var curve elliptic.Curve
+
+		switch k.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, fmt.Errorf("unsupported curve %q", k.Crv)
+		}
+
+		x, err := decodeBigInt(k.X)
+		if err != nil {
+			return nil, err
+		}
+
+		y, err := decodeBigInt(k.Y)
+		if err != nil {
+			return nil, err
+		}
+
+		if !curve.IsOnCurve(x, y) {
+			return nil, errors.New("point is not on curve")
+		}
+
+		return &ecdsa.PublicKey{Curve: curve, X: x, Y: y}, nil
+	}
+
+	return nil, fmt.Errorf("unsupported key type %q", k.Kty)
+}
+
+func decodeBigInt(s string) (*big.Int, error) {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return new(big.Int).SetBytes(b), nil
+}
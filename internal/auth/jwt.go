@@ -0,0 +1,127 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// Права, которые можно получить через JWT. Управление ключами доступно только по API ключу.
+var jwtScopes = []string{
+	ScopeSegmentsRead,
+	ScopeSegmentsWrite,
+	ScopeUsersWrite,
+	ScopeReportsRead,
+	ScopeWebhooksAdmin,
+}
+
+var ErrInvalidToken = errors.New("invalid token")
+
+type JWTConfig struct {
+	// Ожидаемые iss и aud, пустое значение не проверяется
+	Issuer   string
+	Audience string
+	// Claim с правами: строка через пробел (как scope в OAuth2) или массив строк
+	ScopesClaim string
+	// Claim с пространствами имен в том же формате. Если claim нет, доступны все пространства имен
+	NamespacesClaim string
+}
+
+// JWTVerifier проверяет подпись и claims токена и переводит их в Identity.
+type JWTVerifier struct {
+	jwks   *JWKS
+	cfg    JWTConfig
+	parser *jwt.Parser
+}
+
+func NewJWTVerifier(jwks *JWKS, cfg JWTConfig) *JWTVerifier {
+	if cfg.ScopesClaim == "" {
+		cfg.ScopesClaim = "scope"
+	}
+
+	if cfg.NamespacesClaim == "" {
+		cfg.NamespacesClaim = "namespaces"
+	}
+
+	opts := []jwt.ParserOption{
+		// Только асимметричные алгоритмы, иначе открытый ключ можно использовать как HMAC секрет
+		jwt.WithValidMethods([]string{"RS256", "RS384", "RS512", "PS256", "PS384", "PS512", "ES256", "ES384", "ES512"}),
+		jwt.WithExpirationRequired(),
+	}
+
+	if cfg.Issuer != "" {
+		opts = append(opts, jwt.WithIssuer(cfg.Issuer))
+	}
+
+	if cfg.Audience != "" {
+		opts = append(opts, jwt.WithAudience(cfg.Audience))
+	}
+
+	return &JWTVerifier{
+		jwks:   jwks,
+		cfg:    cfg,
+		parser: jwt.NewParser(opts...),
+	}
+}
+
+// Verify возвращает владельца токена. Для любого невалидного токена возвращается ошибка, обернутая в ErrInvalidToken.
+func (v *JWTVerifier) Verify(ctx context.Context, tokenString string) (Identity, error) {
+	claims := jwt.MapClaims{}
+
+	_, err := v.parser.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (any, error) {
+		kid, _ := token.Header["kid"].(string)
+		return v.jwks.Key(ctx, kid)
+	})
+
+	if err != nil {
+		return Identity{}, fmt.Errorf("%w: %w", ErrInvalidToken, err)
+	}
+
+	subject, err := claims.GetSubject()
+
+	if err != nil || subject == "" {
+		return Identity{}, fmt.Errorf("%w: sub claim is required", ErrInvalidToken)
+	}
+
+	return Identity{
+		Method:     MethodJWT,
+		Name:       subject,
+		Scopes:     v.scopes(claims),
+		Namespaces: claimStrings(claims, v.cfg.NamespacesClaim),
+	}, nil
+}
+
+// scopes оставляет из claim только известные права.
+func (v *JWTVerifier) scopes(claims jwt.MapClaims) []string {
+	var scopes []string
+
+	for _, scope := range claimStrings(claims, v.cfg.ScopesClaim) {
+		if slices.Contains(jwtScopes, scope) && !slices.Contains(scopes, scope) {
+			scopes = append(scopes, scope)
+		}
+	}
+
+	return scopes
+}
+
+// claimStrings читает claim, заданный строкой через пробел или массивом строк.
+func claimStrings(claims jwt.MapClaims, name string) []string {
+	var values []string
+
+	switch value := claims[name].(type) {
+	case string:
+		values = strings.Fields(value)
+	case []any:
+		for _, s := range value {
+			if s, ok := s.(string); ok {
+				values = append(values, s)
+			}
+		}
+	}
+
+	return values
+}
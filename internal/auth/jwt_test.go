@@ -0,0 +1,277 @@
+package auth
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/require"
+)
+
+func encodeBigInt(i *big.Int) string {
+	return base64.RawURLEncoding.EncodeToString(i.Bytes())
+}
+
+func writeJWKS(t *testing.T, rsaKey *rsa.PrivateKey, ecKey *ecdsa.PrivateKey) []byte {
+	set := map[string]any{
+		"keys": []map[string]string{
+			{
+				"kty": "RSA",
+				"kid": "rsa-1",
+				"use": "sig",
+				"n":   encodeBigInt(rsaKey.N),
+				"e":   encodeBigInt(big.NewInt(int64(rsaKey.E))),
+			},
+			{
+				"kty": "EC",
+				"kid": "ec-1",
+				"crv": "P-256",
+				"x":   encodeBigInt(ecKey.X),
+				"y":   encodeBigInt(ecKey.Y),
+			},
+		},
+	}
+
+	data, err := json.Marshal(set)
+	require.NoError(t, err)
+
+	return data
+}
+
+func signToken(t *testing.T, method jwt.SigningMethod, kid string, key any, claims jwt.MapClaims) string {
+	token := jwt.NewWithClaims(method, claims)
+	token.Header["kid"] = kid
+
+	signed, err := token.SignedString(key)
+	require.NoError(t, err)
+
+	return signed
+}
+
+func Test_JWTVerifier(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	otherKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	path := filepath.Join(t.TempDir(), "jwks.json")
+	require.NoError(t, os.WriteFile(path, writeJWKS(t, rsaKey, ecKey), 0o600))
+
+	jwks, err := NewJWKS(context.Background(), path)
+	require.NoError(t, err)
+
+	verifier := NewJWTVerifier(jwks, JWTConfig{Issuer: "https://auth.local", Audience: "segments"})
+
+	claims := func(modify func(jwt.MapClaims)) jwt.MapClaims {
+		c := jwt.MapClaims{
+			"sub":   "billing-service",
+			"iss":   "https://auth.local",
+			"aud":   "segments",
+			"exp":   time.Now().Add(time.Hour).Unix(),
+			"scope": "segments:read users:write keys:admin unknown",
+		}
+
+		if modify != nil {
+			modify(c)
+		}
+
+		return c
+	}
+
+	t.Run("Should map claims to identity", func(t *testing.T) {
+		for _, token := range []string{
+			signToken(t, jwt.SigningMethodRS256, "rsa-1", rsaKey, claims(nil)),
+			signToken(t, jwt.SigningMethodES256, "ec-1", ecKey, claims(nil)),
+		} {
+			identity, err := verifier.Verify(context.Background(), token)
+			require.NoError(t, err)
+
+			require.Equal(t, MethodJWT, identity.Method)
+			require.Equal(t, "billing-service", identity.Name)
+			// keys:admin и неизвестные права через JWT не выдаются
+			require.Equal(t, []string{ScopeSegmentsRead, ScopeUsersWrite}, identity.Scopes)
+		}
+	})
+
+	t.Run("Should accept scopes as array", func(t *testing.T) {
+		token := signToken(t, jwt.SigningMethodRS256, "rsa-1", rsaKey, claims(func(c jwt.MapClaims) {
+			c["scope"] = []string{ScopeReportsRead}
+		}))
+
+		identity, err := verifier.Verify(context.Background(), token)
+		require.NoError(t, err)
+		require.Equal(t, []string{ScopeReportsRead}, identity.Scopes)
+	})
+
+	t.Run("Should map namespaces claim", func(t *testing.T) {
+		token := signToken(t, jwt.SigningMethodRS256, "rsa-1", rsaKey, claims(func(c jwt.MapClaims) {
+			c["namespaces"] = []string{"payments", "ads"}
+		}))
+
+		identity, err := verifier.Verify(context.Background(), token)
+		require.NoError(t, err)
+		require.True(t, identity.HasNamespace("payments"))
+		require.False(t, identity.HasNamespace("default"))
+
+		// Без claim доступны все пространства имен
+		identity, err = verifier.Verify(context.Background(), signToken(t, jwt.SigningMethodRS256, "rsa-1", rsaKey, claims(nil)))
+		require.NoError(t, err)
+		require.True(t, identity.HasNamespace("default"))
+	})
+
+	t.Run("Should reject invalid tokens", func(t *testing.T) {
+		tokens := map[string]string{
+			"expired": signToken(t, jwt.SigningMethodRS256, "rsa-1", rsaKey, claims(func(c jwt.MapClaims) {
+				c["exp"] = time.Now().Add(-time.Minute).Unix()
+			})),
+			"without exp": signToken(t, jwt.SigningMethodRS256, "rsa-1", rsaKey, claims(func(c jwt.MapClaims) {
+				delete(c, "exp")
+			})),
+			"wrong issuer": signToken(t, jwt.SigningMethodRS256, "rsa-1", rsaKey, claims(func(c jwt.MapClaims) {
+				c["iss"] = "https://evil.local"
+			})),
+			"wrong audience": signToken(t, jwt.SigningMethodRS256, "rsa-1", rsaKey, claims(func(c jwt.MapClaims) {
+				c["aud"] = "billing"
+			})),
+			"without sub": signToken(t, jwt.SigningMethodRS256, "rsa-1", rsaKey, claims(func(c jwt.MapClaims) {
+				delete(c, "sub")
+			})),
+			"unknown kid":    signToken(t, jwt.SigningMethodRS256, "rsa-2", rsaKey, claims(nil)),
+			"wrong key":      signToken(t, jwt.SigningMethodRS256, "rsa-1", otherKey, claims(nil)),
+			"hmac algorithm": signToken(t, jwt.SigningMethodHS256, "rsa-1", []byte("secret"), claims(nil)),
+			"garbage":        "not.a.token",
+		}
+
+		for name, token := range tokens {
+			_, err := verifier.Verify(context.Background(), token)
+			require.True(t, errors.Is(err, ErrInvalidToken), name)
+		}
+	})
+}
+
+func Test_JWKSFromURL(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	data := writeJWKS(t, rsaKey, ecKey)
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write(data)
+	}))
+	defer srv.Close()
+
+	jwks, err := NewJWKS(context.Background(), srv.URL)
+	require.NoError(t, err)
+
+	key, err := jwks.Key(context.Background(), "ec-1")
+	require.NoError(t, err)
+	require.True(t, ecKey.PublicKey.Equal(key))
+
+	_, err = jwks.Key(context.Background(), "unknown")
+	require.ErrorIs(t, err, ErrUnknownKey)
+}
+
+func Test_JWKSUnsupportedKeys(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	okp := map[string]string{"kty": "OKP", "kid": "ed-1", "crv": "Ed25519", "x": "11qYAYKxCrfVS_7TyWQHOg7hcvPapiMlrwIaaPcHURo"}
+	rsaJWK := map[string]string{"kty": "RSA", "kid": "rsa-1", "n": encodeBigInt(rsaKey.N), "e": encodeBigInt(big.NewInt(int64(rsaKey.E)))}
+
+	load := func(keys ...map[string]string) (*JWKS, error) {
+		data, err := json.Marshal(map[string]any{"keys": keys})
+		require.NoError(t, err)
+
+		path := filepath.Join(t.TempDir(), "jwks.json")
+		require.NoError(t, os.WriteFile(path, data, 0o600))
+
+		return NewJWKS(context.Background(), path)
+	}
+
+	t.Run("Should skip unsupported keys", func(t *testing.T) {
+		jwks, err := load(okp, rsaJWK)
+		require.NoError(t, err)
+
+		key, err := jwks.Key(context.Background(), "rsa-1")
+		require.NoError(t, err)
+		require.True(t, rsaKey.PublicKey.Equal(key))
+
+		_, err = jwks.Key(context.Background(), "ed-1")
+		require.ErrorIs(t, err, ErrUnknownKey)
+	})
+
+	t.Run("Should fail without usable keys", func(t *testing.T) {
+		_, err := load(okp)
+		require.ErrorIs(t, err, ErrNoKeys)
+	})
+}
+
+func Test_JWKSRefreshInterval(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	data := writeJWKS(t, rsaKey, ecKey)
+
+	var requests atomic.Int32
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// После первой загрузки поставщик ключей недоступен
+		if requests.Add(1) > 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+
+		w.Write(data)
+	}))
+	defer srv.Close()
+
+	jwks, err := NewJWKS(context.Background(), srv.URL)
+	require.NoError(t, err)
+
+	// Сразу после загрузки неизвестный kid не перечитывает ключи
+	_, err = jwks.Key(context.Background(), "garbage")
+	require.ErrorIs(t, err, ErrUnknownKey)
+	require.Equal(t, int32(1), requests.Load())
+
+	jwks.refreshedAt = time.Now().Add(-jwksRefreshInterval)
+
+	_, err = jwks.Key(context.Background(), "garbage")
+	require.Error(t, err)
+	require.Equal(t, int32(2), requests.Load())
+
+	// Неудачная попытка тоже откладывает следующую
+	for i := 0; i < 10; i++ {
+		_, err = jwks.Key(context.Background(), "garbage")
+		require.ErrorIs(t, err, ErrUnknownKey)
+	}
+
+	require.Equal(t, int32(2), requests.Load())
+
+	// Известные ключи продолжают работать
+	_, err = jwks.Key(context.Background(), "rsa-1")
+	require.NoError(t, err)
+}
@@ -0,0 +1,140 @@
+package cache
+
+import (
+	"context"
+	"errors"
+
+	"github.com/dezzerlol/avitotech-test-2023/internal/db/models"
+	"github.com/dezzerlol/avitotech-test-2023/internal/metrics"
+	"go.uber.org/zap"
+)
+
+// Режимы кеша сегментов пользователя
+const (
+	ModeOff    = "off"
+	ModeMemory = "memory"
+	// Кеш в памяти процесса и общий кеш в Redis
+	ModeRedis = "redis"
+)
+
+var ErrUnknownMode = errors.New("unknown cache mode")
+
+// Причины сброса кеша
+const (
+	ReasonUserUpdate    = "user_update"
+	ReasonSegmentExpire = "segment_expire"
+	ReasonSegmentDelete = "segment_delete"
+	ReasonRollout       = "rollout"
+	// Изменились атрибуты пользователя, от которых зависят динамические сегменты
+	ReasonUserAttributes = "user_attributes"
+	// Создан динамический сегмент
+	ReasonRuleSegment = "rule_segment"
+)
+
+// Store хранит списки сегментов пользователей в пределах пространства имен.
+type Store interface {
+	// Tier возвращает название уровня кеша для метрик
+	Tier() string
+	Get(ctx context.Context, ns string, userId int64) (segments []*models.Segment, ok bool, err error)
+	Set(ctx context.Context, ns string, userId int64, segments []*models.Segment) error
+	DeleteUser(ctx context.Context, ns string, userId int64) error
+	DeleteNamespace(ctx context.Context, ns string) error
+}
+
+// UserSegments - кеш сегментов пользователя из нескольких уровней, от быстрого к медленному.
+// Ошибки уровней не возвращаются вызывающему: запрос уходит в базу, а ошибка пишется в лог.
+// Запись из базы может попасть в кеш уже после сброса, поэтому данные могут устареть не больше чем на TTL уровня.
+// Методы nil кеша ничего не делают, так кеш выключается.
+type UserSegments struct {
+	logger *zap.SugaredLogger
+	stores []Store
+}
+
+func New(logger *zap.SugaredLogger, stores ...Store) *UserSegments {
+	return &UserSegments{
+		logger: logger,
+		stores: stores,
+	}
+}
+
+// Get ищет сегменты пользователя по уровням. Найденное на медленном уровне копируется в более быстрые.
+// Возвращаемый список общий для всех читателей и не должен изменяться.
+func (c *UserSegments) Get(ctx context.Context, ns string, userId int64) ([]*models.Segment, bool) {
+	if c == nil {
+		return nil, false
+	}
+
+	for i, store := range c.stores {
+		segments, ok, err := store.Get(ctx, ns, userId)
+
+		if err != nil {
+			metrics.ObserveCacheRequest(store.Tier(), metrics.CacheError)
+			c.logger.Warnw("error reading segments cache", "tier", store.Tier(), "err", err)
+
+			continue
+		}
+
+		if !ok {
+			metrics.ObserveCacheRequest(store.Tier(), metrics.CacheMiss)
+			continue
+		}
+
+		metrics.ObserveCacheRequest(store.Tier(), metrics.CacheHit)
+
+		for _, faster := range c.stores[:i] {
+			c.set(ctx, faster, ns, userId, segments)
+		}
+
+		return segments, true
+	}
+
+	return nil, false
+}
+
+// Set сохраняет сегменты пользователя на всех уровнях.
+func (c *UserSegments) Set(ctx context.Context, ns string, userId int64, segments []*models.Segment) {
+	if c == nil {
+		return
+	}
+
+	for _, store := range c.stores {
+		c.set(ctx, store, ns, userId, segments)
+	}
+}
+
+func (c *UserSegments) set(ctx context.Context, store Store, ns string, userId int64, segments []*models.Segment) {
+	if err := store.Set(ctx, ns, userId, segments); err != nil {
+		c.logger.Warnw("error writing segments cache", "tier", store.Tier(), "err", err)
+	}
+}
+
+// InvalidateUser сбрасывает сегменты пользователя после изменения его членства.
+func (c *UserSegments) InvalidateUser(ctx context.Context, ns string, userId int64, reason string) {
+	if c == nil {
+		return
+	}
+
+	metrics.AddCacheInvalidation(reason)
+
+	for _, store := range c.stores {
+		if err := store.DeleteUser(ctx, ns, userId); err != nil {
+			c.logger.Warnw("error invalidating segments cache", "tier", store.Tier(), "err", err)
+		}
+	}
+}
+
+// InvalidateNamespace сбрасывает сегменты всех пользователей пространства имен,
+// например после удаления сегмента или добавления в него случайных пользователей.
+func (c *UserSegments) InvalidateNamespace(ctx context.Context, ns string, reason string) {
+	if c == nil {
+		return
+	}
+
+	metrics.AddCacheInvalidation(reason)
+
+	for _, store := range c.stores {
+		if err := store.DeleteNamespace(ctx, ns); err != nil {
+			c.logger.Warnw("error invalidating segments cache", "tier", store.Tier(), "err", err)
+		}
+	}
+}
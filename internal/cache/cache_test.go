@@ -0,0 +1,97 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/dezzerlol/avitotech-test-2023/internal/db/models"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+// failingStore имитирует недоступный Redis.
+type failingStore struct{}
+
+func (failingStore) Tier() string { return "failing" }
+
+func (failingStore) Get(ctx context.Context, ns string, userId int64) ([]*models.Segment, bool, error) {
+	return nil, false, errors.New("connection refused")
+}
+
+func (failingStore) Set(ctx context.Context, ns string, userId int64, segments []*models.Segment) error {
+	return errors.New("connection refused")
+}
+
+func (failingStore) DeleteUser(ctx context.Context, ns string, userId int64) error {
+	return errors.New("connection refused")
+}
+
+func (failingStore) DeleteNamespace(ctx context.Context, ns string) error {
+	return errors.New("connection refused")
+}
+
+func TestUserSegments(t *testing.T) {
+	ctx := context.Background()
+	segments := []*models.Segment{{Slug: "AVITO_TEST"}}
+
+	t.Run("Should copy value from slower tier", func(t *testing.T) {
+		memory := NewMemoryStore(10, time.Minute)
+		shared := NewMemoryStore(10, time.Minute)
+
+		require.NoError(t, shared.Set(ctx, "default", 1, segments))
+
+		c := New(zap.NewNop().Sugar(), memory, shared)
+
+		got, ok := c.Get(ctx, "default", 1)
+		require.True(t, ok)
+		require.Equal(t, segments, got)
+		require.Equal(t, 1, memory.Len())
+	})
+
+	t.Run("Should invalidate all tiers", func(t *testing.T) {
+		memory := NewMemoryStore(10, time.Minute)
+		shared := NewMemoryStore(10, time.Minute)
+
+		c := New(zap.NewNop().Sugar(), memory, shared)
+		c.Set(ctx, "default", 1, segments)
+		c.Set(ctx, "default", 2, segments)
+
+		c.InvalidateUser(ctx, "default", 1, ReasonUserUpdate)
+
+		_, ok := c.Get(ctx, "default", 1)
+		require.False(t, ok)
+
+		c.InvalidateNamespace(ctx, "default", ReasonSegmentDelete)
+
+		_, ok = c.Get(ctx, "default", 2)
+		require.False(t, ok)
+		require.Equal(t, 0, shared.Len())
+	})
+
+	t.Run("Should fall back to other tiers on error", func(t *testing.T) {
+		memory := NewMemoryStore(10, time.Minute)
+
+		c := New(zap.NewNop().Sugar(), failingStore{}, memory)
+		c.Set(ctx, "default", 1, segments)
+
+		got, ok := c.Get(ctx, "default", 1)
+		require.True(t, ok)
+		require.Equal(t, segments, got)
+
+		c.InvalidateUser(ctx, "default", 1, ReasonUserUpdate)
+		require.Equal(t, 0, memory.Len())
+	})
+
+	t.Run("Should do nothing if cache is disabled", func(t *testing.T) {
+		var c *UserSegments
+
+		c.Set(ctx, "default", 1, segments)
+		c.InvalidateUser(ctx, "default", 1, ReasonUserUpdate)
+		c.InvalidateNamespace(ctx, "default", ReasonSegmentDelete)
+
+		_, ok := c.Get(ctx, "default", 1)
+		require.False(t, ok)
+	})
+}
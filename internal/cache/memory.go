@@ -0,0 +1,131 @@
+package cache
+
+import (
+	"container/list"
+	"context"
+	"sync"
+	"time"
+
+	"github.com/dezzerlol/avitotech-test-2023/internal/db/models"
+	"github.com/dezzerlol/avitotech-test-2023/internal/metrics"
+)
+
+type key struct {
+	ns     string
+	userId int64
+}
+
+type entry struct {
+	key      key
+	segments []*models.Segment
+	expireAt time.Time
+}
+
+// MemoryStore - LRU кеш в памяти процесса с ограничением по числу пользователей и времени жизни записей.
+type MemoryStore struct {
+	mu      sync.Mutex
+	size    int
+	ttl     time.Duration
+	order   *list.List
+	entries map[key]*list.Element
+	now     func() time.Time
+}
+
+func NewMemoryStore(size int, ttl time.Duration) *MemoryStore {
+	return &MemoryStore{
+		size:    size,
+		ttl:     ttl,
+		order:   list.New(),
+		entries: map[key]*list.Element{},
+		now:     time.Now,
+	}
+}
+
+func (s *MemoryStore) Tier() string {
+	return metrics.CacheTierMemory
+}
+
+func (s *MemoryStore) Get(ctx context.Context, ns string, userId int64) ([]*models.Segment, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	el, ok := s.entries[key{ns, userId}]
+
+	if !ok {
+		return nil, false, nil
+	}
+
+	e := el.Value.(*entry)
+
+	if !s.now().Before(e.expireAt) {
+		s.remove(el)
+		return nil, false, nil
+	}
+
+	s.order.MoveToFront(el)
+
+	return e.segments, true, nil
+}
+
+func (s *MemoryStore) Set(ctx context.Context, ns string, userId int64, segments []*models.Segment) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	k := key{ns, userId}
+	expireAt := s.now().Add(s.ttl)
+
+	if el, ok := s.entries[k]; ok {
+		e := el.Value.(*entry)
+		e.segments = segments
+		e.expireAt = expireAt
+		s.order.MoveToFront(el)
+
+		return nil
+	}
+
+	s.entries[k] = s.order.PushFront(&entry{key: k, segments: segments, expireAt: expireAt})
+
+	// Вытесняем пользователей, к которым дольше всего не обращались
+	for s.order.Len() > s.size {
+		s.remove(s.order.Back())
+	}
+
+	return nil
+}
+
+func (s *MemoryStore) DeleteUser(ctx context.Context, ns string, userId int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if el, ok := s.entries[key{ns, userId}]; ok {
+		s.remove(el)
+	}
+
+	return nil
+}
+
+func (s *MemoryStore) DeleteNamespace(ctx context.Context, ns string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for k, el := range s.entries {
+		if k.ns == ns {
+			s.remove(el)
+		}
+	}
+
+	return nil
+}
+
+// Len возвращает число пользователей в кеше.
+func (s *MemoryStore) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.order.Len()
+}
+
+func (s *MemoryStore) remove(el *list.Element) {
+	s.order.Remove(el)
+	delete(s.entries, el.Value.(*entry).key)
+}
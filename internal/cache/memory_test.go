@@ -0,0 +1,86 @@
+package cache
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/dezzerlol/avitotech-test-2023/internal/db/models"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMemoryStore(t *testing.T) {
+	ctx := context.Background()
+	segments := []*models.Segment{{Slug: "AVITO_TEST"}}
+
+	t.Run("Should evict least recently used user", func(t *testing.T) {
+		store := NewMemoryStore(2, time.Minute)
+
+		require.NoError(t, store.Set(ctx, "default", 1, segments))
+		require.NoError(t, store.Set(ctx, "default", 2, segments))
+
+		// Обращение к первому пользователю делает вторым на вытеснение второго
+		_, ok, _ := store.Get(ctx, "default", 1)
+		require.True(t, ok)
+
+		require.NoError(t, store.Set(ctx, "default", 3, segments))
+		require.Equal(t, 2, store.Len())
+
+		_, ok, _ = store.Get(ctx, "default", 2)
+		require.False(t, ok)
+
+		got, ok, _ := store.Get(ctx, "default", 1)
+		require.True(t, ok)
+		require.Equal(t, segments, got)
+	})
+
+	t.Run("Should expire entries after ttl", func(t *testing.T) {
+		now := time.Date(2023, 8, 28, 10, 0, 0, 0, time.UTC)
+
+		store := NewMemoryStore(10, time.Minute)
+		store.now = func() time.Time { return now }
+
+		require.NoError(t, store.Set(ctx, "default", 1, segments))
+
+		now = now.Add(59 * time.Second)
+		_, ok, _ := store.Get(ctx, "default", 1)
+		require.True(t, ok)
+
+		now = now.Add(time.Second)
+		_, ok, _ = store.Get(ctx, "default", 1)
+		require.False(t, ok)
+		require.Equal(t, 0, store.Len())
+	})
+
+	t.Run("Should cache empty list", func(t *testing.T) {
+		store := NewMemoryStore(10, time.Minute)
+
+		require.NoError(t, store.Set(ctx, "default", 1, nil))
+
+		got, ok, _ := store.Get(ctx, "default", 1)
+		require.True(t, ok)
+		require.Empty(t, got)
+	})
+
+	t.Run("Should delete user and namespace", func(t *testing.T) {
+		store := NewMemoryStore(10, time.Minute)
+
+		require.NoError(t, store.Set(ctx, "default", 1, segments))
+		require.NoError(t, store.Set(ctx, "default", 2, segments))
+		require.NoError(t, store.Set(ctx, "payments", 1, segments))
+
+		require.NoError(t, store.DeleteUser(ctx, "default", 1))
+
+		_, ok, _ := store.Get(ctx, "default", 1)
+		require.False(t, ok)
+
+		require.NoError(t, store.DeleteNamespace(ctx, "default"))
+
+		_, ok, _ = store.Get(ctx, "default", 2)
+		require.False(t, ok)
+
+		// Другие пространства имен не затрагиваются
+		_, ok, _ = store.Get(ctx, "payments", 1)
+		require.True(t, ok)
+	})
+}
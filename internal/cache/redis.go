@@ -0,0 +1,116 @@
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/dezzerlol/avitotech-test-2023/internal/db/models"
+	"github.com/dezzerlol/avitotech-test-2023/internal/metrics"
+	"github.com/redis/go-redis/v9"
+)
+
+// Ключи пространства имен: KEYS[1] - номер поколения, KEYS[2] - префикс записей.
+// Сброс пространства имен увеличивает поколение, старые записи перестают читаться и удаляются по TTL.
+// Поколение читается в том же скрипте, что и запись, чтобы обойтись одним запросом к Redis.
+var (
+	getScript = redis.NewScript(`
+local gen = redis.call('GET', KEYS[1]) or '0'
+return redis.call('GET', KEYS[2] .. gen .. ':' .. ARGV[1])
+`)
+
+	setScript = redis.NewScript(`
+local gen = redis.call('GET', KEYS[1]) or '0'
+return redis.call('SET', KEYS[2] .. gen .. ':' .. ARGV[1], ARGV[2], 'PX', ARGV[3])
+`)
+
+	deleteScript = redis.NewScript(`
+local gen = redis.call('GET', KEYS[1]) or '0'
+return redis.call('DEL', KEYS[2] .. gen .. ':' .. ARGV[1])
+`)
+)
+
+// Версия в префиксе меняется вместе с форматом записей, старые записи удаляются по TTL
+const redisKeyPrefix = "segments-cache:v2:"
+
+// RedisStore хранит сегменты пользователей с вариантами в Redis, кеш общий для всех экземпляров сервиса.
+type RedisStore struct {
+	client redis.UniversalClient
+	ttl    time.Duration
+}
+
+func NewRedisStore(client redis.UniversalClient, ttl time.Duration) *RedisStore {
+	return &RedisStore{
+		client: client,
+		ttl:    ttl,
+	}
+}
+
+func (s *RedisStore) Tier() string {
+	return metrics.CacheTierRedis
+}
+
+// keys возвращает ключи пространства имен. Hash tag держит их на одном узле Redis Cluster.
+func keys(ns string) []string {
+	prefix := redisKeyPrefix + "{" + ns + "}:"
+
+	return []string{prefix + "gen", prefix + "users:"}
+}
+
+// redisEntry - сегмент пользователя в записи кеша, короткие ключи уменьшают размер записей.
+type redisEntry struct {
+	Slug    string `json:"s"`
+	Variant string `json:"v,omitempty"`
+}
+
+func (s *RedisStore) Get(ctx context.Context, ns string, userId int64) ([]*models.Segment, bool, error) {
+	raw, err := getScript.Run(ctx, s.client, keys(ns), userId).Text()
+
+	if errors.Is(err, redis.Nil) {
+		return nil, false, nil
+	}
+
+	if err != nil {
+		return nil, false, err
+	}
+
+	var entries []redisEntry
+
+	if err := json.Unmarshal([]byte(raw), &entries); err != nil {
+		return nil, false, err
+	}
+
+	segments := make([]*models.Segment, 0, len(entries))
+
+	for _, entry := range entries {
+		segments = append(segments, &models.Segment{Slug: entry.Slug, Variant: entry.Variant})
+	}
+
+	return segments, true, nil
+}
+
+func (s *RedisStore) Set(ctx context.Context, ns string, userId int64, segments []*models.Segment) error {
+	entries := make([]redisEntry, 0, len(segments))
+
+	for _, segment := range segments {
+		entries = append(entries, redisEntry{Slug: segment.Slug, Variant: segment.Variant})
+	}
+
+	raw, err := json.Marshal(entries)
+
+	if err != nil {
+		return err
+	}
+
+	return setScript.Run(ctx, s.client, keys(ns), userId, raw, strconv.FormatInt(s.ttl.Milliseconds(), 10)).Err()
+}
+
+func (s *RedisStore) DeleteUser(ctx context.Context, ns string, userId int64) error {
+	return deleteScript.Run(ctx, s.client, keys(ns), userId).Err()
+}
+
+func (s *RedisStore) DeleteNamespace(ctx context.Context, ns string) error {
+	return s.client.Incr(ctx, keys(ns)[0]).Err()
+}
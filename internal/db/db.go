@@ -4,11 +4,21 @@ import (
 	"context"
 	"time"
 
+	"github.com/dezzerlol/avitotech-test-2023/internal/tracing"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
 func New(db_dsn string) (*pgxpool.Pool, error) {
-	db, err := pgxpool.New(context.Background(), db_dsn)
+	config, err := pgxpool.ParseConfig(db_dsn)
+
+	if err != nil {
+		return nil, err
+	}
+
+	// Каждый запрос к базе попадает в трейс как отдельный спан
+	config.ConnConfig.Tracer = tracing.NewPgxTracer()
+
+	db, err := pgxpool.NewWithConfig(context.Background(), config)
 
 	if err != nil {
 		return nil, err
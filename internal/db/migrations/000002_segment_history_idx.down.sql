@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS user_segment_history_segment_idx;
+DROP INDEX IF EXISTS user_segment_history_user_idx;
@@ -0,0 +1,5 @@
+CREATE INDEX IF NOT EXISTS user_segment_history_user_idx
+ON user_segment_history (user_id, executed_at);
+
+CREATE INDEX IF NOT EXISTS user_segment_history_segment_idx
+ON user_segment_history (segment_slug, executed_at);
@@ -0,0 +1,21 @@
+CREATE OR REPLACE FUNCTION user_segments_trigger()
+RETURNS TRIGGER AS $$
+BEGIN
+    -- Insert a row into user_segment_history when a row is inserted or deleted
+    IF TG_OP = 'INSERT' THEN
+        INSERT INTO user_segment_history (segment_slug, user_id, operation, executed_at)
+        VALUES (NEW.segment_slug, NEW.user_id, 'I', now());
+        RETURN NEW;
+    ELSIF TG_OP = 'DELETE' THEN
+        INSERT INTO user_segment_history (segment_slug, user_id, operation, executed_at)
+        VALUES (OLD.segment_slug, OLD.user_id, 'D', now());
+        RETURN OLD;
+    END IF;
+    RETURN NULL; -- Return NULL for other operations
+END;
+$$ LANGUAGE plpgsql;
+
+ALTER TABLE user_segment_history
+    DROP COLUMN IF EXISTS request_id,
+    DROP COLUMN IF EXISTS actor,
+    DROP COLUMN IF EXISTS source;
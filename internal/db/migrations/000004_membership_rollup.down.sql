@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS user_segment_history_executed_at_idx;
+DROP TABLE IF EXISTS segment_membership_rollup_state;
+DROP TABLE IF EXISTS segment_membership_daily;
@@ -0,0 +1,22 @@
+-- Число пользователей в сегменте на конец каждого дня (UTC).
+-- Заполняется задачей analytics:refresh_membership по user_segment_history,
+-- строки есть только для дней, в которые были изменения.
+CREATE TABLE IF NOT EXISTS segment_membership_daily (
+    segment_slug varchar (255) NOT NULL,
+    day date NOT NULL,
+    entered bigint NOT NULL,
+    left_count bigint NOT NULL,
+    members bigint NOT NULL,
+
+    PRIMARY KEY (segment_slug, day)
+);
+
+-- Первый день, который нужно пересчитать при следующем обновлении
+CREATE TABLE IF NOT EXISTS segment_membership_rollup_state (
+    id boolean PRIMARY KEY DEFAULT true CHECK (id),
+    recompute_from date NOT NULL,
+    refreshed_at timestamptz NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS user_segment_history_executed_at_idx
+ON user_segment_history (executed_at);
@@ -0,0 +1,9 @@
+CREATE TABLE IF NOT EXISTS api_keys (
+    id bigserial PRIMARY KEY,
+    name varchar (255) NOT NULL,
+    key_hash char (64) NOT NULL UNIQUE, -- sha256 ключа, сам ключ не хранится
+    scopes text[] NOT NULL,
+    created_at timestamptz NOT NULL DEFAULT now(),
+    rotated_at timestamptz,
+    revoked_at timestamptz
+);
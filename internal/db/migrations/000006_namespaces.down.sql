@@ -0,0 +1,56 @@
+-- Вне пространства имен default slug могут повторяться, такие данные не восстановить
+DELETE FROM segments WHERE namespace <> 'default';
+
+CREATE OR REPLACE FUNCTION user_segments_trigger()
+RETURNS TRIGGER AS $$
+DECLARE
+    h_source varchar := NULLIF(current_setting('app.history_source', true), '');
+    h_actor varchar := NULLIF(current_setting('app.history_actor', true), '');
+    h_request_id varchar := NULLIF(current_setting('app.history_request_id', true), '');
+BEGIN
+    -- Insert a row into user_segment_history when a row is inserted or deleted
+    IF TG_OP = 'INSERT' THEN
+        INSERT INTO user_segment_history (segment_slug, user_id, operation, executed_at, source, actor, request_id)
+        VALUES (NEW.segment_slug, NEW.user_id, 'I', now(), h_source, h_actor, h_request_id);
+        RETURN NEW;
+    ELSIF TG_OP = 'DELETE' THEN
+        INSERT INTO user_segment_history (segment_slug, user_id, operation, executed_at, source, actor, request_id)
+        VALUES (OLD.segment_slug, OLD.user_id, 'D', now(), h_source, h_actor, h_request_id);
+        RETURN OLD;
+    END IF;
+    RETURN NULL; -- Return NULL for other operations
+END;
+$$ LANGUAGE plpgsql;
+
+ALTER TABLE api_keys DROP COLUMN IF EXISTS namespaces;
+
+DELETE FROM segment_membership_daily WHERE namespace <> 'default';
+ALTER TABLE segment_membership_daily DROP CONSTRAINT IF EXISTS segment_membership_daily_pkey;
+ALTER TABLE segment_membership_daily DROP COLUMN IF EXISTS namespace;
+ALTER TABLE segment_membership_daily ADD PRIMARY KEY (segment_slug, day);
+
+DROP INDEX IF EXISTS user_segment_history_user_idx;
+DROP INDEX IF EXISTS user_segment_history_segment_idx;
+
+DELETE FROM user_segment_history WHERE namespace <> 'default';
+ALTER TABLE user_segment_history DROP COLUMN IF EXISTS namespace;
+
+CREATE INDEX IF NOT EXISTS user_segment_history_user_idx
+ON user_segment_history (user_id, executed_at);
+
+CREATE INDEX IF NOT EXISTS user_segment_history_segment_idx
+ON user_segment_history (segment_slug, executed_at);
+
+ALTER TABLE user_segments DROP CONSTRAINT IF EXISTS user_segments_segment_fkey;
+ALTER TABLE user_segments DROP CONSTRAINT IF EXISTS user_segments_pkey;
+ALTER TABLE user_segments DROP COLUMN IF EXISTS namespace;
+ALTER TABLE user_segments ADD PRIMARY KEY (user_id, segment_slug);
+
+ALTER TABLE segments DROP CONSTRAINT IF EXISTS segments_pkey;
+ALTER TABLE segments DROP COLUMN IF EXISTS namespace;
+ALTER TABLE segments ADD PRIMARY KEY (slug);
+
+ALTER TABLE user_segments ADD CONSTRAINT user_segments_segment_slug_fkey
+    FOREIGN KEY (segment_slug) REFERENCES segments(slug) ON DELETE CASCADE;
+
+DROP TABLE IF EXISTS namespaces;
@@ -0,0 +1,63 @@
+CREATE TABLE IF NOT EXISTS namespaces (
+    slug varchar (64) PRIMARY KEY,
+    created_at timestamptz NOT NULL DEFAULT now()
+);
+
+-- Существующие данные переносятся в пространство имен default
+INSERT INTO namespaces (slug) VALUES ('default') ON CONFLICT DO NOTHING;
+
+ALTER TABLE user_segments DROP CONSTRAINT IF EXISTS user_segments_segment_slug_fkey;
+ALTER TABLE user_segments DROP CONSTRAINT IF EXISTS user_segments_pkey;
+ALTER TABLE segments DROP CONSTRAINT IF EXISTS segments_pkey;
+
+-- Один и тот же slug может быть в разных пространствах имен
+ALTER TABLE segments ADD COLUMN IF NOT EXISTS namespace varchar (64) NOT NULL DEFAULT 'default' REFERENCES namespaces(slug);
+ALTER TABLE segments ALTER COLUMN namespace DROP DEFAULT;
+ALTER TABLE segments ADD PRIMARY KEY (namespace, slug);
+
+ALTER TABLE user_segments ADD COLUMN IF NOT EXISTS namespace varchar (64) NOT NULL DEFAULT 'default';
+ALTER TABLE user_segments ALTER COLUMN namespace DROP DEFAULT;
+ALTER TABLE user_segments ADD PRIMARY KEY (namespace, user_id, segment_slug);
+ALTER TABLE user_segments ADD CONSTRAINT user_segments_segment_fkey
+    FOREIGN KEY (namespace, segment_slug) REFERENCES segments(namespace, slug) ON DELETE CASCADE;
+
+ALTER TABLE user_segment_history ADD COLUMN IF NOT EXISTS namespace varchar (64) NOT NULL DEFAULT 'default';
+ALTER TABLE user_segment_history ALTER COLUMN namespace DROP DEFAULT;
+
+DROP INDEX IF EXISTS user_segment_history_user_idx;
+DROP INDEX IF EXISTS user_segment_history_segment_idx;
+
+CREATE INDEX IF NOT EXISTS user_segment_history_user_idx
+ON user_segment_history (namespace, user_id, executed_at);
+
+CREATE INDEX IF NOT EXISTS user_segment_history_segment_idx
+ON user_segment_history (namespace, segment_slug, executed_at);
+
+ALTER TABLE segment_membership_daily DROP CONSTRAINT IF EXISTS segment_membership_daily_pkey;
+ALTER TABLE segment_membership_daily ADD COLUMN IF NOT EXISTS namespace varchar (64) NOT NULL DEFAULT 'default';
+ALTER TABLE segment_membership_daily ALTER COLUMN namespace DROP DEFAULT;
+ALTER TABLE segment_membership_daily ADD PRIMARY KEY (namespace, segment_slug, day);
+
+-- Пустой список означает доступ ко всем пространствам имен
+ALTER TABLE api_keys ADD COLUMN IF NOT EXISTS namespaces text[] NOT NULL DEFAULT '{}';
+
+CREATE OR REPLACE FUNCTION user_segments_trigger()
+RETURNS TRIGGER AS $$
+DECLARE
+    h_source varchar := NULLIF(current_setting('app.history_source', true), '');
+    h_actor varchar := NULLIF(current_setting('app.history_actor', true), '');
+    h_request_id varchar := NULLIF(current_setting('app.history_request_id', true), '');
+BEGIN
+    -- Insert a row into user_segment_history when a row is inserted or deleted
+    IF TG_OP = 'INSERT' THEN
+        INSERT INTO user_segment_history (namespace, segment_slug, user_id, operation, executed_at, source, actor, request_id)
+        VALUES (NEW.namespace, NEW.segment_slug, NEW.user_id, 'I', now(), h_source, h_actor, h_request_id);
+        RETURN NEW;
+    ELSIF TG_OP = 'DELETE' THEN
+        INSERT INTO user_segment_history (namespace, segment_slug, user_id, operation, executed_at, source, actor, request_id)
+        VALUES (OLD.namespace, OLD.segment_slug, OLD.user_id, 'D', now(), h_source, h_actor, h_request_id);
+        RETURN OLD;
+    END IF;
+    RETURN NULL; -- Return NULL for other operations
+END;
+$$ LANGUAGE plpgsql;
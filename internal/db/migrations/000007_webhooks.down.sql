@@ -0,0 +1,6 @@
+DROP TRIGGER IF EXISTS user_segments_outbox_trigger ON user_segments;
+DROP FUNCTION IF EXISTS user_segments_outbox_trigger();
+
+DROP TABLE IF EXISTS webhook_deliveries;
+DROP TABLE IF EXISTS membership_outbox;
+DROP TABLE IF EXISTS webhooks;
@@ -0,0 +1,74 @@
+CREATE TABLE IF NOT EXISTS webhooks (
+    id bigserial PRIMARY KEY,
+    namespace varchar (64) NOT NULL REFERENCES namespaces(slug),
+    url text NOT NULL,
+    description varchar (255) NOT NULL DEFAULT '',
+    secret varchar (64) NOT NULL, -- нужен для подписи, поэтому хранится как есть
+    created_at timestamptz NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS webhooks_namespace_idx ON webhooks (namespace);
+
+-- События изменения членства, пишутся триггером в той же транзакции, что и изменение user_segments
+CREATE TABLE IF NOT EXISTS membership_outbox (
+    id bigserial PRIMARY KEY,
+    namespace varchar (64) NOT NULL,
+    segment_slug varchar (255) NOT NULL,
+    user_id bigint NOT NULL,
+    operation varchar (1) NOT NULL, -- I for insert, D for delete
+    source varchar (32),
+    actor varchar (255),
+    request_id varchar (64),
+    created_at timestamptz NOT NULL DEFAULT now(),
+    dispatched_at timestamptz
+);
+
+CREATE INDEX IF NOT EXISTS membership_outbox_pending_idx ON membership_outbox (id) WHERE dispatched_at IS NULL;
+
+-- Журнал попыток доставки, по одной строке на попытку
+CREATE TABLE IF NOT EXISTS webhook_deliveries (
+    id bigserial PRIMARY KEY,
+    webhook_id bigint NOT NULL REFERENCES webhooks(id) ON DELETE CASCADE,
+    event_id bigint, -- пустой у тестовых событий
+    event_type varchar (64) NOT NULL,
+    attempt int NOT NULL,
+    status_code int,
+    error text,
+    duration_ms int NOT NULL,
+    success boolean NOT NULL,
+    created_at timestamptz NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS webhook_deliveries_webhook_idx ON webhook_deliveries (webhook_id, id);
+
+CREATE OR REPLACE FUNCTION user_segments_outbox_trigger()
+RETURNS TRIGGER AS $$
+DECLARE
+    h_source varchar := NULLIF(current_setting('app.history_source', true), '');
+    h_actor varchar := NULLIF(current_setting('app.history_actor', true), '');
+    h_request_id varchar := NULLIF(current_setting('app.history_request_id', true), '');
+    change user_segments%ROWTYPE;
+    op varchar;
+BEGIN
+    IF TG_OP = 'INSERT' THEN
+        change := NEW;
+        op := 'I';
+    ELSE
+        change := OLD;
+        op := 'D';
+    END IF;
+
+    -- Без вебхуков в пространстве имен события доставлять некому
+    IF EXISTS (SELECT 1 FROM webhooks WHERE namespace = change.namespace) THEN
+        INSERT INTO membership_outbox (namespace, segment_slug, user_id, operation, source, actor, request_id)
+        VALUES (change.namespace, change.segment_slug, change.user_id, op, h_source, h_actor, h_request_id);
+    END IF;
+
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER user_segments_outbox_trigger
+AFTER INSERT OR DELETE ON user_segments
+FOR EACH ROW
+EXECUTE FUNCTION user_segments_outbox_trigger();
@@ -0,0 +1,40 @@
+CREATE OR REPLACE FUNCTION user_segments_trigger()
+RETURNS TRIGGER AS $$
+DECLARE
+    h_source varchar := NULLIF(current_setting('app.history_source', true), '');
+    h_actor varchar := NULLIF(current_setting('app.history_actor', true), '');
+    h_request_id varchar := NULLIF(current_setting('app.history_request_id', true), '');
+    h user_segment_history%ROWTYPE;
+BEGIN
+    -- Insert a row into user_segment_history when a row is inserted or deleted
+    IF TG_OP = 'INSERT' THEN
+        INSERT INTO user_segment_history (namespace, segment_slug, user_id, operation, executed_at, source, actor, request_id)
+        VALUES (NEW.namespace, NEW.segment_slug, NEW.user_id, 'I', now(), h_source, h_actor, h_request_id)
+        RETURNING * INTO h;
+    ELSIF TG_OP = 'DELETE' THEN
+        INSERT INTO user_segment_history (namespace, segment_slug, user_id, operation, executed_at, source, actor, request_id)
+        VALUES (OLD.namespace, OLD.segment_slug, OLD.user_id, 'D', now(), h_source, h_actor, h_request_id)
+        RETURNING * INTO h;
+    ELSE
+        RETURN NULL; -- Return NULL for other operations
+    END IF;
+
+    PERFORM pg_notify('segment_events', json_build_object(
+        'id', h.id,
+        'namespace', h.namespace,
+        'segment_slug', h.segment_slug,
+        'user_id', h.user_id,
+        'operation', h.operation,
+        'executed_at', h.executed_at,
+        'source', COALESCE(h.source, ''),
+        'actor', COALESCE(h.actor, ''),
+        'request_id', COALESCE(h.request_id, '')
+    )::text);
+
+    IF TG_OP = 'INSERT' THEN
+        RETURN NEW;
+    END IF;
+
+    RETURN OLD;
+END;
+$$ LANGUAGE plpgsql;
@@ -0,0 +1,4 @@
+ALTER TABLE segments DROP COLUMN IF EXISTS rule;
+
+ALTER TABLE users DROP COLUMN IF EXISTS updated_at;
+ALTER TABLE users DROP COLUMN IF EXISTS attributes;
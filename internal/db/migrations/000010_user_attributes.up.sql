@@ -0,0 +1,6 @@
+-- Атрибуты пользователя для правил динамических сегментов
+ALTER TABLE users ADD COLUMN IF NOT EXISTS attributes jsonb NOT NULL DEFAULT '{}';
+ALTER TABLE users ADD COLUMN IF NOT EXISTS updated_at timestamptz NOT NULL DEFAULT now();
+
+-- Правило динамического сегмента, NULL у обычных сегментов
+ALTER TABLE segments ADD COLUMN IF NOT EXISTS rule text;
@@ -0,0 +1,15 @@
+DROP FUNCTION IF EXISTS layer_bucket(text, bigint);
+
+DROP TRIGGER IF EXISTS user_segments_layer_trigger ON user_segments;
+DROP FUNCTION IF EXISTS user_segments_layer_trigger();
+
+DROP INDEX IF EXISTS user_segments_layer_idx;
+ALTER TABLE user_segments DROP COLUMN IF EXISTS layer;
+
+DROP INDEX IF EXISTS segments_layer_idx;
+ALTER TABLE segments DROP CONSTRAINT IF EXISTS segments_layer_fkey;
+ALTER TABLE segments DROP COLUMN IF EXISTS bucket_to;
+ALTER TABLE segments DROP COLUMN IF EXISTS bucket_from;
+ALTER TABLE segments DROP COLUMN IF EXISTS layer;
+
+DROP TABLE IF EXISTS layers;
@@ -0,0 +1,48 @@
+-- Слои - группы взаимоисключающих сегментов: пользователь состоит не больше чем в одном сегменте слоя
+CREATE TABLE IF NOT EXISTS layers (
+    namespace varchar (64) NOT NULL REFERENCES namespaces(slug),
+    slug varchar (255) NOT NULL,
+    description text NOT NULL DEFAULT '',
+    created_at timestamptz NOT NULL DEFAULT now(),
+
+    PRIMARY KEY (namespace, slug)
+);
+
+-- Раскатка сегмента в слое занимает бакеты [bucket_from, bucket_to), диапазоны сегментов слоя не пересекаются
+ALTER TABLE segments ADD COLUMN IF NOT EXISTS layer varchar (255);
+ALTER TABLE segments ADD COLUMN IF NOT EXISTS bucket_from int;
+ALTER TABLE segments ADD COLUMN IF NOT EXISTS bucket_to int;
+ALTER TABLE segments ADD CONSTRAINT segments_layer_fkey
+    FOREIGN KEY (namespace, layer) REFERENCES layers(namespace, slug);
+
+CREATE INDEX IF NOT EXISTS segments_layer_idx ON segments (namespace, layer) WHERE layer IS NOT NULL;
+
+-- Слой сегмента копируется в членство, чтобы уникальный индекс не давал пользователю два сегмента одного слоя
+-- даже при параллельных запросах
+ALTER TABLE user_segments ADD COLUMN IF NOT EXISTS layer varchar (255);
+
+CREATE UNIQUE INDEX IF NOT EXISTS user_segments_layer_idx
+ON user_segments (namespace, layer, user_id) WHERE layer IS NOT NULL;
+
+CREATE OR REPLACE FUNCTION user_segments_layer_trigger()
+RETURNS TRIGGER AS $$
+BEGIN
+    SELECT layer INTO NEW.layer
+    FROM segments
+    WHERE namespace = NEW.namespace
+    AND slug = NEW.segment_slug;
+
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER user_segments_layer_trigger
+BEFORE INSERT ON user_segments
+FOR EACH ROW
+EXECUTE FUNCTION user_segments_layer_trigger();
+
+-- Бакет пользователя в слое от 0 до 99, не меняется со временем
+CREATE OR REPLACE FUNCTION layer_bucket(layer text, user_id bigint)
+RETURNS int AS $$
+    SELECT ((hashtextextended(layer || ':' || user_id, 0) % 100) + 100) % 100;
+$$ LANGUAGE sql IMMUTABLE;
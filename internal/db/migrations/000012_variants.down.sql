@@ -0,0 +1,104 @@
+DROP TRIGGER IF EXISTS user_segments_outbox_trigger ON user_segments;
+
+CREATE OR REPLACE FUNCTION user_segments_outbox_trigger()
+RETURNS TRIGGER AS $$
+DECLARE
+    h_source varchar := NULLIF(current_setting('app.history_source', true), '');
+    h_actor varchar := NULLIF(current_setting('app.history_actor', true), '');
+    h_request_id varchar := NULLIF(current_setting('app.history_request_id', true), '');
+    change user_segments%ROWTYPE;
+    op varchar;
+BEGIN
+    IF TG_OP = 'INSERT' THEN
+        change := NEW;
+        op := 'I';
+    ELSE
+        change := OLD;
+        op := 'D';
+    END IF;
+
+    -- Без вебхуков в пространстве имен события доставлять некому
+    IF EXISTS (SELECT 1 FROM webhooks WHERE namespace = change.namespace) THEN
+        INSERT INTO membership_outbox (namespace, segment_slug, user_id, operation, source, actor, request_id)
+        VALUES (change.namespace, change.segment_slug, change.user_id, op, h_source, h_actor, h_request_id);
+    END IF;
+
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER user_segments_outbox_trigger
+AFTER INSERT OR DELETE ON user_segments
+FOR EACH ROW
+EXECUTE FUNCTION user_segments_outbox_trigger();
+
+-- Смена варианта в outbox без вариантов не имеет смысла
+DELETE FROM membership_outbox WHERE operation = 'V';
+ALTER TABLE membership_outbox DROP COLUMN IF EXISTS variant;
+
+DROP TRIGGER IF EXISTS user_segments_history_trigger ON user_segments;
+
+CREATE TRIGGER user_segments_history_trigger
+AFTER INSERT OR DELETE ON user_segments
+FOR EACH ROW
+EXECUTE FUNCTION user_segments_trigger();
+
+CREATE OR REPLACE FUNCTION user_segments_trigger()
+RETURNS TRIGGER AS $$
+DECLARE
+    h_source varchar := NULLIF(current_setting('app.history_source', true), '');
+    h_actor varchar := NULLIF(current_setting('app.history_actor', true), '');
+    h_request_id varchar := NULLIF(current_setting('app.history_request_id', true), '');
+    h user_segment_history%ROWTYPE;
+    lower_bound bigint;
+BEGIN
+    IF TG_OP NOT IN ('INSERT', 'DELETE') THEN
+        RETURN NULL; -- Return NULL for other operations
+    END IF;
+
+    -- Блокировка берется один раз за транзакцию и снимается при ее завершении
+    IF NULLIF(current_setting('app.history_feed_locked', true), '') IS NULL THEN
+        SELECT last_value INTO lower_bound FROM user_segment_history_id_seq;
+        PERFORM pg_advisory_xact_lock_shared((lower_bound >> 32)::int, lower_bound::bit(32)::int);
+        PERFORM set_config('app.history_feed_locked', '1', true);
+    END IF;
+
+    -- Insert a row into user_segment_history when a row is inserted or deleted
+    IF TG_OP = 'INSERT' THEN
+        INSERT INTO user_segment_history (namespace, segment_slug, user_id, operation, executed_at, source, actor, request_id)
+        VALUES (NEW.namespace, NEW.segment_slug, NEW.user_id, 'I', now(), h_source, h_actor, h_request_id)
+        RETURNING * INTO h;
+    ELSE
+        INSERT INTO user_segment_history (namespace, segment_slug, user_id, operation, executed_at, source, actor, request_id)
+        VALUES (OLD.namespace, OLD.segment_slug, OLD.user_id, 'D', now(), h_source, h_actor, h_request_id)
+        RETURNING * INTO h;
+    END IF;
+
+    PERFORM pg_notify('segment_events', json_build_object(
+        'id', h.id,
+        'namespace', h.namespace,
+        'segment_slug', h.segment_slug,
+        'user_id', h.user_id,
+        'operation', h.operation,
+        'executed_at', h.executed_at,
+        'source', COALESCE(h.source, ''),
+        'actor', COALESCE(h.actor, ''),
+        'request_id', COALESCE(h.request_id, '')
+    )::text);
+
+    IF TG_OP = 'INSERT' THEN
+        RETURN NEW;
+    END IF;
+
+    RETURN OLD;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS user_segments_variant_trigger ON user_segments;
+DROP FUNCTION IF EXISTS user_segments_variant_trigger();
+DROP FUNCTION IF EXISTS variant_bucket(text, bigint);
+
+ALTER TABLE user_segment_history DROP COLUMN IF EXISTS variant;
+ALTER TABLE user_segments DROP COLUMN IF EXISTS variant;
+
+DROP TABLE IF EXISTS segment_variants;
@@ -0,0 +1,164 @@
+-- Варианты эксперимента. Вес - доля новых участников сегмента в процентах, сумма весов сегмента равна 100.
+-- Порядок вариантов задает их диапазоны бакетов и не меняется после создания
+CREATE TABLE IF NOT EXISTS segment_variants (
+    namespace varchar (64) NOT NULL,
+    segment_slug varchar (255) NOT NULL,
+    name varchar (64) NOT NULL,
+    weight int NOT NULL CHECK (weight BETWEEN 0 AND 100),
+    position int NOT NULL,
+
+    PRIMARY KEY (namespace, segment_slug, name),
+    FOREIGN KEY (namespace, segment_slug) REFERENCES segments(namespace, slug) ON DELETE CASCADE
+);
+
+ALTER TABLE user_segments ADD COLUMN IF NOT EXISTS variant varchar (64);
+ALTER TABLE user_segment_history ADD COLUMN IF NOT EXISTS variant varchar (64);
+
+-- Бакет пользователя в сегменте от 0 до 99, не зависит от бакета в слое
+CREATE OR REPLACE FUNCTION variant_bucket(segment text, user_id bigint)
+RETURNS int AS $$
+    SELECT ((hashtextextended('variant:' || segment || ':' || user_id, 0) % 100) + 100) % 100;
+$$ LANGUAGE sql IMMUTABLE;
+
+-- Вариант назначается один раз при добавлении в сегмент по текущим весам.
+-- Изменение весов не трогает уже назначенные варианты. Вариант с весом 0 не назначается:
+-- его верхняя граница совпала бы с границей предыдущего варианта
+CREATE OR REPLACE FUNCTION user_segments_variant_trigger()
+RETURNS TRIGGER AS $$
+BEGIN
+    IF NEW.variant IS NOT NULL THEN
+        RETURN NEW;
+    END IF;
+
+    SELECT v.name INTO NEW.variant
+    FROM (
+        SELECT name, sum(weight) OVER (ORDER BY position) AS upper_bound
+        FROM segment_variants
+        WHERE namespace = NEW.namespace
+        AND segment_slug = NEW.segment_slug
+        AND weight > 0
+    ) v
+    WHERE v.upper_bound > variant_bucket(NEW.segment_slug, NEW.user_id)
+    ORDER BY v.upper_bound
+    LIMIT 1;
+
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER user_segments_variant_trigger
+BEFORE INSERT ON user_segments
+FOR EACH ROW
+EXECUTE FUNCTION user_segments_variant_trigger();
+
+-- В историю дополнительно пишется вариант, смена варианта записывается операцией V с новым вариантом
+CREATE OR REPLACE FUNCTION user_segments_trigger()
+RETURNS TRIGGER AS $$
+DECLARE
+    h_source varchar := NULLIF(current_setting('app.history_source', true), '');
+    h_actor varchar := NULLIF(current_setting('app.history_actor', true), '');
+    h_request_id varchar := NULLIF(current_setting('app.history_request_id', true), '');
+    h user_segment_history%ROWTYPE;
+    lower_bound bigint;
+BEGIN
+    IF TG_OP NOT IN ('INSERT', 'DELETE', 'UPDATE') THEN
+        RETURN NULL; -- Return NULL for other operations
+    END IF;
+
+    IF TG_OP = 'UPDATE' AND OLD.variant IS NOT DISTINCT FROM NEW.variant THEN
+        RETURN NULL;
+    END IF;
+
+    -- Блокировка берется один раз за транзакцию и снимается при ее завершении, см. 000009_history_feed
+    IF NULLIF(current_setting('app.history_feed_locked', true), '') IS NULL THEN
+        SELECT last_value INTO lower_bound FROM user_segment_history_id_seq;
+        PERFORM pg_advisory_xact_lock_shared((lower_bound >> 32)::int, lower_bound::bit(32)::int);
+        PERFORM set_config('app.history_feed_locked', '1', true);
+    END IF;
+
+    IF TG_OP = 'INSERT' THEN
+        INSERT INTO user_segment_history (namespace, segment_slug, user_id, operation, variant, executed_at, source, actor, request_id)
+        VALUES (NEW.namespace, NEW.segment_slug, NEW.user_id, 'I', NEW.variant, now(), h_source, h_actor, h_request_id)
+        RETURNING * INTO h;
+    ELSIF TG_OP = 'UPDATE' THEN
+        INSERT INTO user_segment_history (namespace, segment_slug, user_id, operation, variant, executed_at, source, actor, request_id)
+        VALUES (NEW.namespace, NEW.segment_slug, NEW.user_id, 'V', NEW.variant, now(), h_source, h_actor, h_request_id)
+        RETURNING * INTO h;
+    ELSE
+        INSERT INTO user_segment_history (namespace, segment_slug, user_id, operation, variant, executed_at, source, actor, request_id)
+        VALUES (OLD.namespace, OLD.segment_slug, OLD.user_id, 'D', OLD.variant, now(), h_source, h_actor, h_request_id)
+        RETURNING * INTO h;
+    END IF;
+
+    PERFORM pg_notify('segment_events', json_build_object(
+        'id', h.id,
+        'namespace', h.namespace,
+        'segment_slug', h.segment_slug,
+        'user_id', h.user_id,
+        'operation', h.operation,
+        'variant', COALESCE(h.variant, ''),
+        'executed_at', h.executed_at,
+        'source', COALESCE(h.source, ''),
+        'actor', COALESCE(h.actor, ''),
+        'request_id', COALESCE(h.request_id, '')
+    )::text);
+
+    IF TG_OP = 'DELETE' THEN
+        RETURN OLD;
+    END IF;
+
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS user_segments_history_trigger ON user_segments;
+
+CREATE TRIGGER user_segments_history_trigger
+AFTER INSERT OR DELETE OR UPDATE OF variant ON user_segments
+FOR EACH ROW
+EXECUTE FUNCTION user_segments_trigger();
+
+
+-- Outbox вебхуков получает вариант и смену варианта (операция V, событие segment.variant_changed)
+ALTER TABLE membership_outbox ADD COLUMN IF NOT EXISTS variant varchar (64);
+
+CREATE OR REPLACE FUNCTION user_segments_outbox_trigger()
+RETURNS TRIGGER AS $$
+DECLARE
+    h_source varchar := NULLIF(current_setting('app.history_source', true), '');
+    h_actor varchar := NULLIF(current_setting('app.history_actor', true), '');
+    h_request_id varchar := NULLIF(current_setting('app.history_request_id', true), '');
+    change user_segments%ROWTYPE;
+    op varchar;
+BEGIN
+    IF TG_OP = 'UPDATE' AND OLD.variant IS NOT DISTINCT FROM NEW.variant THEN
+        RETURN NULL;
+    END IF;
+
+    IF TG_OP = 'INSERT' THEN
+        change := NEW;
+        op := 'I';
+    ELSIF TG_OP = 'UPDATE' THEN
+        change := NEW;
+        op := 'V';
+    ELSE
+        change := OLD;
+        op := 'D';
+    END IF;
+
+    -- Без вебхуков в пространстве имен события доставлять некому
+    IF EXISTS (SELECT 1 FROM webhooks WHERE namespace = change.namespace) THEN
+        INSERT INTO membership_outbox (namespace, segment_slug, user_id, operation, variant, source, actor, request_id)
+        VALUES (change.namespace, change.segment_slug, change.user_id, op, change.variant, h_source, h_actor, h_request_id);
+    END IF;
+
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS user_segments_outbox_trigger ON user_segments;
+
+CREATE TRIGGER user_segments_outbox_trigger
+AFTER INSERT OR DELETE OR UPDATE OF variant ON user_segments
+FOR EACH ROW
+EXECUTE FUNCTION user_segments_outbox_trigger();
\ No newline at end of file
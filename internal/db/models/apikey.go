@@ -0,0 +1,14 @@
+package models
+
+import "time"
+
+type APIKey struct {
+	ID     int64    `json:"id"`
+	Name   string   `json:"name"`
+	Scopes []string `json:"scopes"`
+	// Пространства имен, к которым есть доступ. Пустой список - доступ ко всем
+	Namespaces []string   `json:"namespaces"`
+	CreatedAt  time.Time  `json:"created_at"`
+	RotatedAt  *time.Time `json:"rotated_at,omitempty"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty"`
+}
@@ -0,0 +1,32 @@
+package models
+
+import "time"
+
+// Число бакетов слоя. Раскатка сегмента слоя на N% занимает N бакетов.
+const LayerBuckets = 100
+
+// Layer - группа взаимоисключающих сегментов: пользователь состоит не больше чем в одном сегменте слоя.
+type Layer struct {
+	Slug        string          `json:"slug"`
+	Description string          `json:"description,omitempty"`
+	CreatedAt   time.Time       `json:"created_at"`
+	Segments    []*LayerSegment `json:"segments"`
+	// Бакеты, не занятые раскатками сегментов
+	FreeBuckets int `json:"free_buckets"`
+}
+
+type LayerSegment struct {
+	Slug string `json:"slug"`
+	// Занятые раскаткой бакеты, пусто у сегментов без раскатки
+	Buckets *BucketRange `json:"buckets,omitempty"`
+}
+
+// BucketRange - бакеты слоя [From, To).
+type BucketRange struct {
+	From int `json:"from"`
+	To   int `json:"to"`
+}
+
+func (b BucketRange) Size() int {
+	return b.To - b.From
+}
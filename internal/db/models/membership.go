@@ -0,0 +1,32 @@
+package models
+
+import "time"
+
+// MembershipFilter задает период по дням (включительно) и список сегментов.
+// Пустой список означает все сегменты.
+type MembershipFilter struct {
+	From     time.Time
+	To       time.Time
+	Segments []string
+}
+
+type MembershipPoint struct {
+	SegmentSlug string    `json:"-"`
+	Day         time.Time `json:"day"`
+	Entered     int64     `json:"entered"`
+	Left        int64     `json:"left"`
+	Members     int64     `json:"members"`
+}
+
+type MembershipSeries struct {
+	SegmentSlug string             `json:"segment_slug"`
+	Points      []*MembershipPoint `json:"points"`
+}
+
+type Membership struct {
+	From   time.Time           `json:"from"`
+	To     time.Time           `json:"to"`
+	Series []*MembershipSeries `json:"series"`
+	// Время последнего пересчета, изменения после него в данные не попали
+	RefreshedAt *time.Time `json:"refreshed_at"`
+}
@@ -0,0 +1,8 @@
+package models
+
+import "time"
+
+type Namespace struct {
+	Slug      string    `json:"slug"`
+	CreatedAt time.Time `json:"created_at"`
+}
@@ -6,4 +6,16 @@ type Segment struct {
 	Slug        string     `json:"slug"`
 	UserPercent int8       `json:"user_percent,omitempty"`
 	CreatedAt   *time.Time `json:"-"`
+	// Сегмент удален, заполняется только при запросе сегментов на момент в прошлом
+	Deleted bool `json:"deleted,omitempty"`
+	// Правило динамического сегмента, пустое у обычного сегмента
+	Rule string `json:"rule,omitempty"`
+	// Слой взаимоисключающих сегментов, пустой у сегмента вне слоя
+	Layer string `json:"layer,omitempty"`
+	// Бакеты слоя, занятые раскаткой сегмента
+	Buckets *BucketRange `json:"-"`
+	// Вариант эксперимента пользователя, заполняется в сегментах пользователя
+	Variant string `json:"variant,omitempty"`
+	// Варианты эксперимента при создании сегмента
+	Variants []*SegmentVariant `json:"-"`
 }
@@ -0,0 +1,49 @@
+package models
+
+import "time"
+
+// SegmentEvent - изменение членства пользователя в сегменте. id совпадает с id записи истории
+// и используется как id события SSE.
+type SegmentEvent struct {
+	ID          int64  `json:"id"`
+	Namespace   string `json:"namespace"`
+	SegmentSlug string `json:"segment_slug"`
+	UserID      int64  `json:"user_id"`
+	// I - добавление, D - удаление, V - смена варианта
+	Operation string `json:"operation"`
+	// Вариант эксперимента: назначенный при добавлении, новый при смене, последний при удалении
+	Variant    string    `json:"variant,omitempty"`
+	ExecutedAt time.Time `json:"executed_at"`
+	Source     string    `json:"source"`
+	Actor      string    `json:"actor"`
+	RequestID  string    `json:"request_id"`
+}
+
+// SegmentEventFilter ограничивает события сегментом и/или пользователем. Пустой фильтр пропускает все события.
+type SegmentEventFilter struct {
+	SegmentSlug string
+	UserID      int64
+}
+
+// Match проверяет, подходит ли событие под фильтр. Пространство имен фильтром не проверяется.
+func (f SegmentEventFilter) Match(event *SegmentEvent) bool {
+	if f.SegmentSlug != "" && f.SegmentSlug != event.SegmentSlug {
+		return false
+	}
+
+	if f.UserID != 0 && f.UserID != event.UserID {
+		return false
+	}
+
+	return true
+}
+
+// ChangesPage - страница ленты изменений членства.
+type ChangesPage struct {
+	Changes []*SegmentEvent `json:"changes"`
+	// Курсор для следующего запроса. Передвигается и без изменений в пространстве имен,
+	// поэтому его нужно сохранять после каждого запроса
+	NextCursor string `json:"next_cursor"`
+	// В ленте есть изменения после страницы, следующий запрос можно делать сразу
+	HasMore bool `json:"has_more"`
+}
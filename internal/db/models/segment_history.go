@@ -0,0 +1,90 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+const (
+	BucketDay  = "day"
+	BucketHour = "hour"
+)
+
+// Ограничение на число интервалов в одном отчете
+const MaxSegmentHistoryBuckets = 10000
+
+// Вид выгрузки истории сегмента: агрегаты по интервалам или отдельные события
+const (
+	SegmentHistoryViewBuckets = "buckets"
+	SegmentHistoryViewEvents  = "events"
+)
+
+// SegmentHistoryFilter задает сегмент, период [From, To) и размер интервала агрегации.
+type SegmentHistoryFilter struct {
+	SegmentSlug string
+	From        time.Time
+	To          time.Time
+	Bucket      string
+	Location    *time.Location
+}
+
+// Validate проверяет интервал агрегации и период фильтра.
+func (f SegmentHistoryFilter) Validate() error {
+	if f.Bucket != BucketDay && f.Bucket != BucketHour {
+		return errors.New("bucket must be day or hour")
+	}
+
+	if !f.From.Before(f.To) {
+		return errors.New("from must be before to")
+	}
+
+	step := 24 * time.Hour
+	if f.Bucket == BucketHour {
+		step = time.Hour
+	}
+
+	if f.To.Sub(f.From)/step > MaxSegmentHistoryBuckets {
+		return fmt.Errorf("period must contain at most %d buckets", MaxSegmentHistoryBuckets)
+	}
+
+	return nil
+}
+
+// BucketStart возвращает начало интервала, в который попадает t.
+func (f SegmentHistoryFilter) BucketStart(t time.Time) time.Time {
+	t = t.In(f.Location)
+
+	if f.Bucket == BucketHour {
+		return time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), 0, 0, 0, f.Location)
+	}
+
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, f.Location)
+}
+
+// NextBucket возвращает начало интервала, следующего за start.
+func (f SegmentHistoryFilter) NextBucket(start time.Time) time.Time {
+	if f.Bucket == BucketHour {
+		return start.Add(time.Hour)
+	}
+
+	return start.AddDate(0, 0, 1)
+}
+
+type SegmentHistoryBucket struct {
+	Start   time.Time `json:"start"`
+	Entered int64     `json:"entered"`
+	Left    int64     `json:"left"`
+	Net     int64     `json:"net"`
+	Members int64     `json:"members"`
+}
+
+type SegmentHistory struct {
+	SegmentSlug     string                  `json:"segment_slug"`
+	From            time.Time               `json:"from"`
+	To              time.Time               `json:"to"`
+	Bucket          string                  `json:"bucket"`
+	Buckets         []*SegmentHistoryBucket `json:"buckets"`
+	Events          []*UserHistory          `json:"events"`
+	EventsTruncated bool                    `json:"events_truncated"`
+}
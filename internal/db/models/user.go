@@ -1,5 +1,10 @@
 package models
 
+import "time"
+
 type User struct {
 	Id int `json:"id"`
+	// Атрибуты для правил динамических сегментов
+	Attributes map[string]any `json:"attributes,omitempty"`
+	UpdatedAt  *time.Time     `json:"updated_at,omitempty"`
 }
@@ -7,5 +7,9 @@ type UserHistory struct {
 	SegmentSlug string    `json:"segment_slug"`
 	UserID      int64     `json:"user_id"`
 	Operation   string    `json:"operation"`
+	Variant     string    `json:"variant,omitempty"`
 	ExecutedAt  time.Time `json:"executed_at"`
+	Source      string    `json:"source"`
+	Actor       string    `json:"actor"`
+	RequestID   string    `json:"request_id"`
 }
@@ -22,3 +22,11 @@ func NewExpireDate(ttl int64) sql.NullTime {
 
 	return sql.NullTime{}
 }
+
+// UserSegmentsLookup - сегменты нескольких пользователей
+type UserSegmentsLookup struct {
+	// Сегменты по id пользователя, у пользователя без сегментов пустой список
+	Users map[int64][]*Segment `json:"users"`
+	// id пользователей, которых нет в базе
+	NotFound []int64 `json:"not_found"`
+}
@@ -0,0 +1,13 @@
+package models
+
+// Сумма весов вариантов сегмента
+const VariantWeightTotal = 100
+
+// SegmentVariant - вариант эксперимента. Вес - доля новых участников сегмента в процентах,
+// уже назначенные варианты при изменении весов не меняются.
+type SegmentVariant struct {
+	Name   string `json:"name"`
+	Weight int    `json:"weight"`
+	// Число участников сегмента с этим вариантом, заполняется только при чтении вариантов
+	Users int64 `json:"users"`
+}
@@ -0,0 +1,55 @@
+package models
+
+import "time"
+
+// Типы событий вебхуков
+const (
+	EventUserAdded   = "segment.user_added"
+	EventUserRemoved = "segment.user_removed"
+	// Смена варианта эксперимента
+	EventVariantChanged = "segment.variant_changed"
+	// Тестовое событие, отправляется только по запросу проверки вебхука
+	EventWebhookTest = "webhook.test"
+)
+
+type Webhook struct {
+	ID          int64     `json:"id"`
+	Namespace   string    `json:"namespace"`
+	URL         string    `json:"url"`
+	Description string    `json:"description"`
+	CreatedAt   time.Time `json:"created_at"`
+	// Ключ подписи, возвращается только при создании вебхука
+	Secret string `json:"-"`
+}
+
+// MembershipEvent - событие добавления пользователя в сегмент, удаления из него или смены варианта, тело запроса вебхука.
+type MembershipEvent struct {
+	// id события в outbox, повторные доставки приходят с тем же id. У тестового события 0
+	ID        int64  `json:"id"`
+	Type      string `json:"type"`
+	Namespace string `json:"namespace"`
+	Segment   string `json:"segment,omitempty"`
+	UserID    int64  `json:"user_id,omitempty"`
+	// Вариант эксперимента: назначенный, последний у удаления или новый у смены варианта
+	Variant    string    `json:"variant,omitempty"`
+	Source     string    `json:"source,omitempty"`
+	Actor      string    `json:"actor,omitempty"`
+	RequestID  string    `json:"request_id,omitempty"`
+	OccurredAt time.Time `json:"occurred_at"`
+}
+
+// WebhookDelivery - попытка доставки события.
+type WebhookDelivery struct {
+	ID        int64  `json:"id"`
+	WebhookID int64  `json:"webhook_id"`
+	EventID   *int64 `json:"event_id"`
+	EventType string `json:"event_type"`
+	// Номер попытки, начиная с 1
+	Attempt int `json:"attempt"`
+	// Пустой, если ответ не получен
+	StatusCode *int      `json:"status_code"`
+	Error      string    `json:"error,omitempty"`
+	DurationMs int64     `json:"duration_ms"`
+	Success    bool      `json:"success"`
+	CreatedAt  time.Time `json:"created_at"`
+}
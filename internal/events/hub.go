@@ -0,0 +1,339 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/dezzerlol/avitotech-test-2023/internal/db/models"
+	"github.com/dezzerlol/avitotech-test-2023/internal/metrics"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"go.uber.org/zap"
+)
+
+// Channel - канал PostgreSQL, в который триггер user_segments_trigger отправляет изменения членства.
+const Channel = "segment_events"
+
+var (
+	// Соединение с PostgreSQL для LISTEN не установлено или сервер останавливается
+	ErrUnavailable = errors.New("segment events are unavailable")
+	// Подписка отстала от потока событий или потеряла соединение, события могли пропасть
+	ErrSubscriptionLost = errors.New("segment events subscription lost")
+)
+
+// Сколько событий ждет отправки одному подписчику. Медленный подписчик отключается,
+// чтобы не задерживать остальных, и продолжает с Last-Event-ID.
+const subscriptionBuffer = 1024
+
+// Задержка переподключения к PostgreSQL растет от minReconnectDelay до maxReconnectDelay
+const (
+	minReconnectDelay = time.Second
+	maxReconnectDelay = 30 * time.Second
+)
+
+// Как часто хаб отправляет контрольные точки, пока есть подписчики
+const checkpointInterval = time.Second
+
+// WatermarkReader возвращает границу истории: записи с id не больше нее уже закоммичены или откачены.
+type WatermarkReader interface {
+	GetHistoryWatermark(ctx context.Context) (int64, error)
+}
+
+// Message - событие или контрольная точка подписки.
+type Message struct {
+	Event *models.SegmentEvent
+	// У контрольной точки больше нуля: уведомления обо всех записях истории с id не больше Checkpoint,
+	// закоммиченных после подписки, уже получены подпиской
+	Checkpoint int64
+}
+
+// notification - уведомление канала: событие из триггера или контрольная точка хаба.
+type notification struct {
+	models.SegmentEvent
+	Checkpoint int64 `json:"checkpoint,omitempty"`
+}
+
+// Subscription - подписка на изменения членства в одном пространстве имен.
+type Subscription struct {
+	hub      *Hub
+	ns       string
+	filter   models.SegmentEventFilter
+	messages chan Message
+	lost     chan struct{}
+}
+
+// Messages возвращает события и контрольные точки подписки в порядке получения уведомлений.
+// Порядок совпадает с порядком коммита транзакций, а не с порядком id.
+func (s *Subscription) Messages() <-chan Message {
+	return s.messages
+}
+
+// Lost закрывается, если подписка потеряна. Канал Messages при этом не закрывается.
+func (s *Subscription) Lost() <-chan struct{} {
+	return s.lost
+}
+
+// Close отменяет подписку.
+func (s *Subscription) Close() {
+	s.hub.remove(s)
+}
+
+// Hub держит одно соединение с LISTEN на процесс и раздает уведомления подписчикам.
+// Уведомления, пришедшие без соединения, не повторяются, поэтому при потере соединения
+// все подписки теряются, а клиенты догоняют пропущенное по истории.
+type Hub struct {
+	db         *pgxpool.Pool
+	watermarks WatermarkReader
+	logger     *zap.SugaredLogger
+
+	mu        sync.Mutex
+	subs      map[*Subscription]struct{}
+	listening bool
+	closed    bool
+}
+
+func NewHub(db *pgxpool.Pool, watermarks WatermarkReader, logger *zap.SugaredLogger) *Hub {
+	return &Hub{
+		db:         db,
+		watermarks: watermarks,
+		logger:     logger,
+		subs:       make(map[*Subscription]struct{}),
+	}
+}
+
+// Subscribe подписывается на события пространства имен ns, подходящие под filter.
+// У nil Hub поток событий всегда недоступен.
+func (h *Hub) Subscribe(ns string, filter models.SegmentEventFilter) (*Subscription, error) {
+	if h == nil {
+		return nil, ErrUnavailable
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if !h.listening || h.closed {
+		return nil, ErrUnavailable
+	}
+
+	sub := &Subscription{
+		hub:      h,
+		ns:       ns,
+		filter:   filter,
+		messages: make(chan Message, subscriptionBuffer),
+		lost:     make(chan struct{}),
+	}
+
+	h.subs[sub] = struct{}{}
+	metrics.SetEventSubscribers(len(h.subs))
+
+	return sub, nil
+}
+
+// Run слушает канал и переподключается при ошибках, пока не отменен ctx.
+func (h *Hub) Run(ctx context.Context) {
+	delay := minReconnectDelay
+
+	for {
+		listened, err := h.listen(ctx)
+
+		if ctx.Err() != nil {
+			h.Close()
+			return
+		}
+
+		if listened {
+			delay = minReconnectDelay
+		}
+
+		h.logger.Errorw("error listening segment events", "err", err, "retry_in", delay)
+
+		select {
+		case <-ctx.Done():
+			h.Close()
+			return
+		case <-time.After(delay):
+		}
+
+		delay = min(delay*2, maxReconnectDelay)
+	}
+}
+
+// Close теряет все подписки и перестает принимать новые. Вызывается при остановке сервера,
+// чтобы открытые потоки не задерживали ее.
+func (h *Hub) Close() {
+	if h == nil {
+		return
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.closed = true
+	h.dropAll()
+}
+
+// listen держит соединение с LISTEN до ошибки. listened - удалось ли начать слушать канал.
+func (h *Hub) listen(ctx context.Context) (listened bool, err error) {
+	conn, err := h.db.Acquire(ctx)
+
+	if err != nil {
+		return false, err
+	}
+
+	// Соединение с LISTEN не возвращается в пул, чтобы подписка не досталась другим запросам
+	pgConn := conn.Hijack()
+	defer pgConn.Close(context.Background())
+
+	if _, err := pgConn.Exec(ctx, "LISTEN "+Channel); err != nil {
+		return false, err
+	}
+
+	h.setListening(true)
+	defer h.setListening(false)
+
+	checkpointsCtx, stopCheckpoints := context.WithCancel(ctx)
+	defer stopCheckpoints()
+
+	go h.sendCheckpoints(checkpointsCtx)
+
+	for {
+		received, err := pgConn.WaitForNotification(ctx)
+
+		if err != nil {
+			return true, err
+		}
+
+		var n notification
+
+		if err := json.Unmarshal([]byte(received.Payload), &n); err != nil {
+			h.logger.Errorw("error decoding segment event", "payload", received.Payload, "err", err)
+			continue
+		}
+
+		if n.Checkpoint > 0 {
+			h.publishCheckpoint(n.Checkpoint)
+			continue
+		}
+
+		h.publish(&n.SegmentEvent)
+	}
+}
+
+// sendCheckpoints, пока есть подписчики, отправляет в канал границу истории.
+// Уведомления доставляются в порядке коммита, поэтому вместе с контрольной точкой получены уведомления
+// обо всех записях до границы, закоммиченных после подписки. Это верно для любого слушателя канала,
+// поэтому контрольные точки одного экземпляра сервиса используют и остальные.
+func (h *Hub) sendCheckpoints(ctx context.Context) {
+	ticker := time.NewTicker(checkpointInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		if !h.hasSubscribers() {
+			continue
+		}
+
+		if err := h.sendCheckpoint(ctx); err != nil && ctx.Err() == nil {
+			h.logger.Warnw("error sending segment events checkpoint", "err", err)
+		}
+	}
+}
+
+func (h *Hub) sendCheckpoint(ctx context.Context) error {
+	// Граница читается до отправки: транзакции до нее завершились и их уведомления уже в очереди
+	watermark, err := h.watermarks.GetHistoryWatermark(ctx)
+
+	if err != nil || watermark <= 0 {
+		return err
+	}
+
+	payload, err := json.Marshal(notification{Checkpoint: watermark})
+
+	if err != nil {
+		return err
+	}
+
+	_, err = h.db.Exec(ctx, `SELECT pg_notify($1, $2)`, Channel, string(payload))
+
+	return err
+}
+
+func (h *Hub) hasSubscribers() bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	return len(h.subs) > 0
+}
+
+func (h *Hub) setListening(listening bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.listening = listening
+
+	// Пока соединения нет, уведомления пропадают
+	if !listening {
+		h.dropAll()
+	}
+}
+
+// publish отправляет событие подходящим подписчикам, не дожидаясь их.
+func (h *Hub) publish(event *models.SegmentEvent) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for sub := range h.subs {
+		if sub.ns != event.Namespace || !sub.filter.Match(event) {
+			continue
+		}
+
+		h.send(sub, Message{Event: event})
+	}
+}
+
+// publishCheckpoint отправляет контрольную точку всем подписчикам.
+func (h *Hub) publishCheckpoint(checkpoint int64) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for sub := range h.subs {
+		h.send(sub, Message{Checkpoint: checkpoint})
+	}
+}
+
+// send вызывается под h.mu
+func (h *Hub) send(sub *Subscription, msg Message) {
+	select {
+	case sub.messages <- msg:
+	default:
+		h.drop(sub)
+	}
+}
+
+func (h *Hub) remove(sub *Subscription) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	delete(h.subs, sub)
+	metrics.SetEventSubscribers(len(h.subs))
+}
+
+// drop и dropAll вызываются под h.mu
+func (h *Hub) drop(sub *Subscription) {
+	delete(h.subs, sub)
+	close(sub.lost)
+	metrics.SetEventSubscribers(len(h.subs))
+}
+
+func (h *Hub) dropAll() {
+	for sub := range h.subs {
+		h.drop(sub)
+	}
+}
@@ -0,0 +1,108 @@
+package events
+
+import (
+	"testing"
+
+	"github.com/dezzerlol/avitotech-test-2023/internal/db/models"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func newListeningHub() *Hub {
+	hub := NewHub(nil, nil, zap.NewNop().Sugar())
+	hub.setListening(true)
+
+	return hub
+}
+
+func TestHub(t *testing.T) {
+	event := &models.SegmentEvent{ID: 1, Namespace: "default", SegmentSlug: "AVITO_TEST", UserID: 1000, Operation: "I"}
+
+	t.Run("Should deliver events matching namespace and filter", func(t *testing.T) {
+		hub := newListeningHub()
+
+		all, err := hub.Subscribe("default", models.SegmentEventFilter{})
+		require.NoError(t, err)
+
+		byUser, err := hub.Subscribe("default", models.SegmentEventFilter{UserID: 1001})
+		require.NoError(t, err)
+
+		otherNs, err := hub.Subscribe("payments", models.SegmentEventFilter{})
+		require.NoError(t, err)
+
+		hub.publish(event)
+
+		require.Equal(t, Message{Event: event}, <-all.Messages())
+		require.Empty(t, byUser.Messages())
+		require.Empty(t, otherNs.Messages())
+	})
+
+	t.Run("Should deliver checkpoints to all subscribers", func(t *testing.T) {
+		hub := newListeningHub()
+
+		byUser, err := hub.Subscribe("default", models.SegmentEventFilter{UserID: 1001})
+		require.NoError(t, err)
+
+		otherNs, err := hub.Subscribe("payments", models.SegmentEventFilter{})
+		require.NoError(t, err)
+
+		hub.publish(event)
+		hub.publishCheckpoint(5)
+
+		require.Equal(t, Message{Checkpoint: 5}, <-byUser.Messages())
+		require.Equal(t, Message{Checkpoint: 5}, <-otherNs.Messages())
+	})
+
+	t.Run("Should drop slow subscriber", func(t *testing.T) {
+		hub := newListeningHub()
+
+		sub, err := hub.Subscribe("default", models.SegmentEventFilter{})
+		require.NoError(t, err)
+
+		for i := 0; i <= subscriptionBuffer; i++ {
+			hub.publish(event)
+		}
+
+		require.Len(t, sub.Messages(), subscriptionBuffer)
+		require.Empty(t, hub.subs)
+
+		select {
+		case <-sub.Lost():
+		default:
+			t.Fatal("subscription is not lost")
+		}
+
+		// Отмена потерянной подписки ничего не ломает
+		sub.Close()
+	})
+
+	t.Run("Should lose subscriptions when connection is lost", func(t *testing.T) {
+		hub := newListeningHub()
+
+		sub, err := hub.Subscribe("default", models.SegmentEventFilter{})
+		require.NoError(t, err)
+
+		hub.setListening(false)
+		<-sub.Lost()
+
+		_, err = hub.Subscribe("default", models.SegmentEventFilter{})
+		require.ErrorIs(t, err, ErrUnavailable)
+	})
+
+	t.Run("Should reject subscriptions after close", func(t *testing.T) {
+		hub := newListeningHub()
+
+		sub, err := hub.Subscribe("default", models.SegmentEventFilter{})
+		require.NoError(t, err)
+
+		hub.Close()
+		<-sub.Lost()
+
+		_, err = hub.Subscribe("default", models.SegmentEventFilter{})
+		require.ErrorIs(t, err, ErrUnavailable)
+
+		var nilHub *Hub
+		_, err = nilHub.Subscribe("default", models.SegmentEventFilter{})
+		require.ErrorIs(t, err, ErrUnavailable)
+	})
+}
@@ -0,0 +1,18 @@
+package events
+
+import "github.com/dezzerlol/avitotech-test-2023/internal/db/models"
+
+// Sink получает события одного потока. Методы вызываются из одной горутины.
+type Sink interface {
+	// Open вызывается один раз после подписки, до первого события
+	Open() error
+	// Send отправляет событие с курсором, с которого клиент продолжит поток: все события до курсора уже отправлены
+	Send(event *models.SegmentEvent, cursor int64) error
+	// Checkpoint передвигает курсор клиента без события
+	Checkpoint(cursor int64) error
+	// Reset сообщает, что пропущенных событий слишком много и состояние нужно перечитать целиком.
+	// После него поток продолжается с новых событий.
+	Reset() error
+	// Heartbeat вызывается, пока событий нет, чтобы прокси не закрыли соединение
+	Heartbeat() error
+}
@@ -0,0 +1,69 @@
+package grpc
+
+import (
+	"net/http"
+
+	"github.com/dezzerlol/avitotech-test-2023/internal/apierror"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/runtime/protoiface"
+)
+
+// Домен ErrorInfo, по нему клиент отличает коды API от других ошибок
+const errorDomain = "avitotech-segments"
+
+// Статусы gRPC для HTTP статусов ошибок API
+var grpcCodes = map[int]codes.Code{
+	http.StatusBadRequest:              codes.InvalidArgument,
+	http.StatusUnauthorized:            codes.Unauthenticated,
+	http.StatusForbidden:               codes.PermissionDenied,
+	http.StatusNotFound:                codes.NotFound,
+	http.StatusConflict:                codes.FailedPrecondition,
+	http.StatusTooManyRequests:         codes.ResourceExhausted,
+	http.StatusGatewayTimeout:          codes.DeadlineExceeded,
+	apierror.StatusClientClosedRequest: codes.Canceled,
+	http.StatusServiceUnavailable:      codes.Unavailable,
+	http.StatusInternalServerError:     codes.Internal,
+}
+
+// toStatus переводит ошибку в статус gRPC. Код и статус берутся из apierror,
+// поэтому ошибки repo отображаются так же, как в HTTP API. Код API передается в ErrorInfo.Reason,
+// ошибки валидации - в BadRequest.
+func toStatus(err error) error {
+	apiErr := apierror.FromError(err)
+
+	code, ok := grpcCodes[apiErr.Status]
+
+	if !ok {
+		code = codes.Unknown
+	}
+
+	switch apiErr.Code {
+	case apierror.CodeSegmentExists, apierror.CodeNamespaceExists, apierror.CodeLayerExists, apierror.CodeAlreadyExists:
+		code = codes.AlreadyExists
+	}
+
+	st := status.New(code, apiErr.Message)
+
+	details := []protoiface.MessageV1{&errdetails.ErrorInfo{Reason: string(apiErr.Code), Domain: errorDomain}}
+
+	if len(apiErr.Details) > 0 {
+		badRequest := &errdetails.BadRequest{}
+
+		for _, e := range apiErr.Details {
+			badRequest.FieldViolations = append(badRequest.FieldViolations, &errdetails.BadRequest_FieldViolation{
+				Field:       e.Field,
+				Description: e.Message,
+			})
+		}
+
+		details = append(details, badRequest)
+	}
+
+	if withDetails, err := st.WithDetails(details...); err == nil {
+		st = withDetails
+	}
+
+	return st.Err()
+}
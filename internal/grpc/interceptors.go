@@ -0,0 +1,234 @@
+package grpc
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/dezzerlol/avitotech-test-2023/internal/accesslog"
+	"github.com/dezzerlol/avitotech-test-2023/internal/apierror"
+	"github.com/dezzerlol/avitotech-test-2023/internal/audit"
+	"github.com/dezzerlol/avitotech-test-2023/internal/auth"
+	"github.com/dezzerlol/avitotech-test-2023/internal/namespace"
+	"github.com/dezzerlol/avitotech-test-2023/internal/repo"
+	segmentsv1 "github.com/dezzerlol/avitotech-test-2023/pkg/pb/segments/v1"
+	"github.com/go-chi/chi/v5/middleware"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// Ключи metadata совпадают с заголовками HTTP API
+const (
+	mdAPIKey        = "x-api-key"
+	mdAuthorization = "authorization"
+	mdNamespace     = "x-namespace"
+	mdRequestID     = "x-request-id"
+)
+
+// methodRule описывает метод SegmentService: необходимое право и маршрут HTTP API с той же операцией.
+// По маршруту метод получает те же лимиты и ту же корзину, что и HTTP запрос.
+type methodRule struct {
+	scope   string
+	method  string
+	pattern string
+}
+
+var methodRules = map[string]methodRule{
+	segmentsv1.SegmentService_CreateSegment_FullMethodName:        {auth.ScopeSegmentsWrite, http.MethodPost, "/segment"},
+	segmentsv1.SegmentService_DeleteSegment_FullMethodName:        {auth.ScopeSegmentsWrite, http.MethodDelete, "/segment"},
+	segmentsv1.SegmentService_UpdateUserSegments_FullMethodName:   {auth.ScopeUsersWrite, http.MethodPost, "/segment/user"},
+	segmentsv1.SegmentService_GetUserSegments_FullMethodName:      {auth.ScopeSegmentsRead, http.MethodGet, "/segment/user/{userId}"},
+	segmentsv1.SegmentService_BatchGetUserSegments_FullMethodName: {auth.ScopeSegmentsRead, http.MethodPost, "/segment/users/lookup"},
+	segmentsv1.SegmentService_GetUserHistoryReport_FullMethodName: {auth.ScopeReportsRead, http.MethodGet, "/segment/history/{userId}"},
+	segmentsv1.SegmentService_GetSegmentHistory_FullMethodName:    {auth.ScopeSegmentsRead, http.MethodGet, "/segment/{slug}/history"},
+}
+
+// Сервисы, доступные без ключа. Остальные методы без правила в methodRules отклоняются
+var publicServices = map[string]bool{
+	healthpb.Health_ServiceDesc.ServiceName:    true,
+	"grpc.reflection.v1.ServerReflection":      true,
+	"grpc.reflection.v1alpha.ServerReflection": true,
+}
+
+// publicMethod сообщает, относится ли метод вида /package.Service/Method к публичному сервису.
+func publicMethod(fullMethod string) bool {
+	service, _, ok := strings.Cut(strings.TrimPrefix(fullMethod, "/"), "/")
+
+	return ok && publicServices[service]
+}
+
+var errUnknownMethod = apierror.New(http.StatusForbidden, apierror.CodeForbidden, "method is not available")
+
+// recoverer превращает панику обработчика в ошибку INTERNAL, как middleware.Recoverer в HTTP API.
+func (s *Server) recoverer(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (res any, err error) {
+	defer func() {
+		if rec := recover(); rec != nil {
+			s.logger.Errorw("panic in grpc handler", "method", info.FullMethod, "panic", rec)
+			err = toStatus(errors.New("panic"))
+		}
+	}()
+
+	return handler(ctx, req)
+}
+
+// requestID присваивает вызову id из metadata x-request-id или генерирует новый и возвращает его в заголовке ответа.
+func (s *Server) requestID(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	id := audit.RequestID(mdValue(ctx, mdRequestID))
+
+	_ = grpc.SetHeader(ctx, metadata.Pairs(mdRequestID, id))
+
+	return handler(context.WithValue(ctx, middleware.RequestIDKey, id), req)
+}
+
+// accessLog пишет строку лога на каждый вызов после его выполнения.
+func (s *Server) accessLog(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	start := time.Now()
+
+	ctx, entry := accesslog.WithEntry(ctx)
+
+	res, err := handler(ctx, req)
+
+	fields := []any{
+		"request_id", middleware.GetReqID(ctx),
+		"method", info.FullMethod,
+		"code", status.Code(err).String(),
+		"latency", time.Since(start),
+	}
+
+	if sc := trace.SpanContextFromContext(ctx); sc.IsValid() {
+		fields = append(fields, "trace_id", sc.TraceID().String())
+	}
+
+	if entry.UserID != 0 {
+		fields = append(fields, "user_id", entry.UserID)
+	}
+
+	s.logger.Infow("grpc request", fields...)
+
+	return res, err
+}
+
+// authenticate проверяет Bearer токен или API ключ из metadata так же, как HTTP API,
+// проверяет право на метод и доступ к пространству имен из x-namespace
+// и сохраняет в контексте владельца, пространство имен и метаданные аудита.
+// Метод без правила и не из публичного сервиса отклоняется, чтобы новый метод не оказался доступен без ключа.
+func (s *Server) authenticate(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	rule, ok := methodRules[info.FullMethod]
+
+	if !ok {
+		if publicMethod(info.FullMethod) {
+			return handler(ctx, req)
+		}
+
+		return nil, toStatus(errUnknownMethod)
+	}
+
+	scope := rule.scope
+
+	identity, err := s.identify(ctx)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if !identity.HasScope(scope) {
+		return nil, toStatus(apierror.New(http.StatusForbidden, apierror.CodeForbidden, "api key has no scope "+scope))
+	}
+
+	ns := mdValue(ctx, mdNamespace)
+
+	if ns == "" {
+		ns = namespace.Default
+	}
+
+	if !namespace.Valid(ns) {
+		return nil, toStatus(apierror.New(http.StatusBadRequest, apierror.CodeInvalidRequest, "invalid namespace"))
+	}
+
+	if !identity.HasNamespace(ns) {
+		return nil, toStatus(apierror.New(http.StatusForbidden, apierror.CodeNamespaceForbidden, "no access to namespace "+ns))
+	}
+
+	meta := audit.Meta{
+		Actor:     audit.TruncateActor(identity.Method + ":" + identity.Name),
+		RequestID: middleware.GetReqID(ctx),
+	}
+
+	ctx = auth.WithIdentity(ctx, identity)
+	ctx = namespace.WithNamespace(ctx, ns)
+	ctx = audit.WithMeta(ctx, meta)
+
+	return handler(ctx, req)
+}
+
+// authenticateStream пропускает потоковые методы публичных сервисов (reflection, health Watch).
+// У SegmentService потоковых методов нет, остальные потоки отклоняются.
+func (s *Server) authenticateStream(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	if !publicMethod(info.FullMethod) {
+		return toStatus(errUnknownMethod)
+	}
+
+	return handler(srv, ss)
+}
+
+func (s *Server) identify(ctx context.Context) (auth.Identity, error) {
+	var (
+		identity auth.Identity
+		err      error
+	)
+
+	if token, ok := bearerToken(ctx); ok {
+		if s.jwtVerifier == nil {
+			return identity, toStatus(apierror.New(http.StatusUnauthorized, apierror.CodeInvalidToken, "bearer tokens are not accepted"))
+		}
+
+		identity, err = s.jwtVerifier.Verify(ctx, token)
+	} else {
+		key := mdValue(ctx, mdAPIKey)
+
+		if key == "" {
+			return identity, toStatus(apierror.New(http.StatusUnauthorized, apierror.CodeUnauthorized, "api key or bearer token is required"))
+		}
+
+		identity, err = s.authenticator.Authenticate(ctx, key)
+	}
+
+	switch {
+	case err == nil:
+		return identity, nil
+	case errors.Is(err, repo.ErrAPIKeyNotFound):
+		return identity, toStatus(apierror.New(http.StatusUnauthorized, apierror.CodeInvalidAPIKey, "invalid api key"))
+	case errors.Is(err, auth.ErrInvalidToken):
+		return identity, toStatus(apierror.New(http.StatusUnauthorized, apierror.CodeInvalidToken, "invalid bearer token"))
+	case errors.Is(err, context.Canceled):
+		return identity, toStatus(err)
+	default:
+		s.logger.Errorw("error authenticating request", "err", err)
+		return identity, toStatus(err)
+	}
+}
+
+func bearerToken(ctx context.Context) (string, bool) {
+	scheme, token, ok := strings.Cut(mdValue(ctx, mdAuthorization), " ")
+
+	if !ok || !strings.EqualFold(scheme, "Bearer") {
+		return "", false
+	}
+
+	return strings.TrimSpace(token), true
+}
+
+func mdValue(ctx context.Context, key string) string {
+	values := metadata.ValueFromIncomingContext(ctx, key)
+
+	if len(values) == 0 {
+		return ""
+	}
+
+	return values[0]
+}
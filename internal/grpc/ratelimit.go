@@ -0,0 +1,84 @@
+package grpc
+
+import (
+	"context"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+
+	"github.com/dezzerlol/avitotech-test-2023/internal/apierror"
+	"github.com/dezzerlol/avitotech-test-2023/internal/auth"
+	"github.com/dezzerlol/avitotech-test-2023/internal/ratelimit"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+)
+
+// Заголовок ответа с временем до появления токена, как Retry-After в HTTP API
+const mdRetryAfter = "retry-after"
+
+// rateLimitIP ограничивает число вызовов с одного адреса. Выполняется до authenticate,
+// поэтому ограничивает и вызовы без ключа или с неверным ключом.
+func (s *Server) rateLimitIP(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	return s.limitMethod(ctx, req, info, handler, func(rule methodRule) (ratelimit.Result, bool, error) {
+		return s.limiter.AllowIP(ctx, peerIP(ctx), rule.method, rule.pattern)
+	})
+}
+
+// rateLimit ограничивает число вызовов клиента. Метод расходует корзину маршрута HTTP API с той же операцией,
+// поэтому лимиты RATE_LIMIT_ROUTES и корзины клиента общие для HTTP и gRPC.
+func (s *Server) rateLimit(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	return s.limitMethod(ctx, req, info, handler, func(rule methodRule) (ratelimit.Result, bool, error) {
+		identity, ok := auth.FromContext(ctx)
+
+		if !ok {
+			return ratelimit.Result{}, false, nil
+		}
+
+		return s.limiter.Allow(ctx, ratelimit.ClientKey(identity), rule.method, rule.pattern)
+	})
+}
+
+// limitMethod списывает токен через allow и отклоняет вызов с RESOURCE_EXHAUSTED, если корзина пуста.
+// Методы публичных сервисов не ограничиваются. Если хранилище лимитов недоступно, вызовы пропускаются.
+func (s *Server) limitMethod(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler, allow func(rule methodRule) (ratelimit.Result, bool, error)) (any, error) {
+	rule, ok := methodRules[info.FullMethod]
+
+	if s.limiter == nil || !ok {
+		return handler(ctx, req)
+	}
+
+	res, ok, err := allow(rule)
+
+	if err != nil {
+		s.logger.Warnw("rate limit store error", "err", err)
+		return handler(ctx, req)
+	}
+
+	if ok && !res.Allowed {
+		retryAfter := max(int(math.Ceil(res.RetryAfter.Seconds())), 1)
+
+		_ = grpc.SetHeader(ctx, metadata.Pairs(mdRetryAfter, strconv.Itoa(retryAfter)))
+
+		return nil, toStatus(apierror.New(http.StatusTooManyRequests, apierror.CodeRateLimitExceeded, "rate limit exceeded"))
+	}
+
+	return handler(ctx, req)
+}
+
+func peerIP(ctx context.Context) string {
+	p, ok := peer.FromContext(ctx)
+
+	if !ok {
+		return ""
+	}
+
+	ip, _, err := net.SplitHostPort(p.Addr.String())
+
+	if err != nil {
+		return p.Addr.String()
+	}
+
+	return ip
+}
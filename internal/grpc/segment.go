@@ -0,0 +1,353 @@
+package grpc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/dezzerlol/avitotech-test-2023/internal/accesslog"
+	"github.com/dezzerlol/avitotech-test-2023/internal/apierror"
+	"github.com/dezzerlol/avitotech-test-2023/internal/db/models"
+	"github.com/dezzerlol/avitotech-test-2023/internal/handlers/segment"
+	"github.com/dezzerlol/avitotech-test-2023/internal/repo"
+	"github.com/dezzerlol/avitotech-test-2023/internal/report"
+	"github.com/dezzerlol/avitotech-test-2023/internal/tracing"
+	"github.com/dezzerlol/avitotech-test-2023/pkg/payload"
+	segmentsv1 "github.com/dezzerlol/avitotech-test-2023/pkg/pb/segments/v1"
+	"go.uber.org/zap"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+var tracer = tracing.Tracer("internal/grpc")
+
+// Сколько пользователей можно запросить в BatchGetUserSegments
+const maxBatchUsers = 100
+
+//go:generate mockgen -destination=mocks/mock_segment.go -package=mocks github.com/dezzerlol/avitotech-test-2023/internal/grpc SegmentService
+type SegmentService interface {
+	Create(ctx context.Context, segment *models.Segment) error
+	DeleteBySlug(ctx context.Context, segment *models.Segment) error
+	GetUserSegments(ctx context.Context, userId int64) ([]*models.Segment, error)
+	GetUserSegmentsAt(ctx context.Context, userId int64, at time.Time) ([]*models.Segment, error)
+	LookupUserSegments(ctx context.Context, userIds []int64, slugs []string) (*models.UserSegmentsLookup, error)
+	GetUserHistory(ctx context.Context, userId, month, year int64, opts report.Options) (string, error)
+	GetSegmentHistory(ctx context.Context, filter models.SegmentHistoryFilter) (*models.SegmentHistory, error)
+	UpdateUserSegments(ctx context.Context, userId int64, addSegments []string, ttl int64, deleteSegments []string) (segmentsAdded int64, segmentsDeleted int64, err error)
+}
+
+// segmentServer реализует SegmentService из api/proto. Проверки запросов совпадают с HTTP API.
+type segmentServer struct {
+	segmentsv1.UnimplementedSegmentServiceServer
+
+	logger     *zap.SugaredLogger
+	segmentSvc SegmentService
+}
+
+func (s *segmentServer) CreateSegment(ctx context.Context, req *segmentsv1.CreateSegmentRequest) (*segmentsv1.CreateSegmentResponse, error) {
+	ctx, span := tracer.Start(ctx, "grpc.segment.CreateSegment")
+	defer span.End()
+
+	if req.UserPercent < 0 || req.UserPercent > 100 {
+		return nil, invalidArgument("user_percent must be between 0 and 100")
+	}
+
+	if errs := payload.Validate(segment.CreateRequest{Slug: req.Slug, UserPercent: int8(req.UserPercent)}); errs != nil {
+		return nil, toStatus(apierror.Validation(errs))
+	}
+
+	seg := &models.Segment{
+		Slug:        req.Slug,
+		UserPercent: int8(req.UserPercent),
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	if err := s.segmentSvc.Create(ctx, seg); err != nil {
+		return nil, toStatus(err)
+	}
+
+	res := &segmentsv1.CreateSegmentResponse{}
+
+	if seg.CreatedAt != nil {
+		res.CreatedAt = timestamppb.New(*seg.CreatedAt)
+	}
+
+	return res, nil
+}
+
+func (s *segmentServer) DeleteSegment(ctx context.Context, req *segmentsv1.DeleteSegmentRequest) (*segmentsv1.DeleteSegmentResponse, error) {
+	ctx, span := tracer.Start(ctx, "grpc.segment.DeleteSegment")
+	defer span.End()
+
+	if errs := payload.Validate(segment.DeleteRequest{Slug: req.Slug}); errs != nil {
+		return nil, toStatus(apierror.Validation(errs))
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	if err := s.segmentSvc.DeleteBySlug(ctx, &models.Segment{Slug: req.Slug}); err != nil {
+		return nil, toStatus(err)
+	}
+
+	return &segmentsv1.DeleteSegmentResponse{}, nil
+}
+
+func (s *segmentServer) UpdateUserSegments(ctx context.Context, req *segmentsv1.UpdateUserSegmentsRequest) (*segmentsv1.UpdateUserSegmentsResponse, error) {
+	ctx, span := tracer.Start(ctx, "grpc.segment.UpdateUserSegments")
+	defer span.End()
+
+	httpReq := segment.UpdateUserSegmentsRequest{
+		UserId:         req.UserId,
+		AddSegments:    req.AddSegments,
+		TTL:            req.Ttl,
+		DeleteSegments: req.DeleteSegments,
+	}
+
+	if errs := payload.Validate(httpReq); errs != nil {
+		return nil, toStatus(apierror.Validation(errs))
+	}
+
+	accesslog.SetUserID(ctx, req.UserId)
+
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	added, deleted, err := s.segmentSvc.UpdateUserSegments(ctx, req.UserId, req.AddSegments, req.Ttl, req.DeleteSegments)
+
+	if err != nil {
+		return nil, toStatus(err)
+	}
+
+	return &segmentsv1.UpdateUserSegmentsResponse{SegmentsAdded: added, SegmentsDeleted: deleted}, nil
+}
+
+func (s *segmentServer) GetUserSegments(ctx context.Context, req *segmentsv1.GetUserSegmentsRequest) (*segmentsv1.GetUserSegmentsResponse, error) {
+	ctx, span := tracer.Start(ctx, "grpc.segment.GetUserSegments")
+	defer span.End()
+
+	if req.UserId < 1 {
+		return nil, invalidArgument("user_id must be positive")
+	}
+
+	accesslog.SetUserID(ctx, req.UserId)
+
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	segments, err := s.getUserSegments(ctx, req.UserId, req.At)
+
+	if err != nil {
+		return nil, toStatus(err)
+	}
+
+	return &segmentsv1.GetUserSegmentsResponse{Segments: toSegments(segments)}, nil
+}
+
+func (s *segmentServer) BatchGetUserSegments(ctx context.Context, req *segmentsv1.BatchGetUserSegmentsRequest) (*segmentsv1.BatchGetUserSegmentsResponse, error) {
+	ctx, span := tracer.Start(ctx, "grpc.segment.BatchGetUserSegments")
+	defer span.End()
+
+	if len(req.UserIds) == 0 || len(req.UserIds) > maxBatchUsers {
+		return nil, invalidArgument(fmt.Sprintf("user_ids must contain from 1 to %d users", maxBatchUsers))
+	}
+
+	for _, userId := range req.UserIds {
+		if userId < 1 {
+			return nil, invalidArgument("user_ids must be positive")
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	res := &segmentsv1.BatchGetUserSegmentsResponse{}
+
+	// Текущие сегменты всех пользователей получаем одним запросом, история восстанавливается по одному
+	if req.At == nil {
+		lookup, err := s.segmentSvc.LookupUserSegments(ctx, req.UserIds, nil)
+
+		if err != nil {
+			return nil, toStatus(err)
+		}
+
+		for _, userId := range req.UserIds {
+			segments, ok := lookup.Users[userId]
+
+			if !ok {
+				res.Users = append(res.Users, &segmentsv1.UserSegments{UserId: userId, NotFound: true})
+				continue
+			}
+
+			res.Users = append(res.Users, &segmentsv1.UserSegments{UserId: userId, Segments: toSegments(segments)})
+		}
+
+		return res, nil
+	}
+
+	for _, userId := range req.UserIds {
+		segments, err := s.getUserSegments(ctx, userId, req.At)
+
+		// Отсутствие одного пользователя не мешает вернуть остальных
+		if errors.Is(err, repo.ErrUserNotFound) {
+			res.Users = append(res.Users, &segmentsv1.UserSegments{UserId: userId, NotFound: true})
+			continue
+		}
+
+		if err != nil {
+			return nil, toStatus(err)
+		}
+
+		res.Users = append(res.Users, &segmentsv1.UserSegments{UserId: userId, Segments: toSegments(segments)})
+	}
+
+	return res, nil
+}
+
+func (s *segmentServer) getUserSegments(ctx context.Context, userId int64, at *timestamppb.Timestamp) ([]*models.Segment, error) {
+	if at == nil {
+		return s.segmentSvc.GetUserSegments(ctx, userId)
+	}
+
+	if err := at.CheckValid(); err != nil {
+		return nil, apierror.BadRequest(err)
+	}
+
+	return s.segmentSvc.GetUserSegmentsAt(ctx, userId, at.AsTime())
+}
+
+func (s *segmentServer) GetUserHistoryReport(ctx context.Context, req *segmentsv1.GetUserHistoryReportRequest) (*segmentsv1.GetUserHistoryReportResponse, error) {
+	ctx, span := tracer.Start(ctx, "grpc.segment.GetUserHistoryReport")
+	defer span.End()
+
+	if req.UserId < 1 {
+		return nil, invalidArgument("user_id must be positive")
+	}
+
+	accesslog.SetUserID(ctx, req.UserId)
+
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	link, err := s.segmentSvc.GetUserHistory(ctx, req.UserId, req.Month, req.Year, report.DefaultOptions())
+
+	if err != nil {
+		return nil, toStatus(err)
+	}
+
+	return &segmentsv1.GetUserHistoryReportResponse{ReportLink: link}, nil
+}
+
+func (s *segmentServer) GetSegmentHistory(ctx context.Context, req *segmentsv1.GetSegmentHistoryRequest) (*segmentsv1.GetSegmentHistoryResponse, error) {
+	ctx, span := tracer.Start(ctx, "grpc.segment.GetSegmentHistory")
+	defer span.End()
+
+	filter, err := segmentHistoryFilter(req)
+
+	if err != nil {
+		return nil, toStatus(apierror.BadRequest(err))
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	history, err := s.segmentSvc.GetSegmentHistory(ctx, filter)
+
+	if err != nil {
+		return nil, toStatus(err)
+	}
+
+	res := &segmentsv1.GetSegmentHistoryResponse{
+		Slug:            history.SegmentSlug,
+		From:            timestamppb.New(history.From),
+		To:              timestamppb.New(history.To),
+		Bucket:          history.Bucket,
+		EventsTruncated: history.EventsTruncated,
+	}
+
+	for _, b := range history.Buckets {
+		res.Buckets = append(res.Buckets, &segmentsv1.SegmentHistoryBucket{
+			Start:   timestamppb.New(b.Start),
+			Entered: b.Entered,
+			Left:    b.Left,
+			Net:     b.Net,
+			Members: b.Members,
+		})
+	}
+
+	for _, e := range history.Events {
+		res.Events = append(res.Events, &segmentsv1.HistoryEvent{
+			SegmentSlug: e.SegmentSlug,
+			UserId:      e.UserID,
+			Operation:   e.Operation,
+			ExecutedAt:  timestamppb.New(e.ExecutedAt),
+			Source:      e.Source,
+			Actor:       e.Actor,
+			RequestId:   e.RequestID,
+		})
+	}
+
+	return res, nil
+}
+
+func segmentHistoryFilter(req *segmentsv1.GetSegmentHistoryRequest) (models.SegmentHistoryFilter, error) {
+	filter := models.SegmentHistoryFilter{
+		SegmentSlug: req.Slug,
+		Bucket:      req.Bucket,
+	}
+
+	if len(filter.SegmentSlug) < 3 {
+		return filter, errors.New("invalid segment slug")
+	}
+
+	if filter.Bucket == "" {
+		filter.Bucket = models.BucketDay
+	}
+
+	// Таймзона разбирается так же, как параметр tz отчетов
+	opts, err := report.ParseOptions(url.Values{"tz": {req.Tz}})
+	if err != nil {
+		return filter, err
+	}
+
+	filter.Location = opts.Location
+
+	if req.From == nil || req.To == nil {
+		return filter, errors.New("from and to are required")
+	}
+
+	if err := req.From.CheckValid(); err != nil {
+		return filter, fmt.Errorf("from: %w", err)
+	}
+
+	if err := req.To.CheckValid(); err != nil {
+		return filter, fmt.Errorf("to: %w", err)
+	}
+
+	filter.From = req.From.AsTime().In(filter.Location)
+	filter.To = req.To.AsTime().In(filter.Location)
+
+	return filter, filter.Validate()
+}
+
+func toSegments(segments []*models.Segment) []*segmentsv1.Segment {
+	res := make([]*segmentsv1.Segment, 0, len(segments))
+
+	for _, s := range segments {
+		res = append(res, &segmentsv1.Segment{
+			Slug:        s.Slug,
+			UserPercent: int32(s.UserPercent),
+			Deleted:     s.Deleted,
+		})
+	}
+
+	return res
+}
+
+func invalidArgument(msg string) error {
+	return toStatus(apierror.New(http.StatusBadRequest, apierror.CodeInvalidRequest, msg))
+}
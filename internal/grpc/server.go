@@ -0,0 +1,145 @@
+package grpc
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"time"
+
+	"github.com/dezzerlol/avitotech-test-2023/internal/auth"
+	"github.com/dezzerlol/avitotech-test-2023/internal/health"
+	"github.com/dezzerlol/avitotech-test-2023/internal/ratelimit"
+	segmentsv1 "github.com/dezzerlol/avitotech-test-2023/pkg/pb/segments/v1"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	healthgrpc "google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/reflection"
+)
+
+// Как часто статус health сервиса обновляется по проверкам готовности
+const healthInterval = 5 * time.Second
+
+type Authenticator interface {
+	Authenticate(ctx context.Context, key string) (auth.Identity, error)
+}
+
+// Server - gRPC сервер, работающий рядом с HTTP сервером на тех же сервисах.
+type Server struct {
+	logger        *zap.SugaredLogger
+	segmentSvc    SegmentService
+	authenticator Authenticator
+	// nil, если JWT не настроен
+	jwtVerifier *auth.JWTVerifier
+	// nil, если лимиты выключены
+	limiter *ratelimit.Limiter
+	health  *health.Checker
+
+	srv          *grpc.Server
+	healthServer *healthgrpc.Server
+	stop         chan struct{}
+}
+
+func New(
+	logger *zap.SugaredLogger,
+	segmentSvc SegmentService,
+	authenticator Authenticator,
+	jwtVerifier *auth.JWTVerifier,
+	limiter *ratelimit.Limiter,
+	health *health.Checker,
+) *Server {
+	s := &Server{
+		logger:        logger,
+		segmentSvc:    segmentSvc,
+		authenticator: authenticator,
+		jwtVerifier:   jwtVerifier,
+		limiter:       limiter,
+		health:        health,
+		healthServer:  healthgrpc.NewServer(),
+		stop:          make(chan struct{}),
+	}
+
+	s.srv = grpc.NewServer(
+		grpc.ChainUnaryInterceptor(
+			s.recoverer,
+			s.requestID,
+			s.accessLog,
+			s.rateLimitIP,
+			s.authenticate,
+			s.rateLimit,
+		),
+		grpc.ChainStreamInterceptor(s.authenticateStream),
+	)
+
+	segmentsv1.RegisterSegmentServiceServer(s.srv, &segmentServer{logger: logger, segmentSvc: segmentSvc})
+	healthpb.RegisterHealthServer(s.srv, s.healthServer)
+	reflection.Register(s.srv)
+
+	return s
+}
+
+// Run принимает соединения до вызова Stop.
+func (s *Server) Run(host, port string) {
+	addr := fmt.Sprintf("%s:%s", host, port)
+
+	lis, err := net.Listen("tcp", addr)
+
+	if err != nil {
+		s.logger.Fatal(err)
+	}
+
+	go s.watchHealth()
+
+	s.logger.Infoln("Starting grpc server on: ", addr)
+
+	if err := s.srv.Serve(lis); err != nil {
+		s.logger.Fatal(err)
+	}
+}
+
+// Stop дожидается завершения начатых вызовов и останавливает сервер.
+// Если вызовы не завершились за timeout, соединения закрываются.
+func (s *Server) Stop(timeout time.Duration) {
+	close(s.stop)
+	s.healthServer.Shutdown()
+
+	done := make(chan struct{})
+
+	go func() {
+		s.srv.GracefulStop()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(timeout):
+		s.srv.Stop()
+	}
+
+	s.logger.Infoln("Stopped grpc server")
+}
+
+// watchHealth переносит результат проверок готовности в статус grpc.health.v1.Health
+// для всего сервера и для SegmentService.
+func (s *Server) watchHealth() {
+	ticker := time.NewTicker(healthInterval)
+	defer ticker.Stop()
+
+	for {
+		ready, _ := s.health.Ready(context.Background())
+
+		status := healthpb.HealthCheckResponse_SERVING
+		if !ready {
+			status = healthpb.HealthCheckResponse_NOT_SERVING
+		}
+
+		s.healthServer.SetServingStatus("", status)
+		s.healthServer.SetServingStatus(segmentsv1.SegmentService_ServiceDesc.ServiceName, status)
+
+		select {
+		case <-s.stop:
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,370 @@
+package grpc
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/dezzerlol/avitotech-test-2023/internal/auth"
+	"github.com/dezzerlol/avitotech-test-2023/internal/db/models"
+	mock_grpc "github.com/dezzerlol/avitotech-test-2023/internal/grpc/mocks"
+	"github.com/dezzerlol/avitotech-test-2023/internal/health"
+	"github.com/dezzerlol/avitotech-test-2023/internal/namespace"
+	"github.com/dezzerlol/avitotech-test-2023/internal/ratelimit"
+	"github.com/dezzerlol/avitotech-test-2023/internal/repo"
+	segmentsv1 "github.com/dezzerlol/avitotech-test-2023/pkg/pb/segments/v1"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+type keyAuthenticator map[string]auth.Identity
+
+func (a keyAuthenticator) Authenticate(ctx context.Context, key string) (auth.Identity, error) {
+	identity, ok := a[key]
+
+	if !ok {
+		return auth.Identity{}, repo.ErrAPIKeyNotFound
+	}
+
+	return identity, nil
+}
+
+var testKeys = keyAuthenticator{
+	"admin":    {Method: auth.MethodAPIKey, Name: "admin", KeyID: 1, Scopes: auth.Scopes},
+	"reader":   {Method: auth.MethodAPIKey, Name: "reader", KeyID: 2, Scopes: []string{auth.ScopeSegmentsRead}},
+	"payments": {Method: auth.MethodAPIKey, Name: "payments", KeyID: 3, Scopes: auth.Scopes, Namespaces: []string{"payments"}},
+}
+
+func newTestClient(t *testing.T, segmentSvc SegmentService) (segmentsv1.SegmentServiceClient, *grpc.ClientConn) {
+	return newLimitedTestClient(t, segmentSvc, nil)
+}
+
+func newLimitedTestClient(t *testing.T, segmentSvc SegmentService, limiter *ratelimit.Limiter) (segmentsv1.SegmentServiceClient, *grpc.ClientConn) {
+	s := New(zap.NewNop().Sugar(), segmentSvc, testKeys, nil, limiter, health.NewChecker())
+
+	lis := bufconn.Listen(1 << 20)
+
+	go s.srv.Serve(lis)
+	go s.watchHealth()
+
+	t.Cleanup(func() { s.Stop(time.Second) })
+
+	conn, err := grpc.Dial("bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return lis.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	require.NoError(t, err)
+
+	t.Cleanup(func() { conn.Close() })
+
+	return segmentsv1.NewSegmentServiceClient(conn), conn
+}
+
+func withKey(key string, pairs ...string) context.Context {
+	return metadata.NewOutgoingContext(context.Background(), metadata.Pairs(append([]string{"x-api-key", key}, pairs...)...))
+}
+
+// requireStatus проверяет статус gRPC и код API в ErrorInfo.
+func requireStatus(t *testing.T, err error, code codes.Code, reason string) *status.Status {
+	st, ok := status.FromError(err)
+	require.True(t, ok)
+	require.Equal(t, code, st.Code())
+
+	var info *errdetails.ErrorInfo
+
+	for _, d := range st.Details() {
+		if i, ok := d.(*errdetails.ErrorInfo); ok {
+			info = i
+		}
+	}
+
+	require.NotNil(t, info)
+	require.Equal(t, reason, info.Reason)
+
+	return st
+}
+
+func Test_Auth(t *testing.T) {
+	t.Run("Should return Unauthenticated without api key", func(t *testing.T) {
+		client, _ := newTestClient(t, nil)
+
+		_, err := client.GetUserSegments(context.Background(), &segmentsv1.GetUserSegmentsRequest{UserId: 1})
+
+		requireStatus(t, err, codes.Unauthenticated, "UNAUTHORIZED")
+	})
+
+	t.Run("Should return Unauthenticated for unknown api key", func(t *testing.T) {
+		client, _ := newTestClient(t, nil)
+
+		_, err := client.GetUserSegments(withKey("unknown"), &segmentsv1.GetUserSegmentsRequest{UserId: 1})
+
+		requireStatus(t, err, codes.Unauthenticated, "INVALID_API_KEY")
+	})
+
+	t.Run("Should return PermissionDenied without scope", func(t *testing.T) {
+		client, _ := newTestClient(t, nil)
+
+		_, err := client.CreateSegment(withKey("reader"), &segmentsv1.CreateSegmentRequest{Slug: "AVITO_TEST"})
+
+		requireStatus(t, err, codes.PermissionDenied, "FORBIDDEN")
+	})
+
+	t.Run("Should return PermissionDenied for other namespace", func(t *testing.T) {
+		client, _ := newTestClient(t, nil)
+
+		_, err := client.GetUserSegments(withKey("payments", "x-namespace", "delivery"), &segmentsv1.GetUserSegmentsRequest{UserId: 1})
+
+		requireStatus(t, err, codes.PermissionDenied, "NAMESPACE_FORBIDDEN")
+	})
+
+	t.Run("Should pass namespace and request id to service", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		mockSegmentSvc := mock_grpc.NewMockSegmentService(ctrl)
+		client, _ := newTestClient(t, mockSegmentSvc)
+
+		mockSegmentSvc.EXPECT().GetUserSegments(gomock.Any(), int64(1)).DoAndReturn(func(ctx context.Context, userId int64) ([]*models.Segment, error) {
+			require.Equal(t, "payments", namespace.FromContext(ctx))
+			return nil, nil
+		})
+
+		var header metadata.MD
+
+		_, err := client.GetUserSegments(withKey("payments", "x-namespace", "payments", "x-request-id", "req-1"), &segmentsv1.GetUserSegmentsRequest{UserId: 1}, grpc.Header(&header))
+
+		require.NoError(t, err)
+		require.Equal(t, []string{"req-1"}, header.Get("x-request-id"))
+	})
+}
+
+func Test_AuthUnknownMethod(t *testing.T) {
+	s := &Server{logger: zap.NewNop().Sugar()}
+
+	handler := func(ctx context.Context, req any) (any, error) {
+		return "ok", nil
+	}
+
+	t.Run("Should reject method without scope", func(t *testing.T) {
+		_, err := s.authenticate(context.Background(), nil, &grpc.UnaryServerInfo{FullMethod: "/segments.v1.SegmentService/NewMethod"}, handler)
+
+		requireStatus(t, err, codes.PermissionDenied, "FORBIDDEN")
+	})
+
+	t.Run("Should allow health without api key", func(t *testing.T) {
+		res, err := s.authenticate(context.Background(), nil, &grpc.UnaryServerInfo{FullMethod: healthpb.Health_Check_FullMethodName}, handler)
+
+		require.NoError(t, err)
+		require.Equal(t, "ok", res)
+	})
+
+	t.Run("Should allow only streams of public services", func(t *testing.T) {
+		streamHandler := func(srv any, ss grpc.ServerStream) error {
+			return nil
+		}
+
+		err := s.authenticateStream(nil, nil, &grpc.StreamServerInfo{FullMethod: "/grpc.reflection.v1alpha.ServerReflection/ServerReflectionInfo"}, streamHandler)
+		require.NoError(t, err)
+
+		err = s.authenticateStream(nil, nil, &grpc.StreamServerInfo{FullMethod: "/segments.v1.SegmentService/WatchSegments"}, streamHandler)
+		requireStatus(t, err, codes.PermissionDenied, "FORBIDDEN")
+	})
+}
+
+func Test_RateLimit(t *testing.T) {
+	t.Run("Should share route limit with HTTP API", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		rules, err := ratelimit.ParseRules("off", "GET /segment/user/{userId}=2/m")
+		require.NoError(t, err)
+
+		mockSegmentSvc := mock_grpc.NewMockSegmentService(ctrl)
+		mockSegmentSvc.EXPECT().GetUserSegments(gomock.Any(), int64(1)).Return(nil, nil).Times(3)
+
+		client, _ := newLimitedTestClient(t, mockSegmentSvc, ratelimit.NewLimiter(ratelimit.NewMemoryStore(), rules))
+
+		for i := 0; i < 2; i++ {
+			_, err := client.GetUserSegments(withKey("admin"), &segmentsv1.GetUserSegmentsRequest{UserId: 1})
+			require.NoError(t, err)
+		}
+
+		var header metadata.MD
+
+		_, err = client.GetUserSegments(withKey("admin"), &segmentsv1.GetUserSegmentsRequest{UserId: 1}, grpc.Header(&header))
+		requireStatus(t, err, codes.ResourceExhausted, "RATE_LIMIT_EXCEEDED")
+		require.Equal(t, []string{"30"}, header.Get("retry-after"))
+
+		// Лимиты считаются отдельно для каждого ключа
+		_, err = client.GetUserSegments(withKey("reader"), &segmentsv1.GetUserSegmentsRequest{UserId: 1})
+		require.NoError(t, err)
+	})
+
+	t.Run("Should limit address before authentication", func(t *testing.T) {
+		rules := ratelimit.Rules{IP: ratelimit.Limit{Count: 2, Period: time.Minute}}
+
+		client, _ := newLimitedTestClient(t, nil, ratelimit.NewLimiter(ratelimit.NewMemoryStore(), rules))
+
+		for i := 0; i < 2; i++ {
+			_, err := client.GetUserSegments(withKey("unknown"), &segmentsv1.GetUserSegmentsRequest{UserId: 1})
+			requireStatus(t, err, codes.Unauthenticated, "INVALID_API_KEY")
+		}
+
+		_, err := client.GetUserSegments(context.Background(), &segmentsv1.GetUserSegmentsRequest{UserId: 1})
+		requireStatus(t, err, codes.ResourceExhausted, "RATE_LIMIT_EXCEEDED")
+	})
+}
+
+func Test_SegmentService(t *testing.T) {
+	t.Run("Should create segment", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		mockSegmentSvc := mock_grpc.NewMockSegmentService(ctrl)
+		client, _ := newTestClient(t, mockSegmentSvc)
+
+		createdAt := time.Date(2023, 8, 28, 10, 0, 0, 0, time.UTC)
+
+		mockSegmentSvc.EXPECT().Create(gomock.Any(), &models.Segment{Slug: "AVITO_TEST", UserPercent: 50}).DoAndReturn(func(ctx context.Context, segment *models.Segment) error {
+			segment.CreatedAt = &createdAt
+			return nil
+		})
+
+		res, err := client.CreateSegment(withKey("admin"), &segmentsv1.CreateSegmentRequest{Slug: "AVITO_TEST", UserPercent: 50})
+
+		require.NoError(t, err)
+		require.Equal(t, createdAt, res.CreatedAt.AsTime())
+	})
+
+	t.Run("Should return InvalidArgument if slug is too short", func(t *testing.T) {
+		client, _ := newTestClient(t, nil)
+
+		_, err := client.CreateSegment(withKey("admin"), &segmentsv1.CreateSegmentRequest{Slug: "AV"})
+
+		st := requireStatus(t, err, codes.InvalidArgument, "VALIDATION_FAILED")
+
+		var violations *errdetails.BadRequest
+
+		for _, d := range st.Details() {
+			if v, ok := d.(*errdetails.BadRequest); ok {
+				violations = v
+			}
+		}
+
+		require.NotNil(t, violations)
+		require.Equal(t, "Slug", violations.FieldViolations[0].Field)
+	})
+
+	t.Run("Should return AlreadyExists if segment exists", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		mockSegmentSvc := mock_grpc.NewMockSegmentService(ctrl)
+		client, _ := newTestClient(t, mockSegmentSvc)
+
+		mockSegmentSvc.EXPECT().Create(gomock.Any(), gomock.Any()).Return(repo.ErrSegmentAlreadyExists)
+
+		_, err := client.CreateSegment(withKey("admin"), &segmentsv1.CreateSegmentRequest{Slug: "AVITO_TEST"})
+
+		requireStatus(t, err, codes.AlreadyExists, "SEGMENT_ALREADY_EXISTS")
+	})
+
+	t.Run("Should return NotFound if segment not found", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		mockSegmentSvc := mock_grpc.NewMockSegmentService(ctrl)
+		client, _ := newTestClient(t, mockSegmentSvc)
+
+		mockSegmentSvc.EXPECT().DeleteBySlug(gomock.Any(), &models.Segment{Slug: "AVITO_TEST"}).Return(repo.ErrSegmentNotFound)
+
+		_, err := client.DeleteSegment(withKey("admin"), &segmentsv1.DeleteSegmentRequest{Slug: "AVITO_TEST"})
+
+		requireStatus(t, err, codes.NotFound, "SEGMENT_NOT_FOUND")
+	})
+
+	t.Run("Should return Internal without error text", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		mockSegmentSvc := mock_grpc.NewMockSegmentService(ctrl)
+		client, _ := newTestClient(t, mockSegmentSvc)
+
+		mockSegmentSvc.EXPECT().UpdateUserSegments(gomock.Any(), int64(1), []string{"AVITO_TEST"}, int64(0), gomock.Any()).Return(int64(0), int64(0), errors.New("connection refused"))
+
+		_, err := client.UpdateUserSegments(withKey("admin"), &segmentsv1.UpdateUserSegmentsRequest{UserId: 1, AddSegments: []string{"AVITO_TEST"}})
+
+		st := requireStatus(t, err, codes.Internal, "INTERNAL")
+		require.Equal(t, "internal server error", st.Message())
+	})
+
+	t.Run("Should return segments of several users", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		mockSegmentSvc := mock_grpc.NewMockSegmentService(ctrl)
+		client, _ := newTestClient(t, mockSegmentSvc)
+
+		mockSegmentSvc.EXPECT().LookupUserSegments(gomock.Any(), []int64{1, 2}, nil).Return(&models.UserSegmentsLookup{
+			Users:    map[int64][]*models.Segment{1: {{Slug: "AVITO_TEST"}}},
+			NotFound: []int64{2},
+		}, nil)
+
+		res, err := client.BatchGetUserSegments(withKey("reader"), &segmentsv1.BatchGetUserSegmentsRequest{UserIds: []int64{1, 2}})
+
+		require.NoError(t, err)
+		require.Len(t, res.Users, 2)
+		require.Equal(t, "AVITO_TEST", res.Users[0].Segments[0].Slug)
+		require.True(t, res.Users[1].NotFound)
+	})
+
+	t.Run("Should return segments of several users at moment", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		mockSegmentSvc := mock_grpc.NewMockSegmentService(ctrl)
+		client, _ := newTestClient(t, mockSegmentSvc)
+
+		at := time.Date(2023, 8, 28, 10, 0, 0, 0, time.UTC)
+
+		mockSegmentSvc.EXPECT().GetUserSegmentsAt(gomock.Any(), int64(1), at).Return([]*models.Segment{{Slug: "AVITO_TEST", Deleted: true}}, nil)
+		mockSegmentSvc.EXPECT().GetUserSegmentsAt(gomock.Any(), int64(2), at).Return(nil, repo.ErrUserNotFound)
+
+		res, err := client.BatchGetUserSegments(withKey("reader"), &segmentsv1.BatchGetUserSegmentsRequest{UserIds: []int64{1, 2}, At: timestamppb.New(at)})
+
+		require.NoError(t, err)
+		require.True(t, res.Users[0].Segments[0].Deleted)
+		require.True(t, res.Users[1].NotFound)
+	})
+
+	t.Run("Should return InvalidArgument if history period is invalid", func(t *testing.T) {
+		client, _ := newTestClient(t, nil)
+
+		_, err := client.GetSegmentHistory(withKey("reader"), &segmentsv1.GetSegmentHistoryRequest{Slug: "AVITO_TEST", Bucket: "week"})
+
+		requireStatus(t, err, codes.InvalidArgument, "INVALID_REQUEST")
+	})
+}
+
+func Test_Health(t *testing.T) {
+	_, conn := newTestClient(t, nil)
+
+	client := healthpb.NewHealthClient(conn)
+
+	require.Eventually(t, func() bool {
+		res, err := client.Check(context.Background(), &healthpb.HealthCheckRequest{Service: segmentsv1.SegmentService_ServiceDesc.ServiceName})
+		return err == nil && res.Status == healthpb.HealthCheckResponse_SERVING
+	}, time.Second, 10*time.Millisecond)
+}
@@ -0,0 +1,153 @@
+package analytics
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/dezzerlol/avitotech-test-2023/internal/apierror"
+	"github.com/dezzerlol/avitotech-test-2023/internal/db/models"
+	"github.com/dezzerlol/avitotech-test-2023/internal/report"
+	"github.com/dezzerlol/avitotech-test-2023/pkg/payload"
+	"github.com/go-chi/chi/v5"
+)
+
+// Ограничения на размер одного запроса
+const (
+	maxMembershipDays     = 3660
+	maxMembershipSegments = 100
+)
+
+// GetMembership godoc
+// @Summary      Размер сегментов по дням
+// @Description  Метод получения числа пользователей в сегментах на конец каждого дня (UTC) за период, а также числа вошедших и вышедших за день.
+// @Description  Данные берутся из посуточной сводки, которую периодически пересчитывает воркер, время пересчета возвращается в refreshed_at.
+// @Tags         Analytics
+// @Security     ApiKeyAuth
+// @Security     BearerAuth
+// @Produce      json
+// @Param        from query string true "первый день периода (2006-01-02)"
+// @Param        to query string true "последний день периода (2006-01-02)"
+// @Param        segments query string false "slug сегментов через запятую, по умолчанию все"
+// @Success      200  {object} object{membership=models.Membership}
+// @Failure      400,401,403,429,500  {object} apierror.Response
+// @Router       /api/v1/analytics/membership [get]
+func (h *handler) GetMembership(w http.ResponseWriter, r *http.Request) {
+	filter, err := parseMembershipFilter(r)
+	if err != nil {
+		apierror.Write(w, r, apierror.BadRequest(err))
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+	defer cancel()
+
+	membership, err := h.analyticsSvc.GetMembership(ctx, filter)
+
+	if err != nil {
+		apierror.Write(w, r, err)
+		return
+	}
+
+	payload.WriteJSON(w, http.StatusOK, payload.Data{"membership": membership}, nil)
+}
+
+// ExportMembership godoc
+// @Summary      Выгрузка размера сегментов по дням
+// @Description  Метод выгрузки размера сегментов по дням в формате csv, xlsx или json с настройками отчета как у истории пользователя.
+// @Tags         Analytics
+// @Security     ApiKeyAuth
+// @Security     BearerAuth
+// @Produce      text/csv,application/vnd.openxmlformats-officedocument.spreadsheetml.sheet,application/json
+// @Param        format path string true "формат отчета" Enums(csv, xlsx, json)
+// @Param        from query string true "первый день периода (2006-01-02)"
+// @Param        to query string true "последний день периода (2006-01-02)"
+// @Param        segments query string false "slug сегментов через запятую, по умолчанию все"
+// @Param        delimiter query string false "разделитель csv (один символ или tab)" default(,)
+// @Success      200  {file} file
+// @Failure      400,401,403,429,500  {object} apierror.Response
+// @Header 	 	 200 {string} Content-Disposition "attachment;filename=file_name"
+// @Router       /api/v1/analytics/membership/export.{format} [get]
+func (h *handler) ExportMembership(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	query.Set("format", chi.URLParam(r, "format"))
+
+	opts, err := report.ParseOptions(query)
+	if err != nil {
+		apierror.Write(w, r, apierror.BadRequest(err))
+		return
+	}
+
+	filter, err := parseMembershipFilter(r)
+	if err != nil {
+		apierror.Write(w, r, apierror.BadRequest(err))
+		return
+	}
+
+	http.NewResponseController(w).SetWriteDeadline(time.Time{})
+
+	fileName := fmt.Sprintf("membership-%s-%s.%s", filter.From.Format("20060102"), filter.To.Format("20060102"), opts.Format.Ext())
+	stream := payload.NewFileStream(w, r, opts.Format.ContentType(), fileName)
+
+	err = h.analyticsSvc.ExportMembership(r.Context(), filter, opts, stream)
+
+	if err != nil {
+		// Если заголовки уже отправлены, сообщить об ошибке клиенту нельзя,
+		// поэтому обрываем соединение, чтобы отчет не выглядел полным
+		if stream.Started() {
+			// Клиент закрыл соединение сам, это не ошибка сервера
+			if !errors.Is(err, context.Canceled) {
+				h.logger.Errorw("error streaming membership", "err", err)
+			}
+
+			panic(http.ErrAbortHandler)
+		}
+
+		apierror.Write(w, r, err)
+		return
+	}
+
+	stream.Close()
+}
+
+func parseMembershipFilter(r *http.Request) (models.MembershipFilter, error) {
+	var filter models.MembershipFilter
+
+	from, _, err := payload.QueryTime(r, "from", time.UTC)
+	if err != nil {
+		return filter, fmt.Errorf("from: %w", err)
+	}
+
+	to, _, err := payload.QueryTime(r, "to", time.UTC)
+	if err != nil {
+		return filter, fmt.Errorf("to: %w", err)
+	}
+
+	filter.From = time.Date(from.Year(), from.Month(), from.Day(), 0, 0, 0, 0, time.UTC)
+	filter.To = time.Date(to.Year(), to.Month(), to.Day(), 0, 0, 0, 0, time.UTC)
+
+	if filter.To.Before(filter.From) {
+		return filter, errors.New("from must not be after to")
+	}
+
+	if filter.To.Sub(filter.From) > maxMembershipDays*24*time.Hour {
+		return filter, fmt.Errorf("period must contain at most %d days", maxMembershipDays)
+	}
+
+	if segments := r.URL.Query().Get("segments"); segments != "" {
+		for _, slug := range strings.Split(segments, ",") {
+			if slug = strings.TrimSpace(slug); slug != "" {
+				filter.Segments = append(filter.Segments, slug)
+			}
+		}
+	}
+
+	if len(filter.Segments) > maxMembershipSegments {
+		return filter, fmt.Errorf("at most %d segments allowed", maxMembershipSegments)
+	}
+
+	return filter, nil
+}
@@ -0,0 +1,106 @@
+package analytics
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/dezzerlol/avitotech-test-2023/internal/db/models"
+	mock_analytics "github.com/dezzerlol/avitotech-test-2023/internal/handlers/analytics/mocks"
+	"github.com/dezzerlol/avitotech-test-2023/internal/report"
+	"github.com/go-chi/chi/v5"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_GetMembership(t *testing.T) {
+	t.Run("Should return 200 and membership", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		mockAnalyticsSvc := mock_analytics.NewMockAnalyticsService(ctrl)
+
+		filter := models.MembershipFilter{
+			From:     time.Date(2023, 8, 1, 0, 0, 0, 0, time.UTC),
+			To:       time.Date(2023, 8, 31, 0, 0, 0, 0, time.UTC),
+			Segments: []string{"TEST_SEGMENT1", "TEST_SEGMENT2"},
+		}
+
+		mockAnalyticsSvc.EXPECT().GetMembership(gomock.Any(), filter).Return(&models.Membership{}, nil)
+
+		handler := NewHandler(nil, mockAnalyticsSvc)
+
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodGet, "/analytics/membership?from=2023-08-01&to=2023-08-31&segments=TEST_SEGMENT1,TEST_SEGMENT2", nil)
+		handler.GetMembership(w, r)
+
+		require.Equal(t, http.StatusOK, w.Code)
+	})
+
+	t.Run("Should return 400 if period is invalid", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		mockAnalyticsSvc := mock_analytics.NewMockAnalyticsService(ctrl)
+		handler := NewHandler(nil, mockAnalyticsSvc)
+
+		for _, query := range []string{"from=2023-08-01", "from=2023-08-31&to=2023-08-01", "from=1900-01-01&to=2023-08-01"} {
+			w := httptest.NewRecorder()
+			r := httptest.NewRequest(http.MethodGet, "/analytics/membership?"+query, nil)
+			handler.GetMembership(w, r)
+
+			require.Equal(t, http.StatusBadRequest, w.Code, query)
+		}
+	})
+
+	t.Run("Should return 500 if something goes wrong", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		mockAnalyticsSvc := mock_analytics.NewMockAnalyticsService(ctrl)
+		mockAnalyticsSvc.EXPECT().GetMembership(gomock.Any(), gomock.Any()).Return(nil, errors.New("internal error"))
+
+		handler := NewHandler(nil, mockAnalyticsSvc)
+
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodGet, "/analytics/membership?from=2023-08-01&to=2023-08-31", nil)
+		handler.GetMembership(w, r)
+
+		require.Equal(t, http.StatusInternalServerError, w.Code)
+	})
+}
+
+func Test_ExportMembership(t *testing.T) {
+	t.Run("Should return 200 and stream csv", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		mockAnalyticsSvc := mock_analytics.NewMockAnalyticsService(ctrl)
+		mockAnalyticsSvc.EXPECT().
+			ExportMembership(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).
+			DoAndReturn(func(ctx context.Context, filter models.MembershipFilter, opts report.Options, w io.Writer) error {
+				require.Equal(t, report.FormatCSV, opts.Format)
+
+				_, err := io.WriteString(w, "report")
+				return err
+			})
+
+		handler := NewHandler(nil, mockAnalyticsSvc)
+
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodGet, "/analytics/membership/export.csv?from=2023-08-01&to=2023-08-31", nil)
+		rctx := chi.NewRouteContext()
+		rctx.URLParams.Add("format", "csv")
+		r = r.WithContext(context.WithValue(r.Context(), chi.RouteCtxKey, rctx))
+
+		handler.ExportMembership(w, r)
+
+		require.Equal(t, http.StatusOK, w.Code)
+		require.Equal(t, "text/csv", w.Header().Get("Content-Type"))
+		require.Equal(t, "report", w.Body.String())
+	})
+}
@@ -0,0 +1,34 @@
+package analytics
+
+import (
+	"context"
+	"io"
+	"net/http"
+
+	"github.com/dezzerlol/avitotech-test-2023/internal/db/models"
+	"github.com/dezzerlol/avitotech-test-2023/internal/report"
+	"go.uber.org/zap"
+)
+
+type Handler interface {
+	GetMembership(w http.ResponseWriter, r *http.Request)
+	ExportMembership(w http.ResponseWriter, r *http.Request)
+}
+
+//go:generate mockgen -destination=mocks/mock_analytics.go -package=mocks github.com/dezzerlol/avitotech-test-2023/internal/handlers/analytics AnalyticsService
+type AnalyticsService interface {
+	GetMembership(ctx context.Context, filter models.MembershipFilter) (*models.Membership, error)
+	ExportMembership(ctx context.Context, filter models.MembershipFilter, opts report.Options, w io.Writer) error
+}
+
+type handler struct {
+	logger       *zap.SugaredLogger
+	analyticsSvc AnalyticsService
+}
+
+func NewHandler(logger *zap.SugaredLogger, analyticsSvc AnalyticsService) Handler {
+	return &handler{
+		logger:       logger,
+		analyticsSvc: analyticsSvc,
+	}
+}
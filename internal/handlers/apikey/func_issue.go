@@ -0,0 +1,71 @@
+package apikey
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/dezzerlol/avitotech-test-2023/internal/apierror"
+	"github.com/dezzerlol/avitotech-test-2023/internal/db/models"
+	"github.com/dezzerlol/avitotech-test-2023/internal/namespace"
+	"github.com/dezzerlol/avitotech-test-2023/pkg/payload"
+)
+
+type IssueRequest struct {
+	Name   string   `json:"name" validate:"required,max=255" example:"reports-service"`
+	Scopes []string `json:"scopes" validate:"required,min=1,dive,oneof=segments:read segments:write users:write reports:read keys:admin webhooks:admin" example:"segments:read,reports:read"`
+	// Пустой список - доступ ко всем пространствам имен
+	Namespaces []string `json:"namespaces" validate:"omitempty,dive,required,max=64" example:"payments"`
+}
+
+// Issue godoc
+// @Summary      Выпуск API ключа
+// @Description  Метод выпуска API ключа с заданными правами: segments:read, segments:write, users:write, reports:read, keys:admin, webhooks:admin.
+// @Description  namespaces ограничивает ключ пространствами имен, пустой список дает доступ ко всем.
+// @Description  Ключ возвращается только в ответе на этот запрос, в базе хранится его хэш.
+// @Tags         Admin
+// @Accept       json
+// @Produce      json
+// @Security     ApiKeyAuth
+// @Param        body  body  IssueRequest  true  "Запрос на выпуск ключа"
+// @Success      201  {object} object{api_key=models.APIKey,key=string}
+// @Failure      400,401,403,429,500  {object} apierror.Response
+// @Router       /api/v1/admin/keys [post]
+func (h *handler) Issue(w http.ResponseWriter, r *http.Request) {
+	var req IssueRequest
+
+	if err := payload.ReadJSON(w, r, &req); err != nil {
+		apierror.Write(w, r, apierror.BadRequest(err))
+		return
+	}
+
+	if errs := payload.Validate(req); errs != nil {
+		apierror.Write(w, r, apierror.Validation(errs))
+		return
+	}
+
+	for _, ns := range req.Namespaces {
+		if !namespace.Valid(ns) {
+			apierror.Write(w, r, apierror.Validation([]payload.ValidationError{{Field: "Namespaces", Message: "Invalid namespace " + ns}}))
+			return
+		}
+	}
+
+	apiKey := &models.APIKey{
+		Name:       req.Name,
+		Scopes:     req.Scopes,
+		Namespaces: req.Namespaces,
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	key, err := h.apiKeySvc.Issue(ctx, apiKey)
+
+	if err != nil {
+		apierror.Write(w, r, err)
+		return
+	}
+
+	payload.WriteJSON(w, http.StatusCreated, payload.Data{"api_key": apiKey, "key": key}, nil)
+}
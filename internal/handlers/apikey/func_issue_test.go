@@ -0,0 +1,97 @@
+package apikey
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/dezzerlol/avitotech-test-2023/internal/db/models"
+	mock_apikey "github.com/dezzerlol/avitotech-test-2023/internal/handlers/apikey/mocks"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_IssueAPIKey(t *testing.T) {
+	t.Run("Should return 201 and issued key", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		mockAPIKeySvc := mock_apikey.NewMockAPIKeyService(ctrl)
+		mockAPIKeySvc.EXPECT().
+			Issue(gomock.Any(), gomock.Any()).
+			DoAndReturn(func(ctx context.Context, apiKey *models.APIKey) (string, error) {
+				require.Equal(t, "reports-service", apiKey.Name)
+				require.Equal(t, []string{"segments:read", "reports:read"}, apiKey.Scopes)
+
+				apiKey.ID = 1
+				return "seg_key", nil
+			})
+
+		handler := NewHandler(nil, mockAPIKeySvc)
+
+		body, err := json.Marshal(IssueRequest{Name: "reports-service", Scopes: []string{"segments:read", "reports:read"}})
+		require.NoError(t, err)
+
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodPost, "/admin/keys", bytes.NewReader(body))
+		handler.Issue(w, r)
+
+		require.Equal(t, http.StatusCreated, w.Code)
+
+		var resp struct {
+			APIKey models.APIKey `json:"api_key"`
+			Key    string        `json:"key"`
+		}
+
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		require.Equal(t, int64(1), resp.APIKey.ID)
+		require.Equal(t, "seg_key", resp.Key)
+	})
+
+	t.Run("Should return 400 if scope is unknown", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		mockAPIKeySvc := mock_apikey.NewMockAPIKeyService(ctrl)
+		handler := NewHandler(nil, mockAPIKeySvc)
+
+		for _, req := range []IssueRequest{
+			{Name: "reports-service", Scopes: []string{"segments:delete"}},
+			{Name: "reports-service"},
+			{Scopes: []string{"segments:read"}},
+			{Name: "reports-service", Scopes: []string{"segments:read"}, Namespaces: []string{"Payments!"}},
+		} {
+			body, err := json.Marshal(req)
+			require.NoError(t, err)
+
+			w := httptest.NewRecorder()
+			r := httptest.NewRequest(http.MethodPost, "/admin/keys", bytes.NewReader(body))
+			handler.Issue(w, r)
+
+			require.Equal(t, http.StatusBadRequest, w.Code)
+		}
+	})
+
+	t.Run("Should return 500 if something goes wrong", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		mockAPIKeySvc := mock_apikey.NewMockAPIKeyService(ctrl)
+		mockAPIKeySvc.EXPECT().Issue(gomock.Any(), gomock.Any()).Return("", errors.New("internal error"))
+
+		handler := NewHandler(nil, mockAPIKeySvc)
+
+		body, err := json.Marshal(IssueRequest{Name: "reports-service", Scopes: []string{"segments:read"}})
+		require.NoError(t, err)
+
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodPost, "/admin/keys", bytes.NewReader(body))
+		handler.Issue(w, r)
+
+		require.Equal(t, http.StatusInternalServerError, w.Code)
+	})
+}
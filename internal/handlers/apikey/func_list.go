@@ -0,0 +1,33 @@
+package apikey
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/dezzerlol/avitotech-test-2023/internal/apierror"
+	"github.com/dezzerlol/avitotech-test-2023/pkg/payload"
+)
+
+// List godoc
+// @Summary      Список API ключей
+// @Description  Метод получения всех API ключей, включая отозванные. Сами ключи не возвращаются.
+// @Tags         Admin
+// @Produce      json
+// @Security     ApiKeyAuth
+// @Success      200  {object} object{api_keys=[]models.APIKey}
+// @Failure      401,403,429,500  {object} apierror.Response
+// @Router       /api/v1/admin/keys [get]
+func (h *handler) List(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	keys, err := h.apiKeySvc.List(ctx)
+
+	if err != nil {
+		apierror.Write(w, r, err)
+		return
+	}
+
+	payload.WriteJSON(w, http.StatusOK, payload.Data{"api_keys": keys}, nil)
+}
@@ -0,0 +1,40 @@
+package apikey
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/dezzerlol/avitotech-test-2023/internal/apierror"
+	"github.com/dezzerlol/avitotech-test-2023/pkg/payload"
+)
+
+// Revoke godoc
+// @Summary      Отзыв API ключа
+// @Description  Метод отзыва API ключа. Отозванный ключ нельзя вернуть или ротировать.
+// @Tags         Admin
+// @Produce      json
+// @Security     ApiKeyAuth
+// @Param        id path int true "id ключа"
+// @Success      200  {object} object{message=string}
+// @Failure      400,401,403,404,429,500  {object} apierror.Response
+// @Router       /api/v1/admin/keys/{id} [delete]
+func (h *handler) Revoke(w http.ResponseWriter, r *http.Request) {
+	id, err := payload.ParamInt(r, "id")
+	if err != nil {
+		apierror.Write(w, r, apierror.BadRequest(err))
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	err = h.apiKeySvc.Revoke(ctx, id)
+
+	if err != nil {
+		apierror.Write(w, r, err)
+		return
+	}
+
+	payload.WriteJSON(w, http.StatusOK, payload.Data{"message": "ok"}, nil)
+}
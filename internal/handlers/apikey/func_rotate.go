@@ -0,0 +1,40 @@
+package apikey
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/dezzerlol/avitotech-test-2023/internal/apierror"
+	"github.com/dezzerlol/avitotech-test-2023/pkg/payload"
+)
+
+// Rotate godoc
+// @Summary      Ротация API ключа
+// @Description  Метод выпуска нового ключа с теми же правами. Старый ключ перестает работать сразу.
+// @Tags         Admin
+// @Produce      json
+// @Security     ApiKeyAuth
+// @Param        id path int true "id ключа"
+// @Success      200  {object} object{api_key=models.APIKey,key=string}
+// @Failure      400,401,403,404,429,500  {object} apierror.Response
+// @Router       /api/v1/admin/keys/{id}/rotate [post]
+func (h *handler) Rotate(w http.ResponseWriter, r *http.Request) {
+	id, err := payload.ParamInt(r, "id")
+	if err != nil {
+		apierror.Write(w, r, apierror.BadRequest(err))
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	apiKey, key, err := h.apiKeySvc.Rotate(ctx, id)
+
+	if err != nil {
+		apierror.Write(w, r, err)
+		return
+	}
+
+	payload.WriteJSON(w, http.StatusOK, payload.Data{"api_key": apiKey, "key": key}, nil)
+}
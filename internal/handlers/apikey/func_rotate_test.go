@@ -0,0 +1,101 @@
+package apikey
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/dezzerlol/avitotech-test-2023/internal/db/models"
+	mock_apikey "github.com/dezzerlol/avitotech-test-2023/internal/handlers/apikey/mocks"
+	"github.com/dezzerlol/avitotech-test-2023/internal/repo"
+	"github.com/go-chi/chi/v5"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+)
+
+func newKeyRequest(method, target, id string) *http.Request {
+	r := httptest.NewRequest(method, target, nil)
+	rctx := chi.NewRouteContext()
+	rctx.URLParams.Add("id", id)
+
+	return r.WithContext(context.WithValue(r.Context(), chi.RouteCtxKey, rctx))
+}
+
+func Test_RotateAPIKey(t *testing.T) {
+	t.Run("Should return 200 and new key", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		mockAPIKeySvc := mock_apikey.NewMockAPIKeyService(ctrl)
+		mockAPIKeySvc.EXPECT().Rotate(gomock.Any(), int64(1)).Return(&models.APIKey{ID: 1}, "seg_new_key", nil)
+
+		handler := NewHandler(nil, mockAPIKeySvc)
+
+		w := httptest.NewRecorder()
+		handler.Rotate(w, newKeyRequest(http.MethodPost, "/admin/keys/1/rotate", "1"))
+
+		require.Equal(t, http.StatusOK, w.Code)
+		require.Contains(t, w.Body.String(), "seg_new_key")
+	})
+
+	t.Run("Should return 404 if key not found or revoked", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		mockAPIKeySvc := mock_apikey.NewMockAPIKeyService(ctrl)
+		mockAPIKeySvc.EXPECT().Rotate(gomock.Any(), int64(1)).Return(nil, "", repo.ErrAPIKeyNotFound)
+
+		handler := NewHandler(nil, mockAPIKeySvc)
+
+		w := httptest.NewRecorder()
+		handler.Rotate(w, newKeyRequest(http.MethodPost, "/admin/keys/1/rotate", "1"))
+
+		require.Equal(t, http.StatusNotFound, w.Code)
+	})
+
+	t.Run("Should return 400 if id is invalid", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		mockAPIKeySvc := mock_apikey.NewMockAPIKeyService(ctrl)
+		handler := NewHandler(nil, mockAPIKeySvc)
+
+		w := httptest.NewRecorder()
+		handler.Rotate(w, newKeyRequest(http.MethodPost, "/admin/keys/abc/rotate", "abc"))
+
+		require.Equal(t, http.StatusBadRequest, w.Code)
+	})
+}
+
+func Test_RevokeAPIKey(t *testing.T) {
+	t.Run("Should return 200 if key revoked", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		mockAPIKeySvc := mock_apikey.NewMockAPIKeyService(ctrl)
+		mockAPIKeySvc.EXPECT().Revoke(gomock.Any(), int64(1)).Return(nil)
+
+		handler := NewHandler(nil, mockAPIKeySvc)
+
+		w := httptest.NewRecorder()
+		handler.Revoke(w, newKeyRequest(http.MethodDelete, "/admin/keys/1", "1"))
+
+		require.Equal(t, http.StatusOK, w.Code)
+	})
+
+	t.Run("Should return 404 if key not found", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		mockAPIKeySvc := mock_apikey.NewMockAPIKeyService(ctrl)
+		mockAPIKeySvc.EXPECT().Revoke(gomock.Any(), int64(1)).Return(repo.ErrAPIKeyNotFound)
+
+		handler := NewHandler(nil, mockAPIKeySvc)
+
+		w := httptest.NewRecorder()
+		handler.Revoke(w, newKeyRequest(http.MethodDelete, "/admin/keys/1", "1"))
+
+		require.Equal(t, http.StatusNotFound, w.Code)
+	})
+}
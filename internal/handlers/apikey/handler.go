@@ -0,0 +1,36 @@
+package apikey
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/dezzerlol/avitotech-test-2023/internal/db/models"
+	"go.uber.org/zap"
+)
+
+type Handler interface {
+	Issue(w http.ResponseWriter, r *http.Request)
+	List(w http.ResponseWriter, r *http.Request)
+	Rotate(w http.ResponseWriter, r *http.Request)
+	Revoke(w http.ResponseWriter, r *http.Request)
+}
+
+//go:generate mockgen -destination=mocks/mock_apikey.go -package=mocks github.com/dezzerlol/avitotech-test-2023/internal/handlers/apikey APIKeyService
+type APIKeyService interface {
+	Issue(ctx context.Context, apiKey *models.APIKey) (string, error)
+	List(ctx context.Context) ([]*models.APIKey, error)
+	Rotate(ctx context.Context, id int64) (*models.APIKey, string, error)
+	Revoke(ctx context.Context, id int64) error
+}
+
+type handler struct {
+	logger    *zap.SugaredLogger
+	apiKeySvc APIKeyService
+}
+
+func NewHandler(logger *zap.SugaredLogger, apiKeySvc APIKeyService) Handler {
+	return &handler{
+		logger:    logger,
+		apiKeySvc: apiKeySvc,
+	}
+}
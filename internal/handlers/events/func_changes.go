@@ -0,0 +1,73 @@
+package events
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/dezzerlol/avitotech-test-2023/internal/apierror"
+	"github.com/dezzerlol/avitotech-test-2023/pkg/payload"
+)
+
+const (
+	defaultChangesLimit = 100
+	maxChangesLimit     = 1000
+)
+
+// Changes godoc
+// @Summary      Лента изменений сегментов
+// @Description  Метод получения добавлений пользователей в сегменты и удалений из них в пространстве имен запроса
+// @Description  по возрастанию id записи истории, начиная после курсора after. Первый запрос делается без after.
+// @Description  Изменения незавершенных транзакций в ленту не попадают, пока транзакции не завершатся,
+// @Description  поэтому лента не пропускает изменения, а next_cursor нужно сохранять вместе с обработанными изменениями.
+// @Tags         Events
+// @Security     ApiKeyAuth
+// @Security     BearerAuth
+// @Produce      json
+// @Param        after query string false "курсор из next_cursor предыдущего ответа"
+// @Param        limit query int false "количество изменений, по умолчанию 100, не больше 1000"
+// @Success      200  {object} object{changes=[]models.SegmentEvent,next_cursor=string,has_more=bool}
+// @Failure      400,401,403,429,500  {object} apierror.Response
+// @Router       /api/v1/changes [get]
+func (h *handler) Changes(w http.ResponseWriter, r *http.Request) {
+	var after int64
+	var err error
+
+	if value := r.URL.Query().Get("after"); value != "" {
+		after, err = strconv.ParseInt(value, 10, 64)
+
+		if err != nil || after < 0 {
+			apierror.Write(w, r, apierror.BadRequest(errors.New("invalid cursor")))
+			return
+		}
+	}
+
+	limit := int64(defaultChangesLimit)
+
+	if r.URL.Query().Has("limit") {
+		limit, err = payload.QueryInt(r, "limit")
+		if err != nil {
+			apierror.Write(w, r, apierror.BadRequest(err))
+			return
+		}
+	}
+
+	if limit < 1 || limit > maxChangesLimit {
+		apierror.Write(w, r, apierror.BadRequest(errors.New("limit must be from 1 to 1000")))
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	page, err := h.eventsSvc.Changes(ctx, after, limit)
+
+	if err != nil {
+		apierror.Write(w, r, err)
+		return
+	}
+
+	payload.WriteJSON(w, http.StatusOK, payload.Data{"changes": page.Changes, "next_cursor": page.NextCursor, "has_more": page.HasMore}, nil)
+}
@@ -0,0 +1,81 @@
+package events
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/dezzerlol/avitotech-test-2023/internal/db/models"
+	mock_events "github.com/dezzerlol/avitotech-test-2023/internal/handlers/events/mocks"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_Changes(t *testing.T) {
+	t.Run("Should return 200 and next cursor", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		mockEventsSvc := mock_events.NewMockEventsService(ctrl)
+		mockEventsSvc.EXPECT().
+			Changes(gomock.Any(), int64(41), int64(2)).
+			Return(&models.ChangesPage{
+				Changes:    []*models.SegmentEvent{{ID: 42, Namespace: "default", SegmentSlug: "AVITO_TEST", UserID: 1000, Operation: "I", Source: "api"}},
+				NextCursor: "50",
+			}, nil)
+
+		handler := NewHandler(nil, mockEventsSvc)
+
+		w := httptest.NewRecorder()
+		handler.Changes(w, httptest.NewRequest(http.MethodGet, "/changes?after=41&limit=2", nil))
+
+		require.Equal(t, http.StatusOK, w.Code)
+		require.Contains(t, w.Body.String(), `"next_cursor":"50"`)
+		require.Contains(t, w.Body.String(), `"source":"api"`)
+	})
+
+	t.Run("Should read from the beginning without cursor", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		mockEventsSvc := mock_events.NewMockEventsService(ctrl)
+		mockEventsSvc.EXPECT().Changes(gomock.Any(), int64(0), int64(defaultChangesLimit)).Return(&models.ChangesPage{NextCursor: "0"}, nil)
+
+		handler := NewHandler(nil, mockEventsSvc)
+
+		w := httptest.NewRecorder()
+		handler.Changes(w, httptest.NewRequest(http.MethodGet, "/changes", nil))
+
+		require.Equal(t, http.StatusOK, w.Code)
+	})
+
+	t.Run("Should return 400 if cursor or limit is invalid", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		handler := NewHandler(nil, mock_events.NewMockEventsService(ctrl))
+
+		for _, target := range []string{"/changes?after=abc", "/changes?after=-1", "/changes?limit=0", "/changes?limit=1001"} {
+			w := httptest.NewRecorder()
+			handler.Changes(w, httptest.NewRequest(http.MethodGet, target, nil))
+
+			require.Equal(t, http.StatusBadRequest, w.Code, target)
+		}
+	})
+
+	t.Run("Should return 500 if something goes wrong", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		mockEventsSvc := mock_events.NewMockEventsService(ctrl)
+		mockEventsSvc.EXPECT().Changes(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil, errors.New("internal error"))
+
+		handler := NewHandler(nil, mockEventsSvc)
+
+		w := httptest.NewRecorder()
+		handler.Changes(w, httptest.NewRequest(http.MethodGet, "/changes", nil))
+
+		require.Equal(t, http.StatusInternalServerError, w.Code)
+	})
+}
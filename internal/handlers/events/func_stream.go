@@ -0,0 +1,95 @@
+package events
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/dezzerlol/avitotech-test-2023/internal/apierror"
+	"github.com/dezzerlol/avitotech-test-2023/internal/db/models"
+	segmentevents "github.com/dezzerlol/avitotech-test-2023/internal/events"
+	"github.com/dezzerlol/avitotech-test-2023/pkg/payload"
+)
+
+// Stream godoc
+// @Summary      Поток изменений сегментов
+// @Description  Server-Sent Events поток добавлений пользователей в сегменты (segment.user_added) и удалений из них (segment.user_removed), а также смен варианта эксперимента (segment.variant_changed)
+// @Description  в пространстве имен запроса. id записи истории передается в данных, а id сообщения - курсор: все события до него уже отправлены.
+// @Description  При переподключении с заголовком Last-Event-ID (или параметром last_event_id) сначала отправляются события после курсора,
+// @Description  но не больше 10000: если пропущено больше, отправляется событие reset и поток продолжается с новых событий.
+// @Description  События после курсора могут прийти повторно.
+// @Tags         Events
+// @Security     ApiKeyAuth
+// @Security     BearerAuth
+// @Produce      text/event-stream
+// @Param        segment query string false "slug сегмента"
+// @Param        user_id query int false "id пользователя"
+// @Param        last_event_id query int false "последний полученный курсор, если нельзя передать заголовок Last-Event-ID"
+// @Param        Last-Event-ID header int false "последний полученный курсор"
+// @Success      200  {string} string "поток событий"
+// @Failure      400,401,403,429,500,503  {object} apierror.Response
+// @Router       /api/v1/events [get]
+func (h *handler) Stream(w http.ResponseWriter, r *http.Request) {
+	var filter models.SegmentEventFilter
+	var err error
+
+	filter.SegmentSlug = r.URL.Query().Get("segment")
+
+	if r.URL.Query().Has("user_id") {
+		filter.UserID, err = payload.QueryInt(r, "user_id")
+		if err != nil {
+			apierror.Write(w, r, apierror.BadRequest(err))
+			return
+		}
+	}
+
+	afterId, err := lastEventID(r)
+	if err != nil {
+		apierror.Write(w, r, apierror.BadRequest(err))
+		return
+	}
+
+	sink := newSSESink(w)
+
+	err = h.eventsSvc.Stream(r.Context(), afterId, filter, sink)
+
+	if err == nil || r.Context().Err() != nil {
+		return
+	}
+
+	// Пока поток не начат, можно ответить ошибкой
+	if !sink.opened {
+		apierror.Write(w, r, err)
+		return
+	}
+
+	// Поток уже начат, клиент переподключится с Last-Event-ID
+	if errors.Is(err, segmentevents.ErrSubscriptionLost) {
+		h.logger.Infow("segment events subscription lost", "err", err)
+		return
+	}
+
+	h.logger.Errorw("error streaming segment events", "err", err)
+}
+
+// lastEventID возвращает последний полученный курсор из заголовка Last-Event-ID или параметра last_event_id.
+// 0 - клиент подключается впервые.
+func lastEventID(r *http.Request) (int64, error) {
+	value := r.Header.Get("Last-Event-ID")
+
+	if value == "" {
+		value = r.URL.Query().Get("last_event_id")
+	}
+
+	if value == "" {
+		return 0, nil
+	}
+
+	id, err := strconv.ParseInt(value, 10, 64)
+
+	if err != nil || id < 0 {
+		return 0, errors.New("last event id must be a non-negative integer")
+	}
+
+	return id, nil
+}
@@ -0,0 +1,100 @@
+package events
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/dezzerlol/avitotech-test-2023/internal/db/models"
+	segmentevents "github.com/dezzerlol/avitotech-test-2023/internal/events"
+	mock_events "github.com/dezzerlol/avitotech-test-2023/internal/handlers/events/mocks"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func Test_StreamEvents(t *testing.T) {
+	t.Run("Should stream events after Last-Event-ID", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		mockEventsSvc := mock_events.NewMockEventsService(ctrl)
+		mockEventsSvc.EXPECT().
+			Stream(gomock.Any(), int64(41), models.SegmentEventFilter{SegmentSlug: "AVITO_TEST", UserID: 1000}, gomock.Any()).
+			DoAndReturn(func(ctx context.Context, afterId int64, filter models.SegmentEventFilter, sink segmentevents.Sink) error {
+				require.NoError(t, sink.Open())
+				require.NoError(t, sink.Send(&models.SegmentEvent{ID: 42, Namespace: "default", SegmentSlug: "AVITO_TEST", UserID: 1000, Operation: "D"}, 41))
+				require.NoError(t, sink.Checkpoint(45))
+				require.NoError(t, sink.Heartbeat())
+
+				return nil
+			})
+
+		handler := NewHandler(zap.NewNop().Sugar(), mockEventsSvc)
+
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodGet, "/events?segment=AVITO_TEST&user_id=1000", nil)
+		r.Header.Set("Last-Event-ID", "41")
+		handler.Stream(w, r)
+
+		require.Equal(t, http.StatusOK, w.Code)
+		require.Equal(t, "text/event-stream", w.Header().Get("Content-Type"))
+		require.Contains(t, w.Body.String(), "retry: 1000\n\n")
+		// В id - курсор для переподключения, id записи истории - в данных
+		require.Contains(t, w.Body.String(), "id: 41\nevent: segment.user_removed\ndata: {\"id\":42,")
+		require.Contains(t, w.Body.String(), "\n\nid: 45\n\n: ping\n\n")
+	})
+
+	t.Run("Should send reset if too many events are missed", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		mockEventsSvc := mock_events.NewMockEventsService(ctrl)
+		mockEventsSvc.EXPECT().
+			Stream(gomock.Any(), int64(1), models.SegmentEventFilter{}, gomock.Any()).
+			DoAndReturn(func(ctx context.Context, afterId int64, filter models.SegmentEventFilter, sink segmentevents.Sink) error {
+				require.NoError(t, sink.Open())
+				require.NoError(t, sink.Reset())
+
+				return segmentevents.ErrSubscriptionLost
+			})
+
+		handler := NewHandler(zap.NewNop().Sugar(), mockEventsSvc)
+
+		w := httptest.NewRecorder()
+		handler.Stream(w, httptest.NewRequest(http.MethodGet, "/events?last_event_id=1", nil))
+
+		require.Equal(t, http.StatusOK, w.Code)
+		require.Contains(t, w.Body.String(), "event: reset\n")
+	})
+
+	t.Run("Should return 503 if events are unavailable", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		mockEventsSvc := mock_events.NewMockEventsService(ctrl)
+		mockEventsSvc.EXPECT().Stream(gomock.Any(), int64(0), gomock.Any(), gomock.Any()).Return(segmentevents.ErrUnavailable)
+
+		handler := NewHandler(zap.NewNop().Sugar(), mockEventsSvc)
+
+		w := httptest.NewRecorder()
+		handler.Stream(w, httptest.NewRequest(http.MethodGet, "/events", nil))
+
+		require.Equal(t, http.StatusServiceUnavailable, w.Code)
+	})
+
+	t.Run("Should return 400 if Last-Event-ID is invalid", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		handler := NewHandler(zap.NewNop().Sugar(), mock_events.NewMockEventsService(ctrl))
+
+		for _, target := range []string{"/events?last_event_id=abc", "/events?last_event_id=-1", "/events?user_id=abc"} {
+			w := httptest.NewRecorder()
+			handler.Stream(w, httptest.NewRequest(http.MethodGet, target, nil))
+
+			require.Equal(t, http.StatusBadRequest, w.Code, target)
+		}
+	})
+}
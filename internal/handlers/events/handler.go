@@ -0,0 +1,33 @@
+package events
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/dezzerlol/avitotech-test-2023/internal/db/models"
+	segmentevents "github.com/dezzerlol/avitotech-test-2023/internal/events"
+	"go.uber.org/zap"
+)
+
+type Handler interface {
+	Stream(w http.ResponseWriter, r *http.Request)
+	Changes(w http.ResponseWriter, r *http.Request)
+}
+
+//go:generate mockgen -destination=mocks/mock_events.go -package=mocks github.com/dezzerlol/avitotech-test-2023/internal/handlers/events EventsService
+type EventsService interface {
+	Stream(ctx context.Context, afterId int64, filter models.SegmentEventFilter, sink segmentevents.Sink) error
+	Changes(ctx context.Context, after, limit int64) (*models.ChangesPage, error)
+}
+
+type handler struct {
+	logger    *zap.SugaredLogger
+	eventsSvc EventsService
+}
+
+func NewHandler(logger *zap.SugaredLogger, eventsSvc EventsService) Handler {
+	return &handler{
+		logger:    logger,
+		eventsSvc: eventsSvc,
+	}
+}
@@ -0,0 +1,66 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: github.com/dezzerlol/avitotech-test-2023/internal/handlers/events (interfaces: EventsService)
+
+// Package mocks is a generated GoMock package.
+package mocks
+
+import (
+	context "context"
+	reflect "reflect"
+
+	models "github.com/dezzerlol/avitotech-test-2023/internal/db/models"
+	events "github.com/dezzerlol/avitotech-test-2023/internal/events"
+	gomock "github.com/golang/mock/gomock"
+)
+
+// MockEventsService is a mock of EventsService interface.
+type MockEventsService struct {
+	ctrl     *gomock.Controller
+	recorder *MockEventsServiceMockRecorder
+}
+
+// MockEventsServiceMockRecorder is the mock recorder for MockEventsService.
+type MockEventsServiceMockRecorder struct {
+	mock *MockEventsService
+}
+
+// NewMockEventsService creates a new mock instance.
+func NewMockEventsService(ctrl *gomock.Controller) *MockEventsService {
+	mock := &MockEventsService{ctrl: ctrl}
+	mock.recorder = &MockEventsServiceMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockEventsService) EXPECT() *MockEventsServiceMockRecorder {
+	return m.recorder
+}
+
+// Changes mocks base method.
+func (m *MockEventsService) Changes(arg0 context.Context, arg1, arg2 int64) (*models.ChangesPage, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Changes", arg0, arg1, arg2)
+	ret0, _ := ret[0].(*models.ChangesPage)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Changes indicates an expected call of Changes.
+func (mr *MockEventsServiceMockRecorder) Changes(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Changes", reflect.TypeOf((*MockEventsService)(nil).Changes), arg0, arg1, arg2)
+}
+
+// Stream mocks base method.
+func (m *MockEventsService) Stream(arg0 context.Context, arg1 int64, arg2 models.SegmentEventFilter, arg3 events.Sink) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Stream", arg0, arg1, arg2, arg3)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Stream indicates an expected call of Stream.
+func (mr *MockEventsServiceMockRecorder) Stream(arg0, arg1, arg2, arg3 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Stream", reflect.TypeOf((*MockEventsService)(nil).Stream), arg0, arg1, arg2, arg3)
+}
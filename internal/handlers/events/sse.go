@@ -0,0 +1,88 @@
+package events
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/dezzerlol/avitotech-test-2023/internal/db/models"
+)
+
+// Через сколько миллисекунд EventSource переподключается после разрыва
+const retryMs = 1000
+
+// Событие, после которого клиенту нужно перечитать состояние целиком
+const eventReset = "reset"
+
+// sseSink пишет события в ответ в формате text/event-stream.
+type sseSink struct {
+	w      http.ResponseWriter
+	rc     *http.ResponseController
+	opened bool
+}
+
+func newSSESink(w http.ResponseWriter) *sseSink {
+	return &sseSink{w: w, rc: http.NewResponseController(w)}
+}
+
+func (s *sseSink) Open() error {
+	// Поток открыт, пока клиент не отключится
+	s.rc.SetWriteDeadline(time.Time{})
+
+	s.w.Header().Set("Content-Type", "text/event-stream")
+	s.w.Header().Set("Cache-Control", "no-cache")
+	// Отключает буферизацию ответа в nginx
+	s.w.Header().Set("X-Accel-Buffering", "no")
+	s.w.WriteHeader(http.StatusOK)
+
+	s.opened = true
+
+	return s.write("retry: " + strconv.Itoa(retryMs) + "\n\n")
+}
+
+// Send пишет курсор в поле id: его EventSource передаст в Last-Event-ID при переподключении.
+// id записи истории остается в данных события.
+func (s *sseSink) Send(event *models.SegmentEvent, cursor int64) error {
+	data, err := json.Marshal(event)
+
+	if err != nil {
+		return err
+	}
+
+	return s.write(fmt.Sprintf("id: %d\nevent: %s\ndata: %s\n\n", cursor, eventType(event), data))
+}
+
+// Checkpoint отправляет сообщение только с id: EventSource запоминает его, но событие не вызывает.
+func (s *sseSink) Checkpoint(cursor int64) error {
+	return s.write(fmt.Sprintf("id: %d\n\n", cursor))
+}
+
+func (s *sseSink) Reset() error {
+	return s.write("event: " + eventReset + "\ndata: {}\n\n")
+}
+
+func (s *sseSink) Heartbeat() error {
+	return s.write(": ping\n\n")
+}
+
+func (s *sseSink) write(msg string) error {
+	if _, err := s.w.Write([]byte(msg)); err != nil {
+		return err
+	}
+
+	return s.rc.Flush()
+}
+
+// Тип события совпадает с типом события вебхука
+func eventType(event *models.SegmentEvent) string {
+	switch event.Operation {
+	case "D":
+		return models.EventUserRemoved
+	case "V":
+		return models.EventVariantChanged
+	}
+
+	return models.EventUserAdded
+}
@@ -0,0 +1,57 @@
+package layer
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/dezzerlol/avitotech-test-2023/internal/apierror"
+	"github.com/dezzerlol/avitotech-test-2023/internal/db/models"
+	"github.com/dezzerlol/avitotech-test-2023/pkg/payload"
+)
+
+type CreateRequest struct {
+	Slug        string `json:"slug" validate:"required,min=3,max=255" example:"CHECKOUT_EXPERIMENTS"`
+	Description string `json:"description" validate:"max=1000" example:"Эксперименты с оформлением заказа"`
+}
+
+// Create godoc
+// @Summary      Создание слоя
+// @Description  Метод создания слоя - группы взаимоисключающих сегментов: пользователь состоит не больше чем в одном сегменте слоя.
+// @Description  Сегмент попадает в слой при создании (поле layer).
+// @Tags         Layer
+// @Security     ApiKeyAuth
+// @Security     BearerAuth
+// @Accept       json
+// @Produce      json
+// @Param        body  body  CreateRequest  true  "Запрос на создание"
+// @Success      201  {object} object{layer=models.Layer}
+// @Failure      400,401,403,404,409,429,500  {object} apierror.Response
+// @Router       /api/v1/layers [post]
+func (h *handler) Create(w http.ResponseWriter, r *http.Request) {
+	var req CreateRequest
+
+	if err := payload.ReadJSON(w, r, &req); err != nil {
+		apierror.Write(w, r, apierror.BadRequest(err))
+		return
+	}
+
+	if errs := payload.Validate(req); errs != nil {
+		apierror.Write(w, r, apierror.Validation(errs))
+		return
+	}
+
+	layer := &models.Layer{Slug: req.Slug, Description: req.Description}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	err := h.layerSvc.Create(ctx, layer)
+
+	if err != nil {
+		apierror.Write(w, r, err)
+		return
+	}
+
+	payload.WriteJSON(w, http.StatusCreated, payload.Data{"layer": layer}, nil)
+}
@@ -0,0 +1,74 @@
+package layer
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dezzerlol/avitotech-test-2023/internal/db/models"
+	mock_layer "github.com/dezzerlol/avitotech-test-2023/internal/handlers/layer/mocks"
+	"github.com/dezzerlol/avitotech-test-2023/internal/repo"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_CreateLayer(t *testing.T) {
+	t.Run("Should return 201 and create layer", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		mockLayerSvc := mock_layer.NewMockLayerService(ctrl)
+		mockLayerSvc.EXPECT().
+			Create(gomock.Any(), &models.Layer{Slug: "CHECKOUT", Description: "checkout"}).
+			DoAndReturn(func(ctx context.Context, layer *models.Layer) error {
+				layer.CreatedAt = time.Now()
+				layer.Segments = []*models.LayerSegment{}
+				layer.FreeBuckets = models.LayerBuckets
+				return nil
+			})
+
+		handler := NewHandler(nil, mockLayerSvc)
+
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodPost, "/layers", strings.NewReader(`{"slug": "CHECKOUT", "description": "checkout"}`))
+		handler.Create(w, r)
+
+		require.Equal(t, http.StatusCreated, w.Code)
+		require.Contains(t, w.Body.String(), `"free_buckets":100`)
+	})
+
+	t.Run("Should return 400 if slug is invalid", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		handler := NewHandler(nil, mock_layer.NewMockLayerService(ctrl))
+
+		for _, body := range []string{`{}`, `{"slug": "CH"}`} {
+			w := httptest.NewRecorder()
+			r := httptest.NewRequest(http.MethodPost, "/layers", strings.NewReader(body))
+			handler.Create(w, r)
+
+			require.Equal(t, http.StatusBadRequest, w.Code, body)
+		}
+	})
+
+	t.Run("Should return 409 if layer already exists", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		mockLayerSvc := mock_layer.NewMockLayerService(ctrl)
+		mockLayerSvc.EXPECT().Create(gomock.Any(), gomock.Any()).Return(repo.ErrLayerAlreadyExists)
+
+		handler := NewHandler(nil, mockLayerSvc)
+
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodPost, "/layers", strings.NewReader(`{"slug": "CHECKOUT"}`))
+		handler.Create(w, r)
+
+		require.Equal(t, http.StatusConflict, w.Code)
+		require.Contains(t, w.Body.String(), "LAYER_ALREADY_EXISTS")
+	})
+}
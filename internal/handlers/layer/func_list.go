@@ -0,0 +1,34 @@
+package layer
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/dezzerlol/avitotech-test-2023/internal/apierror"
+	"github.com/dezzerlol/avitotech-test-2023/pkg/payload"
+)
+
+// List godoc
+// @Summary      Список слоев
+// @Description  Метод получения слоев пространства имен с сегментами, занятыми ими бакетами и числом свободных бакетов.
+// @Tags         Layer
+// @Security     ApiKeyAuth
+// @Security     BearerAuth
+// @Produce      json
+// @Success      200  {object} object{layers=[]models.Layer}
+// @Failure      401,403,429,500  {object} apierror.Response
+// @Router       /api/v1/layers [get]
+func (h *handler) List(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	layers, err := h.layerSvc.List(ctx)
+
+	if err != nil {
+		apierror.Write(w, r, err)
+		return
+	}
+
+	payload.WriteJSON(w, http.StatusOK, payload.Data{"layers": layers}, nil)
+}
@@ -0,0 +1,32 @@
+package layer
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/dezzerlol/avitotech-test-2023/internal/db/models"
+	"go.uber.org/zap"
+)
+
+type Handler interface {
+	Create(w http.ResponseWriter, r *http.Request)
+	List(w http.ResponseWriter, r *http.Request)
+}
+
+//go:generate mockgen -destination=mocks/mock_layer.go -package=mocks github.com/dezzerlol/avitotech-test-2023/internal/handlers/layer LayerService
+type LayerService interface {
+	Create(ctx context.Context, layer *models.Layer) error
+	List(ctx context.Context) ([]*models.Layer, error)
+}
+
+type handler struct {
+	logger   *zap.SugaredLogger
+	layerSvc LayerService
+}
+
+func NewHandler(logger *zap.SugaredLogger, layerSvc LayerService) Handler {
+	return &handler{
+		logger:   logger,
+		layerSvc: layerSvc,
+	}
+}
@@ -0,0 +1,65 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: github.com/dezzerlol/avitotech-test-2023/internal/handlers/layer (interfaces: LayerService)
+
+// Package mocks is a generated GoMock package.
+package mocks
+
+import (
+	context "context"
+	reflect "reflect"
+
+	models "github.com/dezzerlol/avitotech-test-2023/internal/db/models"
+	gomock "github.com/golang/mock/gomock"
+)
+
+// MockLayerService is a mock of LayerService interface.
+type MockLayerService struct {
+	ctrl     *gomock.Controller
+	recorder *MockLayerServiceMockRecorder
+}
+
+// MockLayerServiceMockRecorder is the mock recorder for MockLayerService.
+type MockLayerServiceMockRecorder struct {
+	mock *MockLayerService
+}
+
+// NewMockLayerService creates a new mock instance.
+func NewMockLayerService(ctrl *gomock.Controller) *MockLayerService {
+	mock := &MockLayerService{ctrl: ctrl}
+	mock.recorder = &MockLayerServiceMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockLayerService) EXPECT() *MockLayerServiceMockRecorder {
+	return m.recorder
+}
+
+// Create mocks base method.
+func (m *MockLayerService) Create(arg0 context.Context, arg1 *models.Layer) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Create", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Create indicates an expected call of Create.
+func (mr *MockLayerServiceMockRecorder) Create(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Create", reflect.TypeOf((*MockLayerService)(nil).Create), arg0, arg1)
+}
+
+// List mocks base method.
+func (m *MockLayerService) List(arg0 context.Context) ([]*models.Layer, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "List", arg0)
+	ret0, _ := ret[0].([]*models.Layer)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// List indicates an expected call of List.
+func (mr *MockLayerServiceMockRecorder) List(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "List", reflect.TypeOf((*MockLayerService)(nil).List), arg0)
+}
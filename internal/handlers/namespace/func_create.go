@@ -0,0 +1,62 @@
+package namespace
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/dezzerlol/avitotech-test-2023/internal/apierror"
+	"github.com/dezzerlol/avitotech-test-2023/internal/db/models"
+	"github.com/dezzerlol/avitotech-test-2023/internal/namespace"
+	"github.com/dezzerlol/avitotech-test-2023/pkg/payload"
+)
+
+type CreateRequest struct {
+	Slug string `json:"slug" validate:"required,max=64" example:"payments"`
+}
+
+// Create godoc
+// @Summary      Создание пространства имен
+// @Description  Метод создания пространства имен (проекта). Сегменты, их история и отчеты хранятся отдельно для каждого пространства имен,
+// @Description  поэтому один и тот же slug сегмента может быть в разных пространствах имен.
+// @Description  slug: строчные латинские буквы, цифры, "_" и "-", до 64 символов.
+// @Tags         Admin
+// @Accept       json
+// @Produce      json
+// @Security     ApiKeyAuth
+// @Param        body  body  CreateRequest  true  "Запрос на создание"
+// @Success      201  {object} object{namespace=models.Namespace}
+// @Failure      400,401,403,409,429,500  {object} apierror.Response
+// @Router       /api/v1/admin/namespaces [post]
+func (h *handler) Create(w http.ResponseWriter, r *http.Request) {
+	var req CreateRequest
+
+	if err := payload.ReadJSON(w, r, &req); err != nil {
+		apierror.Write(w, r, apierror.BadRequest(err))
+		return
+	}
+
+	if errs := payload.Validate(req); errs != nil {
+		apierror.Write(w, r, apierror.Validation(errs))
+		return
+	}
+
+	if !namespace.Valid(req.Slug) {
+		apierror.Write(w, r, apierror.Validation([]payload.ValidationError{{Field: "Slug", Message: "This field must match " + namespace.Pattern}}))
+		return
+	}
+
+	ns := &models.Namespace{Slug: req.Slug}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	err := h.namespaceSvc.Create(ctx, ns)
+
+	if err != nil {
+		apierror.Write(w, r, err)
+		return
+	}
+
+	payload.WriteJSON(w, http.StatusCreated, payload.Data{"namespace": ns}, nil)
+}
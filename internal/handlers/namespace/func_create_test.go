@@ -0,0 +1,88 @@
+package namespace
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dezzerlol/avitotech-test-2023/internal/db/models"
+	mock_namespace "github.com/dezzerlol/avitotech-test-2023/internal/handlers/namespace/mocks"
+	"github.com/dezzerlol/avitotech-test-2023/internal/repo"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_CreateNamespace(t *testing.T) {
+	t.Run("Should return 201 and create namespace", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		mockNamespaceSvc := mock_namespace.NewMockNamespaceService(ctrl)
+		mockNamespaceSvc.EXPECT().
+			Create(gomock.Any(), &models.Namespace{Slug: "payments"}).
+			DoAndReturn(func(ctx context.Context, ns *models.Namespace) error {
+				ns.CreatedAt = time.Now()
+				return nil
+			})
+
+		handler := NewHandler(nil, mockNamespaceSvc)
+
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodPost, "/admin/namespaces", strings.NewReader(`{"slug": "payments"}`))
+		handler.Create(w, r)
+
+		require.Equal(t, http.StatusCreated, w.Code)
+	})
+
+	t.Run("Should return 400 if slug is invalid", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		mockNamespaceSvc := mock_namespace.NewMockNamespaceService(ctrl)
+		handler := NewHandler(nil, mockNamespaceSvc)
+
+		for _, body := range []string{`{}`, `{"slug": "Payments"}`, `{"slug": "pay/ments"}`} {
+			w := httptest.NewRecorder()
+			r := httptest.NewRequest(http.MethodPost, "/admin/namespaces", strings.NewReader(body))
+			handler.Create(w, r)
+
+			require.Equal(t, http.StatusBadRequest, w.Code, body)
+		}
+	})
+
+	t.Run("Should return 409 if namespace already exists", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		mockNamespaceSvc := mock_namespace.NewMockNamespaceService(ctrl)
+		mockNamespaceSvc.EXPECT().Create(gomock.Any(), gomock.Any()).Return(repo.ErrNamespaceAlreadyExists)
+
+		handler := NewHandler(nil, mockNamespaceSvc)
+
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodPost, "/admin/namespaces", strings.NewReader(`{"slug": "payments"}`))
+		handler.Create(w, r)
+
+		require.Equal(t, http.StatusConflict, w.Code)
+	})
+
+	t.Run("Should return 500 if something goes wrong", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		mockNamespaceSvc := mock_namespace.NewMockNamespaceService(ctrl)
+		mockNamespaceSvc.EXPECT().Create(gomock.Any(), gomock.Any()).Return(errors.New("internal error"))
+
+		handler := NewHandler(nil, mockNamespaceSvc)
+
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodPost, "/admin/namespaces", strings.NewReader(`{"slug": "payments"}`))
+		handler.Create(w, r)
+
+		require.Equal(t, http.StatusInternalServerError, w.Code)
+	})
+}
@@ -0,0 +1,33 @@
+package namespace
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/dezzerlol/avitotech-test-2023/internal/apierror"
+	"github.com/dezzerlol/avitotech-test-2023/pkg/payload"
+)
+
+// List godoc
+// @Summary      Список пространств имен
+// @Description  Метод получения всех пространств имен.
+// @Tags         Admin
+// @Produce      json
+// @Security     ApiKeyAuth
+// @Success      200  {object} object{namespaces=[]models.Namespace}
+// @Failure      401,403,429,500  {object} apierror.Response
+// @Router       /api/v1/admin/namespaces [get]
+func (h *handler) List(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	namespaces, err := h.namespaceSvc.List(ctx)
+
+	if err != nil {
+		apierror.Write(w, r, err)
+		return
+	}
+
+	payload.WriteJSON(w, http.StatusOK, payload.Data{"namespaces": namespaces}, nil)
+}
@@ -0,0 +1,32 @@
+package namespace
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/dezzerlol/avitotech-test-2023/internal/db/models"
+	"go.uber.org/zap"
+)
+
+type Handler interface {
+	Create(w http.ResponseWriter, r *http.Request)
+	List(w http.ResponseWriter, r *http.Request)
+}
+
+//go:generate mockgen -destination=mocks/mock_namespace.go -package=mocks github.com/dezzerlol/avitotech-test-2023/internal/handlers/namespace NamespaceService
+type NamespaceService interface {
+	Create(ctx context.Context, ns *models.Namespace) error
+	List(ctx context.Context) ([]*models.Namespace, error)
+}
+
+type handler struct {
+	logger       *zap.SugaredLogger
+	namespaceSvc NamespaceService
+}
+
+func NewHandler(logger *zap.SugaredLogger, namespaceSvc NamespaceService) Handler {
+	return &handler{
+		logger:       logger,
+		namespaceSvc: namespaceSvc,
+	}
+}
@@ -0,0 +1,65 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: github.com/dezzerlol/avitotech-test-2023/internal/handlers/namespace (interfaces: NamespaceService)
+
+// Package mocks is a generated GoMock package.
+package mocks
+
+import (
+	context "context"
+	reflect "reflect"
+
+	models "github.com/dezzerlol/avitotech-test-2023/internal/db/models"
+	gomock "github.com/golang/mock/gomock"
+)
+
+// MockNamespaceService is a mock of NamespaceService interface.
+type MockNamespaceService struct {
+	ctrl     *gomock.Controller
+	recorder *MockNamespaceServiceMockRecorder
+}
+
+// MockNamespaceServiceMockRecorder is the mock recorder for MockNamespaceService.
+type MockNamespaceServiceMockRecorder struct {
+	mock *MockNamespaceService
+}
+
+// NewMockNamespaceService creates a new mock instance.
+func NewMockNamespaceService(ctrl *gomock.Controller) *MockNamespaceService {
+	mock := &MockNamespaceService{ctrl: ctrl}
+	mock.recorder = &MockNamespaceServiceMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockNamespaceService) EXPECT() *MockNamespaceServiceMockRecorder {
+	return m.recorder
+}
+
+// Create mocks base method.
+func (m *MockNamespaceService) Create(arg0 context.Context, arg1 *models.Namespace) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Create", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Create indicates an expected call of Create.
+func (mr *MockNamespaceServiceMockRecorder) Create(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Create", reflect.TypeOf((*MockNamespaceService)(nil).Create), arg0, arg1)
+}
+
+// List mocks base method.
+func (m *MockNamespaceService) List(arg0 context.Context) ([]*models.Namespace, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "List", arg0)
+	ret0, _ := ret[0].([]*models.Namespace)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// List indicates an expected call of List.
+func (mr *MockNamespaceServiceMockRecorder) List(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "List", reflect.TypeOf((*MockNamespaceService)(nil).List), arg0)
+}
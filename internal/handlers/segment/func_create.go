@@ -6,43 +6,101 @@ import (
 	"net/http"
 	"time"
 
+	"github.com/dezzerlol/avitotech-test-2023/internal/apierror"
 	"github.com/dezzerlol/avitotech-test-2023/internal/db/models"
-	"github.com/dezzerlol/avitotech-test-2023/internal/repo"
+	"github.com/dezzerlol/avitotech-test-2023/internal/rules"
 	"github.com/dezzerlol/avitotech-test-2023/pkg/payload"
 )
 
 type CreateRequest struct {
 	Slug        string `json:"slug" validate:"required,min=3" example:"AVITO_VOICE_MESSAGES"`
 	UserPercent int8   `json:"user_percent" validate:"omitempty,min=1,max=100" example:"50"`
+	Rule        string `json:"rule" validate:"omitempty,max=1000" example:"city in [\"msk\", \"spb\"] && platform == \"ios\""`
+	Layer       string `json:"layer" validate:"omitempty,max=255" example:"CHECKOUT_EXPERIMENTS"`
+	// Варианты эксперимента, сумма весов - 100
+	Variants []VariantRequest `json:"variants" validate:"omitempty,min=2,max=20,dive"`
 }
 
 // Create godoc
 // @Summary      Создание сегмента
 // @Description  Метод создания сегмента. Принимает slug (название) сегмента.
+// @Description  Сегмент создается в пространстве имен запроса, пространство имен должно существовать.
 // @Description  Если указан user_percent, то сегмент будет добавлен случайным пользователям в заданном проценте от общего числа.
+// @Description  Если указан rule, то сегмент динамический: он есть у всех пользователей, атрибуты которых подходят под правило.
+// @Description  Правило нельзя изменить после создания, rule и user_percent не указываются вместе.
+// @Description  Если указан layer, то сегмент входит в слой взаимоисключающих сегментов. Раскатка на user_percent занимает
+// @Description  свободный диапазон бакетов слоя и не задевает пользователей других сегментов слоя; если бакетов не хватает - 409 LAYER_FULL.
+// @Description  Если указаны variants, то сегмент - эксперимент: каждому участнику при добавлении назначается вариант
+// @Description  по хешу id пользователя с учетом весов. Вариант возвращается в сегментах пользователя и не меняется при изменении весов.
 // @Tags         Segment
+// @Security     ApiKeyAuth
+// @Security     BearerAuth
 // @Accept       json
 // @Produce      json
 // @Param        body  body  CreateRequest  true  "Запрос на создание"
 // @Success      201  {object} object{created_at=string}
-// @Failure      400,500  {object} object{error=string}
-// @Router       /segment [post]
+// @Failure      400,401,403,404,409,429,500  {object} apierror.Response
+// @Router       /api/v1/segment [post]
 func (h *handler) Create(w http.ResponseWriter, r *http.Request) {
+	ctx, span := tracer.Start(r.Context(), "handlers.segment.Create")
+	defer span.End()
+
+	r = r.WithContext(ctx)
+
 	var req CreateRequest
 
 	if err := payload.ReadJSON(w, r, &req); err != nil {
-		payload.WriteJSON(w, http.StatusBadRequest, payload.Data{"error": err.Error()}, nil)
+		apierror.Write(w, r, apierror.BadRequest(err))
 		return
 	}
 
 	if errs := payload.Validate(req); errs != nil {
-		payload.WriteJSON(w, http.StatusBadRequest, payload.Data{"error": errs}, nil)
+		apierror.Write(w, r, apierror.Validation(errs))
 		return
 	}
 
+	if req.Rule != "" {
+		if req.UserPercent > 0 {
+			apierror.Write(w, r, apierror.BadRequest(errors.New("rule and user_percent cannot be used together")))
+			return
+		}
+
+		// Членство по правилу не хранится, поэтому его нельзя проверить на пересечение с сегментами слоя
+		if req.Layer != "" {
+			apierror.Write(w, r, apierror.BadRequest(errors.New("rule and layer cannot be used together")))
+			return
+		}
+
+		// Вариант хранится в членстве, а членство в динамическом сегменте не хранится
+		if len(req.Variants) > 0 {
+			apierror.Write(w, r, apierror.BadRequest(errors.New("rule and variants cannot be used together")))
+			return
+		}
+
+		if _, err := rules.Parse(req.Rule); err != nil {
+			apierror.Write(w, r, apierror.BadRequest(err))
+			return
+		}
+	}
+
+	var variants []*models.SegmentVariant
+
+	if len(req.Variants) > 0 {
+		var err error
+
+		variants, err = toVariants(req.Variants)
+		if err != nil {
+			apierror.Write(w, r, apierror.BadRequest(err))
+			return
+		}
+	}
+
 	segment := &models.Segment{
 		Slug:        req.Slug,
 		UserPercent: req.UserPercent,
+		Rule:        req.Rule,
+		Layer:       req.Layer,
+		Variants:    variants,
 	}
 
 	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
@@ -51,14 +109,8 @@ func (h *handler) Create(w http.ResponseWriter, r *http.Request) {
 	err := h.segmentSvc.Create(ctx, segment)
 
 	if err != nil {
-		switch {
-		case errors.Is(err, repo.ErrSegmentAlreadyExists):
-			payload.WriteJSON(w, http.StatusBadRequest, payload.Data{"error": err.Error()}, nil)
-			return
-		default:
-			payload.WriteJSON(w, http.StatusInternalServerError, payload.Data{"error": "Internal server error"}, nil)
-			return
-		}
+		apierror.Write(w, r, err)
+		return
 	}
 
 	payload.WriteJSON(w, http.StatusCreated, payload.Data{"created_at": segment.CreatedAt}, nil)
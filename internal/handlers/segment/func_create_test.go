@@ -67,7 +67,25 @@ func Test_CreateSegment(t *testing.T) {
 		require.Equal(t, http.StatusBadRequest, w.Code)
 	})
 
-	t.Run("Should return 400 if slug already exists", func(t *testing.T) {
+	t.Run("Should return 404 if namespace not found", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		mockSegmentSvc := mock_segment.NewMockSegmentService(ctrl)
+		mockSegmentSvc.EXPECT().Create(gomock.Any(), gomock.Any()).Return(repo.ErrNamespaceNotFound)
+
+		handler := NewHandler(nil, mockSegmentSvc)
+
+		body := `{"slug": "TEST_SEGMENT"}`
+
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodPost, "/segment", strings.NewReader(body))
+		handler.Create(w, r)
+
+		require.Equal(t, http.StatusNotFound, w.Code)
+	})
+
+	t.Run("Should return 409 if slug already exists", func(t *testing.T) {
 		ctrl := gomock.NewController(t)
 		defer ctrl.Finish()
 
@@ -82,7 +100,7 @@ func Test_CreateSegment(t *testing.T) {
 		r := httptest.NewRequest(http.MethodPost, "/segment", strings.NewReader(body))
 		handler.Create(w, r)
 
-		require.Equal(t, http.StatusBadRequest, w.Code)
+		require.Equal(t, http.StatusConflict, w.Code)
 	})
 
 	t.Run("Should return 500 if something goes wrong", func(t *testing.T) {
@@ -119,4 +137,95 @@ func Test_CreateSegment(t *testing.T) {
 
 		require.Equal(t, http.StatusBadRequest, w.Code)
 	})
+
+	t.Run("Should return 201 and create rule segment", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		segment := &models.Segment{
+			Slug: "TEST_SEGMENT",
+			Rule: `city in ["msk", "spb"] && platform == "ios"`,
+		}
+
+		mockSegmentSvc := mock_segment.NewMockSegmentService(ctrl)
+
+		mockSegmentSvc.EXPECT().Create(gomock.Any(), segment).Return(nil)
+
+		handler := NewHandler(nil, mockSegmentSvc)
+
+		body := `{"slug": "TEST_SEGMENT", "rule": "city in [\"msk\", \"spb\"] && platform == \"ios\""}`
+
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodPost, "/segment", strings.NewReader(body))
+		handler.Create(w, r)
+
+		require.Equal(t, http.StatusCreated, w.Code)
+	})
+
+	t.Run("Should return 400 if rule is invalid", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		mockSegmentSvc := mock_segment.NewMockSegmentService(ctrl)
+
+		handler := NewHandler(nil, mockSegmentSvc)
+
+		for _, body := range []string{
+			`{"slug": "TEST_SEGMENT", "rule": "city =="}`,
+			`{"slug": "TEST_SEGMENT", "rule": "city == \"msk\"", "user_percent": 50}`,
+			`{"slug": "TEST_SEGMENT", "rule": "city == \"msk\"", "layer": "CHECKOUT"}`,
+			`{"slug": "TEST_SEGMENT", "rule": "city == \"msk\"", "variants": [{"name": "a", "weight": 50}, {"name": "b", "weight": 50}]}`,
+		} {
+			w := httptest.NewRecorder()
+			r := httptest.NewRequest(http.MethodPost, "/segment", strings.NewReader(body))
+			handler.Create(w, r)
+
+			require.Equal(t, http.StatusBadRequest, w.Code, body)
+		}
+	})
+
+	t.Run("Should return 201 and create segment with variants", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		segment := &models.Segment{
+			Slug:        "TEST_SEGMENT",
+			UserPercent: 20,
+			Variants: []*models.SegmentVariant{
+				{Name: "control", Weight: 50},
+				{Name: "treatment_a", Weight: 25},
+				{Name: "treatment_b", Weight: 25},
+			},
+		}
+
+		mockSegmentSvc := mock_segment.NewMockSegmentService(ctrl)
+		mockSegmentSvc.EXPECT().Create(gomock.Any(), segment).Return(nil)
+
+		handler := NewHandler(nil, mockSegmentSvc)
+
+		body := `{"slug": "TEST_SEGMENT", "user_percent": 20, "variants": [{"name": "control", "weight": 50}, {"name": "treatment_a", "weight": 25}, {"name": "treatment_b", "weight": 25}]}`
+
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodPost, "/segment", strings.NewReader(body))
+		handler.Create(w, r)
+
+		require.Equal(t, http.StatusCreated, w.Code)
+	})
+
+	t.Run("Should return 400 if variant weights are invalid", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		mockSegmentSvc := mock_segment.NewMockSegmentService(ctrl)
+
+		handler := NewHandler(nil, mockSegmentSvc)
+
+		body := `{"slug": "TEST_SEGMENT", "variants": [{"name": "control", "weight": 50}, {"name": "treatment", "weight": 25}]}`
+
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodPost, "/segment", strings.NewReader(body))
+		handler.Create(w, r)
+
+		require.Equal(t, http.StatusBadRequest, w.Code)
+	})
 }
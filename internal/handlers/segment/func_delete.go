@@ -2,12 +2,11 @@ package segment
 
 import (
 	"context"
-	"errors"
 	"net/http"
 	"time"
 
+	"github.com/dezzerlol/avitotech-test-2023/internal/apierror"
 	"github.com/dezzerlol/avitotech-test-2023/internal/db/models"
-	"github.com/dezzerlol/avitotech-test-2023/internal/repo"
 	"github.com/dezzerlol/avitotech-test-2023/pkg/payload"
 )
 
@@ -19,22 +18,29 @@ type DeleteRequest struct {
 // @Summary      Удаление сегмента
 // @Description  Метод удаления сегмента. Принимает slug (название) сегмента.
 // @Tags         Segment
+// @Security     ApiKeyAuth
+// @Security     BearerAuth
 // @Accept       json
 // @Produce      json
 // @Param        body  body  DeleteRequest  true  "Данные сегмента"
 // @Success      200  {object} object{message=string}
-// @Failure      400,404,500  {object} object{error=string}
-// @Router       /segment [delete]
+// @Failure      400,401,403,404,429,500  {object} apierror.Response
+// @Router       /api/v1/segment [delete]
 func (h *handler) Delete(w http.ResponseWriter, r *http.Request) {
+	ctx, span := tracer.Start(r.Context(), "handlers.segment.Delete")
+	defer span.End()
+
+	r = r.WithContext(ctx)
+
 	var req DeleteRequest
 
 	if err := payload.ReadJSON(w, r, &req); err != nil {
-		payload.WriteJSON(w, http.StatusBadRequest, payload.Data{"error": err.Error()}, nil)
+		apierror.Write(w, r, apierror.BadRequest(err))
 		return
 	}
 
 	if errs := payload.Validate(req); errs != nil {
-		payload.WriteJSON(w, http.StatusBadRequest, payload.Data{"error": errs}, nil)
+		apierror.Write(w, r, apierror.Validation(errs))
 		return
 	}
 
@@ -48,14 +54,8 @@ func (h *handler) Delete(w http.ResponseWriter, r *http.Request) {
 	err := h.segmentSvc.DeleteBySlug(ctx, segment)
 
 	if err != nil {
-		switch {
-		case errors.Is(err, repo.ErrSegmentNotFound):
-			payload.WriteJSON(w, http.StatusNotFound, payload.Data{"error": err.Error()}, nil)
-			return
-		default:
-			payload.WriteJSON(w, http.StatusInternalServerError, payload.Data{"error": "Internal server error"}, nil)
-			return
-		}
+		apierror.Write(w, r, err)
+		return
 	}
 
 	payload.WriteJSON(w, http.StatusOK, payload.Data{"message": "ok"}, nil)
@@ -1,41 +1,52 @@
 package segment
 
 import (
-	"fmt"
 	"net/http"
 	"os"
+	"path/filepath"
+	"strings"
 
-	"github.com/dezzerlol/avitotech-test-2023/pkg/payload"
+	"github.com/dezzerlol/avitotech-test-2023/internal/apierror"
+	"github.com/dezzerlol/avitotech-test-2023/internal/namespace"
+	"github.com/dezzerlol/avitotech-test-2023/internal/report"
 	"github.com/go-chi/chi/v5"
 )
 
 // DownloadReport godoc
 // @Summary      Скачивание отчета
-// @Description  Метод скачивания csv отчета по истории сегментов пользователя.
-// @Description  Отчет в формате: идентификатор пользователя 1;сегмент1;операция (добавление = 'I' / удаление = "D");дата и время
+// @Description  Метод скачивания отчета по истории сегментов пользователя.
+// @Description  Первая строка отчета описывает запрос, далее: идентификатор пользователя 1;сегмент1;операция (добавление = 'I' / удаление = "D" / смена варианта = "V");дата и время
 // @Tags         Segment
-// @Produce      text/csv
+// @Security     ApiKeyAuth
+// @Security     BearerAuth
+// @Produce      text/csv,application/vnd.openxmlformats-officedocument.spreadsheetml.sheet,application/json
 // @Param        fileName path string true "file_name.csv"
 // @Success      200  {file} file
-// @Failure      400  {object} object{error=string}
-// @Header	 	 200 {string} Content-Type "text/csv"
+// @Failure      400,401,403,404,429,500  {object} apierror.Response
 // @Header 	 	 200 {string} Content-Disposition "attachment;filename=file_name"
-// @Router       /segment/reports/{fileName} [get]
+// @Router       /api/v1/segment/reports/{fileName} [get]
 func (h *handler) DownloadReport(w http.ResponseWriter, r *http.Request) {
 	fileName := chi.URLParam(r, "fileName")
 
-	fullPath := fmt.Sprintf("./reports/%s", fileName)
+	// Не даем выйти из папки отчетов пространства имен
+	if fileName != filepath.Base(fileName) || strings.HasPrefix(fileName, ".") {
+		apierror.Write(w, r, apierror.New(http.StatusBadRequest, apierror.CodeInvalidRequest, "invalid file name"))
+		return
+	}
+
+	// Отчеты хранятся отдельно для каждого пространства имен
+	fullPath := filepath.Join(report.Dir, namespace.FromContext(r.Context()), fileName)
 
 	// Проверяем существует ли файл
 	_, err := os.Stat(fullPath)
 
 	if os.IsNotExist(err) {
-		payload.WriteJSON(w, http.StatusBadRequest, payload.Data{"error": "file not found"}, nil)
+		apierror.Write(w, r, apierror.New(http.StatusNotFound, apierror.CodeReportNotFound, "report not found"))
 		return
 	}
 
 	// Устанавливаем заголовки позволяющие браузеру скачать файл
-	w.Header().Set("Content-Type", "text/csv")
+	w.Header().Set("Content-Type", report.FormatByExt(fileName).ContentType())
 	w.Header().Set("Content-Disposition", "attachment;filename="+fileName)
 
 	// TODO: добавить удаление файла после скачивания
@@ -0,0 +1,96 @@
+package segment
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/dezzerlol/avitotech-test-2023/internal/apierror"
+	"github.com/dezzerlol/avitotech-test-2023/internal/report"
+	"github.com/dezzerlol/avitotech-test-2023/pkg/payload"
+	"github.com/go-chi/chi/v5"
+)
+
+// ExportUserHistory godoc
+// @Summary      Потоковая выгрузка истории сегментов пользователя
+// @Description  Метод выгрузки истории сегментов пользователя за указанный месяц и год в формате csv, xlsx или json.
+// @Description  Строки читаются из базы и сразу пишутся в ответ, без создания временного файла.
+// @Description  Если клиент передает Accept-Encoding: gzip, ответ сжимается.
+// @Tags         Segment
+// @Security     ApiKeyAuth
+// @Security     BearerAuth
+// @Produce      text/csv,application/vnd.openxmlformats-officedocument.spreadsheetml.sheet,application/json
+// @Param        userId path string true "id пользователя"
+// @Param        format path string true "формат отчета" Enums(csv, xlsx, json)
+// @Param        month query int true "месяц"
+// @Param        year query int true "год"
+// @Param        delimiter query string false "разделитель csv (один символ или tab)" default(,)
+// @Param        lang query string false "подписи операций" Enums(code, en, ru) default(code)
+// @Param        time_format query string false "формат времени: datetime, date, rfc3339, unix или layout Go" default(datetime)
+// @Param        tz query string false "таймзона IANA" default(UTC)
+// @Success      200  {file} file
+// @Failure      400,401,403,429,500  {object} apierror.Response
+// @Header 	 	 200 {string} Content-Disposition "attachment;filename=file_name"
+// @Router       /api/v1/segment/history/{userId}/export.{format} [get]
+func (h *handler) ExportUserHistory(w http.ResponseWriter, r *http.Request) {
+	ctx, span := tracer.Start(r.Context(), "handlers.segment.ExportUserHistory")
+	defer span.End()
+
+	r = r.WithContext(ctx)
+
+	userId, err := payload.ParamInt(r, "userId")
+	if err != nil {
+		apierror.Write(w, r, apierror.BadRequest(err))
+		return
+	}
+
+	month, err := payload.QueryInt(r, "month")
+	if err != nil {
+		apierror.Write(w, r, apierror.BadRequest(err))
+		return
+	}
+
+	year, err := payload.QueryInt(r, "year")
+	if err != nil {
+		apierror.Write(w, r, apierror.BadRequest(err))
+		return
+	}
+
+	query := r.URL.Query()
+	query.Set("format", chi.URLParam(r, "format"))
+
+	opts, err := report.ParseOptions(query)
+	if err != nil {
+		apierror.Write(w, r, apierror.BadRequest(err))
+		return
+	}
+
+	// Выгрузка может длиться дольше WriteTimeout сервера,
+	// поэтому снимаем дедлайн записи для этого ответа
+	http.NewResponseController(w).SetWriteDeadline(time.Time{})
+
+	fileName := fmt.Sprintf("%d-%d-%02d.%s", userId, year, month, opts.Format.Ext())
+	stream := payload.NewFileStream(w, r, opts.Format.ContentType(), fileName)
+
+	err = h.segmentSvc.ExportUserHistory(r.Context(), userId, month, year, opts, stream)
+
+	if err != nil {
+		// Если заголовки уже отправлены, сообщить об ошибке клиенту нельзя,
+		// поэтому обрываем соединение, чтобы отчет не выглядел полным
+		if stream.Started() {
+			// Клиент закрыл соединение сам, это не ошибка сервера
+			if !errors.Is(err, context.Canceled) {
+				h.logger.Errorw("error streaming user history", "user_id", userId, "err", err)
+			}
+
+			panic(http.ErrAbortHandler)
+		}
+
+		apierror.Write(w, r, err)
+		return
+	}
+
+	stream.Close()
+}
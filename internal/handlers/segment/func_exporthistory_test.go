@@ -0,0 +1,154 @@
+package segment
+
+import (
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	mock_segment "github.com/dezzerlol/avitotech-test-2023/internal/handlers/segment/mocks"
+	"github.com/dezzerlol/avitotech-test-2023/internal/report"
+	"github.com/go-chi/chi/v5"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+)
+
+func newExportRequest(userId any, format, query string) *http.Request {
+	r := httptest.NewRequest(http.MethodGet, "/segment/history/export."+format+"?"+query, nil)
+	rctx := chi.NewRouteContext()
+	rctx.URLParams.Add("userId", fmt.Sprint(userId))
+	rctx.URLParams.Add("format", format)
+
+	return r.WithContext(context.WithValue(r.Context(), chi.RouteCtxKey, rctx))
+}
+
+func Test_ExportUserHistory(t *testing.T) {
+	csvReport := "user_id,segment_slug,operation,executed_at\n1,TEST_SEGMENT,I,2023-08-01 10:00:00\n"
+
+	t.Run("Should return 200 and stream csv", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		mockSegmentSvc := mock_segment.NewMockSegmentService(ctrl)
+		mockSegmentSvc.EXPECT().
+			ExportUserHistory(gomock.Any(), int64(1), int64(8), int64(2023), gomock.Any(), gomock.Any()).
+			DoAndReturn(func(ctx context.Context, userId, month, year int64, opts report.Options, w io.Writer) error {
+				_, err := io.WriteString(w, csvReport)
+				return err
+			})
+
+		handler := NewHandler(nil, mockSegmentSvc)
+
+		w := httptest.NewRecorder()
+		handler.ExportUserHistory(w, newExportRequest(1, "csv", "month=8&year=2023"))
+
+		require.Equal(t, http.StatusOK, w.Code)
+		require.Equal(t, "text/csv", w.Header().Get("Content-Type"))
+		require.Empty(t, w.Header().Get("Content-Encoding"))
+		require.Equal(t, csvReport, w.Body.String())
+	})
+
+	t.Run("Should gzip response if client accepts it", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		mockSegmentSvc := mock_segment.NewMockSegmentService(ctrl)
+		mockSegmentSvc.EXPECT().
+			ExportUserHistory(gomock.Any(), int64(1), int64(8), int64(2023), gomock.Any(), gomock.Any()).
+			DoAndReturn(func(ctx context.Context, userId, month, year int64, opts report.Options, w io.Writer) error {
+				_, err := io.WriteString(w, csvReport)
+				return err
+			})
+
+		handler := NewHandler(nil, mockSegmentSvc)
+
+		w := httptest.NewRecorder()
+		r := newExportRequest(1, "csv", "month=8&year=2023")
+		r.Header.Set("Accept-Encoding", "gzip, deflate")
+		handler.ExportUserHistory(w, r)
+
+		require.Equal(t, http.StatusOK, w.Code)
+		require.Equal(t, "gzip", w.Header().Get("Content-Encoding"))
+
+		gz, err := gzip.NewReader(w.Body)
+		require.NoError(t, err)
+
+		body, err := io.ReadAll(gz)
+		require.NoError(t, err)
+		require.Equal(t, csvReport, string(body))
+	})
+
+	t.Run("Should pass report options from query", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		mockSegmentSvc := mock_segment.NewMockSegmentService(ctrl)
+		mockSegmentSvc.EXPECT().
+			ExportUserHistory(gomock.Any(), int64(1), int64(8), int64(2023), gomock.Any(), gomock.Any()).
+			DoAndReturn(func(ctx context.Context, userId, month, year int64, opts report.Options, w io.Writer) error {
+				require.Equal(t, report.FormatXLSX, opts.Format)
+				require.Equal(t, report.LangEN, opts.Lang)
+				require.Equal(t, "Europe/Moscow", opts.Location.String())
+
+				_, err := io.WriteString(w, "xlsx")
+				return err
+			})
+
+		handler := NewHandler(nil, mockSegmentSvc)
+
+		w := httptest.NewRecorder()
+		handler.ExportUserHistory(w, newExportRequest(1, "xlsx", "month=8&year=2023&lang=en&tz=Europe/Moscow"))
+
+		require.Equal(t, http.StatusOK, w.Code)
+		require.Equal(t, report.FormatXLSX.ContentType(), w.Header().Get("Content-Type"))
+		require.Contains(t, w.Header().Get("Content-Disposition"), "1-2023-08.xlsx")
+	})
+
+	t.Run("Should return 400 if format is unknown", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		mockSegmentSvc := mock_segment.NewMockSegmentService(ctrl)
+		handler := NewHandler(nil, mockSegmentSvc)
+
+		w := httptest.NewRecorder()
+		handler.ExportUserHistory(w, newExportRequest(1, "pdf", "month=8&year=2023"))
+
+		require.Equal(t, http.StatusBadRequest, w.Code)
+	})
+
+	t.Run("Should return 400 if query is invalid", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		mockSegmentSvc := mock_segment.NewMockSegmentService(ctrl)
+		handler := NewHandler(nil, mockSegmentSvc)
+
+		w := httptest.NewRecorder()
+		handler.ExportUserHistory(w, newExportRequest(1, "csv", "month=8"))
+
+		require.Equal(t, http.StatusBadRequest, w.Code)
+	})
+
+	t.Run("Should return 500 if nothing was written", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		mockSegmentSvc := mock_segment.NewMockSegmentService(ctrl)
+		mockSegmentSvc.EXPECT().
+			ExportUserHistory(gomock.Any(), int64(1), int64(8), int64(2023), gomock.Any(), gomock.Any()).
+			Return(errors.New("internal error"))
+
+		handler := NewHandler(nil, mockSegmentSvc)
+
+		w := httptest.NewRecorder()
+		handler.ExportUserHistory(w, newExportRequest(1, "csv", "month=8&year=2023"))
+
+		require.Equal(t, http.StatusInternalServerError, w.Code)
+		require.Contains(t, w.Header().Get("Content-Type"), "application/json")
+	})
+}
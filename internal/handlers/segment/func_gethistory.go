@@ -5,46 +5,67 @@ import (
 	"net/http"
 	"time"
 
+	"github.com/dezzerlol/avitotech-test-2023/internal/apierror"
+	"github.com/dezzerlol/avitotech-test-2023/internal/report"
 	"github.com/dezzerlol/avitotech-test-2023/pkg/payload"
 )
 
 // GetUserHistory godoc
 // @Summary      Получение истории сегментов пользователя
-// @Description  Метод получения истории сегментов пользователя за указанный месяц и год. На вход: год и месяц. На выходе ссылка на CSV файл.
+// @Description  Метод получения истории сегментов пользователя за указанный месяц и год. На вход: год и месяц. На выходе ссылка на файл отчета.
+// @Description  По умолчанию отчет в формате CSV, формат, разделитель, язык подписей операций, формат времени и таймзону можно задать параметрами.
 // @Tags         Segment
+// @Security     ApiKeyAuth
+// @Security     BearerAuth
 // @Produce      json
 // @Param        userId path string true "id пользователя"
 // @Param        month query int true "месяц"
 // @Param        year query int true "год"
+// @Param        format query string false "формат отчета" Enums(csv, xlsx, json) default(csv)
+// @Param        delimiter query string false "разделитель csv (один символ или tab)" default(,)
+// @Param        lang query string false "подписи операций" Enums(code, en, ru) default(code)
+// @Param        time_format query string false "формат времени: datetime, date, rfc3339, unix или layout Go" default(datetime)
+// @Param        tz query string false "таймзона IANA" default(UTC)
 // @Success      200  {object} object{report_link=string}
-// @Failure      400,500  {object} object{error=string}
-// @Router       /segment/history/{userId} [get]
+// @Failure      400,401,403,429,500  {object} apierror.Response
+// @Router       /api/v1/segment/history/{userId} [get]
 func (h *handler) GetUserHistory(w http.ResponseWriter, r *http.Request) {
+	ctx, span := tracer.Start(r.Context(), "handlers.segment.GetUserHistory")
+	defer span.End()
+
+	r = r.WithContext(ctx)
+
 	userId, err := payload.ParamInt(r, "userId")
 	if err != nil {
-		payload.WriteJSON(w, http.StatusBadRequest, payload.Data{"error": err.Error()}, nil)
+		apierror.Write(w, r, apierror.BadRequest(err))
 		return
 	}
 
 	month, err := payload.QueryInt(r, "month")
 	if err != nil {
-		payload.WriteJSON(w, http.StatusBadRequest, payload.Data{"error": err.Error()}, nil)
+		apierror.Write(w, r, apierror.BadRequest(err))
 		return
 	}
 
 	year, err := payload.QueryInt(r, "year")
 	if err != nil {
-		payload.WriteJSON(w, http.StatusBadRequest, payload.Data{"error": err.Error()}, nil)
+		apierror.Write(w, r, apierror.BadRequest(err))
+		return
+	}
+
+	opts, err := report.ParseOptions(r.URL.Query())
+	if err != nil {
+		apierror.Write(w, r, apierror.BadRequest(err))
 		return
 	}
 
 	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
 	defer cancel()
 
-	downloadLink, err := h.segmentSvc.GetUserHistory(ctx, userId, month, year)
+	downloadLink, err := h.segmentSvc.GetUserHistory(ctx, userId, month, year, opts)
 
 	if err != nil {
-		payload.WriteJSON(w, http.StatusInternalServerError, payload.Data{"error": "Internal server error"}, nil)
+		apierror.Write(w, r, err)
 		return
 	}
 
@@ -5,32 +5,60 @@ import (
 	"net/http"
 	"time"
 
+	"github.com/dezzerlol/avitotech-test-2023/internal/apierror"
+	"github.com/dezzerlol/avitotech-test-2023/internal/db/models"
 	"github.com/dezzerlol/avitotech-test-2023/pkg/payload"
 )
 
 // GetSegmentsForUser godoc
 // @Summary      Получение сегментов пользователя
 // @Description Метод получения активных сегментов пользователя. Принимает на вход id пользователя.
+// @Description В ответ попадают и динамические сегменты, правила которых подходят под атрибуты пользователя.
+// @Description У сегментов-экспериментов в variant указан вариант пользователя.
+// @Description Если передан at, возвращает сегменты, в которых пользователь состоял в этот момент, восстановленные по истории.
+// @Description В этом случае в ответ попадают и сегменты, удаленные позже (deleted = true), а динамические сегменты не учитываются.
 // @Tags         Segment
+// @Security     ApiKeyAuth
+// @Security     BearerAuth
 // @Produce      json
 // @Param        userId path string true "id пользователя"
+// @Param        at query string false "момент времени (RFC3339 или дата 2006-01-02)"
 // @Success      200  {object} []models.Segment
-// @Failure      400,500  {object} object{error=string}
-// @Router       /segment/user/{userId} [get]
+// @Failure      400,401,403,404,429,500  {object} apierror.Response
+// @Router       /api/v1/segment/user/{userId} [get]
 func (h *handler) GetSegmentsForUser(w http.ResponseWriter, r *http.Request) {
+	ctx, span := tracer.Start(r.Context(), "handlers.segment.GetSegmentsForUser")
+	defer span.End()
+
+	r = r.WithContext(ctx)
+
 	userId, err := payload.ParamInt(r, "userId")
 	if err != nil {
-		payload.WriteJSON(w, http.StatusBadRequest, payload.Data{"error": err.Error()}, nil)
+		apierror.Write(w, r, apierror.BadRequest(err))
 		return
 	}
 
 	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
 	defer cancel()
 
-	segments, err := h.segmentSvc.GetUserSegments(ctx, userId)
+	var segments []*models.Segment
+
+	if r.URL.Query().Has("at") {
+		var at time.Time
+
+		at, _, err = payload.QueryTime(r, "at", time.UTC)
+		if err != nil {
+			apierror.Write(w, r, apierror.BadRequest(err))
+			return
+		}
+
+		segments, err = h.segmentSvc.GetUserSegmentsAt(ctx, userId, at)
+	} else {
+		segments, err = h.segmentSvc.GetUserSegments(ctx, userId)
+	}
 
 	if err != nil {
-		payload.WriteJSON(w, http.StatusInternalServerError, payload.Data{"error": "Internal server error"}, nil)
+		apierror.Write(w, r, err)
 		return
 	}
 
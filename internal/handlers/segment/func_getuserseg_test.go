@@ -7,6 +7,7 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 
 	"github.com/dezzerlol/avitotech-test-2023/internal/db/models"
 	mock_segment "github.com/dezzerlol/avitotech-test-2023/internal/handlers/segment/mocks"
@@ -82,3 +83,69 @@ func Test_GetUserSegments(t *testing.T) {
 		require.Equal(t, http.StatusInternalServerError, w.Code)
 	})
 }
+
+func Test_GetUserSegmentsAt(t *testing.T) {
+	t.Run("Should return 200 and user segments at given time", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		mockSegmentSvc := mock_segment.NewMockSegmentService(ctrl)
+		handler := NewHandler(nil, mockSegmentSvc)
+
+		var userId int64 = 42
+		at := time.Date(2026, 9, 1, 12, 0, 0, 0, time.UTC)
+
+		segments := []*models.Segment{{Slug: "TEST_SEGMENT", Deleted: true}}
+
+		mockSegmentSvc.EXPECT().GetUserSegmentsAt(gomock.Any(), userId, at).Return(segments, nil)
+
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodGet, "/segment/user/42?at=2026-09-01T12:00:00Z", nil)
+		rctx := chi.NewRouteContext()
+		rctx.URLParams.Add("userId", fmt.Sprint(userId))
+		r = r.WithContext(context.WithValue(r.Context(), chi.RouteCtxKey, rctx))
+
+		handler.GetSegmentsForUser(w, r)
+
+		require.Equal(t, http.StatusOK, w.Code)
+		require.JSONEq(t, `{"segments": [{"slug": "TEST_SEGMENT", "deleted": true}]}`, w.Body.String())
+	})
+
+	t.Run("Should return 400 if time is invalid", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		mockSegmentSvc := mock_segment.NewMockSegmentService(ctrl)
+		handler := NewHandler(nil, mockSegmentSvc)
+
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodGet, "/segment/user/42?at=yesterday", nil)
+		rctx := chi.NewRouteContext()
+		rctx.URLParams.Add("userId", "42")
+		r = r.WithContext(context.WithValue(r.Context(), chi.RouteCtxKey, rctx))
+
+		handler.GetSegmentsForUser(w, r)
+
+		require.Equal(t, http.StatusBadRequest, w.Code)
+	})
+
+	t.Run("Should return 500 if something goes wrong", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		mockSegmentSvc := mock_segment.NewMockSegmentService(ctrl)
+		mockSegmentSvc.EXPECT().GetUserSegmentsAt(gomock.Any(), int64(42), gomock.Any()).Return(nil, errors.New("internal error"))
+
+		handler := NewHandler(nil, mockSegmentSvc)
+
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodGet, "/segment/user/42?at=2026-09-01", nil)
+		rctx := chi.NewRouteContext()
+		rctx.URLParams.Add("userId", "42")
+		r = r.WithContext(context.WithValue(r.Context(), chi.RouteCtxKey, rctx))
+
+		handler.GetSegmentsForUser(w, r)
+
+		require.Equal(t, http.StatusInternalServerError, w.Code)
+	})
+}
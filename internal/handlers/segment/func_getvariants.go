@@ -0,0 +1,42 @@
+package segment
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/dezzerlol/avitotech-test-2023/internal/apierror"
+	"github.com/dezzerlol/avitotech-test-2023/pkg/payload"
+	"github.com/go-chi/chi/v5"
+)
+
+// GetVariants godoc
+// @Summary      Получение вариантов сегмента
+// @Description  Метод получения вариантов эксперимента с весами и числом участников сегмента в каждом варианте.
+// @Description  У сегмента без вариантов пустой список.
+// @Tags         Segment
+// @Security     ApiKeyAuth
+// @Security     BearerAuth
+// @Produce      json
+// @Param        slug path string true "slug сегмента"
+// @Success      200  {object} object{variants=[]models.SegmentVariant}
+// @Failure      401,403,404,429,500  {object} apierror.Response
+// @Router       /api/v1/segment/{slug}/variants [get]
+func (h *handler) GetVariants(w http.ResponseWriter, r *http.Request) {
+	ctx, span := tracer.Start(r.Context(), "handlers.segment.GetVariants")
+	defer span.End()
+
+	r = r.WithContext(ctx)
+
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	variants, err := h.segmentSvc.GetVariants(ctx, chi.URLParam(r, "slug"))
+
+	if err != nil {
+		apierror.Write(w, r, err)
+		return
+	}
+
+	payload.WriteJSON(w, http.StatusOK, payload.Data{"variants": variants}, nil)
+}
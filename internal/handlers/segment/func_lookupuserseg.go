@@ -0,0 +1,62 @@
+package segment
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/dezzerlol/avitotech-test-2023/internal/apierror"
+	"github.com/dezzerlol/avitotech-test-2023/pkg/payload"
+)
+
+type LookupUserSegmentsRequest struct {
+	UserIds []int64 `json:"user_ids" validate:"required,min=1,max=500,dive,min=1" example:"1,2,3"`
+	// Если задан, возвращаются только перечисленные сегменты
+	Segments []string `json:"segments" validate:"omitempty,max=100,dive,min=3" example:"AVITO_VOICE_MESSAGES,AVITO_DISCOUNT_50"`
+}
+
+// LookupUserSegments godoc
+// @Summary      Получение сегментов нескольких пользователей
+// @Description  Метод получения активных сегментов до 500 пользователей одним запросом. Принимает массив id пользователей
+// @Description  и необязательный массив slug сегментов: если он задан, в ответ попадают только эти сегменты.
+// @Description  Возвращает сегменты по id пользователя и id пользователей, которых нет в базе (not_found).
+// @Description  У сегментов-экспериментов в variant указан вариант пользователя.
+// @Tags         Segment
+// @Security     ApiKeyAuth
+// @Security     BearerAuth
+// @Accept       json
+// @Produce      json
+// @Param        body  body  LookupUserSegmentsRequest  true  "id пользователей и фильтр сегментов"
+// @Success      200  {object} models.UserSegmentsLookup
+// @Failure      400,401,403,429,500  {object} apierror.Response
+// @Router       /api/v1/segment/users/lookup [post]
+func (h *handler) LookupUserSegments(w http.ResponseWriter, r *http.Request) {
+	ctx, span := tracer.Start(r.Context(), "handlers.segment.LookupUserSegments")
+	defer span.End()
+
+	r = r.WithContext(ctx)
+
+	var req LookupUserSegmentsRequest
+
+	if err := payload.ReadJSON(w, r, &req); err != nil {
+		apierror.Write(w, r, apierror.BadRequest(err))
+		return
+	}
+
+	if errs := payload.Validate(req); errs != nil {
+		apierror.Write(w, r, apierror.Validation(errs))
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	lookup, err := h.segmentSvc.LookupUserSegments(ctx, req.UserIds, req.Segments)
+
+	if err != nil {
+		apierror.Write(w, r, err)
+		return
+	}
+
+	payload.WriteJSON(w, http.StatusOK, payload.Data{"users": lookup.Users, "not_found": lookup.NotFound}, nil)
+}
@@ -0,0 +1,96 @@
+package segment
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/dezzerlol/avitotech-test-2023/internal/db/models"
+	mock_segment "github.com/dezzerlol/avitotech-test-2023/internal/handlers/segment/mocks"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_LookupUserSegments(t *testing.T) {
+	newRequest := func(body LookupUserSegmentsRequest) *http.Request {
+		var buf bytes.Buffer
+		json.NewEncoder(&buf).Encode(body)
+
+		return httptest.NewRequest(http.MethodPost, "/segment/users/lookup", &buf)
+	}
+
+	t.Run("Should return 200 and segments by user id", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		mockSegmentSvc := mock_segment.NewMockSegmentService(ctrl)
+		handler := NewHandler(nil, mockSegmentSvc)
+
+		userIds := []int64{1, 2, 3}
+		slugs := []string{"AVITO_TEST"}
+
+		mockSegmentSvc.EXPECT().LookupUserSegments(gomock.Any(), userIds, slugs).Return(&models.UserSegmentsLookup{
+			Users: map[int64][]*models.Segment{
+				1: {{Slug: "AVITO_TEST"}},
+				2: {},
+			},
+			NotFound: []int64{3},
+		}, nil)
+
+		w := httptest.NewRecorder()
+		handler.LookupUserSegments(w, newRequest(LookupUserSegmentsRequest{UserIds: userIds, Segments: slugs}))
+
+		require.Equal(t, http.StatusOK, w.Code)
+		require.JSONEq(t, `{"users": {"1": [{"slug": "AVITO_TEST"}], "2": []}, "not_found": [3]}`, w.Body.String())
+	})
+
+	t.Run("Should return 400 if user ids are empty", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		mockSegmentSvc := mock_segment.NewMockSegmentService(ctrl)
+		handler := NewHandler(nil, mockSegmentSvc)
+
+		w := httptest.NewRecorder()
+		handler.LookupUserSegments(w, newRequest(LookupUserSegmentsRequest{}))
+
+		require.Equal(t, http.StatusBadRequest, w.Code)
+	})
+
+	t.Run("Should return 400 if there are more than 500 users", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		mockSegmentSvc := mock_segment.NewMockSegmentService(ctrl)
+		handler := NewHandler(nil, mockSegmentSvc)
+
+		userIds := make([]int64, 501)
+
+		for i := range userIds {
+			userIds[i] = int64(i + 1)
+		}
+
+		w := httptest.NewRecorder()
+		handler.LookupUserSegments(w, newRequest(LookupUserSegmentsRequest{UserIds: userIds}))
+
+		require.Equal(t, http.StatusBadRequest, w.Code)
+	})
+
+	t.Run("Should return 500 if service returns error", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		mockSegmentSvc := mock_segment.NewMockSegmentService(ctrl)
+		handler := NewHandler(nil, mockSegmentSvc)
+
+		mockSegmentSvc.EXPECT().LookupUserSegments(gomock.Any(), []int64{1}, nil).Return(nil, errors.New("internal error"))
+
+		w := httptest.NewRecorder()
+		handler.LookupUserSegments(w, newRequest(LookupUserSegmentsRequest{UserIds: []int64{1}}))
+
+		require.Equal(t, http.StatusInternalServerError, w.Code)
+	})
+}
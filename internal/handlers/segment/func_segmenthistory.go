@@ -0,0 +1,185 @@
+package segment
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/dezzerlol/avitotech-test-2023/internal/apierror"
+	"github.com/dezzerlol/avitotech-test-2023/internal/db/models"
+	"github.com/dezzerlol/avitotech-test-2023/internal/report"
+	"github.com/dezzerlol/avitotech-test-2023/pkg/payload"
+	"github.com/go-chi/chi/v5"
+)
+
+// GetSegmentHistory godoc
+// @Summary      Получение истории сегмента
+// @Description  Метод получения истории сегмента за период [from, to): число вошедших и вышедших пользователей по дням или часам,
+// @Description  изменение и размер сегмента на конец каждого интервала, а также первые 1000 событий за период.
+// @Description  Если from или to переданы датой, to включается в период целиком.
+// @Tags         Segment
+// @Security     ApiKeyAuth
+// @Security     BearerAuth
+// @Produce      json
+// @Param        slug path string true "slug сегмента"
+// @Param        from query string true "начало периода (2006-01-02 или RFC3339)"
+// @Param        to query string true "конец периода (2006-01-02 или RFC3339)"
+// @Param        bucket query string false "интервал агрегации" Enums(day, hour) default(day)
+// @Param        tz query string false "таймзона IANA для границ интервалов" default(UTC)
+// @Success      200  {object} models.SegmentHistory
+// @Failure      400,401,403,429,500  {object} apierror.Response
+// @Router       /api/v1/segment/{slug}/history [get]
+func (h *handler) GetSegmentHistory(w http.ResponseWriter, r *http.Request) {
+	ctx, span := tracer.Start(r.Context(), "handlers.segment.GetSegmentHistory")
+	defer span.End()
+
+	r = r.WithContext(ctx)
+
+	opts, err := report.ParseOptions(r.URL.Query())
+	if err != nil {
+		apierror.Write(w, r, apierror.BadRequest(err))
+		return
+	}
+
+	filter, err := parseSegmentHistoryFilter(r, opts.Location)
+	if err != nil {
+		apierror.Write(w, r, apierror.BadRequest(err))
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+	defer cancel()
+
+	history, err := h.segmentSvc.GetSegmentHistory(ctx, filter)
+
+	if err != nil {
+		apierror.Write(w, r, err)
+		return
+	}
+
+	payload.WriteJSON(w, http.StatusOK, payload.Data{"history": history}, nil)
+}
+
+// ExportSegmentHistory godoc
+// @Summary      Выгрузка истории сегмента
+// @Description  Метод выгрузки истории сегмента за период в формате csv, xlsx или json.
+// @Description  view=buckets выгружает агрегаты по интервалам, view=events - все события сегмента за период.
+// @Description  Поддерживает те же настройки отчета, что и отчет по пользователю.
+// @Tags         Segment
+// @Security     ApiKeyAuth
+// @Security     BearerAuth
+// @Produce      text/csv,application/vnd.openxmlformats-officedocument.spreadsheetml.sheet,application/json
+// @Param        slug path string true "slug сегмента"
+// @Param        format path string true "формат отчета" Enums(csv, xlsx, json)
+// @Param        from query string true "начало периода (2006-01-02 или RFC3339)"
+// @Param        to query string true "конец периода (2006-01-02 или RFC3339)"
+// @Param        bucket query string false "интервал агрегации" Enums(day, hour) default(day)
+// @Param        view query string false "вид выгрузки" Enums(buckets, events) default(buckets)
+// @Param        delimiter query string false "разделитель csv (один символ или tab)" default(,)
+// @Param        lang query string false "подписи операций" Enums(code, en, ru) default(code)
+// @Param        time_format query string false "формат времени: datetime, date, rfc3339, unix или layout Go" default(datetime)
+// @Param        tz query string false "таймзона IANA" default(UTC)
+// @Success      200  {file} file
+// @Failure      400,401,403,429,500  {object} apierror.Response
+// @Header 	 	 200 {string} Content-Disposition "attachment;filename=file_name"
+// @Router       /api/v1/segment/{slug}/history/export.{format} [get]
+func (h *handler) ExportSegmentHistory(w http.ResponseWriter, r *http.Request) {
+	ctx, span := tracer.Start(r.Context(), "handlers.segment.ExportSegmentHistory")
+	defer span.End()
+
+	r = r.WithContext(ctx)
+
+	query := r.URL.Query()
+	query.Set("format", chi.URLParam(r, "format"))
+
+	opts, err := report.ParseOptions(query)
+	if err != nil {
+		apierror.Write(w, r, apierror.BadRequest(err))
+		return
+	}
+
+	filter, err := parseSegmentHistoryFilter(r, opts.Location)
+	if err != nil {
+		apierror.Write(w, r, apierror.BadRequest(err))
+		return
+	}
+
+	view := query.Get("view")
+
+	switch view {
+	case "":
+		view = models.SegmentHistoryViewBuckets
+	case models.SegmentHistoryViewBuckets, models.SegmentHistoryViewEvents:
+	default:
+		apierror.Write(w, r, apierror.New(http.StatusBadRequest, apierror.CodeInvalidRequest, "view must be buckets or events"))
+		return
+	}
+
+	// Выгрузка событий может длиться дольше WriteTimeout сервера
+	http.NewResponseController(w).SetWriteDeadline(time.Time{})
+
+	fileName := fmt.Sprintf("%s-%s.%s", filter.SegmentSlug, view, opts.Format.Ext())
+	stream := payload.NewFileStream(w, r, opts.Format.ContentType(), fileName)
+
+	err = h.segmentSvc.ExportSegmentHistory(r.Context(), filter, view, opts, stream)
+
+	if err != nil {
+		// Если заголовки уже отправлены, сообщить об ошибке клиенту нельзя,
+		// поэтому обрываем соединение, чтобы отчет не выглядел полным
+		if stream.Started() {
+			// Клиент закрыл соединение сам, это не ошибка сервера
+			if !errors.Is(err, context.Canceled) {
+				h.logger.Errorw("error streaming segment history", "segment_slug", filter.SegmentSlug, "err", err)
+			}
+
+			panic(http.ErrAbortHandler)
+		}
+
+		apierror.Write(w, r, err)
+		return
+	}
+
+	stream.Close()
+}
+
+func parseSegmentHistoryFilter(r *http.Request, loc *time.Location) (models.SegmentHistoryFilter, error) {
+	filter := models.SegmentHistoryFilter{
+		SegmentSlug: chi.URLParam(r, "slug"),
+		Bucket:      r.URL.Query().Get("bucket"),
+		Location:    loc,
+	}
+
+	if len(filter.SegmentSlug) < 3 {
+		return filter, errors.New("invalid segment slug")
+	}
+
+	switch filter.Bucket {
+	case "":
+		filter.Bucket = models.BucketDay
+	case models.BucketDay, models.BucketHour:
+	default:
+		return filter, errors.New("bucket must be day or hour")
+	}
+
+	from, _, err := payload.QueryTime(r, "from", loc)
+	if err != nil {
+		return filter, fmt.Errorf("from: %w", err)
+	}
+
+	to, dateOnly, err := payload.QueryTime(r, "to", loc)
+	if err != nil {
+		return filter, fmt.Errorf("to: %w", err)
+	}
+
+	// Дата в to означает, что день включается в период целиком
+	if dateOnly {
+		to = to.AddDate(0, 0, 1)
+	}
+
+	filter.From = from
+	filter.To = to
+
+	return filter, filter.Validate()
+}
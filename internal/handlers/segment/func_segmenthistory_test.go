@@ -0,0 +1,133 @@
+package segment
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/dezzerlol/avitotech-test-2023/internal/db/models"
+	mock_segment "github.com/dezzerlol/avitotech-test-2023/internal/handlers/segment/mocks"
+	"github.com/dezzerlol/avitotech-test-2023/internal/report"
+	"github.com/go-chi/chi/v5"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+)
+
+func newSegmentHistoryRequest(slug, format, query string) *http.Request {
+	r := httptest.NewRequest(http.MethodGet, "/segment/"+slug+"/history?"+query, nil)
+	rctx := chi.NewRouteContext()
+	rctx.URLParams.Add("slug", slug)
+
+	if format != "" {
+		rctx.URLParams.Add("format", format)
+	}
+
+	return r.WithContext(context.WithValue(r.Context(), chi.RouteCtxKey, rctx))
+}
+
+func Test_GetSegmentHistory(t *testing.T) {
+	t.Run("Should return 200 and segment history", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		mockSegmentSvc := mock_segment.NewMockSegmentService(ctrl)
+		mockSegmentSvc.EXPECT().
+			GetSegmentHistory(gomock.Any(), gomock.Any()).
+			DoAndReturn(func(ctx context.Context, filter models.SegmentHistoryFilter) (*models.SegmentHistory, error) {
+				require.Equal(t, "TEST_SEGMENT", filter.SegmentSlug)
+				require.Equal(t, models.BucketDay, filter.Bucket)
+				require.Equal(t, time.Date(2023, 8, 1, 0, 0, 0, 0, time.UTC), filter.From)
+				// Дата в to включается целиком
+				require.Equal(t, time.Date(2023, 9, 1, 0, 0, 0, 0, time.UTC), filter.To)
+
+				return &models.SegmentHistory{SegmentSlug: filter.SegmentSlug}, nil
+			})
+
+		handler := NewHandler(nil, mockSegmentSvc)
+
+		w := httptest.NewRecorder()
+		handler.GetSegmentHistory(w, newSegmentHistoryRequest("TEST_SEGMENT", "", "from=2023-08-01&to=2023-08-31"))
+
+		require.Equal(t, http.StatusOK, w.Code)
+	})
+
+	t.Run("Should return 400 if period is invalid", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		mockSegmentSvc := mock_segment.NewMockSegmentService(ctrl)
+		handler := NewHandler(nil, mockSegmentSvc)
+
+		for _, query := range []string{
+			"from=2023-08-01",
+			"from=2023-08-31&to=2023-08-01",
+			"from=2023-08-01&to=2023-08-31&bucket=week",
+			"from=2000-01-01&to=2023-08-31&bucket=hour",
+		} {
+			w := httptest.NewRecorder()
+			handler.GetSegmentHistory(w, newSegmentHistoryRequest("TEST_SEGMENT", "", query))
+
+			require.Equal(t, http.StatusBadRequest, w.Code, query)
+		}
+	})
+
+	t.Run("Should return 500 if something goes wrong", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		mockSegmentSvc := mock_segment.NewMockSegmentService(ctrl)
+		mockSegmentSvc.EXPECT().GetSegmentHistory(gomock.Any(), gomock.Any()).Return(nil, errors.New("internal error"))
+
+		handler := NewHandler(nil, mockSegmentSvc)
+
+		w := httptest.NewRecorder()
+		handler.GetSegmentHistory(w, newSegmentHistoryRequest("TEST_SEGMENT", "", "from=2023-08-01&to=2023-08-31"))
+
+		require.Equal(t, http.StatusInternalServerError, w.Code)
+	})
+}
+
+func Test_ExportSegmentHistory(t *testing.T) {
+	t.Run("Should return 200 and stream report", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		mockSegmentSvc := mock_segment.NewMockSegmentService(ctrl)
+		mockSegmentSvc.EXPECT().
+			ExportSegmentHistory(gomock.Any(), gomock.Any(), models.SegmentHistoryViewEvents, gomock.Any(), gomock.Any()).
+			DoAndReturn(func(ctx context.Context, filter models.SegmentHistoryFilter, view string, opts report.Options, w io.Writer) error {
+				require.Equal(t, models.BucketHour, filter.Bucket)
+				require.Equal(t, report.FormatCSV, opts.Format)
+
+				_, err := io.WriteString(w, "report")
+				return err
+			})
+
+		handler := NewHandler(nil, mockSegmentSvc)
+
+		w := httptest.NewRecorder()
+		handler.ExportSegmentHistory(w, newSegmentHistoryRequest("TEST_SEGMENT", "csv", "from=2023-08-01&to=2023-08-02&bucket=hour&view=events"))
+
+		require.Equal(t, http.StatusOK, w.Code)
+		require.Equal(t, "text/csv", w.Header().Get("Content-Type"))
+		require.Contains(t, w.Header().Get("Content-Disposition"), "TEST_SEGMENT-events.csv")
+		require.Equal(t, "report", w.Body.String())
+	})
+
+	t.Run("Should return 400 if view is unknown", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		mockSegmentSvc := mock_segment.NewMockSegmentService(ctrl)
+		handler := NewHandler(nil, mockSegmentSvc)
+
+		w := httptest.NewRecorder()
+		handler.ExportSegmentHistory(w, newSegmentHistoryRequest("TEST_SEGMENT", "csv", "from=2023-08-01&to=2023-08-02&view=users"))
+
+		require.Equal(t, http.StatusBadRequest, w.Code)
+	})
+}
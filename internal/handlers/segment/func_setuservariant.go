@@ -0,0 +1,67 @@
+package segment
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/dezzerlol/avitotech-test-2023/internal/apierror"
+	"github.com/dezzerlol/avitotech-test-2023/pkg/payload"
+	"github.com/go-chi/chi/v5"
+)
+
+type SetUserVariantRequest struct {
+	Variant string `json:"variant" validate:"required,max=64" example:"treatment_a"`
+}
+
+// SetUserVariant godoc
+// @Summary      Смена варианта пользователя
+// @Description  Метод ручного перевода участника сегмента в другой вариант эксперимента, например для проверки варианта.
+// @Description  Смена записывается в историю операцией V. Если пользователь не состоит в сегменте - 404 USER_NOT_IN_SEGMENT.
+// @Tags         Segment
+// @Security     ApiKeyAuth
+// @Security     BearerAuth
+// @Accept       json
+// @Produce      json
+// @Param        slug path string true "slug сегмента"
+// @Param        userId path string true "id пользователя"
+// @Param        body  body  SetUserVariantRequest  true  "Вариант"
+// @Success      200  {object} object{message=string}
+// @Failure      400,401,403,404,429,500  {object} apierror.Response
+// @Router       /api/v1/segment/{slug}/user/{userId}/variant [put]
+func (h *handler) SetUserVariant(w http.ResponseWriter, r *http.Request) {
+	ctx, span := tracer.Start(r.Context(), "handlers.segment.SetUserVariant")
+	defer span.End()
+
+	r = r.WithContext(ctx)
+
+	userId, err := payload.ParamInt(r, "userId")
+	if err != nil {
+		apierror.Write(w, r, apierror.BadRequest(err))
+		return
+	}
+
+	var req SetUserVariantRequest
+
+	if err := payload.ReadJSON(w, r, &req); err != nil {
+		apierror.Write(w, r, apierror.BadRequest(err))
+		return
+	}
+
+	if errs := payload.Validate(req); errs != nil {
+		apierror.Write(w, r, apierror.Validation(errs))
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	err = h.segmentSvc.SetUserVariant(ctx, chi.URLParam(r, "slug"), userId, req.Variant)
+
+	if err != nil {
+		apierror.Write(w, r, err)
+		return
+	}
+
+	payload.WriteJSON(w, http.StatusOK, payload.Data{"message": "ok"}, nil)
+}
@@ -0,0 +1,91 @@
+package segment
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	mock_segment "github.com/dezzerlol/avitotech-test-2023/internal/handlers/segment/mocks"
+	"github.com/dezzerlol/avitotech-test-2023/internal/repo"
+	"github.com/go-chi/chi/v5"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+)
+
+func newSetUserVariantRequest(userId, body string) *http.Request {
+	r := httptest.NewRequest(http.MethodPut, "/segment/AVITO_CHECKOUT/user/"+userId+"/variant", strings.NewReader(body))
+	rctx := chi.NewRouteContext()
+	rctx.URLParams.Add("slug", "AVITO_CHECKOUT")
+	rctx.URLParams.Add("userId", userId)
+
+	return r.WithContext(context.WithValue(r.Context(), chi.RouteCtxKey, rctx))
+}
+
+func Test_SetUserVariant(t *testing.T) {
+	t.Run("Should return 200 and change variant", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		mockSegmentSvc := mock_segment.NewMockSegmentService(ctrl)
+		mockSegmentSvc.EXPECT().SetUserVariant(gomock.Any(), "AVITO_CHECKOUT", int64(1000), "treatment_a").Return(nil)
+
+		handler := NewHandler(nil, mockSegmentSvc)
+
+		w := httptest.NewRecorder()
+		handler.SetUserVariant(w, newSetUserVariantRequest("1000", `{"variant": "treatment_a"}`))
+
+		require.Equal(t, http.StatusOK, w.Code)
+	})
+
+	t.Run("Should return 400 if request is invalid", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		mockSegmentSvc := mock_segment.NewMockSegmentService(ctrl)
+		handler := NewHandler(nil, mockSegmentSvc)
+
+		for _, tc := range []struct{ userId, body string }{
+			{"lol", `{"variant": "treatment_a"}`},
+			{"1000", `{"variant": ""}`},
+			{"1000", `{"variant": "treatment_a"`},
+		} {
+			w := httptest.NewRecorder()
+			handler.SetUserVariant(w, newSetUserVariantRequest(tc.userId, tc.body))
+
+			require.Equal(t, http.StatusBadRequest, w.Code, tc.body)
+		}
+	})
+
+	t.Run("Should return 404 if user is not in segment", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		mockSegmentSvc := mock_segment.NewMockSegmentService(ctrl)
+		mockSegmentSvc.EXPECT().SetUserVariant(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(repo.ErrUserNotInSegment)
+
+		handler := NewHandler(nil, mockSegmentSvc)
+
+		w := httptest.NewRecorder()
+		handler.SetUserVariant(w, newSetUserVariantRequest("1000", `{"variant": "treatment_a"}`))
+
+		require.Equal(t, http.StatusNotFound, w.Code)
+		require.Contains(t, w.Body.String(), `"code":"USER_NOT_IN_SEGMENT"`)
+	})
+
+	t.Run("Should return 404 if variant not found", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		mockSegmentSvc := mock_segment.NewMockSegmentService(ctrl)
+		mockSegmentSvc.EXPECT().SetUserVariant(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(repo.ErrVariantNotFound)
+
+		handler := NewHandler(nil, mockSegmentSvc)
+
+		w := httptest.NewRecorder()
+		handler.SetUserVariant(w, newSetUserVariantRequest("1000", `{"variant": "missing"}`))
+
+		require.Equal(t, http.StatusNotFound, w.Code)
+	})
+}
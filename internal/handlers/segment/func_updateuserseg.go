@@ -5,6 +5,8 @@ import (
 	"net/http"
 	"time"
 
+	"github.com/dezzerlol/avitotech-test-2023/internal/accesslog"
+	"github.com/dezzerlol/avitotech-test-2023/internal/apierror"
 	"github.com/dezzerlol/avitotech-test-2023/pkg/payload"
 )
 
@@ -19,26 +21,38 @@ type UpdateUserSegmentsRequest struct {
 // @Summary      Добавление/удаление сегментов у пользователя
 // @Description  Метод добавления пользователя в сегмент. Принимает массив slug (названий) сегментов которые нужно добавить пользователю,
 // @Description  массив slug (названий) сегментов которые нужно удалить у пользователя, id пользователя, ttl (в секундах).
+// @Description  Сначала удаляются сегменты delete_segments, затем добавляются add_segments, сегмент из обоих списков остается удаленным.
+// @Description  Если пользователь уже состоит в другом сегменте слоя добавляемого сегмента или добавляется в два сегмента одного слоя,
+// @Description  то возвращается 409 LAYER_CONFLICT и сегменты не добавляются.
 // @Tags         Segment
+// @Security     ApiKeyAuth
+// @Security     BearerAuth
 // @Accept       json
 // @Produce      json
 // @Param        body  body  UpdateUserSegmentsRequest  true  "Данные сегмента и пользователя"
 // @Success      200  {object} object{segments_added=int,segments_deleted=int}
-// @Failure      400,500  {object} object{error=string}
-// @Router       /segment/user [post]
+// @Failure      400,401,403,409,429,500  {object} apierror.Response
+// @Router       /api/v1/segment/user [post]
 func (h *handler) UpdateUserSegments(w http.ResponseWriter, r *http.Request) {
+	ctx, span := tracer.Start(r.Context(), "handlers.segment.UpdateUserSegments")
+	defer span.End()
+
+	r = r.WithContext(ctx)
+
 	var req UpdateUserSegmentsRequest
 
 	if err := payload.ReadJSON(w, r, &req); err != nil {
-		payload.WriteJSON(w, http.StatusBadRequest, payload.Data{"error": err.Error()}, nil)
+		apierror.Write(w, r, apierror.BadRequest(err))
 		return
 	}
 
 	if errs := payload.Validate(req); errs != nil {
-		payload.WriteJSON(w, http.StatusBadRequest, payload.Data{"error": errs}, nil)
+		apierror.Write(w, r, apierror.Validation(errs))
 		return
 	}
 
+	accesslog.SetUserID(r.Context(), req.UserId)
+
 	ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
 	defer cancel()
 
@@ -51,7 +65,7 @@ func (h *handler) UpdateUserSegments(w http.ResponseWriter, r *http.Request) {
 	)
 
 	if err != nil {
-		payload.WriteJSON(w, http.StatusInternalServerError, payload.Data{"error": "Internal server error"}, nil)
+		apierror.Write(w, r, err)
 		return
 	}
 
@@ -11,6 +11,7 @@ import (
 	"testing"
 
 	mock_segment "github.com/dezzerlol/avitotech-test-2023/internal/handlers/segment/mocks"
+	"github.com/dezzerlol/avitotech-test-2023/internal/repo"
 	"github.com/golang/mock/gomock"
 	"github.com/stretchr/testify/require"
 )
@@ -125,4 +126,30 @@ func Test_UpdateUserSegments(t *testing.T) {
 
 		require.Equal(t, http.StatusInternalServerError, w.Code)
 	})
+
+	t.Run("Should return 409 if user is in another segment of the layer", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		var userId int64 = 1
+		addSegments := []string{"AVITO_CHECKOUT_B"}
+
+		mockSegmentSvc := mock_segment.NewMockSegmentService(ctrl)
+		mockSegmentSvc.EXPECT().
+			UpdateUserSegments(gomock.Any(), userId, addSegments, int64(0), nil).
+			Return(int64(0), int64(0), &repo.LayerConflictError{Layer: "CHECKOUT", Segment: "AVITO_CHECKOUT_B", Conflicting: "AVITO_CHECKOUT_A"})
+
+		handler := NewHandler(nil, mockSegmentSvc)
+
+		var buf bytes.Buffer
+		json.NewEncoder(&buf).Encode(UpdateUserSegmentsRequest{UserId: userId, AddSegments: addSegments})
+
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodPost, "/segment/user", &buf)
+		handler.UpdateUserSegments(w, r)
+
+		require.Equal(t, http.StatusConflict, w.Code)
+		require.Contains(t, w.Body.String(), "LAYER_CONFLICT")
+		require.Contains(t, w.Body.String(), "AVITO_CHECKOUT_A")
+	})
 }
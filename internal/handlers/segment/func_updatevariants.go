@@ -0,0 +1,99 @@
+package segment
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/dezzerlol/avitotech-test-2023/internal/apierror"
+	"github.com/dezzerlol/avitotech-test-2023/internal/db/models"
+	"github.com/dezzerlol/avitotech-test-2023/pkg/payload"
+	"github.com/go-chi/chi/v5"
+)
+
+type VariantRequest struct {
+	Name   string `json:"name" validate:"required,max=64" example:"control"`
+	Weight int    `json:"weight" validate:"min=0,max=100" example:"50"`
+}
+
+type UpdateVariantsRequest struct {
+	Variants []VariantRequest `json:"variants" validate:"required,min=2,max=20,dive"`
+}
+
+// UpdateVariants godoc
+// @Summary      Изменение весов вариантов
+// @Description  Метод изменения весов вариантов эксперимента. Принимает все варианты сегмента с новыми весами, сумма весов - 100.
+// @Description  Новые веса действуют только для новых участников сегмента, назначенные варианты не меняются.
+// @Description  Варианты, которых еще нет, добавляются. Удалить вариант нельзя, вместо этого ему ставится вес 0.
+// @Tags         Segment
+// @Security     ApiKeyAuth
+// @Security     BearerAuth
+// @Accept       json
+// @Produce      json
+// @Param        slug path string true "slug сегмента"
+// @Param        body  body  UpdateVariantsRequest  true  "Варианты"
+// @Success      200  {object} object{variants=[]models.SegmentVariant}
+// @Failure      400,401,403,404,429,500  {object} apierror.Response
+// @Router       /api/v1/segment/{slug}/variants [put]
+func (h *handler) UpdateVariants(w http.ResponseWriter, r *http.Request) {
+	ctx, span := tracer.Start(r.Context(), "handlers.segment.UpdateVariants")
+	defer span.End()
+
+	r = r.WithContext(ctx)
+
+	var req UpdateVariantsRequest
+
+	if err := payload.ReadJSON(w, r, &req); err != nil {
+		apierror.Write(w, r, apierror.BadRequest(err))
+		return
+	}
+
+	if errs := payload.Validate(req); errs != nil {
+		apierror.Write(w, r, apierror.Validation(errs))
+		return
+	}
+
+	variants, err := toVariants(req.Variants)
+	if err != nil {
+		apierror.Write(w, r, apierror.BadRequest(err))
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	variants, err = h.segmentSvc.UpdateVariants(ctx, chi.URLParam(r, "slug"), variants)
+
+	if err != nil {
+		apierror.Write(w, r, err)
+		return
+	}
+
+	payload.WriteJSON(w, http.StatusOK, payload.Data{"variants": variants}, nil)
+}
+
+// toVariants проверяет, что имена вариантов не повторяются, а сумма весов равна 100.
+func toVariants(req []VariantRequest) ([]*models.SegmentVariant, error) {
+	variants := make([]*models.SegmentVariant, 0, len(req))
+	names := make(map[string]bool, len(req))
+	total := 0
+
+	for _, v := range req {
+		if names[v.Name] {
+			return nil, fmt.Errorf("duplicate variant %q", v.Name)
+		}
+
+		names[v.Name] = true
+		total += v.Weight
+
+		variants = append(variants, &models.SegmentVariant{Name: v.Name, Weight: v.Weight})
+	}
+
+	if total != models.VariantWeightTotal {
+		return nil, errors.New("variant weights must sum to 100")
+	}
+
+	return variants, nil
+}
@@ -0,0 +1,107 @@
+package segment
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/dezzerlol/avitotech-test-2023/internal/db/models"
+	mock_segment "github.com/dezzerlol/avitotech-test-2023/internal/handlers/segment/mocks"
+	"github.com/dezzerlol/avitotech-test-2023/internal/repo"
+	"github.com/go-chi/chi/v5"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+)
+
+func newUpdateVariantsRequest(slug, body string) *http.Request {
+	r := httptest.NewRequest(http.MethodPut, "/segment/"+slug+"/variants", strings.NewReader(body))
+	rctx := chi.NewRouteContext()
+	rctx.URLParams.Add("slug", slug)
+
+	return r.WithContext(context.WithValue(r.Context(), chi.RouteCtxKey, rctx))
+}
+
+func Test_UpdateVariants(t *testing.T) {
+	t.Run("Should return 200 and updated variants", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		variants := []*models.SegmentVariant{
+			{Name: "control", Weight: 34},
+			{Name: "treatment_a", Weight: 33},
+			{Name: "treatment_b", Weight: 33},
+		}
+
+		mockSegmentSvc := mock_segment.NewMockSegmentService(ctrl)
+		mockSegmentSvc.EXPECT().
+			UpdateVariants(gomock.Any(), "AVITO_CHECKOUT", variants).
+			Return([]*models.SegmentVariant{{Name: "control", Weight: 34, Users: 10}}, nil)
+
+		handler := NewHandler(nil, mockSegmentSvc)
+
+		body := `{"variants": [{"name": "control", "weight": 34}, {"name": "treatment_a", "weight": 33}, {"name": "treatment_b", "weight": 33}]}`
+
+		w := httptest.NewRecorder()
+		handler.UpdateVariants(w, newUpdateVariantsRequest("AVITO_CHECKOUT", body))
+
+		require.Equal(t, http.StatusOK, w.Code)
+		require.JSONEq(t, `{"variants": [{"name": "control", "weight": 34, "users": 10}]}`, w.Body.String())
+	})
+
+	t.Run("Should return 400 if variants are invalid", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		mockSegmentSvc := mock_segment.NewMockSegmentService(ctrl)
+		handler := NewHandler(nil, mockSegmentSvc)
+
+		for _, body := range []string{
+			`{"variants": [{"name": "control", "weight": 50}, {"name": "treatment", "weight": 40}]}`,
+			`{"variants": [{"name": "control", "weight": 50}, {"name": "control", "weight": 50}]}`,
+			`{"variants": [{"name": "control", "weight": 100}]}`,
+			`{"variants": [{"name": "control", "weight": 150}, {"name": "treatment", "weight": -50}]}`,
+		} {
+			w := httptest.NewRecorder()
+			handler.UpdateVariants(w, newUpdateVariantsRequest("AVITO_CHECKOUT", body))
+
+			require.Equal(t, http.StatusBadRequest, w.Code, body)
+		}
+	})
+
+	t.Run("Should return 400 if variant is removed", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		mockSegmentSvc := mock_segment.NewMockSegmentService(ctrl)
+		mockSegmentSvc.EXPECT().UpdateVariants(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil, repo.ErrVariantRemoved)
+
+		handler := NewHandler(nil, mockSegmentSvc)
+
+		body := `{"variants": [{"name": "control", "weight": 50}, {"name": "treatment_a", "weight": 50}]}`
+
+		w := httptest.NewRecorder()
+		handler.UpdateVariants(w, newUpdateVariantsRequest("AVITO_CHECKOUT", body))
+
+		require.Equal(t, http.StatusBadRequest, w.Code)
+		require.Contains(t, w.Body.String(), `"code":"VARIANT_REMOVED"`)
+	})
+
+	t.Run("Should return 404 if segment not found", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		mockSegmentSvc := mock_segment.NewMockSegmentService(ctrl)
+		mockSegmentSvc.EXPECT().UpdateVariants(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil, repo.ErrSegmentNotFound)
+
+		handler := NewHandler(nil, mockSegmentSvc)
+
+		body := `{"variants": [{"name": "control", "weight": 50}, {"name": "treatment_a", "weight": 50}]}`
+
+		w := httptest.NewRecorder()
+		handler.UpdateVariants(w, newUpdateVariantsRequest("AVITO_CHECKOUT", body))
+
+		require.Equal(t, http.StatusNotFound, w.Code)
+	})
+}
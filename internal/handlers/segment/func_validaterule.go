@@ -0,0 +1,65 @@
+package segment
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/dezzerlol/avitotech-test-2023/internal/apierror"
+	"github.com/dezzerlol/avitotech-test-2023/internal/rules"
+	"github.com/dezzerlol/avitotech-test-2023/pkg/payload"
+)
+
+type ValidateRuleRequest struct {
+	Rule string `json:"rule" validate:"required" example:"city in [\"msk\", \"spb\"] && platform == \"ios\""`
+	// Атрибуты для пробного вычисления правила
+	Attributes map[string]any `json:"attributes" swaggertype:"object" example:"city:msk,platform:ios"`
+}
+
+// ValidateRule godoc
+// @Summary      Проверка правила динамического сегмента
+// @Description  Метод разбирает правило и возвращает ошибку с позицией (в символах, начиная с 1), если правило некорректно.
+// @Description  Для корректного правила возвращает используемые атрибуты, а если переданы attributes - подходят ли они под правило (matches).
+// @Tags         Segment
+// @Security     ApiKeyAuth
+// @Security     BearerAuth
+// @Accept       json
+// @Produce      json
+// @Param        body  body  ValidateRuleRequest  true  "Правило и атрибуты"
+// @Success      200  {object} object{valid=bool,error=rules.Error,attributes=[]string,matches=bool}
+// @Failure      400,401,403,429  {object} apierror.Response
+// @Router       /api/v1/segment/rules/validate [post]
+func (h *handler) ValidateRule(w http.ResponseWriter, r *http.Request) {
+	var req ValidateRuleRequest
+
+	if err := payload.ReadJSON(w, r, &req); err != nil {
+		apierror.Write(w, r, apierror.BadRequest(err))
+		return
+	}
+
+	if errs := payload.Validate(req); errs != nil {
+		apierror.Write(w, r, apierror.Validation(errs))
+		return
+	}
+
+	rule, err := rules.Parse(req.Rule)
+
+	if err != nil {
+		var ruleErr *rules.Error
+
+		if !errors.As(err, &ruleErr) {
+			apierror.Write(w, r, err)
+			return
+		}
+
+		payload.WriteJSON(w, http.StatusOK, payload.Data{"valid": false, "error": ruleErr}, nil)
+		return
+	}
+
+	data := payload.Data{"valid": true, "attributes": rule.Attributes()}
+
+	if req.Attributes != nil {
+		data["matches"] = rule.Match(req.Attributes)
+	}
+
+	payload.WriteJSON(w, http.StatusOK, data, nil)
+}
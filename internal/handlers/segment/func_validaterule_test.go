@@ -0,0 +1,64 @@
+package segment
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	mock_segment "github.com/dezzerlol/avitotech-test-2023/internal/handlers/segment/mocks"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_ValidateRule(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		code int
+		want string
+	}{
+		{
+			name: "Should return used attributes for valid rule",
+			body: `{"rule": "city in [\"msk\", \"spb\"] && platform == \"ios\""}`,
+			code: http.StatusOK,
+			want: `{"valid": true, "attributes": ["city", "platform"]}`,
+		},
+		{
+			name: "Should evaluate rule for attributes",
+			body: `{"rule": "city in [\"msk\", \"spb\"] && platform == \"ios\"", "attributes": {"city": "msk", "platform": "android"}}`,
+			code: http.StatusOK,
+			want: `{"valid": true, "attributes": ["city", "platform"], "matches": false}`,
+		},
+		{
+			name: "Should return error position for invalid rule",
+			body: `{"rule": "city in [\"msk\" \"spb\"]"}`,
+			code: http.StatusOK,
+			want: `{"valid": false, "error": {"position": 16, "message": "expected \",\" or \"]\", got \"spb\""}}`,
+		},
+		{
+			name: "Should return 400 if rule is empty",
+			body: `{"rule": ""}`,
+			code: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			handler := NewHandler(nil, mock_segment.NewMockSegmentService(ctrl))
+
+			w := httptest.NewRecorder()
+			r := httptest.NewRequest(http.MethodPost, "/segment/rules/validate", strings.NewReader(tt.body))
+			handler.ValidateRule(w, r)
+
+			require.Equal(t, tt.code, w.Code)
+
+			if tt.want != "" {
+				require.JSONEq(t, tt.want, w.Body.String())
+			}
+		})
+	}
+}
@@ -2,20 +2,34 @@ package segment
 
 import (
 	"context"
+	"io"
 	"net/http"
+	"time"
 
 	"github.com/dezzerlol/avitotech-test-2023/internal/db/models"
+	"github.com/dezzerlol/avitotech-test-2023/internal/report"
+	"github.com/dezzerlol/avitotech-test-2023/internal/tracing"
 	"go.uber.org/zap"
 )
 
+var tracer = tracing.Tracer("internal/handlers/segment")
+
 type Handler interface {
 	Create(w http.ResponseWriter, r *http.Request)
 	Delete(w http.ResponseWriter, r *http.Request)
+	ValidateRule(w http.ResponseWriter, r *http.Request)
+	GetVariants(w http.ResponseWriter, r *http.Request)
+	UpdateVariants(w http.ResponseWriter, r *http.Request)
 
 	UpdateUserSegments(w http.ResponseWriter, r *http.Request)
 	GetSegmentsForUser(w http.ResponseWriter, r *http.Request)
+	LookupUserSegments(w http.ResponseWriter, r *http.Request)
+	SetUserVariant(w http.ResponseWriter, r *http.Request)
 
 	GetUserHistory(w http.ResponseWriter, r *http.Request)
+	ExportUserHistory(w http.ResponseWriter, r *http.Request)
+	GetSegmentHistory(w http.ResponseWriter, r *http.Request)
+	ExportSegmentHistory(w http.ResponseWriter, r *http.Request)
 	DownloadReport(w http.ResponseWriter, r *http.Request)
 }
 
@@ -24,8 +38,16 @@ type SegmentService interface {
 	Create(ctx context.Context, segment *models.Segment) error
 	DeleteBySlug(ctx context.Context, segment *models.Segment) error
 	GetUserSegments(ctx context.Context, userId int64) ([]*models.Segment, error)
-	GetUserHistory(ctx context.Context, userId, month, year int64) (string, error)
+	GetUserSegmentsAt(ctx context.Context, userId int64, at time.Time) ([]*models.Segment, error)
+	LookupUserSegments(ctx context.Context, userIds []int64, slugs []string) (*models.UserSegmentsLookup, error)
+	GetUserHistory(ctx context.Context, userId, month, year int64, opts report.Options) (string, error)
+	ExportUserHistory(ctx context.Context, userId, month, year int64, opts report.Options, w io.Writer) error
+	GetSegmentHistory(ctx context.Context, filter models.SegmentHistoryFilter) (*models.SegmentHistory, error)
+	ExportSegmentHistory(ctx context.Context, filter models.SegmentHistoryFilter, view string, opts report.Options, w io.Writer) error
 	UpdateUserSegments(ctx context.Context, userId int64, addSegments []string, ttl int64, deleteSegments []string) (segmentsAdded int64, segmentsDeleted int64, err error)
+	GetVariants(ctx context.Context, slug string) ([]*models.SegmentVariant, error)
+	UpdateVariants(ctx context.Context, slug string, variants []*models.SegmentVariant) ([]*models.SegmentVariant, error)
+	SetUserVariant(ctx context.Context, slug string, userId int64, variant string) error
 }
 
 type handler struct {
@@ -6,9 +6,12 @@ package mocks
 
 import (
 	context "context"
+	io "io"
 	reflect "reflect"
+	time "time"
 
 	models "github.com/dezzerlol/avitotech-test-2023/internal/db/models"
+	report "github.com/dezzerlol/avitotech-test-2023/internal/report"
 	gomock "github.com/golang/mock/gomock"
 )
 
@@ -63,19 +66,62 @@ func (mr *MockSegmentServiceMockRecorder) DeleteBySlug(arg0, arg1 interface{}) *
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteBySlug", reflect.TypeOf((*MockSegmentService)(nil).DeleteBySlug), arg0, arg1)
 }
 
+// ExportSegmentHistory mocks base method.
+func (m *MockSegmentService) ExportSegmentHistory(arg0 context.Context, arg1 models.SegmentHistoryFilter, arg2 string, arg3 report.Options, arg4 io.Writer) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ExportSegmentHistory", arg0, arg1, arg2, arg3, arg4)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ExportSegmentHistory indicates an expected call of ExportSegmentHistory.
+func (mr *MockSegmentServiceMockRecorder) ExportSegmentHistory(arg0, arg1, arg2, arg3, arg4 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ExportSegmentHistory", reflect.TypeOf((*MockSegmentService)(nil).ExportSegmentHistory), arg0, arg1, arg2, arg3, arg4)
+}
+
+// ExportUserHistory mocks base method.
+func (m *MockSegmentService) ExportUserHistory(arg0 context.Context, arg1, arg2, arg3 int64, arg4 report.Options, arg5 io.Writer) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ExportUserHistory", arg0, arg1, arg2, arg3, arg4, arg5)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ExportUserHistory indicates an expected call of ExportUserHistory.
+func (mr *MockSegmentServiceMockRecorder) ExportUserHistory(arg0, arg1, arg2, arg3, arg4, arg5 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ExportUserHistory", reflect.TypeOf((*MockSegmentService)(nil).ExportUserHistory), arg0, arg1, arg2, arg3, arg4, arg5)
+}
+
+// GetSegmentHistory mocks base method.
+func (m *MockSegmentService) GetSegmentHistory(arg0 context.Context, arg1 models.SegmentHistoryFilter) (*models.SegmentHistory, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetSegmentHistory", arg0, arg1)
+	ret0, _ := ret[0].(*models.SegmentHistory)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetSegmentHistory indicates an expected call of GetSegmentHistory.
+func (mr *MockSegmentServiceMockRecorder) GetSegmentHistory(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetSegmentHistory", reflect.TypeOf((*MockSegmentService)(nil).GetSegmentHistory), arg0, arg1)
+}
+
 // GetUserHistory mocks base method.
-func (m *MockSegmentService) GetUserHistory(arg0 context.Context, arg1, arg2, arg3 int64) (string, error) {
+func (m *MockSegmentService) GetUserHistory(arg0 context.Context, arg1, arg2, arg3 int64, arg4 report.Options) (string, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "GetUserHistory", arg0, arg1, arg2, arg3)
+	ret := m.ctrl.Call(m, "GetUserHistory", arg0, arg1, arg2, arg3, arg4)
 	ret0, _ := ret[0].(string)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // GetUserHistory indicates an expected call of GetUserHistory.
-func (mr *MockSegmentServiceMockRecorder) GetUserHistory(arg0, arg1, arg2, arg3 interface{}) *gomock.Call {
+func (mr *MockSegmentServiceMockRecorder) GetUserHistory(arg0, arg1, arg2, arg3, arg4 interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetUserHistory", reflect.TypeOf((*MockSegmentService)(nil).GetUserHistory), arg0, arg1, arg2, arg3)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetUserHistory", reflect.TypeOf((*MockSegmentService)(nil).GetUserHistory), arg0, arg1, arg2, arg3, arg4)
 }
 
 // GetUserSegments mocks base method.
@@ -93,6 +139,65 @@ func (mr *MockSegmentServiceMockRecorder) GetUserSegments(arg0, arg1 interface{}
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetUserSegments", reflect.TypeOf((*MockSegmentService)(nil).GetUserSegments), arg0, arg1)
 }
 
+// GetUserSegmentsAt mocks base method.
+func (m *MockSegmentService) GetUserSegmentsAt(arg0 context.Context, arg1 int64, arg2 time.Time) ([]*models.Segment, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetUserSegmentsAt", arg0, arg1, arg2)
+	ret0, _ := ret[0].([]*models.Segment)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetUserSegmentsAt indicates an expected call of GetUserSegmentsAt.
+func (mr *MockSegmentServiceMockRecorder) GetUserSegmentsAt(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetUserSegmentsAt", reflect.TypeOf((*MockSegmentService)(nil).GetUserSegmentsAt), arg0, arg1, arg2)
+}
+
+// GetVariants mocks base method.
+func (m *MockSegmentService) GetVariants(arg0 context.Context, arg1 string) ([]*models.SegmentVariant, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetVariants", arg0, arg1)
+	ret0, _ := ret[0].([]*models.SegmentVariant)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetVariants indicates an expected call of GetVariants.
+func (mr *MockSegmentServiceMockRecorder) GetVariants(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetVariants", reflect.TypeOf((*MockSegmentService)(nil).GetVariants), arg0, arg1)
+}
+
+// LookupUserSegments mocks base method.
+func (m *MockSegmentService) LookupUserSegments(arg0 context.Context, arg1 []int64, arg2 []string) (*models.UserSegmentsLookup, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "LookupUserSegments", arg0, arg1, arg2)
+	ret0, _ := ret[0].(*models.UserSegmentsLookup)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// LookupUserSegments indicates an expected call of LookupUserSegments.
+func (mr *MockSegmentServiceMockRecorder) LookupUserSegments(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "LookupUserSegments", reflect.TypeOf((*MockSegmentService)(nil).LookupUserSegments), arg0, arg1, arg2)
+}
+
+// SetUserVariant mocks base method.
+func (m *MockSegmentService) SetUserVariant(arg0 context.Context, arg1 string, arg2 int64, arg3 string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetUserVariant", arg0, arg1, arg2, arg3)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SetUserVariant indicates an expected call of SetUserVariant.
+func (mr *MockSegmentServiceMockRecorder) SetUserVariant(arg0, arg1, arg2, arg3 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetUserVariant", reflect.TypeOf((*MockSegmentService)(nil).SetUserVariant), arg0, arg1, arg2, arg3)
+}
+
 // UpdateUserSegments mocks base method.
 func (m *MockSegmentService) UpdateUserSegments(arg0 context.Context, arg1 int64, arg2 []string, arg3 int64, arg4 []string) (int64, int64, error) {
 	m.ctrl.T.Helper()
@@ -108,3 +213,18 @@ func (mr *MockSegmentServiceMockRecorder) UpdateUserSegments(arg0, arg1, arg2, a
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateUserSegments", reflect.TypeOf((*MockSegmentService)(nil).UpdateUserSegments), arg0, arg1, arg2, arg3, arg4)
 }
+
+// UpdateVariants mocks base method.
+func (m *MockSegmentService) UpdateVariants(arg0 context.Context, arg1 string, arg2 []*models.SegmentVariant) ([]*models.SegmentVariant, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateVariants", arg0, arg1, arg2)
+	ret0, _ := ret[0].([]*models.SegmentVariant)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// UpdateVariants indicates an expected call of UpdateVariants.
+func (mr *MockSegmentServiceMockRecorder) UpdateVariants(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateVariants", reflect.TypeOf((*MockSegmentService)(nil).UpdateVariants), arg0, arg1, arg2)
+}
@@ -5,6 +5,7 @@ import (
 	"net/http"
 	"time"
 
+	"github.com/dezzerlol/avitotech-test-2023/internal/apierror"
 	"github.com/dezzerlol/avitotech-test-2023/pkg/payload"
 )
 
@@ -13,10 +14,12 @@ import (
 // @Description  Метод создания пользователя.
 // @Description  Используется в случае необходимости вручную добавить пользователя, так как при добавлении сегмента пользователь сохраняется автоматически.
 // @Tags         User
+// @Security     ApiKeyAuth
+// @Security     BearerAuth
 // @Produce      json
 // @Success      201  {object} object{user_id=int64}
-// @Failure      400  {object} object{error=string}
-// @Router       /user [post]
+// @Failure      400,401,403,429  {object} apierror.Response
+// @Router       /api/v1/user [post]
 func (h *handler) Create(w http.ResponseWriter, r *http.Request) {
 	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
 	defer cancel()
@@ -24,7 +27,7 @@ func (h *handler) Create(w http.ResponseWriter, r *http.Request) {
 	userId, err := h.userSvc.Create(ctx)
 
 	if err != nil {
-		payload.WriteJSON(w, http.StatusInternalServerError, payload.Data{"error": "Internal server error"}, nil)
+		apierror.Write(w, r, err)
 		return
 	}
 
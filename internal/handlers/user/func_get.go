@@ -0,0 +1,41 @@
+package user
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/dezzerlol/avitotech-test-2023/internal/apierror"
+	"github.com/dezzerlol/avitotech-test-2023/pkg/payload"
+)
+
+// Get godoc
+// @Summary      Получение пользователя
+// @Description  Метод получения пользователя с атрибутами, по которым вычисляются динамические сегменты.
+// @Tags         User
+// @Security     ApiKeyAuth
+// @Security     BearerAuth
+// @Produce      json
+// @Param        userId path string true "id пользователя"
+// @Success      200  {object} object{user=models.User}
+// @Failure      400,401,403,404,429,500  {object} apierror.Response
+// @Router       /api/v1/user/{userId} [get]
+func (h *handler) Get(w http.ResponseWriter, r *http.Request) {
+	userId, err := payload.ParamInt(r, "userId")
+	if err != nil {
+		apierror.Write(w, r, apierror.BadRequest(err))
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	user, err := h.userSvc.Get(ctx, userId)
+
+	if err != nil {
+		apierror.Write(w, r, err)
+		return
+	}
+
+	payload.WriteJSON(w, http.StatusOK, payload.Data{"user": user}, nil)
+}
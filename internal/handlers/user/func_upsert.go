@@ -0,0 +1,89 @@
+package user
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/dezzerlol/avitotech-test-2023/internal/apierror"
+	"github.com/dezzerlol/avitotech-test-2023/pkg/payload"
+)
+
+type UpsertRequest struct {
+	Attributes map[string]any `json:"attributes" validate:"required,max=100" swaggertype:"object" example:"city:msk,platform:ios"`
+}
+
+// Upsert godoc
+// @Summary      Создание пользователя с атрибутами
+// @Description  Метод создает пользователя с заданным id или заменяет его атрибуты.
+// @Description  Атрибуты - произвольный JSON объект (не больше 100 ключей), по ним вычисляются правила динамических сегментов.
+// @Description  Атрибуты со значением null не сохраняются. Атрибуты общие для всех пространств имен.
+// @Tags         User
+// @Security     ApiKeyAuth
+// @Security     BearerAuth
+// @Accept       json
+// @Produce      json
+// @Param        userId path string true "id пользователя"
+// @Param        body  body  UpsertRequest  true  "Атрибуты пользователя"
+// @Success      200  {object} object{user=models.User}
+// @Failure      400,401,403,429,500  {object} apierror.Response
+// @Router       /api/v1/user/{userId} [put]
+func (h *handler) Upsert(w http.ResponseWriter, r *http.Request) {
+	h.upsert(w, r, false)
+}
+
+// Patch godoc
+// @Summary      Обновление атрибутов пользователя
+// @Description  Метод дописывает атрибуты к существующим, создавая пользователя при необходимости.
+// @Description  Атрибут со значением null удаляется. Вложенные объекты заменяются целиком.
+// @Tags         User
+// @Security     ApiKeyAuth
+// @Security     BearerAuth
+// @Accept       json
+// @Produce      json
+// @Param        userId path string true "id пользователя"
+// @Param        body  body  UpsertRequest  true  "Изменяемые атрибуты"
+// @Success      200  {object} object{user=models.User}
+// @Failure      400,401,403,429,500  {object} apierror.Response
+// @Router       /api/v1/user/{userId} [patch]
+func (h *handler) Patch(w http.ResponseWriter, r *http.Request) {
+	h.upsert(w, r, true)
+}
+
+func (h *handler) upsert(w http.ResponseWriter, r *http.Request, merge bool) {
+	userId, err := payload.ParamInt(r, "userId")
+	if err != nil {
+		apierror.Write(w, r, apierror.BadRequest(err))
+		return
+	}
+
+	if userId < 1 {
+		apierror.Write(w, r, apierror.BadRequest(errors.New("user id must be positive")))
+		return
+	}
+
+	var req UpsertRequest
+
+	if err := payload.ReadJSON(w, r, &req); err != nil {
+		apierror.Write(w, r, apierror.BadRequest(err))
+		return
+	}
+
+	if errs := payload.Validate(req); errs != nil {
+		apierror.Write(w, r, apierror.Validation(errs))
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	user, err := h.userSvc.Upsert(ctx, userId, req.Attributes, merge)
+
+	if err != nil {
+		apierror.Write(w, r, err)
+		return
+	}
+
+	payload.WriteJSON(w, http.StatusOK, payload.Data{"user": user}, nil)
+}
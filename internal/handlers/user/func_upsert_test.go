@@ -0,0 +1,111 @@
+package user
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/dezzerlol/avitotech-test-2023/internal/db/models"
+	mock_user "github.com/dezzerlol/avitotech-test-2023/internal/handlers/user/mocks"
+	"github.com/dezzerlol/avitotech-test-2023/internal/repo"
+	"github.com/go-chi/chi/v5"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+)
+
+func newUserRequest(method, userId, body string) *http.Request {
+	r := httptest.NewRequest(method, "/user/"+userId, strings.NewReader(body))
+	rctx := chi.NewRouteContext()
+	rctx.URLParams.Add("userId", userId)
+
+	return r.WithContext(context.WithValue(r.Context(), chi.RouteCtxKey, rctx))
+}
+
+func Test_UpsertUser(t *testing.T) {
+	t.Run("Should replace attributes on PUT and merge on PATCH", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		mockUserSvc := mock_user.NewMockUserService(ctrl)
+		handler := NewHandler(nil, mockUserSvc)
+
+		attributes := map[string]any{"city": "msk", "age": float64(27)}
+		user := &models.User{Id: 1, Attributes: attributes}
+
+		mockUserSvc.EXPECT().Upsert(gomock.Any(), int64(1), attributes, false).Return(user, nil)
+		mockUserSvc.EXPECT().Upsert(gomock.Any(), int64(1), attributes, true).Return(user, nil)
+
+		body := `{"attributes": {"city": "msk", "age": 27}}`
+
+		w := httptest.NewRecorder()
+		handler.Upsert(w, newUserRequest(http.MethodPut, "1", body))
+
+		require.Equal(t, http.StatusOK, w.Code)
+		require.JSONEq(t, `{"user": {"id": 1, "attributes": {"city": "msk", "age": 27}}}`, w.Body.String())
+
+		w = httptest.NewRecorder()
+		handler.Patch(w, newUserRequest(http.MethodPatch, "1", body))
+
+		require.Equal(t, http.StatusOK, w.Code)
+	})
+
+	t.Run("Should return 400 if request is invalid", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		handler := NewHandler(nil, mock_user.NewMockUserService(ctrl))
+
+		tests := []struct {
+			userId string
+			body   string
+		}{
+			{"lol", `{"attributes": {}}`},
+			{"0", `{"attributes": {}}`},
+			{"1", `{}`},
+			{"1", `{"attributes": ["msk"]}`},
+		}
+
+		for _, tt := range tests {
+			w := httptest.NewRecorder()
+			handler.Upsert(w, newUserRequest(http.MethodPut, tt.userId, tt.body))
+
+			require.Equal(t, http.StatusBadRequest, w.Code, tt)
+		}
+	})
+}
+
+func Test_GetUser(t *testing.T) {
+	t.Run("Should return user with attributes", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		mockUserSvc := mock_user.NewMockUserService(ctrl)
+		handler := NewHandler(nil, mockUserSvc)
+
+		mockUserSvc.EXPECT().Get(gomock.Any(), int64(1)).Return(&models.User{Id: 1, Attributes: map[string]any{"city": "msk"}}, nil)
+
+		w := httptest.NewRecorder()
+		handler.Get(w, newUserRequest(http.MethodGet, "1", ""))
+
+		require.Equal(t, http.StatusOK, w.Code)
+		require.JSONEq(t, `{"user": {"id": 1, "attributes": {"city": "msk"}}}`, w.Body.String())
+	})
+
+	t.Run("Should return 404 if user not found", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		mockUserSvc := mock_user.NewMockUserService(ctrl)
+		handler := NewHandler(nil, mockUserSvc)
+
+		mockUserSvc.EXPECT().Get(gomock.Any(), int64(2)).Return(nil, repo.ErrUserNotFound)
+
+		w := httptest.NewRecorder()
+		handler.Get(w, newUserRequest(http.MethodGet, fmt.Sprint(2), ""))
+
+		require.Equal(t, http.StatusNotFound, w.Code)
+	})
+}
@@ -4,16 +4,22 @@ import (
 	"context"
 	"net/http"
 
+	"github.com/dezzerlol/avitotech-test-2023/internal/db/models"
 	"go.uber.org/zap"
 )
 
 type Handler interface {
 	Create(w http.ResponseWriter, r *http.Request)
+	Get(w http.ResponseWriter, r *http.Request)
+	Upsert(w http.ResponseWriter, r *http.Request)
+	Patch(w http.ResponseWriter, r *http.Request)
 }
 
 //go:generate mockgen -destination=mocks/mock_user.go -package=mocks github.com/dezzerlol/avitotech-test-2023/internal/handlers/user UserService
 type UserService interface {
 	Create(ctx context.Context) (int64, error)
+	Get(ctx context.Context, userId int64) (*models.User, error)
+	Upsert(ctx context.Context, userId int64, attributes map[string]any, merge bool) (*models.User, error)
 }
 
 type handler struct {
@@ -0,0 +1,62 @@
+package webhook
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/dezzerlol/avitotech-test-2023/internal/apierror"
+	"github.com/dezzerlol/avitotech-test-2023/internal/db/models"
+	"github.com/dezzerlol/avitotech-test-2023/pkg/payload"
+)
+
+type CreateRequest struct {
+	URL         string `json:"url" validate:"required,http_url,max=2048" example:"https://crm.example.com/hooks/segments"`
+	Description string `json:"description" validate:"max=255" example:"CRM"`
+}
+
+// Create godoc
+// @Summary      Регистрация вебхука
+// @Description  Метод регистрации адреса, на который POST запросом отправляются события добавления пользователя в сегмент,
+// @Description  удаления из него и смены варианта (segment.user_added, segment.user_removed, segment.variant_changed) в пространстве имен запроса.
+// @Description  Запросы подписываются HMAC-SHA256 ключом secret, который возвращается только в ответе на этот запрос.
+// @Description  Адрес должен разрешаться в публичные адреса: loopback, частные и link-local адреса возвращают 400 WEBHOOK_URL_FORBIDDEN.
+// @Tags         Webhook
+// @Security     ApiKeyAuth
+// @Security     BearerAuth
+// @Accept       json
+// @Produce      json
+// @Param        body  body  CreateRequest  true  "Адрес вебхука"
+// @Success      201  {object} object{webhook=models.Webhook,secret=string}
+// @Failure      400,401,403,429,500  {object} apierror.Response
+// @Router       /api/v1/webhooks [post]
+func (h *handler) Create(w http.ResponseWriter, r *http.Request) {
+	var req CreateRequest
+
+	if err := payload.ReadJSON(w, r, &req); err != nil {
+		apierror.Write(w, r, apierror.BadRequest(err))
+		return
+	}
+
+	if errs := payload.Validate(req); errs != nil {
+		apierror.Write(w, r, apierror.Validation(errs))
+		return
+	}
+
+	hook := &models.Webhook{
+		URL:         req.URL,
+		Description: req.Description,
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	secret, err := h.webhookSvc.Create(ctx, hook)
+
+	if err != nil {
+		apierror.Write(w, r, err)
+		return
+	}
+
+	payload.WriteJSON(w, http.StatusCreated, payload.Data{"webhook": hook, "secret": secret}, nil)
+}
@@ -0,0 +1,92 @@
+package webhook
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/dezzerlol/avitotech-test-2023/internal/db/models"
+	mock_webhook "github.com/dezzerlol/avitotech-test-2023/internal/handlers/webhook/mocks"
+	internalwebhook "github.com/dezzerlol/avitotech-test-2023/internal/webhook"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_CreateWebhook(t *testing.T) {
+	t.Run("Should return 201 and secret", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		mockWebhookSvc := mock_webhook.NewMockWebhookService(ctrl)
+		mockWebhookSvc.EXPECT().
+			Create(gomock.Any(), &models.Webhook{URL: "https://example.com/hook", Description: "CRM"}).
+			DoAndReturn(func(ctx context.Context, hook *models.Webhook) (string, error) {
+				hook.ID = 1
+				return "whsec_test", nil
+			})
+
+		handler := NewHandler(nil, mockWebhookSvc)
+
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodPost, "/webhooks", strings.NewReader(`{"url": "https://example.com/hook", "description": "CRM"}`))
+		handler.Create(w, r)
+
+		require.Equal(t, http.StatusCreated, w.Code)
+		require.Contains(t, w.Body.String(), `"secret":"whsec_test"`)
+	})
+
+	t.Run("Should return 400 if url is invalid", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		mockWebhookSvc := mock_webhook.NewMockWebhookService(ctrl)
+		handler := NewHandler(nil, mockWebhookSvc)
+
+		for _, body := range []string{`{}`, `{"url": "example.com"}`, `{"url": "ftp://example.com/hook"}`} {
+			w := httptest.NewRecorder()
+			r := httptest.NewRequest(http.MethodPost, "/webhooks", strings.NewReader(body))
+			handler.Create(w, r)
+
+			require.Equal(t, http.StatusBadRequest, w.Code, body)
+		}
+	})
+
+	t.Run("Should return 400 if url resolves to private address", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		mockWebhookSvc := mock_webhook.NewMockWebhookService(ctrl)
+		mockWebhookSvc.EXPECT().
+			Create(gomock.Any(), &models.Webhook{URL: "http://169.254.169.254/latest/meta-data"}).
+			Return("", fmt.Errorf("%w: 169.254.169.254", internalwebhook.ErrForbiddenAddress))
+
+		handler := NewHandler(nil, mockWebhookSvc)
+
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodPost, "/webhooks", strings.NewReader(`{"url": "http://169.254.169.254/latest/meta-data"}`))
+		handler.Create(w, r)
+
+		require.Equal(t, http.StatusBadRequest, w.Code)
+		require.Contains(t, w.Body.String(), `"code":"WEBHOOK_URL_FORBIDDEN"`)
+	})
+
+	t.Run("Should return 500 if something goes wrong", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		mockWebhookSvc := mock_webhook.NewMockWebhookService(ctrl)
+		mockWebhookSvc.EXPECT().Create(gomock.Any(), gomock.Any()).Return("", errors.New("internal error"))
+
+		handler := NewHandler(nil, mockWebhookSvc)
+
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodPost, "/webhooks", strings.NewReader(`{"url": "https://example.com/hook"}`))
+		handler.Create(w, r)
+
+		require.Equal(t, http.StatusInternalServerError, w.Code)
+	})
+}
@@ -0,0 +1,41 @@
+package webhook
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/dezzerlol/avitotech-test-2023/internal/apierror"
+	"github.com/dezzerlol/avitotech-test-2023/pkg/payload"
+)
+
+// Delete godoc
+// @Summary      Удаление вебхука
+// @Description  Метод удаления вебхука вместе с журналом доставки. Недоставленные события вебхуку больше не отправляются.
+// @Tags         Webhook
+// @Security     ApiKeyAuth
+// @Security     BearerAuth
+// @Produce      json
+// @Param        id path int true "id вебхука"
+// @Success      200  {object} object{message=string}
+// @Failure      400,401,403,404,429,500  {object} apierror.Response
+// @Router       /api/v1/webhooks/{id} [delete]
+func (h *handler) Delete(w http.ResponseWriter, r *http.Request) {
+	id, err := payload.ParamInt(r, "id")
+	if err != nil {
+		apierror.Write(w, r, apierror.BadRequest(err))
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	err = h.webhookSvc.Delete(ctx, id)
+
+	if err != nil {
+		apierror.Write(w, r, err)
+		return
+	}
+
+	payload.WriteJSON(w, http.StatusOK, payload.Data{"message": "ok"}, nil)
+}
@@ -0,0 +1,63 @@
+package webhook
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/dezzerlol/avitotech-test-2023/internal/apierror"
+	"github.com/dezzerlol/avitotech-test-2023/pkg/payload"
+)
+
+const (
+	defaultDeliveriesLimit = 50
+	maxDeliveriesLimit     = 500
+)
+
+// ListDeliveries godoc
+// @Summary      Журнал доставки вебхука
+// @Description  Метод получения последних попыток доставки событий вебхуку, новые первыми.
+// @Tags         Webhook
+// @Security     ApiKeyAuth
+// @Security     BearerAuth
+// @Produce      json
+// @Param        id path int true "id вебхука"
+// @Param        limit query int false "количество попыток, по умолчанию 50, не больше 500"
+// @Success      200  {object} object{deliveries=[]models.WebhookDelivery}
+// @Failure      400,401,403,404,429,500  {object} apierror.Response
+// @Router       /api/v1/webhooks/{id}/deliveries [get]
+func (h *handler) ListDeliveries(w http.ResponseWriter, r *http.Request) {
+	id, err := payload.ParamInt(r, "id")
+	if err != nil {
+		apierror.Write(w, r, apierror.BadRequest(err))
+		return
+	}
+
+	limit := int64(defaultDeliveriesLimit)
+
+	if r.URL.Query().Has("limit") {
+		limit, err = payload.QueryInt(r, "limit")
+		if err != nil {
+			apierror.Write(w, r, apierror.BadRequest(err))
+			return
+		}
+	}
+
+	if limit < 1 || limit > maxDeliveriesLimit {
+		apierror.Write(w, r, apierror.BadRequest(errors.New("limit must be from 1 to 500")))
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	deliveries, err := h.webhookSvc.ListDeliveries(ctx, id, limit)
+
+	if err != nil {
+		apierror.Write(w, r, err)
+		return
+	}
+
+	payload.WriteJSON(w, http.StatusOK, payload.Data{"deliveries": deliveries}, nil)
+}
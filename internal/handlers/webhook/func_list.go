@@ -0,0 +1,34 @@
+package webhook
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/dezzerlol/avitotech-test-2023/internal/apierror"
+	"github.com/dezzerlol/avitotech-test-2023/pkg/payload"
+)
+
+// List godoc
+// @Summary      Список вебхуков
+// @Description  Метод получения вебхуков пространства имен запроса. Ключи подписи не возвращаются.
+// @Tags         Webhook
+// @Security     ApiKeyAuth
+// @Security     BearerAuth
+// @Produce      json
+// @Success      200  {object} object{webhooks=[]models.Webhook}
+// @Failure      401,403,429,500  {object} apierror.Response
+// @Router       /api/v1/webhooks [get]
+func (h *handler) List(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	hooks, err := h.webhookSvc.List(ctx)
+
+	if err != nil {
+		apierror.Write(w, r, err)
+		return
+	}
+
+	payload.WriteJSON(w, http.StatusOK, payload.Data{"webhooks": hooks}, nil)
+}
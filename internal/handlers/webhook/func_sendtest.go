@@ -0,0 +1,43 @@
+package webhook
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/dezzerlol/avitotech-test-2023/internal/apierror"
+	"github.com/dezzerlol/avitotech-test-2023/pkg/payload"
+)
+
+// SendTest godoc
+// @Summary      Проверка вебхука
+// @Description  Метод сразу отправляет вебхуку событие webhook.test и возвращает результат попытки.
+// @Description  Недоступность адреса не считается ошибкой запроса: результат возвращается с success = false.
+// @Tags         Webhook
+// @Security     ApiKeyAuth
+// @Security     BearerAuth
+// @Produce      json
+// @Param        id path int true "id вебхука"
+// @Success      200  {object} object{delivery=models.WebhookDelivery}
+// @Failure      400,401,403,404,429,500  {object} apierror.Response
+// @Router       /api/v1/webhooks/{id}/test [post]
+func (h *handler) SendTest(w http.ResponseWriter, r *http.Request) {
+	id, err := payload.ParamInt(r, "id")
+	if err != nil {
+		apierror.Write(w, r, apierror.BadRequest(err))
+		return
+	}
+
+	// Таймаут больше времени ожидания ответа получателя
+	ctx, cancel := context.WithTimeout(r.Context(), 20*time.Second)
+	defer cancel()
+
+	delivery, err := h.webhookSvc.SendTest(ctx, id)
+
+	if err != nil {
+		apierror.Write(w, r, err)
+		return
+	}
+
+	payload.WriteJSON(w, http.StatusOK, payload.Data{"delivery": delivery}, nil)
+}
@@ -0,0 +1,101 @@
+package webhook
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/dezzerlol/avitotech-test-2023/internal/db/models"
+	mock_webhook "github.com/dezzerlol/avitotech-test-2023/internal/handlers/webhook/mocks"
+	"github.com/dezzerlol/avitotech-test-2023/internal/repo"
+	"github.com/go-chi/chi/v5"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+)
+
+func newWebhookRequest(method, target, id string) *http.Request {
+	r := httptest.NewRequest(method, target, nil)
+	rctx := chi.NewRouteContext()
+	rctx.URLParams.Add("id", id)
+
+	return r.WithContext(context.WithValue(r.Context(), chi.RouteCtxKey, rctx))
+}
+
+func Test_SendTestWebhook(t *testing.T) {
+	t.Run("Should return 200 and failed delivery", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		mockWebhookSvc := mock_webhook.NewMockWebhookService(ctrl)
+		mockWebhookSvc.EXPECT().
+			SendTest(gomock.Any(), int64(1)).
+			Return(&models.WebhookDelivery{WebhookID: 1, EventType: models.EventWebhookTest, Attempt: 1, Error: "connection refused"}, nil)
+
+		handler := NewHandler(nil, mockWebhookSvc)
+
+		w := httptest.NewRecorder()
+		handler.SendTest(w, newWebhookRequest(http.MethodPost, "/webhooks/1/test", "1"))
+
+		require.Equal(t, http.StatusOK, w.Code)
+		require.Contains(t, w.Body.String(), `"success":false`)
+	})
+
+	t.Run("Should return 404 if webhook not found", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		mockWebhookSvc := mock_webhook.NewMockWebhookService(ctrl)
+		mockWebhookSvc.EXPECT().SendTest(gomock.Any(), int64(2)).Return(nil, repo.ErrWebhookNotFound)
+
+		handler := NewHandler(nil, mockWebhookSvc)
+
+		w := httptest.NewRecorder()
+		handler.SendTest(w, newWebhookRequest(http.MethodPost, "/webhooks/2/test", "2"))
+
+		require.Equal(t, http.StatusNotFound, w.Code)
+	})
+
+	t.Run("Should return 400 if id is invalid", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		handler := NewHandler(nil, mock_webhook.NewMockWebhookService(ctrl))
+
+		w := httptest.NewRecorder()
+		handler.SendTest(w, newWebhookRequest(http.MethodPost, "/webhooks/abc/test", "abc"))
+
+		require.Equal(t, http.StatusBadRequest, w.Code)
+	})
+}
+
+func Test_ListWebhookDeliveries(t *testing.T) {
+	t.Run("Should use default limit", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		mockWebhookSvc := mock_webhook.NewMockWebhookService(ctrl)
+		mockWebhookSvc.EXPECT().ListDeliveries(gomock.Any(), int64(1), int64(defaultDeliveriesLimit)).Return([]*models.WebhookDelivery{}, nil)
+
+		handler := NewHandler(nil, mockWebhookSvc)
+
+		w := httptest.NewRecorder()
+		handler.ListDeliveries(w, newWebhookRequest(http.MethodGet, "/webhooks/1/deliveries", "1"))
+
+		require.Equal(t, http.StatusOK, w.Code)
+	})
+
+	t.Run("Should return 400 if limit is out of range", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		handler := NewHandler(nil, mock_webhook.NewMockWebhookService(ctrl))
+
+		for _, limit := range []string{"0", "501", "abc"} {
+			w := httptest.NewRecorder()
+			handler.ListDeliveries(w, newWebhookRequest(http.MethodGet, "/webhooks/1/deliveries?limit="+limit, "1"))
+
+			require.Equal(t, http.StatusBadRequest, w.Code, limit)
+		}
+	})
+}
@@ -0,0 +1,38 @@
+package webhook
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/dezzerlol/avitotech-test-2023/internal/db/models"
+	"go.uber.org/zap"
+)
+
+type Handler interface {
+	Create(w http.ResponseWriter, r *http.Request)
+	List(w http.ResponseWriter, r *http.Request)
+	Delete(w http.ResponseWriter, r *http.Request)
+	SendTest(w http.ResponseWriter, r *http.Request)
+	ListDeliveries(w http.ResponseWriter, r *http.Request)
+}
+
+//go:generate mockgen -destination=mocks/mock_webhook.go -package=mocks github.com/dezzerlol/avitotech-test-2023/internal/handlers/webhook WebhookService
+type WebhookService interface {
+	Create(ctx context.Context, hook *models.Webhook) (string, error)
+	List(ctx context.Context) ([]*models.Webhook, error)
+	Delete(ctx context.Context, id int64) error
+	SendTest(ctx context.Context, id int64) (*models.WebhookDelivery, error)
+	ListDeliveries(ctx context.Context, id int64, limit int64) ([]*models.WebhookDelivery, error)
+}
+
+type handler struct {
+	logger     *zap.SugaredLogger
+	webhookSvc WebhookService
+}
+
+func NewHandler(logger *zap.SugaredLogger, webhookSvc WebhookService) Handler {
+	return &handler{
+		logger:     logger,
+		webhookSvc: webhookSvc,
+	}
+}
@@ -0,0 +1,72 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/hibiken/asynq"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/redis/go-redis/v9"
+)
+
+var ErrWorkerNotRunning = errors.New("worker processor is not running")
+
+func PingDB(db *pgxpool.Pool) Check {
+	return func(ctx context.Context) error {
+		return db.Ping(ctx)
+	}
+}
+
+func PingRedis(client redis.UniversalClient) Check {
+	return func(ctx context.Context) error {
+		return client.Ping(ctx).Err()
+	}
+}
+
+// WorkerHeartbeat проверяет, что обработчик задач этого процесса запущен.
+// asynq раз в несколько секунд сохраняет в Redis состояние каждого обработчика, его и проверяем.
+func WorkerHeartbeat(inspector *asynq.Inspector) Check {
+	host, _ := os.Hostname()
+	pid := os.Getpid()
+
+	return func(ctx context.Context) error {
+		servers, err := inspector.Servers()
+
+		if err != nil {
+			return err
+		}
+
+		for _, server := range servers {
+			if server.Host == host && server.PID == pid {
+				if server.Status != "active" {
+					return fmt.Errorf("%w: status %s", ErrWorkerNotRunning, server.Status)
+				}
+
+				return nil
+			}
+		}
+
+		return ErrWorkerNotRunning
+	}
+}
+
+// WritableDir проверяет, что в папку можно записать файл.
+func WritableDir(dir string) Check {
+	return func(ctx context.Context) error {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return err
+		}
+
+		file, err := os.CreateTemp(dir, ".readyz-*")
+
+		if err != nil {
+			return err
+		}
+
+		file.Close()
+
+		return os.Remove(file.Name())
+	}
+}
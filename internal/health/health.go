@@ -0,0 +1,112 @@
+package health
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Статус проверки
+const (
+	StatusOK   = "ok"
+	StatusFail = "fail"
+	// Сервис останавливается и не принимает новые запросы
+	StatusShuttingDown = "shutting_down"
+)
+
+// Сколько ждать ответа одной зависимости
+const checkTimeout = 2 * time.Second
+
+// Check проверяет одну зависимость сервиса.
+type Check func(ctx context.Context) error
+
+type Result struct {
+	Status    string  `json:"status"`
+	LatencyMs float64 `json:"latency_ms"`
+	Error     string  `json:"error,omitempty"`
+}
+
+type Report struct {
+	Status string             `json:"status"`
+	Checks map[string]*Result `json:"checks"`
+}
+
+// Checker выполняет проверки готовности и хранит признак остановки сервиса.
+type Checker struct {
+	checks       map[string]Check
+	shuttingDown atomic.Bool
+}
+
+func NewChecker() *Checker {
+	return &Checker{checks: map[string]Check{}}
+}
+
+// Add добавляет проверку зависимости. Вызывается до начала обработки запросов.
+func (c *Checker) Add(name string, check Check) {
+	c.checks[name] = check
+}
+
+// ShutDown переводит сервис в состояние остановки, после чего он перестает быть готовым.
+func (c *Checker) ShutDown() {
+	c.shuttingDown.Store(true)
+}
+
+// Ready одновременно выполняет все проверки. Сервис готов, если все они прошли и он не останавливается.
+func (c *Checker) Ready(ctx context.Context) (bool, *Report) {
+	ctx, cancel := context.WithTimeout(ctx, checkTimeout)
+	defer cancel()
+
+	report := &Report{
+		Status: StatusOK,
+		Checks: make(map[string]*Result, len(c.checks)),
+	}
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+
+	for name, check := range c.checks {
+		wg.Add(1)
+
+		go func(name string, check Check) {
+			defer wg.Done()
+
+			res := run(ctx, check)
+
+			mu.Lock()
+			report.Checks[name] = res
+			mu.Unlock()
+		}(name, check)
+	}
+
+	wg.Wait()
+
+	for _, res := range report.Checks {
+		if res.Status != StatusOK {
+			report.Status = StatusFail
+		}
+	}
+
+	if c.shuttingDown.Load() {
+		report.Status = StatusShuttingDown
+	}
+
+	return report.Status == StatusOK, report
+}
+
+func run(ctx context.Context, check Check) *Result {
+	start := time.Now()
+	err := check(ctx)
+
+	res := &Result{
+		Status:    StatusOK,
+		LatencyMs: float64(time.Since(start).Microseconds()) / 1000,
+	}
+
+	if err != nil {
+		res.Status = StatusFail
+		res.Error = err.Error()
+	}
+
+	return res
+}
@@ -0,0 +1,72 @@
+package health
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheckerReady(t *testing.T) {
+	checker := NewChecker()
+	checker.Add("db", func(ctx context.Context) error { return nil })
+	checker.Add("redis", func(ctx context.Context) error { return nil })
+
+	ready, report := checker.Ready(context.Background())
+	require.True(t, ready)
+	require.Equal(t, StatusOK, report.Status)
+	require.Len(t, report.Checks, 2)
+	require.Equal(t, StatusOK, report.Checks["db"].Status)
+
+	checker.Add("worker", func(ctx context.Context) error { return ErrWorkerNotRunning })
+
+	ready, report = checker.Ready(context.Background())
+	require.False(t, ready)
+	require.Equal(t, StatusFail, report.Status)
+	require.Equal(t, StatusFail, report.Checks["worker"].Status)
+	require.Equal(t, ErrWorkerNotRunning.Error(), report.Checks["worker"].Error)
+	require.Equal(t, StatusOK, report.Checks["redis"].Status)
+}
+
+func TestCheckerShutDown(t *testing.T) {
+	checker := NewChecker()
+	checker.Add("db", func(ctx context.Context) error { return nil })
+
+	checker.ShutDown()
+
+	ready, report := checker.Ready(context.Background())
+	require.False(t, ready)
+	require.Equal(t, StatusShuttingDown, report.Status)
+	require.Equal(t, StatusOK, report.Checks["db"].Status)
+}
+
+func TestCheckerTimeout(t *testing.T) {
+	checker := NewChecker()
+	checker.Add("db", func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	ready, report := checker.Ready(ctx)
+	require.False(t, ready)
+	require.Equal(t, context.Canceled.Error(), report.Checks["db"].Error)
+}
+
+func TestWritableDir(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "reports")
+
+	require.NoError(t, WritableDir(dir)(context.Background()))
+
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	require.Empty(t, entries)
+
+	file := filepath.Join(t.TempDir(), "file")
+	require.NoError(t, os.WriteFile(file, nil, 0o644))
+	require.Error(t, WritableDir(file)(context.Background()))
+}
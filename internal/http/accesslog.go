@@ -0,0 +1,69 @@
+package http
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/dezzerlol/avitotech-test-2023/internal/accesslog"
+	"github.com/dezzerlol/avitotech-test-2023/internal/metrics"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// accessLog пишет строку лога и метрики на каждый запрос после его выполнения.
+// id пользователя берется из обработчика, а если он его не задал - из параметра маршрута userId.
+func (s *Server) accessLog(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+
+		ctx, entry := accesslog.WithEntry(r.Context())
+		ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+
+		defer func() {
+			latency := time.Since(start)
+			route := chi.RouteContext(ctx).RoutePattern()
+			metricsRoute := route
+
+			if route == "" {
+				route = r.URL.Path
+				metricsRoute = metrics.UnmatchedRoute
+			}
+
+			status := ww.Status()
+
+			// Обработчик ничего не записал, net/http ответит 200
+			if status == 0 {
+				status = http.StatusOK
+			}
+
+			metrics.ObserveHTTPRequest(r.Method, metricsRoute, status, latency)
+
+			fields := []any{
+				"request_id", middleware.GetReqID(ctx),
+				"method", r.Method,
+				"route", route,
+				"status", status,
+				"latency", latency,
+				"bytes", ww.BytesWritten(),
+			}
+
+			if sc := trace.SpanContextFromContext(ctx); sc.IsValid() {
+				fields = append(fields, "trace_id", sc.TraceID().String())
+			}
+
+			if entry.UserID == 0 {
+				entry.UserID, _ = strconv.ParseInt(chi.RouteContext(ctx).URLParam("userId"), 10, 64)
+			}
+
+			if entry.UserID != 0 {
+				fields = append(fields, "user_id", entry.UserID)
+			}
+
+			s.logger.Infow("http request", fields...)
+		}()
+
+		next.ServeHTTP(ww, r.WithContext(ctx))
+	})
+}
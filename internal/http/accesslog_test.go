@@ -0,0 +1,77 @@
+package http
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/dezzerlol/avitotech-test-2023/internal/accesslog"
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func Test_RequestIDAndAccessLog(t *testing.T) {
+	core, logs := observer.New(zap.InfoLevel)
+	s := &Server{logger: zap.New(core).Sugar()}
+
+	r := chi.NewRouter()
+	r.Use(requestID)
+	r.Use(s.accessLog)
+	r.Get("/segment/user/{userId}", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	})
+	r.Post("/segment/user", func(w http.ResponseWriter, r *http.Request) {
+		accesslog.SetUserID(r.Context(), 7)
+	})
+
+	tests := []struct {
+		name      string
+		method    string
+		path      string
+		requestID string
+		route     string
+		status    int
+		userID    int64
+	}{
+		{"user id from route", http.MethodGet, "/segment/user/5", "req-1", "/segment/user/{userId}", http.StatusNotFound, 5},
+		{"user id from handler", http.MethodPost, "/segment/user", "", "/segment/user", http.StatusOK, 7},
+		{"invalid request id", http.MethodPost, "/segment/user", "bad id", "/segment/user", http.StatusOK, 7},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			logs.TakeAll()
+
+			req := httptest.NewRequest(tt.method, tt.path, nil)
+
+			if tt.requestID != "" {
+				req.Header.Set("X-Request-Id", tt.requestID)
+			}
+
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+
+			id := w.Header().Get("X-Request-Id")
+			require.NotEmpty(t, id)
+
+			if tt.requestID == "req-1" {
+				require.Equal(t, tt.requestID, id)
+			} else {
+				require.Len(t, id, 32)
+			}
+
+			entries := logs.TakeAll()
+			require.Len(t, entries, 1)
+
+			fields := entries[0].ContextMap()
+			require.Equal(t, id, fields["request_id"])
+			require.Equal(t, tt.method, fields["method"])
+			require.Equal(t, tt.route, fields["route"])
+			require.EqualValues(t, tt.status, fields["status"])
+			require.EqualValues(t, tt.userID, fields["user_id"])
+			require.Contains(t, fields, "latency")
+		})
+	}
+}
@@ -0,0 +1,108 @@
+package http
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/dezzerlol/avitotech-test-2023/internal/apierror"
+	"github.com/dezzerlol/avitotech-test-2023/internal/auth"
+	"github.com/dezzerlol/avitotech-test-2023/internal/repo"
+)
+
+type Authenticator interface {
+	Authenticate(ctx context.Context, key string) (auth.Identity, error)
+}
+
+// authenticate проверяет Bearer токен из заголовка Authorization или ключ из заголовка X-Api-Key
+// и сохраняет владельца в контексте, откуда его получают auditContext и сервисы.
+func (s *Server) authenticate(authenticator Authenticator) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			var (
+				identity auth.Identity
+				err      error
+			)
+
+			if token, ok := bearerToken(r); ok {
+				if s.jwtVerifier == nil {
+					apierror.Write(w, r, apierror.New(http.StatusUnauthorized, apierror.CodeInvalidToken, "bearer tokens are not accepted"))
+					return
+				}
+
+				identity, err = s.jwtVerifier.Verify(r.Context(), token)
+			} else {
+				key := r.Header.Get("X-Api-Key")
+
+				if key == "" {
+					apierror.Write(w, r, apierror.New(http.StatusUnauthorized, apierror.CodeUnauthorized, "api key or bearer token is required"))
+					return
+				}
+
+				identity, err = authenticator.Authenticate(r.Context(), key)
+			}
+
+			if err != nil {
+				switch {
+				case errors.Is(err, repo.ErrAPIKeyNotFound):
+					apierror.Write(w, r, apierror.New(http.StatusUnauthorized, apierror.CodeInvalidAPIKey, "invalid api key"))
+					return
+				case errors.Is(err, auth.ErrInvalidToken):
+					apierror.Write(w, r, apierror.New(http.StatusUnauthorized, apierror.CodeInvalidToken, "invalid bearer token"))
+					return
+				case errors.Is(err, context.Canceled):
+					apierror.Write(w, r, err)
+					return
+				default:
+					s.logger.Errorw("error authenticating request", "err", err)
+					apierror.Write(w, r, err)
+					return
+				}
+			}
+
+			next.ServeHTTP(w, r.WithContext(auth.WithIdentity(r.Context(), identity)))
+		})
+	}
+}
+
+func bearerToken(r *http.Request) (string, bool) {
+	scheme, token, ok := strings.Cut(r.Header.Get("Authorization"), " ")
+
+	if !ok || !strings.EqualFold(scheme, "Bearer") {
+		return "", false
+	}
+
+	return strings.TrimSpace(token), true
+}
+
+// requireScope пропускает запрос, только если у ключа есть право scope.
+func requireScope(scope string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			identity, ok := auth.FromContext(r.Context())
+
+			if !ok || !identity.HasScope(scope) {
+				apierror.Write(w, r, apierror.New(http.StatusForbidden, apierror.CodeForbidden, "api key has no scope "+scope))
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// requireAllNamespaces пропускает только ключи без ограничения по пространствам имен.
+// Иначе ограниченный ключ с правом keys:admin мог бы выпустить ключ с доступом ко всем пространствам имен.
+func requireAllNamespaces(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		identity, ok := auth.FromContext(r.Context())
+
+		if !ok || len(identity.Namespaces) > 0 {
+			apierror.Write(w, r, apierror.New(http.StatusForbidden, apierror.CodeForbidden, "api key is restricted to namespaces"))
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,80 @@
+package http
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/dezzerlol/avitotech-test-2023/internal/audit"
+	"github.com/dezzerlol/avitotech-test-2023/internal/auth"
+	"github.com/dezzerlol/avitotech-test-2023/internal/repo"
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+type authenticatorFunc func(ctx context.Context, key string) (auth.Identity, error)
+
+func (f authenticatorFunc) Authenticate(ctx context.Context, key string) (auth.Identity, error) {
+	return f(ctx, key)
+}
+
+func Test_Authenticate(t *testing.T) {
+	s := &Server{logger: zap.NewNop().Sugar()}
+
+	authenticator := authenticatorFunc(func(ctx context.Context, key string) (auth.Identity, error) {
+		if key != "seg_key" {
+			return auth.Identity{}, repo.ErrAPIKeyNotFound
+		}
+
+		return auth.Identity{Method: auth.MethodAPIKey, KeyID: 1, Name: "reports-service", Scopes: []string{auth.ScopeSegmentsRead}}, nil
+	})
+
+	var actor string
+
+	r := chi.NewRouter()
+	r.Use(s.authenticate(authenticator))
+	r.Use(auditContext)
+	r.With(requireScope(auth.ScopeSegmentsRead)).Get("/read", func(w http.ResponseWriter, r *http.Request) {
+		actor = audit.FromContext(r.Context()).Actor
+	})
+	r.With(requireScope(auth.ScopeSegmentsWrite)).Post("/write", func(w http.ResponseWriter, r *http.Request) {})
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		key    string
+		bearer string
+		status int
+	}{
+		{"missing key", http.MethodGet, "/read", "", "", http.StatusUnauthorized},
+		{"unknown key", http.MethodGet, "/read", "seg_other", "", http.StatusUnauthorized},
+		{"bearer without jwks", http.MethodGet, "/read", "seg_key", "token", http.StatusUnauthorized},
+		{"missing scope", http.MethodPost, "/write", "seg_key", "", http.StatusForbidden},
+		{"allowed", http.MethodGet, "/read", "seg_key", "", http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.path, nil)
+
+			if tt.key != "" {
+				req.Header.Set("X-Api-Key", tt.key)
+			}
+
+			if tt.bearer != "" {
+				req.Header.Set("Authorization", "Bearer "+tt.bearer)
+			}
+
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+
+			require.Equal(t, tt.status, w.Code)
+		})
+	}
+
+	// Изменения попадают в историю с именем ключа
+	require.Equal(t, "api_key:reports-service", actor)
+}
@@ -0,0 +1,44 @@
+package http
+
+import (
+	"net/http"
+
+	"github.com/dezzerlol/avitotech-test-2023/internal/health"
+	"github.com/dezzerlol/avitotech-test-2023/pkg/payload"
+)
+
+// healthz godoc
+// @Summary      Проверка работы процесса
+// @Description  Отвечает 200, пока процесс запущен. Зависимости не проверяются.
+// @Tags         Health
+// @Produce      json
+// @Success      200  {object} health.Report
+// @Router       /healthz [get]
+func (s *Server) healthz(w http.ResponseWriter, r *http.Request) {
+	checks := map[string]*health.Result{
+		"process": {Status: health.StatusOK},
+	}
+
+	payload.WriteJSON(w, http.StatusOK, payload.Data{"status": health.StatusOK, "checks": checks}, nil)
+}
+
+// readyz godoc
+// @Summary      Проверка готовности
+// @Description  Проверяет PostgreSQL, Redis, обработчик задач и запись в папку отчетов.
+// @Description  Отвечает 503, если хотя бы одна проверка не прошла или сервис останавливается.
+// @Tags         Health
+// @Produce      json
+// @Success      200  {object} health.Report
+// @Failure      503  {object} health.Report
+// @Router       /readyz [get]
+func (s *Server) readyz(w http.ResponseWriter, r *http.Request) {
+	ready, report := s.health.Ready(r.Context())
+
+	status := http.StatusOK
+
+	if !ready {
+		status = http.StatusServiceUnavailable
+	}
+
+	payload.WriteJSON(w, status, payload.Data{"status": report.Status, "checks": report.Checks}, nil)
+}
@@ -0,0 +1,108 @@
+package http
+
+import (
+	"context"
+	"net"
+	"net/http"
+
+	"github.com/dezzerlol/avitotech-test-2023/internal/apierror"
+	"github.com/dezzerlol/avitotech-test-2023/internal/audit"
+	"github.com/dezzerlol/avitotech-test-2023/internal/auth"
+	"github.com/dezzerlol/avitotech-test-2023/internal/namespace"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+)
+
+// requestID присваивает запросу id из заголовка X-Request-Id или генерирует новый.
+// id возвращается в ответе, пишется в access log и историю сегментов и передается в задачи воркера.
+func requestID(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := audit.RequestID(r.Header.Get(middleware.RequestIDHeader))
+
+		w.Header().Set(middleware.RequestIDHeader, id)
+
+		ctx := context.WithValue(r.Context(), middleware.RequestIDKey, id)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// auditContext сохраняет в контексте, кто выполнил запрос, чтобы изменения
+// сегментов попали в историю вместе с actor и request_id.
+// Actor - имя API ключа или sub токена запроса. Без них actor берется из заголовка X-Actor,
+// а если его нет - из адреса клиента.
+func auditContext(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		actor := r.Header.Get("X-Actor")
+
+		if identity, ok := auth.FromContext(r.Context()); ok {
+			actor = identity.Method + ":" + identity.Name
+		}
+
+		if actor == "" {
+			actor, _, _ = net.SplitHostPort(r.RemoteAddr)
+		}
+
+		meta := audit.Meta{
+			Actor:     audit.TruncateActor(actor),
+			RequestID: middleware.GetReqID(r.Context()),
+		}
+
+		next.ServeHTTP(w, r.WithContext(audit.WithMeta(r.Context(), meta)))
+	})
+}
+
+// namespaceContext выбирает пространство имен запроса из префикса /ns/{namespace}
+// или заголовка X-Namespace. Без них используется default.
+// Ключ или токен запроса должен иметь доступ к выбранному пространству имен.
+func namespaceContext(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ns := chi.URLParam(r, "namespace")
+		header := r.Header.Get("X-Namespace")
+
+		if ns != "" && header != "" && ns != header {
+			apierror.Write(w, r, apierror.New(http.StatusBadRequest, apierror.CodeInvalidRequest, "namespace in path and X-Namespace header differ"))
+			return
+		}
+
+		if ns == "" {
+			ns = header
+		}
+
+		if ns == "" {
+			ns = namespace.Default
+		}
+
+		if !namespace.Valid(ns) {
+			apierror.Write(w, r, apierror.New(http.StatusBadRequest, apierror.CodeInvalidRequest, "invalid namespace"))
+			return
+		}
+
+		if identity, ok := auth.FromContext(r.Context()); !ok || !identity.HasNamespace(ns) {
+			apierror.Write(w, r, apierror.New(http.StatusForbidden, apierror.CodeNamespaceForbidden, "no access to namespace "+ns))
+			return
+		}
+
+		next.ServeHTTP(w, r.WithContext(namespace.WithNamespace(r.Context(), ns)))
+	})
+}
+
+// apiPrefix - префикс текущей версии API.
+const apiPrefix = "/api/v1"
+
+// deprecatedRoute помечает ответы путей без версии заголовками Deprecation и Link на путь /api/v1.
+func deprecatedRoute(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Deprecation", "true")
+		w.Header().Set("Link", "<"+apiPrefix+r.URL.Path+`>; rel="successor-version"`)
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+func notFound(w http.ResponseWriter, r *http.Request) {
+	apierror.Write(w, r, apierror.New(http.StatusNotFound, apierror.CodeRouteNotFound, "route not found"))
+}
+
+func methodNotAllowed(w http.ResponseWriter, r *http.Request) {
+	apierror.Write(w, r, apierror.New(http.StatusMethodNotAllowed, apierror.CodeMethodNotAllowed, "method not allowed"))
+}
@@ -0,0 +1,105 @@
+package http
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/dezzerlol/avitotech-test-2023/internal/auth"
+	"github.com/dezzerlol/avitotech-test-2023/internal/namespace"
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_NamespaceContext(t *testing.T) {
+	var got string
+
+	routes := func(r chi.Router) {
+		r.Use(namespaceContext)
+		r.Get("/segment", func(w http.ResponseWriter, r *http.Request) {
+			got = namespace.FromContext(r.Context())
+		})
+	}
+
+	r := chi.NewRouter()
+	r.Group(routes)
+	r.Route("/ns/{namespace}", routes)
+
+	tests := []struct {
+		name       string
+		path       string
+		header     string
+		namespaces []string
+		status     int
+		want       string
+	}{
+		{"default", "/segment", "", nil, http.StatusOK, namespace.Default},
+		{"path prefix", "/ns/payments/segment", "", nil, http.StatusOK, "payments"},
+		{"header", "/segment", "payments", nil, http.StatusOK, "payments"},
+		{"same path and header", "/ns/payments/segment", "payments", nil, http.StatusOK, "payments"},
+		{"different path and header", "/ns/payments/segment", "ads", nil, http.StatusBadRequest, ""},
+		{"invalid namespace", "/ns/Payments!/segment", "", nil, http.StatusBadRequest, ""},
+		{"allowed namespace", "/ns/payments/segment", "", []string{"payments"}, http.StatusOK, "payments"},
+		{"denied namespace", "/ns/ads/segment", "", []string{"payments"}, http.StatusForbidden, ""},
+		{"denied default", "/segment", "", []string{"payments"}, http.StatusForbidden, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got = ""
+
+			req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			req = req.WithContext(auth.WithIdentity(req.Context(), auth.Identity{Namespaces: tt.namespaces}))
+
+			if tt.header != "" {
+				req.Header.Set("X-Namespace", tt.header)
+			}
+
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+
+			require.Equal(t, tt.status, w.Code)
+			require.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func Test_VersionedRoutes(t *testing.T) {
+	api := func(r chi.Router) {
+		r.Get("/segment", func(w http.ResponseWriter, r *http.Request) {})
+	}
+
+	r := chi.NewRouter()
+	r.NotFound(notFound)
+	r.MethodNotAllowed(methodNotAllowed)
+	r.Route(apiPrefix, api)
+	r.Group(func(r chi.Router) {
+		r.Use(deprecatedRoute)
+		api(r)
+	})
+
+	do := func(method, path string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(method, path, nil))
+
+		return w
+	}
+
+	w := do(http.MethodGet, "/api/v1/segment")
+	require.Equal(t, http.StatusOK, w.Code)
+	require.Empty(t, w.Header().Get("Deprecation"))
+
+	// Старые пути работают, но помечены устаревшими
+	w = do(http.MethodGet, "/segment")
+	require.Equal(t, http.StatusOK, w.Code)
+	require.Equal(t, "true", w.Header().Get("Deprecation"))
+	require.Equal(t, `</api/v1/segment>; rel="successor-version"`, w.Header().Get("Link"))
+
+	w = do(http.MethodGet, "/api/v1/unknown")
+	require.Equal(t, http.StatusNotFound, w.Code)
+	require.Contains(t, w.Body.String(), `"code":"ROUTE_NOT_FOUND"`)
+
+	w = do(http.MethodPost, "/api/v1/segment")
+	require.Equal(t, http.StatusMethodNotAllowed, w.Code)
+	require.Contains(t, w.Body.String(), `"code":"METHOD_NOT_ALLOWED"`)
+}
@@ -0,0 +1,118 @@
+package http
+
+import (
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/dezzerlol/avitotech-test-2023/internal/apierror"
+	"github.com/dezzerlol/avitotech-test-2023/internal/auth"
+	"github.com/dezzerlol/avitotech-test-2023/internal/ratelimit"
+	"github.com/go-chi/chi/v5"
+)
+
+// rateLimitIP ограничивает число запросов с одного адреса к маршруту. Выполняется до authenticate,
+// поэтому ограничивает и запросы без ключа или с неверным ключом или токеном.
+func (s *Server) rateLimitIP(next http.Handler) http.Handler {
+	return s.limitRoute(next, func(r *http.Request, pattern string) (ratelimit.Result, bool, error) {
+		return s.limiter.AllowIP(r.Context(), clientIP(r), r.Method, pattern)
+	})
+}
+
+// rateLimit ограничивает число запросов клиента к маршруту. Клиент определяется по API ключу или токену,
+// поэтому middleware выполняется после authenticate.
+func (s *Server) rateLimit(next http.Handler) http.Handler {
+	return s.limitRoute(next, func(r *http.Request, pattern string) (ratelimit.Result, bool, error) {
+		client, ok := rateLimitClient(r)
+
+		if !ok {
+			return ratelimit.Result{}, false, nil
+		}
+
+		return s.limiter.Allow(r.Context(), client, r.Method, pattern)
+	})
+}
+
+// limitRoute списывает токен через allow и отклоняет запрос, если корзина пуста.
+// Лимиты задаются для шаблона маршрута и одинаково действуют с префиксами /api/v1 и /ns/{namespace} и без них.
+// Если хранилище лимитов недоступно, запросы пропускаются.
+func (s *Server) limitRoute(next http.Handler, allow func(r *http.Request, pattern string) (ratelimit.Result, bool, error)) http.Handler {
+	if s.limiter == nil {
+		return next
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rctx := chi.RouteContext(r.Context())
+
+		// RoutePath внутри /api/v1 содержит только часть пути, поэтому шаблон ищется по полному пути
+		path := r.URL.Path
+
+		if len(path) > 1 {
+			path = strings.TrimSuffix(path, "/")
+		}
+
+		// Шаблон маршрута известен только после роутинга, поэтому ищем его заранее
+		tctx := chi.NewRouteContext()
+
+		if !rctx.Routes.Match(tctx, r.Method, path) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		pattern := strings.TrimPrefix(tctx.RoutePattern(), apiPrefix)
+		pattern = strings.TrimPrefix(pattern, "/ns/{namespace}")
+
+		res, ok, err := allow(r, pattern)
+
+		if err != nil {
+			s.logger.Warnw("rate limit store error", "err", err)
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		if !ok {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		w.Header().Set("X-RateLimit-Limit", strconv.Itoa(res.Limit))
+		w.Header().Set("X-RateLimit-Remaining", strconv.Itoa(res.Remaining))
+		w.Header().Set("X-RateLimit-Reset", strconv.Itoa(ceilSeconds(res.Reset)))
+
+		if !res.Allowed {
+			w.Header().Set("Retry-After", strconv.Itoa(max(ceilSeconds(res.RetryAfter), 1)))
+			apierror.Write(w, r, apierror.New(http.StatusTooManyRequests, apierror.CodeRateLimitExceeded, "rate limit exceeded"))
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// rateLimitClient возвращает клиента, прошедшего аутентификацию.
+func rateLimitClient(r *http.Request) (string, bool) {
+	identity, ok := auth.FromContext(r.Context())
+
+	if !ok {
+		return "", false
+	}
+
+	return ratelimit.ClientKey(identity), true
+}
+
+func clientIP(r *http.Request) string {
+	ip, _, err := net.SplitHostPort(r.RemoteAddr)
+
+	if err != nil {
+		return r.RemoteAddr
+	}
+
+	return ip
+}
+
+func ceilSeconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
@@ -0,0 +1,134 @@
+package http
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/dezzerlol/avitotech-test-2023/internal/auth"
+	"github.com/dezzerlol/avitotech-test-2023/internal/ratelimit"
+	"github.com/dezzerlol/avitotech-test-2023/internal/repo"
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func Test_RateLimit(t *testing.T) {
+	rules, err := ratelimit.ParseRules("off", "POST /segment/user=2/m")
+	require.NoError(t, err)
+
+	s := &Server{
+		logger:  zap.NewNop().Sugar(),
+		limiter: ratelimit.NewLimiter(ratelimit.NewMemoryStore(), rules),
+	}
+
+	routes := func(r chi.Router) {
+		r.Post("/segment/user", func(w http.ResponseWriter, r *http.Request) {})
+		r.Get("/segment/user/{userId}", func(w http.ResponseWriter, r *http.Request) {})
+	}
+
+	api := func(r chi.Router) {
+		r.Use(s.rateLimit)
+		r.Group(routes)
+		r.Route("/ns/{namespace}", routes)
+	}
+
+	r := chi.NewRouter()
+	r.Route("/api/v1", api)
+	r.Group(api)
+
+	do := func(method, path string, keyID int64) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, nil)
+		req = req.WithContext(auth.WithIdentity(req.Context(), auth.Identity{Method: auth.MethodAPIKey, KeyID: keyID}))
+
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+
+		return w
+	}
+
+	w := do(http.MethodPost, "/segment/user", 1)
+	require.Equal(t, http.StatusOK, w.Code)
+	require.Equal(t, "2", w.Header().Get("X-RateLimit-Limit"))
+	require.Equal(t, "1", w.Header().Get("X-RateLimit-Remaining"))
+
+	// Маршрут с префиксом пространства имен использует ту же корзину
+	w = do(http.MethodPost, "/ns/payments/segment/user", 1)
+	require.Equal(t, http.StatusOK, w.Code)
+	require.Equal(t, "0", w.Header().Get("X-RateLimit-Remaining"))
+
+	// И маршрут с префиксом версии
+	w = do(http.MethodPost, "/api/v1/ns/payments/segment/user", 1)
+	require.Equal(t, http.StatusTooManyRequests, w.Code)
+	require.Equal(t, "30", w.Header().Get("Retry-After"))
+
+	w = do(http.MethodPost, "/segment/user", 1)
+	require.Equal(t, http.StatusTooManyRequests, w.Code)
+
+	// Лимиты считаются отдельно для каждого ключа
+	w = do(http.MethodPost, "/segment/user", 2)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	// Маршруты без лимита не ограничиваются
+	w = do(http.MethodGet, "/segment/user/1", 1)
+	require.Equal(t, http.StatusOK, w.Code)
+	require.Empty(t, w.Header().Get("X-RateLimit-Limit"))
+}
+
+func Test_RateLimitIP(t *testing.T) {
+	rules, err := ratelimit.ParseRules("off", "")
+	require.NoError(t, err)
+
+	rules.IP = ratelimit.Limit{Count: 2, Period: time.Minute}
+
+	s := &Server{
+		logger:  zap.NewNop().Sugar(),
+		limiter: ratelimit.NewLimiter(ratelimit.NewMemoryStore(), rules),
+	}
+
+	var lookups int
+
+	authenticator := authenticatorFunc(func(ctx context.Context, key string) (auth.Identity, error) {
+		lookups++
+		return auth.Identity{}, repo.ErrAPIKeyNotFound
+	})
+
+	r := chi.NewRouter()
+	r.Use(s.rateLimitIP)
+	r.Use(s.authenticate(authenticator))
+	r.Use(s.rateLimit)
+	r.Get("/segment/user/{userId}", func(w http.ResponseWriter, r *http.Request) {})
+
+	do := func(key, remoteAddr string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/segment/user/1", nil)
+		req.RemoteAddr = remoteAddr
+
+		if key != "" {
+			req.Header.Set("X-Api-Key", key)
+		}
+
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+
+		return w
+	}
+
+	// Перебор ключей с одного адреса
+	require.Equal(t, http.StatusUnauthorized, do("seg_guess_1", "10.0.0.1:1234").Code)
+	require.Equal(t, http.StatusUnauthorized, do("seg_guess_2", "10.0.0.1:1235").Code)
+
+	w := do("seg_guess_3", "10.0.0.1:1236")
+	require.Equal(t, http.StatusTooManyRequests, w.Code)
+	require.Equal(t, "30", w.Header().Get("Retry-After"))
+
+	// Отклоненный запрос не доходит до проверки ключа
+	require.Equal(t, 2, lookups)
+
+	// Запросы без ключа тоже ограничиваются
+	require.Equal(t, http.StatusTooManyRequests, do("", "10.0.0.1:1237").Code)
+
+	// Лимит считается отдельно для каждого адреса
+	require.Equal(t, http.StatusUnauthorized, do("", "10.0.0.2:1234").Code)
+}
@@ -1,8 +1,17 @@
 package http
 
 import (
+	"github.com/dezzerlol/avitotech-test-2023/cfg"
+	"github.com/dezzerlol/avitotech-test-2023/internal/auth"
+	"github.com/dezzerlol/avitotech-test-2023/internal/handlers/analytics"
+	"github.com/dezzerlol/avitotech-test-2023/internal/handlers/apikey"
+	"github.com/dezzerlol/avitotech-test-2023/internal/handlers/events"
+	"github.com/dezzerlol/avitotech-test-2023/internal/handlers/layer"
+	"github.com/dezzerlol/avitotech-test-2023/internal/handlers/namespace"
 	"github.com/dezzerlol/avitotech-test-2023/internal/handlers/segment"
 	"github.com/dezzerlol/avitotech-test-2023/internal/handlers/user"
+	"github.com/dezzerlol/avitotech-test-2023/internal/handlers/webhook"
+	"github.com/dezzerlol/avitotech-test-2023/internal/metrics"
 	"github.com/dezzerlol/avitotech-test-2023/internal/repo"
 	"github.com/dezzerlol/avitotech-test-2023/internal/service"
 
@@ -16,37 +25,164 @@ func (s *Server) setHTTPRouter() *chi.Mux {
 	r := chi.NewRouter()
 
 	r.Use(middleware.StripSlashes)
+	r.Use(requestID)
+	r.Use(traceRequest)
+	r.Use(s.accessLog)
 	r.Use(middleware.Recoverer)
 
 	r.Get("/swagger/*", httpSwagger.Handler(
 		httpSwagger.URL("http://localhost:8080/swagger/doc.json"), //The url pointing to API definition
 	))
 
+	// Метрики для Prometheus, доступны без ключа
+	r.Handle("/metrics", metrics.Handler())
+
+	// Проверки работы и готовности сервиса, доступны без ключа
+	r.Get("/healthz", s.healthz)
+	r.Get("/readyz", s.readyz)
+
 	segmentRepo := repo.NewSegmentRepo(s.db)
 	userRepo := repo.NewUserRepo(s.db)
+	analyticsRepo := repo.NewAnalyticsRepo(s.db)
+	apiKeyRepo := repo.NewAPIKeyRepo(s.db)
+	namespaceRepo := repo.NewNamespaceRepo(s.db)
+	webhookRepo := repo.NewWebhookRepo(s.db)
+	layerRepo := repo.NewLayerRepo(s.db)
 
-	segmentService := service.NewSegmentSvc(s.worker, segmentRepo, userRepo)
-	userService := service.NewUserSvc(userRepo)
+	segmentService := service.NewSegmentSvc(s.worker, segmentRepo, userRepo, s.cache)
+	userService := service.NewUserSvc(userRepo, namespaceRepo, s.cache)
+	analyticsService := service.NewAnalyticsSvc(analyticsRepo)
+	apiKeyService := service.NewAPIKeySvc(apiKeyRepo, cfg.Get().ADMIN_API_KEY)
+	namespaceService := service.NewNamespaceSvc(namespaceRepo)
+	webhookService := service.NewWebhookSvc(webhookRepo, cfg.Get().WEBHOOK_TIMEOUT, cfg.Get().WEBHOOK_ALLOW_PRIVATE)
+	eventsService := service.NewEventsSvc(s.events, segmentRepo)
+	layerService := service.NewLayerSvc(layerRepo)
 
 	segmentHandler := segment.NewHandler(s.logger, segmentService)
 	userHandler := user.NewHandler(s.logger, userService)
+	analyticsHandler := analytics.NewHandler(s.logger, analyticsService)
+	apiKeyHandler := apikey.NewHandler(s.logger, apiKeyService)
+	namespaceHandler := namespace.NewHandler(s.logger, namespaceService)
+	webhookHandler := webhook.NewHandler(s.logger, webhookService)
+	eventsHandler := events.NewHandler(s.logger, eventsService)
+	layerHandler := layer.NewHandler(s.logger, layerService)
+
+	// Методы сегментов работают в пространстве имен из префикса /ns/{namespace} или заголовка X-Namespace
+	namespaced := func(r chi.Router) {
+		r.Use(namespaceContext)
+
+		// Создание пользователя
+		r.With(requireScope(auth.ScopeUsersWrite)).Post("/user", userHandler.Create)
+		// Создание пользователя с атрибутами или замена атрибутов
+		r.With(requireScope(auth.ScopeUsersWrite)).Put("/user/{userId}", userHandler.Upsert)
+		// Изменение атрибутов пользователя
+		r.With(requireScope(auth.ScopeUsersWrite)).Patch("/user/{userId}", userHandler.Patch)
+		// Получение пользователя с атрибутами
+		r.With(requireScope(auth.ScopeSegmentsRead)).Get("/user/{userId}", userHandler.Get)
+
+		// Создание сегмента
+		r.With(requireScope(auth.ScopeSegmentsWrite)).Post("/segment", segmentHandler.Create)
+		// Удаление сегмента
+		r.With(requireScope(auth.ScopeSegmentsWrite)).Delete("/segment", segmentHandler.Delete)
+		// Проверка правила динамического сегмента
+		r.With(requireScope(auth.ScopeSegmentsRead)).Post("/segment/rules/validate", segmentHandler.ValidateRule)
+		// Варианты эксперимента с числом участников
+		r.With(requireScope(auth.ScopeSegmentsRead)).Get("/segment/{slug}/variants", segmentHandler.GetVariants)
+		// Изменение весов вариантов без перераспределения участников
+		r.With(requireScope(auth.ScopeSegmentsWrite)).Put("/segment/{slug}/variants", segmentHandler.UpdateVariants)
+
+		// Создание слоя взаимоисключающих сегментов
+		r.With(requireScope(auth.ScopeSegmentsWrite)).Post("/layers", layerHandler.Create)
+		// Список слоев
+		r.With(requireScope(auth.ScopeSegmentsRead)).Get("/layers", layerHandler.List)
+
+		// Добавление/удаление сегментов у пользователя
+		r.With(requireScope(auth.ScopeUsersWrite)).Post("/segment/user", segmentHandler.UpdateUserSegments)
+		// Получение всех сегментов пользователя
+		r.With(requireScope(auth.ScopeSegmentsRead)).Get("/segment/user/{userId}", segmentHandler.GetSegmentsForUser)
+		// Получение сегментов нескольких пользователей
+		r.With(requireScope(auth.ScopeSegmentsRead)).Post("/segment/users/lookup", segmentHandler.LookupUserSegments)
+		// Ручная смена варианта эксперимента у пользователя
+		r.With(requireScope(auth.ScopeUsersWrite)).Put("/segment/{slug}/user/{userId}/variant", segmentHandler.SetUserVariant)
+		// Получение ссылки на отчет по сегментам пользователя
+		r.With(requireScope(auth.ScopeReportsRead)).Get("/segment/history/{userId}", segmentHandler.GetUserHistory)
+		// Потоковая выгрузка истории сегментов пользователя в csv, xlsx или json
+		r.With(requireScope(auth.ScopeReportsRead)).Get("/segment/history/{userId}/export.{format}", segmentHandler.ExportUserHistory)
+		// Получение истории сегмента с агрегатами по дням или часам
+		r.With(requireScope(auth.ScopeSegmentsRead)).Get("/segment/{slug}/history", segmentHandler.GetSegmentHistory)
+		// Выгрузка истории сегмента в csv, xlsx или json
+		r.With(requireScope(auth.ScopeReportsRead)).Get("/segment/{slug}/history/export.{format}", segmentHandler.ExportSegmentHistory)
+		// Скачивание отчета пользователя по сегментам
+		r.With(requireScope(auth.ScopeReportsRead)).Get("/segment/reports/{fileName}", segmentHandler.DownloadReport)
+
+		// Размер сегментов по дням
+		r.With(requireScope(auth.ScopeSegmentsRead)).Get("/analytics/membership", analyticsHandler.GetMembership)
+		// Выгрузка размера сегментов по дням в csv, xlsx или json
+		r.With(requireScope(auth.ScopeReportsRead)).Get("/analytics/membership/export.{format}", analyticsHandler.ExportMembership)
+
+		// Поток изменений членства в сегментах (Server-Sent Events)
+		r.With(requireScope(auth.ScopeSegmentsRead)).Get("/events", eventsHandler.Stream)
+		// Лента изменений членства по курсору
+		r.With(requireScope(auth.ScopeSegmentsRead)).Get("/changes", eventsHandler.Changes)
+
+		// Вебхуки на изменение членства в сегментах
+		r.Route("/webhooks", func(r chi.Router) {
+			r.Use(requireScope(auth.ScopeWebhooksAdmin))
+
+			// Регистрация вебхука
+			r.Post("/", webhookHandler.Create)
+			// Список вебхуков
+			r.Get("/", webhookHandler.List)
+			// Удаление вебхука
+			r.Delete("/{id}", webhookHandler.Delete)
+			// Отправка тестового события
+			r.Post("/{id}/test", webhookHandler.SendTest)
+			// Журнал доставки
+			r.Get("/{id}/deliveries", webhookHandler.ListDeliveries)
+		})
+	}
+
+	// Все методы, кроме swagger, метрик и проверок, доступны только с API ключом
+	api := func(r chi.Router) {
+		r.Use(s.rateLimitIP)
+		r.Use(s.authenticate(apiKeyService))
+		r.Use(s.rateLimit)
+		r.Use(auditContext)
+
+		r.Group(namespaced)
+		r.Route("/ns/{namespace}", namespaced)
+
+		// Управление ключами и пространствами имен доступно только ключам без ограничения по пространствам имен
+		r.Route("/admin", func(r chi.Router) {
+			r.Use(requireScope(auth.ScopeKeysAdmin))
+			r.Use(requireAllNamespaces)
+
+			// Выпуск API ключа
+			r.Post("/keys", apiKeyHandler.Issue)
+			// Список API ключей
+			r.Get("/keys", apiKeyHandler.List)
+			// Ротация API ключа
+			r.Post("/keys/{id}/rotate", apiKeyHandler.Rotate)
+			// Отзыв API ключа
+			r.Delete("/keys/{id}", apiKeyHandler.Revoke)
+
+			// Создание пространства имен
+			r.Post("/namespaces", namespaceHandler.Create)
+			// Список пространств имен
+			r.Get("/namespaces", namespaceHandler.List)
+		})
+	}
+
+	r.NotFound(notFound)
+	r.MethodNotAllowed(methodNotAllowed)
+
+	r.Route("/api/v1", api)
 
-	// Создание пользователя
-	r.Post("/user", userHandler.Create)
-
-	// Создание сегмента
-	r.Post("/segment", segmentHandler.Create)
-	// Удаление сегмента
-	r.Delete("/segment", segmentHandler.Delete)
-
-	// Добавление/удаление сегментов у пользователя
-	r.Post("/segment/user", segmentHandler.UpdateUserSegments)
-	// Получение всех сегментов пользователя
-	r.Get("/segment/user/{userId}", segmentHandler.GetSegmentsForUser)
-	// Получение ссылки на отчет по сегментам пользователя
-	r.Get("/segment/history/{userId}", segmentHandler.GetUserHistory)
-	// Скачивание отчета пользователя по сегментам
-	r.Get("/segment/reports/{fileName}", segmentHandler.DownloadReport)
+	// Пути без версии оставлены для старых клиентов и будут удалены
+	r.Group(func(r chi.Router) {
+		r.Use(deprecatedRoute)
+		api(r)
+	})
 
 	return r
 }
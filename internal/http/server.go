@@ -10,6 +10,12 @@ import (
 	"syscall"
 	"time"
 
+	"github.com/dezzerlol/avitotech-test-2023/cfg"
+	"github.com/dezzerlol/avitotech-test-2023/internal/auth"
+	"github.com/dezzerlol/avitotech-test-2023/internal/cache"
+	"github.com/dezzerlol/avitotech-test-2023/internal/events"
+	"github.com/dezzerlol/avitotech-test-2023/internal/health"
+	"github.com/dezzerlol/avitotech-test-2023/internal/ratelimit"
 	"github.com/dezzerlol/avitotech-test-2023/internal/worker"
 	"github.com/jackc/pgx/v5/pgxpool"
 	"go.uber.org/zap"
@@ -19,16 +25,44 @@ type Server struct {
 	logger *zap.SugaredLogger
 	db     *pgxpool.Pool
 	worker worker.TaskDistributor
+	// nil, если JWT не настроен
+	jwtVerifier *auth.JWTVerifier
+	// nil, если ограничение запросов выключено
+	limiter *ratelimit.Limiter
+	// Кеш сегментов пользователя, общий с gRPC сервером и воркером. nil, если выключен
+	cache *cache.UserSegments
+	// Подписки на изменения членства для потока событий. nil в тестах без LISTEN
+	events *events.Hub
+	health *health.Checker
 }
 
-func New(logger *zap.SugaredLogger, db *pgxpool.Pool, worker worker.TaskDistributor) *Server {
+func New(
+	logger *zap.SugaredLogger,
+	db *pgxpool.Pool,
+	worker worker.TaskDistributor,
+	jwtVerifier *auth.JWTVerifier,
+	limiter *ratelimit.Limiter,
+	cache *cache.UserSegments,
+	events *events.Hub,
+	health *health.Checker,
+) *Server {
 	return &Server{
-		logger: logger,
-		db:     db,
-		worker: worker,
+		logger:      logger,
+		db:          db,
+		worker:      worker,
+		jwtVerifier: jwtVerifier,
+		limiter:     limiter,
+		cache:       cache,
+		events:      events,
+		health:      health,
 	}
 }
 
+// Handler возвращает роутер со всеми маршрутами API.
+func (s *Server) Handler() http.Handler {
+	return s.setHTTPRouter()
+}
+
 func (s Server) Run(host, port string) {
 	router := s.setHTTPRouter()
 
@@ -42,6 +76,9 @@ func (s Server) Run(host, port string) {
 		WriteTimeout: 30 * time.Second,
 	}
 
+	// Потоки событий открыты, пока клиент не отключится, поэтому закрываются при остановке сами
+	srv.RegisterOnShutdown(s.events.Close)
+
 	shutdownError := make(chan error)
 
 	// Graceful shutdown
@@ -55,6 +92,11 @@ func (s Server) Run(host, port string) {
 
 		s.logger.Infoln("Shutting down server...")
 
+		// Сначала перестаем быть готовыми и ждем, пока балансировщик уберет сервис,
+		// и только потом перестаем принимать соединения
+		s.health.ShutDown()
+		time.Sleep(cfg.Get().SHUTDOWN_DELAY)
+
 		ctx, cancel := context.WithTimeout(context.Background(), 20*time.Second)
 		defer cancel()
 
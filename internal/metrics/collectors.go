@@ -0,0 +1,111 @@
+package metrics
+
+import (
+	"github.com/hibiken/asynq"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/prometheus/client_golang/prometheus"
+	"go.uber.org/zap"
+)
+
+// poolCollector снимает статистику пула соединений с базой в момент сбора метрик.
+type poolCollector struct {
+	pool *pgxpool.Pool
+
+	acquiredConns        *prometheus.Desc
+	idleConns            *prometheus.Desc
+	totalConns           *prometheus.Desc
+	maxConns             *prometheus.Desc
+	acquireCount         *prometheus.Desc
+	acquireDuration      *prometheus.Desc
+	emptyAcquireCount    *prometheus.Desc
+	canceledAcquireCount *prometheus.Desc
+}
+
+func NewPoolCollector(pool *pgxpool.Pool) prometheus.Collector {
+	return &poolCollector{
+		pool:                 pool,
+		acquiredConns:        prometheus.NewDesc("pgxpool_acquired_conns", "Количество занятых соединений.", nil, nil),
+		idleConns:            prometheus.NewDesc("pgxpool_idle_conns", "Количество свободных соединений.", nil, nil),
+		totalConns:           prometheus.NewDesc("pgxpool_total_conns", "Количество открытых соединений.", nil, nil),
+		maxConns:             prometheus.NewDesc("pgxpool_max_conns", "Максимальный размер пула.", nil, nil),
+		acquireCount:         prometheus.NewDesc("pgxpool_acquire_total", "Количество успешно полученных из пула соединений.", nil, nil),
+		acquireDuration:      prometheus.NewDesc("pgxpool_acquire_duration_seconds_total", "Суммарное время ожидания соединений из пула.", nil, nil),
+		emptyAcquireCount:    prometheus.NewDesc("pgxpool_empty_acquire_total", "Количество ожиданий соединения при пустом пуле.", nil, nil),
+		canceledAcquireCount: prometheus.NewDesc("pgxpool_canceled_acquire_total", "Количество ожиданий соединения, отмененных контекстом.", nil, nil),
+	}
+}
+
+func (c *poolCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.acquiredConns
+	ch <- c.idleConns
+	ch <- c.totalConns
+	ch <- c.maxConns
+	ch <- c.acquireCount
+	ch <- c.acquireDuration
+	ch <- c.emptyAcquireCount
+	ch <- c.canceledAcquireCount
+}
+
+func (c *poolCollector) Collect(ch chan<- prometheus.Metric) {
+	stat := c.pool.Stat()
+
+	ch <- prometheus.MustNewConstMetric(c.acquiredConns, prometheus.GaugeValue, float64(stat.AcquiredConns()))
+	ch <- prometheus.MustNewConstMetric(c.idleConns, prometheus.GaugeValue, float64(stat.IdleConns()))
+	ch <- prometheus.MustNewConstMetric(c.totalConns, prometheus.GaugeValue, float64(stat.TotalConns()))
+	ch <- prometheus.MustNewConstMetric(c.maxConns, prometheus.GaugeValue, float64(stat.MaxConns()))
+	ch <- prometheus.MustNewConstMetric(c.acquireCount, prometheus.CounterValue, float64(stat.AcquireCount()))
+	ch <- prometheus.MustNewConstMetric(c.acquireDuration, prometheus.CounterValue, stat.AcquireDuration().Seconds())
+	ch <- prometheus.MustNewConstMetric(c.emptyAcquireCount, prometheus.CounterValue, float64(stat.EmptyAcquireCount()))
+	ch <- prometheus.MustNewConstMetric(c.canceledAcquireCount, prometheus.CounterValue, float64(stat.CanceledAcquireCount()))
+}
+
+// queueCollector запрашивает у Redis размеры очередей asynq в момент сбора метрик.
+type queueCollector struct {
+	inspector *asynq.Inspector
+	logger    *zap.SugaredLogger
+
+	size *prometheus.Desc
+}
+
+func NewQueueCollector(inspector *asynq.Inspector, logger *zap.SugaredLogger) prometheus.Collector {
+	return &queueCollector{
+		inspector: inspector,
+		logger:    logger,
+		size:      prometheus.NewDesc("asynq_queue_tasks", "Количество задач в очереди asynq по состояниям.", []string{"queue", "state"}, nil),
+	}
+}
+
+func (c *queueCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.size
+}
+
+func (c *queueCollector) Collect(ch chan<- prometheus.Metric) {
+	queues, err := c.inspector.Queues()
+
+	// Недоступный Redis не должен ломать отдачу остальных метрик
+	if err != nil {
+		c.logger.Warnw("error listing asynq queues", "err", err)
+		return
+	}
+
+	for _, queue := range queues {
+		info, err := c.inspector.GetQueueInfo(queue)
+
+		if err != nil {
+			c.logger.Warnw("error getting asynq queue info", "queue", queue, "err", err)
+			continue
+		}
+
+		states := map[string]int{
+			"pending":   info.Pending,
+			"active":    info.Active,
+			"scheduled": info.Scheduled,
+			"retry":     info.Retry,
+			"archived":  info.Archived,
+		}
+
+		for state, count := range states {
+			ch <- prometheus.MustNewConstMetric(c.size, prometheus.GaugeValue, float64(count), queue, state)
+		}
+	}
+}
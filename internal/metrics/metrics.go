@@ -0,0 +1,142 @@
+package metrics
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// Операции изменения членства в сегменте
+const (
+	OperationAdd    = "add"
+	OperationDelete = "delete"
+	// Смена варианта эксперимента участника сегмента
+	OperationVariant = "variant"
+)
+
+// Результат обработки задачи воркером
+const (
+	OutcomeSuccess = "success"
+	OutcomeFailure = "failure"
+)
+
+// Уровень кеша сегментов пользователя
+const (
+	CacheTierMemory = "memory"
+	CacheTierRedis  = "redis"
+)
+
+// Результат обращения к кешу
+const (
+	CacheHit   = "hit"
+	CacheMiss  = "miss"
+	CacheError = "error"
+)
+
+// Маршрут запросов, не попавших ни в один маршрут chi.
+// Путь запроса в метки не попадает, чтобы не раздувать число временных рядов.
+const UnmatchedRoute = "unmatched"
+
+var (
+	httpRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "http_requests_total",
+		Help: "Количество HTTP запросов по маршрутам chi.",
+	}, []string{"method", "route", "status"})
+
+	httpRequestDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "http_request_duration_seconds",
+		Help:    "Время обработки HTTP запросов по маршрутам chi.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"method", "route"})
+
+	membershipChanges = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "segment_membership_changes_total",
+		Help: "Количество добавлений пользователей в сегменты и удалений из них.",
+	}, []string{"namespace", "segment", "operation", "source"})
+
+	workerTasks = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "worker_tasks_total",
+		Help: "Количество обработанных воркером задач по типам и результату.",
+	}, []string{"task_type", "outcome"})
+
+	webhookDeliveries = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "webhook_deliveries_total",
+		Help: "Количество попыток доставки вебхуков по результату.",
+	}, []string{"event_type", "outcome"})
+
+	cacheRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "segment_cache_requests_total",
+		Help: "Количество обращений к кешу сегментов пользователя по уровням и результату.",
+	}, []string{"tier", "result"})
+
+	cacheInvalidations = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "segment_cache_invalidations_total",
+		Help: "Количество сбросов кеша сегментов пользователя по причинам.",
+	}, []string{"reason"})
+
+	eventSubscribers = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "segment_event_subscribers",
+		Help: "Количество открытых потоков событий изменения членства в сегментах.",
+	})
+
+	workerTaskDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "worker_task_duration_seconds",
+		Help:    "Время обработки задач воркером по типам.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"task_type"})
+)
+
+// Handler отдает метрики в формате Prometheus.
+func Handler() http.Handler {
+	return promhttp.Handler()
+}
+
+func ObserveHTTPRequest(method, route string, status int, duration time.Duration) {
+	httpRequests.WithLabelValues(method, route, strconv.Itoa(status)).Inc()
+	httpRequestDuration.WithLabelValues(method, route).Observe(duration.Seconds())
+}
+
+func AddMembershipChanges(namespace, segment, operation, source string, count int) {
+	if count <= 0 {
+		return
+	}
+
+	membershipChanges.WithLabelValues(namespace, segment, operation, source).Add(float64(count))
+}
+
+func ObserveTask(taskType string, err error, duration time.Duration) {
+	outcome := OutcomeSuccess
+
+	if err != nil {
+		outcome = OutcomeFailure
+	}
+
+	workerTasks.WithLabelValues(taskType, outcome).Inc()
+	workerTaskDuration.WithLabelValues(taskType).Observe(duration.Seconds())
+}
+
+func ObserveCacheRequest(tier, result string) {
+	cacheRequests.WithLabelValues(tier, result).Inc()
+}
+
+func AddCacheInvalidation(reason string) {
+	cacheInvalidations.WithLabelValues(reason).Inc()
+}
+
+func ObserveWebhookDelivery(eventType string, err error) {
+	outcome := OutcomeSuccess
+
+	if err != nil {
+		outcome = OutcomeFailure
+	}
+
+	webhookDeliveries.WithLabelValues(eventType, outcome).Inc()
+}
+
+func SetEventSubscribers(count int) {
+	eventSubscribers.Set(float64(count))
+}
@@ -0,0 +1,35 @@
+package metrics
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAddMembershipChanges(t *testing.T) {
+	AddMembershipChanges("default", "AVITO_TEST", OperationAdd, "api", 2)
+	AddMembershipChanges("default", "AVITO_TEST", OperationAdd, "api", 0)
+	AddMembershipChanges("default", "AVITO_TEST", OperationDelete, "ttl", 1)
+
+	require.Equal(t, 2.0, testutil.ToFloat64(membershipChanges.WithLabelValues("default", "AVITO_TEST", OperationAdd, "api")))
+	require.Equal(t, 1.0, testutil.ToFloat64(membershipChanges.WithLabelValues("default", "AVITO_TEST", OperationDelete, "ttl")))
+}
+
+func TestObserveTask(t *testing.T) {
+	ObserveTask("segment:test", nil, time.Second)
+	ObserveTask("segment:test", errors.New("failed"), time.Second)
+	ObserveTask("segment:test", nil, time.Second)
+
+	require.Equal(t, 2.0, testutil.ToFloat64(workerTasks.WithLabelValues("segment:test", OutcomeSuccess)))
+	require.Equal(t, 1.0, testutil.ToFloat64(workerTasks.WithLabelValues("segment:test", OutcomeFailure)))
+}
+
+func TestObserveHTTPRequest(t *testing.T) {
+	ObserveHTTPRequest("GET", "/segment/user/{userId}", 200, 10*time.Millisecond)
+
+	require.Equal(t, 1.0, testutil.ToFloat64(httpRequests.WithLabelValues("GET", "/segment/user/{userId}", "200")))
+	require.Equal(t, 1, testutil.CollectAndCount(httpRequestDuration))
+}
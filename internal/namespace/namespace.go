@@ -0,0 +1,35 @@
+package namespace
+
+import (
+	"context"
+	"regexp"
+)
+
+// Пространство имен, в котором работают запросы без префикса /ns/{namespace} и заголовка X-Namespace.
+// В него же перенесены данные, созданные до появления пространств имен.
+const Default = "default"
+
+// Pattern - допустимый slug пространства имен
+const Pattern = `^[a-z0-9][a-z0-9_-]{0,63}$`
+
+var slugRe = regexp.MustCompile(Pattern)
+
+// Valid проверяет slug пространства имен: строчные латинские буквы, цифры, "_" и "-", до 64 символов.
+func Valid(ns string) bool {
+	return slugRe.MatchString(ns)
+}
+
+type namespaceKey struct{}
+
+func WithNamespace(ctx context.Context, ns string) context.Context {
+	return context.WithValue(ctx, namespaceKey{}, ns)
+}
+
+// FromContext возвращает пространство имен запроса или Default, если оно не задано.
+func FromContext(ctx context.Context) string {
+	if ns, ok := ctx.Value(namespaceKey{}).(string); ok && ns != "" {
+		return ns
+	}
+
+	return Default
+}
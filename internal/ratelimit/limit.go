@@ -0,0 +1,227 @@
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/dezzerlol/avitotech-test-2023/internal/auth"
+)
+
+// Limit задает емкость корзины токенов: Count запросов за Period.
+// Корзина пополняется равномерно, поэтому после простоя клиент может сделать до Count запросов подряд.
+// Count равный нулю означает отсутствие ограничения.
+type Limit struct {
+	Count  int
+	Period time.Duration
+}
+
+func (l Limit) Unlimited() bool {
+	return l.Count <= 0
+}
+
+// rate возвращает скорость пополнения корзины в токенах в секунду.
+func (l Limit) rate() float64 {
+	return float64(l.Count) / l.Period.Seconds()
+}
+
+func (l Limit) String() string {
+	if l.Unlimited() {
+		return "off"
+	}
+
+	return fmt.Sprintf("%d/%s", l.Count, l.Period)
+}
+
+// Result описывает решение по запросу и состояние корзины после него.
+type Result struct {
+	Allowed   bool
+	Limit     int
+	Remaining int
+	// Через сколько в корзине появится токен, заполняется для отклоненных запросов
+	RetryAfter time.Duration
+	// Через сколько корзина заполнится полностью
+	Reset time.Duration
+}
+
+// Store хранит корзины клиентов и списывает из них токены.
+type Store interface {
+	Take(ctx context.Context, key string, limit Limit) (Result, error)
+}
+
+// result считает заголовки по числу токенов, оставшихся в корзине.
+func result(allowed bool, tokens float64, limit Limit) Result {
+	rate := limit.rate()
+
+	res := Result{
+		Allowed:   allowed,
+		Limit:     limit.Count,
+		Remaining: int(tokens),
+		Reset:     time.Duration((float64(limit.Count) - tokens) / rate * float64(time.Second)),
+	}
+
+	if !allowed {
+		res.RetryAfter = time.Duration((1 - tokens) / rate * float64(time.Second))
+	}
+
+	return res
+}
+
+var periods = map[string]time.Duration{
+	"s": time.Second,
+	"m": time.Minute,
+	"h": time.Hour,
+}
+
+// ParseLimit разбирает лимит в формате "100/m" (s, m или h). "off" или "0" отключают ограничение.
+func ParseLimit(s string) (Limit, error) {
+	s = strings.TrimSpace(s)
+
+	if s == "off" || s == "0" {
+		return Limit{}, nil
+	}
+
+	count, period, ok := strings.Cut(s, "/")
+
+	if !ok {
+		return Limit{}, fmt.Errorf("invalid limit %q, expected count/period", s)
+	}
+
+	n, err := strconv.Atoi(strings.TrimSpace(count))
+
+	if err != nil || n < 0 {
+		return Limit{}, fmt.Errorf("invalid limit count %q", count)
+	}
+
+	d, ok := periods[strings.TrimSpace(period)]
+
+	if !ok {
+		return Limit{}, fmt.Errorf("invalid limit period %q, expected s, m or h", period)
+	}
+
+	return Limit{Count: n, Period: d}, nil
+}
+
+// Rules хранит лимит по умолчанию и лимиты отдельных маршрутов.
+type Rules struct {
+	Default Limit
+	// Ключ - метод и шаблон маршрута chi, например "POST /segment/user"
+	Routes map[string]Limit
+	// Лимит адреса на маршрут, проверяется до аутентификации
+	IP Limit
+}
+
+// ParseRules разбирает лимиты маршрутов в формате "POST /segment/user=10/s; GET /segment/user/{userId}=50/s".
+func ParseRules(defaultLimit, routes string) (Rules, error) {
+	rules := Rules{Routes: map[string]Limit{}}
+
+	var err error
+
+	rules.Default, err = ParseLimit(defaultLimit)
+
+	if err != nil {
+		return rules, err
+	}
+
+	for _, rule := range strings.Split(routes, ";") {
+		if strings.TrimSpace(rule) == "" {
+			continue
+		}
+
+		route, value, ok := strings.Cut(rule, "=")
+
+		if !ok {
+			return rules, fmt.Errorf("invalid route limit %q, expected METHOD /pattern=limit", rule)
+		}
+
+		method, pattern, ok := strings.Cut(strings.TrimSpace(route), " ")
+
+		if !ok || !strings.HasPrefix(strings.TrimSpace(pattern), "/") {
+			return rules, fmt.Errorf("invalid route %q, expected METHOD /pattern", route)
+		}
+
+		limit, err := ParseLimit(value)
+
+		if err != nil {
+			return rules, err
+		}
+
+		rules.Routes[routeKey(method, pattern)] = limit
+	}
+
+	return rules, nil
+}
+
+// For возвращает лимит маршрута или лимит по умолчанию.
+func (r Rules) For(method, pattern string) Limit {
+	if limit, ok := r.Routes[routeKey(method, pattern)]; ok {
+		return limit
+	}
+
+	return r.Default
+}
+
+func routeKey(method, pattern string) string {
+	return strings.ToUpper(strings.TrimSpace(method)) + " " + strings.TrimSpace(pattern)
+}
+
+var ErrUnknownMode = errors.New("unknown rate limit mode")
+
+// Режимы хранения корзин
+const (
+	ModeOff    = "off"
+	ModeMemory = "memory"
+	ModeRedis  = "redis"
+)
+
+// Limiter применяет правила к клиентам. Каждый маршрут ограничивается отдельной корзиной.
+type Limiter struct {
+	store Store
+	rules Rules
+}
+
+func NewLimiter(store Store, rules Rules) *Limiter {
+	return &Limiter{
+		store: store,
+		rules: rules,
+	}
+}
+
+// Allow списывает токен из корзины клиента для маршрута. ok равен false, если маршрут не ограничен.
+func (l *Limiter) Allow(ctx context.Context, client, method, pattern string) (res Result, ok bool, err error) {
+	limit := l.rules.For(method, pattern)
+
+	if limit.Unlimited() {
+		return Result{}, false, nil
+	}
+
+	res, err = l.store.Take(ctx, client+"|"+routeKey(method, pattern), limit)
+
+	return res, true, err
+}
+
+// AllowIP списывает токен из корзины адреса для маршрута. Проверяется до аутентификации,
+// поэтому ограничивает и перебор ключей, и запросы с неверными токенами. ok равен false, если лимит адреса выключен.
+func (l *Limiter) AllowIP(ctx context.Context, ip, method, pattern string) (res Result, ok bool, err error) {
+	if l.rules.IP.Unlimited() {
+		return Result{}, false, nil
+	}
+
+	res, err = l.store.Take(ctx, "ip:"+ip+"|"+routeKey(method, pattern), l.rules.IP)
+
+	return res, true, err
+}
+
+// ClientKey возвращает клиента, которому принадлежит корзина. HTTP и gRPC API используют один ключ,
+// поэтому запросы клиента через оба API расходуют одни и те же корзины.
+func ClientKey(identity auth.Identity) string {
+	// Имена ключей могут повторяться, поэтому для API ключей используется id
+	if identity.KeyID != 0 {
+		return auth.MethodAPIKey + ":" + strconv.FormatInt(identity.KeyID, 10)
+	}
+
+	return identity.Method + ":" + identity.Name
+}
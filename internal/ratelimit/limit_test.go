@@ -0,0 +1,54 @@
+package ratelimit
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseLimit(t *testing.T) {
+	testCases := []struct {
+		name     string
+		input    string
+		expected Limit
+		wantErr  bool
+	}{
+		{name: "per second", input: "10/s", expected: Limit{Count: 10, Period: time.Second}},
+		{name: "per minute", input: " 100 / m ", expected: Limit{Count: 100, Period: time.Minute}},
+		{name: "off", input: "off", expected: Limit{}},
+		{name: "zero", input: "0", expected: Limit{}},
+		{name: "no period", input: "10", wantErr: true},
+		{name: "unknown period", input: "10/d", wantErr: true},
+		{name: "negative", input: "-1/s", wantErr: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			limit, err := ParseLimit(tc.input)
+
+			if tc.wantErr {
+				require.Error(t, err)
+				return
+			}
+
+			require.NoError(t, err)
+			require.Equal(t, tc.expected, limit)
+		})
+	}
+}
+
+func TestParseRules(t *testing.T) {
+	rules, err := ParseRules("50/s", "post /segment/user=10/s; GET /segment/user/{userId}=off;")
+	require.NoError(t, err)
+
+	require.Equal(t, Limit{Count: 10, Period: time.Second}, rules.For("POST", "/segment/user"))
+	require.True(t, rules.For("GET", "/segment/user/{userId}").Unlimited())
+	require.Equal(t, Limit{Count: 50, Period: time.Second}, rules.For("GET", "/segment/history"))
+
+	_, err = ParseRules("50/s", "/segment/user=10/s")
+	require.Error(t, err)
+
+	_, err = ParseRules("50/s", "POST /segment/user")
+	require.Error(t, err)
+}
@@ -0,0 +1,64 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestMemoryStoreTake(t *testing.T) {
+	now := time.Date(2023, 8, 28, 10, 0, 0, 0, time.UTC)
+
+	store := NewMemoryStore()
+	store.now = func() time.Time { return now }
+
+	limit := Limit{Count: 2, Period: time.Second}
+
+	res, err := store.Take(context.Background(), "client", limit)
+	require.NoError(t, err)
+	require.True(t, res.Allowed)
+	require.Equal(t, 1, res.Remaining)
+
+	res, err = store.Take(context.Background(), "client", limit)
+	require.NoError(t, err)
+	require.True(t, res.Allowed)
+	require.Equal(t, 0, res.Remaining)
+
+	// Корзина пуста, токен появится через полсекунды
+	res, err = store.Take(context.Background(), "client", limit)
+	require.NoError(t, err)
+	require.False(t, res.Allowed)
+	require.Equal(t, 500*time.Millisecond, res.RetryAfter)
+
+	// У другого клиента своя корзина
+	res, err = store.Take(context.Background(), "other", limit)
+	require.NoError(t, err)
+	require.True(t, res.Allowed)
+
+	now = now.Add(500 * time.Millisecond)
+
+	res, err = store.Take(context.Background(), "client", limit)
+	require.NoError(t, err)
+	require.True(t, res.Allowed)
+}
+
+func TestMemoryStoreSweep(t *testing.T) {
+	now := time.Date(2023, 8, 28, 10, 0, 0, 0, time.UTC)
+
+	store := NewMemoryStore()
+	store.now = func() time.Time { return now }
+	store.lastSweep = now
+
+	_, err := store.Take(context.Background(), "client", Limit{Count: 1, Period: time.Second})
+	require.NoError(t, err)
+	require.Len(t, store.buckets, 1)
+
+	now = now.Add(2 * memorySweepInterval)
+
+	_, err = store.Take(context.Background(), "other", Limit{Count: 1, Period: time.Second})
+	require.NoError(t, err)
+	require.Len(t, store.buckets, 1)
+	require.Contains(t, store.buckets, "other")
+}
@@ -0,0 +1,73 @@
+package ratelimit
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Скрипт атомарно пополняет корзину и списывает токен.
+// Время передается из приложения, чтобы скрипт был детерминированным.
+var takeScript = redis.NewScript(`
+local capacity = tonumber(ARGV[1])
+local rate = tonumber(ARGV[2])
+local now = tonumber(ARGV[3])
+
+local state = redis.call('HMGET', KEYS[1], 'tokens', 'ts')
+local tokens = tonumber(state[1]) or capacity
+local ts = tonumber(state[2]) or now
+
+tokens = math.min(capacity, tokens + math.max(0, now - ts) * rate)
+
+local allowed = 0
+if tokens >= 1 then
+	tokens = tokens - 1
+	allowed = 1
+end
+
+redis.call('HSET', KEYS[1], 'tokens', tostring(tokens), 'ts', tostring(now))
+redis.call('PEXPIRE', KEYS[1], math.ceil(capacity / rate * 1000))
+
+return {allowed, tostring(tokens)}
+`)
+
+const redisKeyPrefix = "ratelimit:"
+
+// RedisStore хранит корзины в Redis, лимиты общие для всех экземпляров сервиса.
+type RedisStore struct {
+	client redis.UniversalClient
+	now    func() time.Time
+}
+
+func NewRedisStore(client redis.UniversalClient) *RedisStore {
+	return &RedisStore{
+		client: client,
+		now:    time.Now,
+	}
+}
+
+func (s *RedisStore) Take(ctx context.Context, key string, limit Limit) (Result, error) {
+	now := float64(s.now().UnixMicro()) / 1e6
+
+	values, err := takeScript.Run(ctx, s.client, []string{redisKeyPrefix + key},
+		limit.Count,
+		strconv.FormatFloat(limit.rate(), 'f', -1, 64),
+		strconv.FormatFloat(now, 'f', 6, 64),
+	).Slice()
+
+	if err != nil {
+		return Result{}, err
+	}
+
+	allowed, _ := values[0].(int64)
+	raw, _ := values[1].(string)
+	tokens, err := strconv.ParseFloat(raw, 64)
+
+	if err != nil {
+		return Result{}, err
+	}
+
+	return result(allowed == 1, tokens, limit), nil
+}
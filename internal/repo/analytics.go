@@ -0,0 +1,224 @@
+package repo
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/dezzerlol/avitotech-test-2023/internal/db/models"
+	"github.com/dezzerlol/avitotech-test-2023/internal/namespace"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Изменения, закоммиченные позже начала пересчета, могут иметь executed_at немного раньше него.
+// Поэтому последние дни пересчитываются повторно с таким запасом.
+const rollupLateEventsWindow = time.Hour
+
+type Analytics struct {
+	DB *pgxpool.Pool
+}
+
+func NewAnalyticsRepo(db *pgxpool.Pool) *Analytics {
+	return &Analytics{DB: db}
+}
+
+// RefreshMembershipRollup пересчитывает segment_membership_daily начиная с первого незавершенного дня.
+// Полностью история читается только при первом запуске, дальше только новые события.
+func (r Analytics) RefreshMembershipRollup(ctx context.Context, now time.Time) error {
+	tx, err := r.DB.Begin(ctx)
+
+	if err != nil {
+		return err
+	}
+
+	defer tx.Rollback(ctx)
+
+	// Не даем двум обновлениям выполняться одновременно
+	_, err = tx.Exec(ctx, `SELECT pg_advisory_xact_lock(hashtext('segment_membership_rollup'))`)
+
+	if err != nil {
+		return err
+	}
+
+	var from *time.Time
+
+	err = tx.
+		QueryRow(ctx, `SELECT recompute_from FROM segment_membership_rollup_state`).
+		Scan(&from)
+
+	if errors.Is(err, pgx.ErrNoRows) {
+		// Первый запуск, считаем с самого первого события
+		err = tx.
+			QueryRow(ctx, `SELECT (min(executed_at) AT TIME ZONE 'UTC')::date FROM user_segment_history`).
+			Scan(&from)
+	}
+
+	if err != nil {
+		return err
+	}
+
+	if from != nil {
+		_, err = tx.Exec(ctx, `DELETE FROM segment_membership_daily WHERE day >= $1`, *from)
+
+		if err != nil {
+			return err
+		}
+
+		// Размер сегмента на конец дня = размер на конец последнего посчитанного дня до from
+		// плюс нарастающий итог изменений
+		query := `
+			INSERT INTO segment_membership_daily (namespace, segment_slug, day, entered, left_count, members)
+			SELECT
+				d.namespace,
+				d.segment_slug,
+				d.day,
+				d.entered,
+				d.left_count,
+				COALESCE((
+					SELECT p.members
+					FROM segment_membership_daily p
+					WHERE p.namespace = d.namespace
+					AND p.segment_slug = d.segment_slug
+					AND p.day < $1::date
+					ORDER BY p.day DESC
+					LIMIT 1
+				), 0) + sum(d.entered - d.left_count) OVER (PARTITION BY d.namespace, d.segment_slug ORDER BY d.day)
+			FROM (
+				SELECT
+					namespace,
+					segment_slug,
+					(executed_at AT TIME ZONE 'UTC')::date AS day,
+					count(*) FILTER (WHERE operation = 'I') AS entered,
+					count(*) FILTER (WHERE operation = 'D') AS left_count
+				FROM user_segment_history
+				WHERE executed_at >= $2
+				GROUP BY namespace, segment_slug, day
+			) d`
+
+		args := []any{
+			*from,
+			time.Date(from.Year(), from.Month(), from.Day(), 0, 0, 0, 0, time.UTC),
+		}
+
+		_, err = tx.Exec(ctx, query, args...)
+
+		if err != nil {
+			return err
+		}
+	}
+
+	recomputeFrom := now.UTC().Add(-rollupLateEventsWindow)
+
+	_, err = tx.Exec(ctx, `
+		INSERT INTO segment_membership_rollup_state (id, recompute_from, refreshed_at)
+		VALUES (true, $1, $2)
+		ON CONFLICT (id) DO UPDATE
+		SET recompute_from = EXCLUDED.recompute_from, refreshed_at = EXCLUDED.refreshed_at`,
+		time.Date(recomputeFrom.Year(), recomputeFrom.Month(), recomputeFrom.Day(), 0, 0, 0, 0, time.UTC),
+		now,
+	)
+
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+// GetRollupRefreshedAt возвращает время последнего пересчета или nil, если его еще не было.
+func (r Analytics) GetRollupRefreshedAt(ctx context.Context) (*time.Time, error) {
+	var refreshedAt time.Time
+
+	err := r.DB.
+		QueryRow(ctx, `SELECT refreshed_at FROM segment_membership_rollup_state`).
+		Scan(&refreshedAt)
+
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &refreshedAt, nil
+}
+
+// StreamMembership возвращает размер сегментов пространства имен на конец каждого дня периода,
+// отсортированный по сегменту и дню. Для дней без изменений берется значение последнего дня с изменениями.
+func (r Analytics) StreamMembership(ctx context.Context, filter models.MembershipFilter, fn func(*models.MembershipPoint) error) error {
+	query := `
+		WITH slugs AS (
+			SELECT unnest($3::text[]) AS segment_slug
+			UNION
+			SELECT DISTINCT segment_slug
+			FROM segment_membership_daily
+			WHERE cardinality($3::text[]) = 0
+			AND namespace = $4
+			AND day <= $2
+		)
+		SELECT
+			s.segment_slug,
+			d.day::date,
+			COALESCE(m.entered, 0),
+			COALESCE(m.left_count, 0),
+			COALESCE(m.members, (
+				SELECT p.members
+				FROM segment_membership_daily p
+				WHERE p.namespace = $4
+				AND p.segment_slug = s.segment_slug
+				AND p.day < d.day
+				ORDER BY p.day DESC
+				LIMIT 1
+			), 0)
+		FROM slugs s
+		CROSS JOIN generate_series($1::date, $2::date, interval '1 day') AS d(day)
+		LEFT JOIN segment_membership_daily m
+		ON m.namespace = $4
+		AND m.segment_slug = s.segment_slug
+		AND m.day = d.day
+		ORDER BY s.segment_slug, d.day`
+
+	segments := filter.Segments
+	if segments == nil {
+		segments = []string{}
+	}
+
+	args := []any{
+		filter.From,
+		filter.To,
+		segments,
+		namespace.FromContext(ctx),
+	}
+
+	rows, err := r.DB.Query(ctx, query, args...)
+
+	if err != nil {
+		return err
+	}
+
+	defer rows.Close()
+
+	var point models.MembershipPoint
+
+	for rows.Next() {
+		err := rows.Scan(
+			&point.SegmentSlug,
+			&point.Day,
+			&point.Entered,
+			&point.Left,
+			&point.Members,
+		)
+
+		if err != nil {
+			return err
+		}
+
+		if err := fn(&point); err != nil {
+			return err
+		}
+	}
+
+	return rows.Err()
+}
@@ -0,0 +1,62 @@
+package repo
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/dezzerlol/avitotech-test-2023/internal/db/models"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_RefreshMembershipRollup(t *testing.T) {
+	segmentRepo := NewSegmentRepo(testDbInstance)
+	analyticsRepo := NewAnalyticsRepo(testDbInstance)
+
+	segment := createSegment(t, segmentRepo)
+
+	for i := 0; i < 2; i++ {
+		userId := createUser(t, NewUserRepo(testDbInstance))
+
+		_, _, err := segmentRepo.UpdateUserSegments(context.Background(), userId, []string{segment.Slug}, 0, nil)
+		require.NoError(t, err)
+	}
+
+	now := time.Now().UTC()
+
+	err := analyticsRepo.RefreshMembershipRollup(context.Background(), now)
+	require.NoError(t, err)
+
+	// Повторный пересчет не должен удваивать данные
+	err = analyticsRepo.RefreshMembershipRollup(context.Background(), now)
+	require.NoError(t, err)
+
+	refreshedAt, err := analyticsRepo.GetRollupRefreshedAt(context.Background())
+	require.NoError(t, err)
+	require.NotNil(t, refreshedAt)
+
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+
+	filter := models.MembershipFilter{
+		From:     today.AddDate(0, 0, -1),
+		To:       today.AddDate(0, 0, 1),
+		Segments: []string{segment.Slug},
+	}
+
+	var points []models.MembershipPoint
+
+	err = analyticsRepo.StreamMembership(context.Background(), filter, func(point *models.MembershipPoint) error {
+		points = append(points, *point)
+		return nil
+	})
+
+	require.NoError(t, err)
+	require.Len(t, points, 3)
+
+	require.Equal(t, int64(0), points[0].Members)
+	require.Equal(t, int64(2), points[1].Entered)
+	require.Equal(t, int64(2), points[1].Members)
+	// Дни без изменений берут размер последнего дня с изменениями
+	require.Equal(t, int64(0), points[2].Entered)
+	require.Equal(t, int64(2), points[2].Members)
+}
@@ -0,0 +1,161 @@
+package repo
+
+import (
+	"context"
+	"errors"
+
+	"github.com/dezzerlol/avitotech-test-2023/internal/db/models"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type APIKey struct {
+	DB *pgxpool.Pool
+}
+
+func NewAPIKeyRepo(db *pgxpool.Pool) *APIKey {
+	return &APIKey{DB: db}
+}
+
+func (r APIKey) Create(ctx context.Context, key *models.APIKey, keyHash string) error {
+	query := `
+		INSERT INTO api_keys (name, key_hash, scopes, namespaces)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id, created_at
+	`
+
+	args := []any{
+		key.Name,
+		keyHash,
+		key.Scopes,
+		key.Namespaces,
+	}
+
+	return r.DB.
+		QueryRow(ctx, query, args...).
+		Scan(&key.ID, &key.CreatedAt)
+}
+
+// GetByHash возвращает действующий ключ по хэшу. Отозванные ключи не возвращаются.
+func (r APIKey) GetByHash(ctx context.Context, keyHash string) (*models.APIKey, error) {
+	query := `
+		SELECT id, name, scopes, namespaces, created_at, rotated_at
+		FROM api_keys
+		WHERE key_hash = $1
+		AND revoked_at IS NULL
+	`
+
+	args := []any{keyHash}
+
+	var key models.APIKey
+
+	err := r.DB.
+		QueryRow(ctx, query, args...).
+		Scan(&key.ID, &key.Name, &key.Scopes, &key.Namespaces, &key.CreatedAt, &key.RotatedAt)
+
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrAPIKeyNotFound
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &key, nil
+}
+
+func (r APIKey) List(ctx context.Context) ([]*models.APIKey, error) {
+	query := `
+		SELECT id, name, scopes, namespaces, created_at, rotated_at, revoked_at
+		FROM api_keys
+		ORDER BY id
+	`
+
+	rows, err := r.DB.Query(ctx, query)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	var keys []*models.APIKey
+
+	for rows.Next() {
+		var key models.APIKey
+
+		err := rows.Scan(
+			&key.ID,
+			&key.Name,
+			&key.Scopes,
+			&key.Namespaces,
+			&key.CreatedAt,
+			&key.RotatedAt,
+			&key.RevokedAt,
+		)
+
+		if err != nil {
+			return nil, err
+		}
+
+		keys = append(keys, &key)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return keys, nil
+}
+
+// Rotate заменяет хэш действующего ключа, старый ключ сразу перестает работать.
+func (r APIKey) Rotate(ctx context.Context, id int64, keyHash string) (*models.APIKey, error) {
+	query := `
+		UPDATE api_keys
+		SET key_hash = $2, rotated_at = now()
+		WHERE id = $1
+		AND revoked_at IS NULL
+		RETURNING id, name, scopes, namespaces, created_at, rotated_at
+	`
+
+	args := []any{id, keyHash}
+
+	var key models.APIKey
+
+	err := r.DB.
+		QueryRow(ctx, query, args...).
+		Scan(&key.ID, &key.Name, &key.Scopes, &key.Namespaces, &key.CreatedAt, &key.RotatedAt)
+
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrAPIKeyNotFound
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &key, nil
+}
+
+func (r APIKey) Revoke(ctx context.Context, id int64) error {
+	query := `
+		UPDATE api_keys
+		SET revoked_at = now()
+		WHERE id = $1
+		AND revoked_at IS NULL
+	`
+
+	args := []any{id}
+
+	ct, err := r.DB.Exec(ctx, query, args...)
+
+	if err != nil {
+		return err
+	}
+
+	if ct.RowsAffected() == 0 {
+		return ErrAPIKeyNotFound
+	}
+
+	return nil
+}
@@ -0,0 +1,75 @@
+package repo
+
+import (
+	"context"
+	"testing"
+
+	"github.com/dezzerlol/avitotech-test-2023/internal/db/models"
+	"github.com/dezzerlol/avitotech-test-2023/pkg/testhelper"
+	"github.com/stretchr/testify/require"
+)
+
+func createAPIKey(t *testing.T, repo *APIKey) (*models.APIKey, string) {
+	key := &models.APIKey{
+		Name:   testhelper.RandomString(12),
+		Scopes: []string{"segments:read", "reports:read"},
+	}
+
+	keyHash := testhelper.RandomString(64)
+
+	err := repo.Create(context.Background(), key, keyHash)
+	require.NoError(t, err)
+	require.NotZero(t, key.ID)
+
+	return key, keyHash
+}
+
+func Test_GetAPIKeyByHash(t *testing.T) {
+	repo := NewAPIKeyRepo(testDbInstance)
+
+	key, keyHash := createAPIKey(t, repo)
+
+	found, err := repo.GetByHash(context.Background(), keyHash)
+	require.NoError(t, err)
+	require.Equal(t, key.ID, found.ID)
+	require.Equal(t, key.Scopes, found.Scopes)
+
+	_, err = repo.GetByHash(context.Background(), testhelper.RandomString(64))
+	require.ErrorIs(t, err, ErrAPIKeyNotFound)
+}
+
+func Test_RotateAPIKey(t *testing.T) {
+	repo := NewAPIKeyRepo(testDbInstance)
+
+	key, oldHash := createAPIKey(t, repo)
+	newHash := testhelper.RandomString(64)
+
+	rotated, err := repo.Rotate(context.Background(), key.ID, newHash)
+	require.NoError(t, err)
+	require.NotNil(t, rotated.RotatedAt)
+
+	_, err = repo.GetByHash(context.Background(), oldHash)
+	require.ErrorIs(t, err, ErrAPIKeyNotFound)
+
+	_, err = repo.GetByHash(context.Background(), newHash)
+	require.NoError(t, err)
+}
+
+func Test_RevokeAPIKey(t *testing.T) {
+	repo := NewAPIKeyRepo(testDbInstance)
+
+	key, keyHash := createAPIKey(t, repo)
+
+	err := repo.Revoke(context.Background(), key.ID)
+	require.NoError(t, err)
+
+	_, err = repo.GetByHash(context.Background(), keyHash)
+	require.ErrorIs(t, err, ErrAPIKeyNotFound)
+
+	// Отозванный ключ нельзя отозвать повторно или ротировать
+	err = repo.Revoke(context.Background(), key.ID)
+	require.ErrorIs(t, err, ErrAPIKeyNotFound)
+
+	_, err = repo.Rotate(context.Background(), key.ID, testhelper.RandomString(64))
+	require.ErrorIs(t, err, ErrAPIKeyNotFound)
+}
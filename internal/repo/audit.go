@@ -0,0 +1,82 @@
+package repo
+
+import (
+	"context"
+
+	"github.com/dezzerlol/avitotech-test-2023/internal/audit"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// beginWithAudit начинает транзакцию, в которой заданы источник, actor и request_id
+// из контекста. Триггер user_segments_trigger сохраняет их в user_segment_history.
+func beginWithAudit(ctx context.Context, db *pgxpool.Pool) (pgx.Tx, error) {
+	tx, err := db.Begin(ctx)
+
+	if err != nil {
+		return nil, err
+	}
+
+	meta := audit.FromContext(ctx)
+
+	_, err = tx.Exec(ctx, `
+		SELECT
+			set_config('app.history_source', $1, true),
+			set_config('app.history_actor', $2, true),
+			set_config('app.history_request_id', $3, true)`,
+		meta.Source, meta.Actor, meta.RequestID,
+	)
+
+	if err != nil {
+		tx.Rollback(ctx)
+		return nil, err
+	}
+
+	return tx, nil
+}
+
+// execWithAudit выполняет запрос в транзакции с метаданными аудита.
+func execWithAudit(ctx context.Context, db *pgxpool.Pool, query string, args ...any) (pgconn.CommandTag, error) {
+	tx, err := beginWithAudit(ctx, db)
+
+	if err != nil {
+		return pgconn.CommandTag{}, err
+	}
+
+	defer tx.Rollback(ctx)
+
+	ct, err := tx.Exec(ctx, query, args...)
+
+	if err != nil {
+		return ct, err
+	}
+
+	return ct, tx.Commit(ctx)
+}
+
+// queryStringsWithAudit выполняет запрос в транзакции с метаданными аудита
+// и возвращает значения первой колонки результата.
+func queryStringsWithAudit(ctx context.Context, db *pgxpool.Pool, query string, args ...any) ([]string, error) {
+	tx, err := beginWithAudit(ctx, db)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer tx.Rollback(ctx)
+
+	rows, err := tx.Query(ctx, query, args...)
+
+	if err != nil {
+		return nil, err
+	}
+
+	values, err := pgx.CollectRows(rows, pgx.RowTo[string])
+
+	if err != nil {
+		return nil, err
+	}
+
+	return values, tx.Commit(ctx)
+}
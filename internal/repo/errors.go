@@ -1,9 +1,13 @@
 package repo
 
-import "errors"
+import (
+	"errors"
+	"fmt"
+)
 
 var (
 	UniqueConstraintViolation = "23505"
+	ForeignKeyViolation       = "23503"
 )
 
 var (
@@ -17,4 +21,42 @@ var (
 
 	// User errors
 	ErrUserNotFound = errors.New("user not found")
+
+	// Namespace errors
+	ErrNamespaceNotFound      = errors.New("namespace not found")
+	ErrNamespaceAlreadyExists = errors.New("namespace already exists")
+
+	// API key errors
+	ErrAPIKeyNotFound = errors.New("api key not found")
+
+	// Webhook errors
+	ErrWebhookNotFound = errors.New("webhook not found")
+
+	// Layer errors
+	ErrLayerNotFound      = errors.New("layer not found")
+	ErrLayerAlreadyExists = errors.New("layer already exists")
+	ErrLayerFull          = errors.New("not enough free buckets in layer")
+	ErrLayerConflict      = errors.New("user is already in another segment of the layer")
+
+	// Variant errors
+	ErrVariantNotFound      = errors.New("variant not found")
+	ErrVariantRemoved       = errors.New("variants cannot be removed, set weight to 0 instead")
+	ErrSegmentHasNoVariants = errors.New("segment has no variants")
+	ErrUserNotInSegment     = errors.New("user is not in segment")
 )
+
+// LayerConflictError - пользователь уже состоит в другом сегменте слоя или добавляется в два сегмента одного слоя.
+type LayerConflictError struct {
+	Layer   string
+	Segment string
+	// Сегмент слоя, в котором пользователь уже состоит или в который добавляется тем же запросом
+	Conflicting string
+}
+
+func (e *LayerConflictError) Error() string {
+	return fmt.Sprintf("segment %s conflicts with segment %s in layer %s", e.Segment, e.Conflicting, e.Layer)
+}
+
+func (e *LayerConflictError) Is(target error) bool {
+	return target == ErrLayerConflict
+}
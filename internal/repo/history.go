@@ -0,0 +1,434 @@
+package repo
+
+import (
+	"context"
+	"time"
+
+	"github.com/dezzerlol/avitotech-test-2023/internal/db/models"
+	"github.com/dezzerlol/avitotech-test-2023/internal/namespace"
+)
+
+// CountSegmentMembersAt возвращает число пользователей в сегменте на момент at,
+// считая по истории добавлений и удалений.
+func (r Segment) CountSegmentMembersAt(ctx context.Context, slug string, at time.Time) (int64, error) {
+	query := `
+		SELECT
+			count(*) FILTER (WHERE operation = 'I') -
+			count(*) FILTER (WHERE operation = 'D')
+		FROM user_segment_history
+		WHERE namespace = $3
+		AND segment_slug = $1
+		AND executed_at < $2`
+
+	args := []any{slug, at, namespace.FromContext(ctx)}
+
+	var members int64
+
+	err := r.DB.
+		QueryRow(ctx, query, args...).
+		Scan(&members)
+
+	return members, err
+}
+
+// GetSegmentHistoryBuckets возвращает число добавлений и удалений по интервалам.
+// Интервалы без событий в результат не попадают.
+func (r Segment) GetSegmentHistoryBuckets(ctx context.Context, filter models.SegmentHistoryFilter) ([]*models.SegmentHistoryBucket, error) {
+	query := `
+		SELECT
+			date_trunc($4, executed_at, $5) AS bucket_start,
+			count(*) FILTER (WHERE operation = 'I'),
+			count(*) FILTER (WHERE operation = 'D')
+		FROM user_segment_history
+		WHERE namespace = $6
+		AND segment_slug = $1
+		AND executed_at >= $2
+		AND executed_at < $3
+		GROUP BY bucket_start
+		ORDER BY bucket_start`
+
+	args := []any{
+		filter.SegmentSlug,
+		filter.From,
+		filter.To,
+		filter.Bucket,
+		filter.Location.String(),
+		namespace.FromContext(ctx),
+	}
+
+	rows, err := r.DB.Query(ctx, query, args...)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	var buckets []*models.SegmentHistoryBucket
+
+	for rows.Next() {
+		var bucket models.SegmentHistoryBucket
+
+		err := rows.Scan(
+			&bucket.Start,
+			&bucket.Entered,
+			&bucket.Left,
+		)
+
+		if err != nil {
+			return nil, err
+		}
+
+		buckets = append(buckets, &bucket)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return buckets, nil
+}
+
+// StreamSegmentHistory построчно читает события сегмента за период.
+// Если limit больше нуля, читается не больше limit событий.
+func (r Segment) StreamSegmentHistory(ctx context.Context, filter models.SegmentHistoryFilter, limit int64, fn func(*models.UserHistory) error) error {
+	query := `
+		SELECT user_id, segment_slug, operation, COALESCE(variant, ''), executed_at,
+			COALESCE(source, ''), COALESCE(actor, ''), COALESCE(request_id, '')
+		FROM user_segment_history
+		WHERE namespace = $5
+		AND segment_slug = $1
+		AND executed_at >= $2
+		AND executed_at < $3
+		ORDER BY id
+		LIMIT NULLIF($4::bigint, 0)`
+
+	args := []any{
+		filter.SegmentSlug,
+		filter.From,
+		filter.To,
+		limit,
+		namespace.FromContext(ctx),
+	}
+
+	rows, err := r.DB.Query(ctx, query, args...)
+
+	if err != nil {
+		return err
+	}
+
+	defer rows.Close()
+
+	var history models.UserHistory
+
+	for rows.Next() {
+		err := rows.Scan(
+			&history.UserID,
+			&history.SegmentSlug,
+			&history.Operation,
+			&history.Variant,
+			&history.ExecutedAt,
+			&history.Source,
+			&history.Actor,
+			&history.RequestID,
+		)
+
+		if err != nil {
+			return err
+		}
+
+		if err := fn(&history); err != nil {
+			return err
+		}
+	}
+
+	return rows.Err()
+}
+
+// StreamUserHistory построчно читает историю пользователя за месяц и передает каждую запись в fn,
+// не загружая весь результат в память.
+func (r Segment) StreamUserHistory(ctx context.Context, userId int64, date time.Time, fn func(*models.UserHistory) error) error {
+	query := `
+		SELECT user_id, segment_slug, operation, COALESCE(variant, ''), executed_at,
+			COALESCE(source, ''), COALESCE(actor, ''), COALESCE(request_id, '')
+		FROM user_segment_history
+		WHERE namespace = $4
+		AND user_id = $1
+		AND executed_at >= $2
+		AND executed_at < $3
+		ORDER BY id`
+
+	from := time.Date(date.Year(), date.Month(), 1, 0, 0, 0, 0, date.Location())
+
+	args := []any{
+		userId,
+		from,
+		from.AddDate(0, 1, 0),
+		namespace.FromContext(ctx),
+	}
+
+	rows, err := r.DB.Query(ctx, query, args...)
+
+	if err != nil {
+		return err
+	}
+
+	defer rows.Close()
+
+	var userHistory models.UserHistory
+
+	for rows.Next() {
+		err := rows.Scan(
+			&userHistory.UserID,
+			&userHistory.SegmentSlug,
+			&userHistory.Operation,
+			&userHistory.Variant,
+			&userHistory.ExecutedAt,
+			&userHistory.Source,
+			&userHistory.Actor,
+			&userHistory.RequestID,
+		)
+
+		if err != nil {
+			return err
+		}
+
+		if err := fn(&userHistory); err != nil {
+			return err
+		}
+	}
+
+	return rows.Err()
+}
+
+// GetUserSegmentsAt восстанавливает сегменты пользователя на момент at по истории:
+// для каждого сегмента берется последняя операция не позже at, вариант - из последнего добавления или смены варианта.
+// Сегменты, удаленные после at, тоже попадают в результат с пометкой deleted. Сегмент считается удаленным,
+// если сегмента с таким slug нет или он создан заново после последней операции: slug мог освободиться и занят снова.
+func (r Segment) GetUserSegmentsAt(ctx context.Context, userId int64, at time.Time) ([]*models.Segment, error) {
+	query := `
+		SELECT h.segment_slug, COALESCE(h.variant, ''), s.slug IS NULL OR s.created_at > h.executed_at
+		FROM (
+			SELECT DISTINCT ON (segment_slug) segment_slug, operation, variant, executed_at
+			FROM user_segment_history
+			WHERE namespace = $3
+			AND user_id = $1
+			AND executed_at <= $2
+			ORDER BY segment_slug, id DESC
+		) h
+		LEFT JOIN segments s
+		ON s.namespace = $3
+		AND s.slug = h.segment_slug
+		WHERE h.operation <> 'D'
+		ORDER BY h.segment_slug`
+
+	args := []any{userId, at, namespace.FromContext(ctx)}
+
+	rows, err := r.DB.Query(ctx, query, args...)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	var segments []*models.Segment
+
+	for rows.Next() {
+		var segment models.Segment
+
+		err := rows.Scan(
+			&segment.Slug,
+			&segment.Variant,
+			&segment.Deleted,
+		)
+
+		if err != nil {
+			return nil, err
+		}
+
+		segments = append(segments, &segment)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return segments, nil
+}
+
+// GetSegmentEventsAfter возвращает до limit изменений членства с id больше afterId по возрастанию id.
+// Используется для продолжения потока событий с Last-Event-ID. В отличие от GetChangesAfter записи
+// не ограничиваются границей истории: записи после нее, закоммиченные до подписки, в поток уже не придут.
+func (r Segment) GetSegmentEventsAfter(ctx context.Context, afterId int64, filter models.SegmentEventFilter, limit int64) ([]*models.SegmentEvent, error) {
+	query := `
+		SELECT id, namespace, segment_slug, user_id, operation, COALESCE(variant, ''), executed_at,
+			COALESCE(source, ''), COALESCE(actor, ''), COALESCE(request_id, '')
+		FROM user_segment_history
+		WHERE namespace = $1
+		AND id > $2
+		AND ($3::text = '' OR segment_slug = $3)
+		AND ($4::bigint = 0 OR user_id = $4)
+		ORDER BY id
+		LIMIT $5`
+
+	args := []any{
+		namespace.FromContext(ctx),
+		afterId,
+		filter.SegmentSlug,
+		filter.UserID,
+		limit,
+	}
+
+	rows, err := r.DB.Query(ctx, query, args...)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	var events []*models.SegmentEvent
+
+	for rows.Next() {
+		var event models.SegmentEvent
+
+		err := rows.Scan(
+			&event.ID,
+			&event.Namespace,
+			&event.SegmentSlug,
+			&event.UserID,
+			&event.Operation,
+			&event.Variant,
+			&event.ExecutedAt,
+			&event.Source,
+			&event.Actor,
+			&event.RequestID,
+		)
+
+		if err != nil {
+			return nil, err
+		}
+
+		events = append(events, &event)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return events, nil
+}
+
+// GetHistoryWatermark возвращает границу, до которой записи истории уже не появятся:
+// транзакции с записями до нее закоммичены или откачены, после нее могут закоммититься незавершенные транзакции.
+func (r Segment) GetHistoryWatermark(ctx context.Context) (int64, error) {
+	var lastValue int64
+	var isCalled bool
+
+	// Последовательность читается до pg_locks: транзакция, взявшая блокировку позже,
+	// получит id больше lastValue
+	err := r.DB.
+		QueryRow(ctx, `SELECT last_value, is_called FROM user_segment_history_id_seq`).
+		Scan(&lastValue, &isCalled)
+
+	if err != nil {
+		return 0, err
+	}
+
+	// Последовательность еще не выдавала id, last_value будет выдан первым
+	if !isCalled {
+		lastValue--
+	}
+
+	// Наименьший ключ блокировок незавершенных транзакций, см. миграцию 000009_history_feed
+	var lowerBound *int64
+
+	err = r.DB.
+		QueryRow(ctx, `
+			SELECT min((classid::bigint << 32) | objid::bigint)
+			FROM pg_locks
+			WHERE locktype = 'advisory'
+			AND objsubid = 2
+			AND mode = 'ShareLock'
+			AND database = (SELECT oid FROM pg_database WHERE datname = current_database())`).
+		Scan(&lowerBound)
+
+	if err != nil {
+		return 0, err
+	}
+
+	watermark := lastValue
+
+	if lowerBound != nil && *lowerBound-1 < watermark {
+		watermark = *lowerBound - 1
+	}
+
+	return watermark, nil
+}
+
+// GetChangesAfter возвращает до limit изменений членства с id больше after по возрастанию id
+// и границу истории (см. GetHistoryWatermark). Возвращаются только записи не дальше границы.
+func (r Segment) GetChangesAfter(ctx context.Context, after, limit int64) ([]*models.SegmentEvent, int64, error) {
+	watermark, err := r.GetHistoryWatermark(ctx)
+
+	if err != nil {
+		return nil, 0, err
+	}
+
+	query := `
+		SELECT id, namespace, segment_slug, user_id, operation, COALESCE(variant, ''), executed_at,
+			COALESCE(source, ''), COALESCE(actor, ''), COALESCE(request_id, '')
+		FROM user_segment_history
+		WHERE namespace = $1
+		AND id > $2
+		AND id <= $3
+		ORDER BY id
+		LIMIT $4`
+
+	args := []any{
+		namespace.FromContext(ctx),
+		after,
+		watermark,
+		limit,
+	}
+
+	rows, err := r.DB.Query(ctx, query, args...)
+
+	if err != nil {
+		return nil, 0, err
+	}
+
+	defer rows.Close()
+
+	changes := []*models.SegmentEvent{}
+
+	for rows.Next() {
+		var event models.SegmentEvent
+
+		err := rows.Scan(
+			&event.ID,
+			&event.Namespace,
+			&event.SegmentSlug,
+			&event.UserID,
+			&event.Operation,
+			&event.Variant,
+			&event.ExecutedAt,
+			&event.Source,
+			&event.Actor,
+			&event.RequestID,
+		)
+
+		if err != nil {
+			return nil, 0, err
+		}
+
+		changes = append(changes, &event)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, 0, err
+	}
+
+	return changes, watermark, nil
+}
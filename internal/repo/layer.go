@@ -0,0 +1,194 @@
+package repo
+
+import (
+	"context"
+	"errors"
+
+	"github.com/dezzerlol/avitotech-test-2023/internal/db/models"
+	"github.com/dezzerlol/avitotech-test-2023/internal/namespace"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type Layer struct {
+	DB *pgxpool.Pool
+}
+
+func NewLayerRepo(db *pgxpool.Pool) *Layer {
+	return &Layer{DB: db}
+}
+
+func (r Layer) Create(ctx context.Context, layer *models.Layer) error {
+	query := `
+		INSERT INTO layers (namespace, slug, description)
+		VALUES ($1, $2, $3)
+		RETURNING created_at
+	`
+
+	args := []any{namespace.FromContext(ctx), layer.Slug, layer.Description}
+
+	err := r.DB.
+		QueryRow(ctx, query, args...).
+		Scan(&layer.CreatedAt)
+
+	if err != nil {
+		var pgErr *pgconn.PgError
+
+		if ok := errors.As(err, &pgErr); ok {
+			if pgErr.Code == UniqueConstraintViolation {
+				return ErrLayerAlreadyExists
+			}
+
+			if pgErr.Code == ForeignKeyViolation {
+				return ErrNamespaceNotFound
+			}
+		}
+	}
+
+	layer.Segments = []*models.LayerSegment{}
+	layer.FreeBuckets = models.LayerBuckets
+
+	return err
+}
+
+// List возвращает слои пространства имен с сегментами и занятыми ими бакетами.
+func (r Layer) List(ctx context.Context) ([]*models.Layer, error) {
+	query := `
+		SELECT l.slug, l.description, l.created_at, s.slug, s.bucket_from, s.bucket_to
+		FROM layers l
+		LEFT JOIN segments s
+		ON s.namespace = l.namespace
+		AND s.layer = l.slug
+		WHERE l.namespace = $1
+		ORDER BY l.slug, s.bucket_from NULLS LAST, s.slug
+	`
+
+	args := []any{namespace.FromContext(ctx)}
+
+	rows, err := r.DB.Query(ctx, query, args...)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	layers := []*models.Layer{}
+
+	for rows.Next() {
+		var (
+			layer      models.Layer
+			slug       *string
+			bucketFrom *int
+			bucketTo   *int
+		)
+
+		err := rows.Scan(
+			&layer.Slug,
+			&layer.Description,
+			&layer.CreatedAt,
+			&slug,
+			&bucketFrom,
+			&bucketTo,
+		)
+
+		if err != nil {
+			return nil, err
+		}
+
+		if len(layers) == 0 || layers[len(layers)-1].Slug != layer.Slug {
+			layer.Segments = []*models.LayerSegment{}
+			layer.FreeBuckets = models.LayerBuckets
+			layers = append(layers, &layer)
+		}
+
+		if slug == nil {
+			continue
+		}
+
+		current := layers[len(layers)-1]
+		segment := &models.LayerSegment{Slug: *slug}
+
+		if bucketFrom != nil && bucketTo != nil {
+			segment.Buckets = &models.BucketRange{From: *bucketFrom, To: *bucketTo}
+			current.FreeBuckets -= segment.Buckets.Size()
+		}
+
+		current.Segments = append(current.Segments, segment)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return layers, nil
+}
+
+// reserveBuckets блокирует слой до конца транзакции и возвращает первый свободный диапазон из size бакетов.
+// При size = 0 только проверяет, что слой существует.
+func reserveBuckets(ctx context.Context, tx pgx.Tx, layer string, size int) (*models.BucketRange, error) {
+	ns := namespace.FromContext(ctx)
+
+	// Блокировка слоя не дает параллельным запросам занять один и тот же диапазон
+	var exists bool
+
+	err := tx.
+		QueryRow(ctx, `SELECT true FROM layers WHERE namespace = $1 AND slug = $2 FOR UPDATE`, ns, layer).
+		Scan(&exists)
+
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrLayerNotFound
+	}
+
+	if err != nil || size == 0 {
+		return nil, err
+	}
+
+	rows, err := tx.Query(ctx, `
+		SELECT bucket_from, bucket_to
+		FROM segments
+		WHERE namespace = $1
+		AND layer = $2
+		AND bucket_from IS NOT NULL
+		ORDER BY bucket_from`,
+		ns, layer,
+	)
+
+	if err != nil {
+		return nil, err
+	}
+
+	taken, err := pgx.CollectRows(rows, pgx.RowToStructByPos[models.BucketRange])
+
+	if err != nil {
+		return nil, err
+	}
+
+	buckets, ok := freeBucketRange(taken, size)
+
+	if !ok {
+		return nil, ErrLayerFull
+	}
+
+	return &buckets, nil
+}
+
+// freeBucketRange ищет первый промежуток из size бакетов между занятыми диапазонами, отсортированными по From.
+func freeBucketRange(taken []models.BucketRange, size int) (models.BucketRange, bool) {
+	from := 0
+
+	for _, r := range taken {
+		if r.From-from >= size {
+			break
+		}
+
+		from = max(from, r.To)
+	}
+
+	if from+size > models.LayerBuckets {
+		return models.BucketRange{}, false
+	}
+
+	return models.BucketRange{From: from, To: from + size}, true
+}
@@ -0,0 +1,215 @@
+package repo
+
+import (
+	"context"
+	"testing"
+
+	"github.com/dezzerlol/avitotech-test-2023/internal/db/models"
+	"github.com/dezzerlol/avitotech-test-2023/internal/namespace"
+	"github.com/dezzerlol/avitotech-test-2023/pkg/testhelper"
+	"github.com/stretchr/testify/require"
+)
+
+func createLayer(t *testing.T, ctx context.Context, repo *Layer) string {
+	layer := &models.Layer{Slug: testhelper.RandomString(12)}
+
+	err := repo.Create(ctx, layer)
+	require.NoError(t, err)
+	require.False(t, layer.CreatedAt.IsZero())
+
+	return layer.Slug
+}
+
+func Test_FreeBucketRange(t *testing.T) {
+	tests := []struct {
+		name  string
+		taken []models.BucketRange
+		size  int
+		want  models.BucketRange
+		ok    bool
+	}{
+		{"empty layer", nil, 30, models.BucketRange{From: 0, To: 30}, true},
+		{"after taken", []models.BucketRange{{From: 0, To: 30}}, 50, models.BucketRange{From: 30, To: 80}, true},
+		{"gap between", []models.BucketRange{{From: 0, To: 20}, {From: 50, To: 100}}, 30, models.BucketRange{From: 20, To: 50}, true},
+		{"gap too small", []models.BucketRange{{From: 0, To: 20}, {From: 25, To: 90}}, 10, models.BucketRange{From: 90, To: 100}, true},
+		{"full", []models.BucketRange{{From: 0, To: 60}}, 50, models.BucketRange{}, false},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got, ok := freeBucketRange(tc.taken, tc.size)
+
+			require.Equal(t, tc.ok, ok)
+			require.Equal(t, tc.want, got)
+		})
+	}
+}
+
+func Test_Layers(t *testing.T) {
+	ctx := namespace.WithNamespace(context.Background(), createNamespace(t, NewNamespaceRepo(testDbInstance)))
+	layerRepo := NewLayerRepo(testDbInstance)
+	segmentRepo := NewSegmentRepo(testDbInstance)
+
+	layer := createLayer(t, ctx, layerRepo)
+
+	err := layerRepo.Create(ctx, &models.Layer{Slug: layer})
+	require.ErrorIs(t, err, ErrLayerAlreadyExists)
+
+	err = segmentRepo.Create(ctx, &models.Segment{Slug: testhelper.RandomString(12), Layer: "missing"})
+	require.ErrorIs(t, err, ErrLayerNotFound)
+
+	first := &models.Segment{Slug: testhelper.RandomString(12), Layer: layer, UserPercent: 60}
+	require.NoError(t, segmentRepo.Create(ctx, first))
+	require.Equal(t, &models.BucketRange{From: 0, To: 60}, first.Buckets)
+
+	err = segmentRepo.Create(ctx, &models.Segment{Slug: testhelper.RandomString(12), Layer: layer, UserPercent: 50})
+	require.ErrorIs(t, err, ErrLayerFull)
+
+	second := &models.Segment{Slug: testhelper.RandomString(12), Layer: layer, UserPercent: 40}
+	require.NoError(t, segmentRepo.Create(ctx, second))
+	require.Equal(t, &models.BucketRange{From: 60, To: 100}, second.Buckets)
+
+	manual := &models.Segment{Slug: testhelper.RandomString(12), Layer: layer}
+	require.NoError(t, segmentRepo.Create(ctx, manual))
+	require.Nil(t, manual.Buckets)
+
+	layers, err := layerRepo.List(ctx)
+	require.NoError(t, err)
+	require.Len(t, layers, 1)
+	require.Equal(t, 0, layers[0].FreeBuckets)
+	require.Len(t, layers[0].Segments, 3)
+	require.Equal(t, first.Slug, layers[0].Segments[0].Slug)
+	require.Equal(t, manual.Slug, layers[0].Segments[2].Slug)
+}
+
+func Test_LayerMembership(t *testing.T) {
+	ctx := namespace.WithNamespace(context.Background(), createNamespace(t, NewNamespaceRepo(testDbInstance)))
+	userRepo := NewUserRepo(testDbInstance)
+	segmentRepo := NewSegmentRepo(testDbInstance)
+
+	layer := createLayer(t, ctx, NewLayerRepo(testDbInstance))
+
+	first := &models.Segment{Slug: testhelper.RandomString(12), Layer: layer}
+	require.NoError(t, segmentRepo.Create(ctx, first))
+
+	second := &models.Segment{Slug: testhelper.RandomString(12), Layer: layer}
+	require.NoError(t, segmentRepo.Create(ctx, second))
+
+	other := &models.Segment{Slug: testhelper.RandomString(12)}
+	require.NoError(t, segmentRepo.Create(ctx, other))
+
+	userId := createUser(t, userRepo)
+
+	t.Run("Should reject two segments of one layer in one request", func(t *testing.T) {
+		_, _, err := segmentRepo.UpdateUserSegments(ctx, userId, []string{first.Slug, second.Slug, other.Slug}, 0, nil)
+
+		var conflictErr *LayerConflictError
+		require.ErrorAs(t, err, &conflictErr)
+		require.Equal(t, layer, conflictErr.Layer)
+
+		segments, err := segmentRepo.GetUserSegments(ctx, userId)
+		require.NoError(t, err)
+		require.Empty(t, segments)
+	})
+
+	t.Run("Should reject segment if user is in another segment of the layer", func(t *testing.T) {
+		added, _, err := segmentRepo.UpdateUserSegments(ctx, userId, []string{first.Slug, other.Slug}, 0, nil)
+		require.NoError(t, err)
+		require.Equal(t, int64(2), added)
+
+		// Повторное добавление в тот же сегмент - не конфликт
+		added, _, err = segmentRepo.UpdateUserSegments(ctx, userId, []string{first.Slug}, 0, nil)
+		require.NoError(t, err)
+		require.Equal(t, int64(0), added)
+
+		_, _, err = segmentRepo.UpdateUserSegments(ctx, userId, []string{second.Slug}, 0, nil)
+		require.ErrorIs(t, err, ErrLayerConflict)
+
+		var conflictErr *LayerConflictError
+		require.ErrorAs(t, err, &conflictErr)
+		require.Equal(t, &LayerConflictError{Layer: layer, Segment: second.Slug, Conflicting: first.Slug}, conflictErr)
+	})
+
+	t.Run("Should rollback delete if add conflicts", func(t *testing.T) {
+		var historyBefore, historyAfter int
+
+		countHistory := `SELECT count(*) FROM user_segment_history WHERE namespace = $1 AND user_id = $2`
+
+		err := testDbInstance.QueryRow(ctx, countHistory, namespace.FromContext(ctx), userId).Scan(&historyBefore)
+		require.NoError(t, err)
+
+		// Удаление other проходит, добавление second конфликтует с first
+		_, _, err = segmentRepo.UpdateUserSegments(ctx, userId, []string{second.Slug}, 0, []string{other.Slug})
+		require.ErrorIs(t, err, ErrLayerConflict)
+
+		segments, err := segmentRepo.GetUserSegments(ctx, userId)
+		require.NoError(t, err)
+		require.Len(t, segments, 2)
+		require.ElementsMatch(t, []string{first.Slug, other.Slug}, []string{segments[0].Slug, segments[1].Slug})
+
+		err = testDbInstance.QueryRow(ctx, countHistory, namespace.FromContext(ctx), userId).Scan(&historyAfter)
+		require.NoError(t, err)
+		require.Equal(t, historyBefore, historyAfter)
+	})
+
+	t.Run("Should move user to another segment of the layer in one update", func(t *testing.T) {
+		added, deleted, err := segmentRepo.UpdateUserSegments(ctx, userId, []string{second.Slug}, 0, []string{first.Slug})
+		require.NoError(t, err)
+		require.Equal(t, int64(1), added)
+		require.Equal(t, int64(1), deleted)
+	})
+
+	t.Run("Should move user to another segment of the layer after delete", func(t *testing.T) {
+		_, err := segmentRepo.DeleteUserSegments(ctx, userId, []string{second.Slug})
+		require.NoError(t, err)
+
+		added, _, err := segmentRepo.UpdateUserSegments(ctx, userId, []string{first.Slug}, 0, nil)
+		require.NoError(t, err)
+		require.Equal(t, int64(1), added)
+	})
+}
+
+func Test_AddLayerUsersSegment(t *testing.T) {
+	ctx := namespace.WithNamespace(context.Background(), createNamespace(t, NewNamespaceRepo(testDbInstance)))
+	userRepo := NewUserRepo(testDbInstance)
+	segmentRepo := NewSegmentRepo(testDbInstance)
+
+	for i := 0; i < 50; i++ {
+		createUser(t, userRepo)
+	}
+
+	layer := createLayer(t, ctx, NewLayerRepo(testDbInstance))
+
+	// Пользователь из ручного сегмента слоя не попадает в раскатку
+	manual := &models.Segment{Slug: testhelper.RandomString(12), Layer: layer}
+	require.NoError(t, segmentRepo.Create(ctx, manual))
+
+	manualUser := createUser(t, userRepo)
+	_, _, err := segmentRepo.UpdateUserSegments(ctx, manualUser, []string{manual.Slug}, 0, nil)
+	require.NoError(t, err)
+
+	first := &models.Segment{Slug: testhelper.RandomString(12), Layer: layer, UserPercent: 50}
+	require.NoError(t, segmentRepo.Create(ctx, first))
+	require.NoError(t, segmentRepo.AddLayerUsersSegment(ctx, first.Slug))
+
+	second := &models.Segment{Slug: testhelper.RandomString(12), Layer: layer, UserPercent: 50}
+	require.NoError(t, segmentRepo.Create(ctx, second))
+	require.NoError(t, segmentRepo.AddLayerUsersSegment(ctx, second.Slug))
+
+	var total, inLayer, overlapping int
+
+	err = testDbInstance.QueryRow(ctx, `SELECT count(*) FROM users`).Scan(&total)
+	require.NoError(t, err)
+
+	err = testDbInstance.QueryRow(ctx, `
+		SELECT count(*), count(*) - count(DISTINCT user_id)
+		FROM user_segments
+		WHERE namespace = $1 AND layer = $2`,
+		namespace.FromContext(ctx), layer,
+	).Scan(&inLayer, &overlapping)
+	require.NoError(t, err)
+
+	// Диапазоны двух раскаток покрывают весь слой, поэтому в слое все пользователи ровно по одному разу
+	require.Equal(t, total, inLayer)
+	require.Zero(t, overlapping)
+}
@@ -0,0 +1,83 @@
+package repo
+
+import (
+	"context"
+	"errors"
+
+	"github.com/dezzerlol/avitotech-test-2023/internal/db/models"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type Namespace struct {
+	DB *pgxpool.Pool
+}
+
+func NewNamespaceRepo(db *pgxpool.Pool) *Namespace {
+	return &Namespace{DB: db}
+}
+
+func (r Namespace) Create(ctx context.Context, ns *models.Namespace) error {
+	query := `
+		INSERT INTO namespaces (slug)
+		VALUES ($1)
+		RETURNING created_at
+	`
+
+	args := []any{ns.Slug}
+
+	err := r.DB.
+		QueryRow(ctx, query, args...).
+		Scan(&ns.CreatedAt)
+
+	if err != nil {
+		var pgErr *pgconn.PgError
+
+		if ok := errors.As(err, &pgErr); ok {
+			if pgErr.Code == UniqueConstraintViolation {
+				return ErrNamespaceAlreadyExists
+			}
+		}
+	}
+
+	return err
+}
+
+func (r Namespace) List(ctx context.Context) ([]*models.Namespace, error) {
+	query := `
+		SELECT slug, created_at
+		FROM namespaces
+		ORDER BY slug
+	`
+
+	rows, err := r.DB.Query(ctx, query)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	var namespaces []*models.Namespace
+
+	for rows.Next() {
+		var ns models.Namespace
+
+		err := rows.Scan(
+			&ns.Slug,
+			&ns.CreatedAt,
+		)
+
+		if err != nil {
+			return nil, err
+		}
+
+		namespaces = append(namespaces, &ns)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return namespaces, nil
+}
@@ -0,0 +1,78 @@
+package repo
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/dezzerlol/avitotech-test-2023/internal/db/models"
+	"github.com/dezzerlol/avitotech-test-2023/internal/namespace"
+	"github.com/dezzerlol/avitotech-test-2023/pkg/testhelper"
+	"github.com/stretchr/testify/require"
+)
+
+func createNamespace(t *testing.T, repo *Namespace) string {
+	ns := &models.Namespace{Slug: testhelper.RandomString(12)}
+
+	err := repo.Create(context.Background(), ns)
+	require.NoError(t, err)
+
+	return ns.Slug
+}
+
+func Test_CreateNamespace(t *testing.T) {
+	repo := NewNamespaceRepo(testDbInstance)
+
+	slug := createNamespace(t, repo)
+
+	err := repo.Create(context.Background(), &models.Namespace{Slug: slug})
+	require.ErrorIs(t, err, ErrNamespaceAlreadyExists)
+}
+
+func Test_SegmentsInNamespaces(t *testing.T) {
+	segmentRepo := NewSegmentRepo(testDbInstance)
+	namespaceRepo := NewNamespaceRepo(testDbInstance)
+
+	ctxA := namespace.WithNamespace(context.Background(), createNamespace(t, namespaceRepo))
+	ctxB := namespace.WithNamespace(context.Background(), createNamespace(t, namespaceRepo))
+
+	userId := createUser(t, NewUserRepo(testDbInstance))
+	slug := testhelper.RandomString(12)
+
+	// Один и тот же slug в разных пространствах имен
+	require.NoError(t, segmentRepo.Create(ctxA, &models.Segment{Slug: slug}))
+	require.NoError(t, segmentRepo.Create(ctxB, &models.Segment{Slug: slug}))
+	require.ErrorIs(t, segmentRepo.Create(ctxA, &models.Segment{Slug: slug}), ErrSegmentAlreadyExists)
+
+	added, _, err := segmentRepo.UpdateUserSegments(ctxA, userId, []string{slug}, 0, nil)
+	require.NoError(t, err)
+	require.Equal(t, int64(1), added)
+
+	segmentsA, err := segmentRepo.GetUserSegments(ctxA, userId)
+	require.NoError(t, err)
+	require.Len(t, segmentsA, 1)
+
+	segmentsB, err := segmentRepo.GetUserSegments(ctxB, userId)
+	require.NoError(t, err)
+	require.Empty(t, segmentsB)
+
+	// Удаление сегмента в одном пространстве имен не затрагивает другое
+	require.NoError(t, segmentRepo.DeleteBySlug(ctxB, &models.Segment{Slug: slug}))
+
+	segmentsA, err = segmentRepo.GetUserSegments(ctxA, userId)
+	require.NoError(t, err)
+	require.Len(t, segmentsA, 1)
+
+	historyB, err := segmentRepo.GetUserSegmentsAt(ctxB, userId, time.Now())
+	require.NoError(t, err)
+	require.Empty(t, historyB)
+}
+
+func Test_CreateSegmentInUnknownNamespace(t *testing.T) {
+	segmentRepo := NewSegmentRepo(testDbInstance)
+
+	ctx := namespace.WithNamespace(context.Background(), testhelper.RandomString(12))
+
+	err := segmentRepo.Create(ctx, &models.Segment{Slug: testhelper.RandomString(12)})
+	require.ErrorIs(t, err, ErrNamespaceNotFound)
+}
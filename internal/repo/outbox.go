@@ -0,0 +1,171 @@
+package repo
+
+import (
+	"context"
+	"time"
+
+	"github.com/dezzerlol/avitotech-test-2023/internal/db/models"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type Outbox struct {
+	DB *pgxpool.Pool
+}
+
+func NewOutboxRepo(db *pgxpool.Pool) *Outbox {
+	return &Outbox{DB: db}
+}
+
+// Dispatch берет до limit неотправленных событий и для каждого вызывает fn с id вебхуков, которые должны его получить.
+// События отмечаются отправленными в той же транзакции, поэтому при ошибке fn они будут взяты снова.
+// Строки блокируются с SKIP LOCKED, несколько воркеров не возьмут одно событие одновременно.
+// Возвращает число обработанных событий.
+func (r Outbox) Dispatch(ctx context.Context, limit int, fn func(event models.MembershipEvent, webhookIds []int64) error) (int, error) {
+	tx, err := r.DB.Begin(ctx)
+
+	if err != nil {
+		return 0, err
+	}
+
+	defer tx.Rollback(ctx)
+
+	query := `
+		SELECT id, namespace, segment_slug, user_id, operation, COALESCE(variant, ''), COALESCE(source, ''), COALESCE(actor, ''), COALESCE(request_id, ''), created_at
+		FROM membership_outbox
+		WHERE dispatched_at IS NULL
+		ORDER BY id
+		LIMIT $1
+		FOR UPDATE SKIP LOCKED
+	`
+
+	rows, err := tx.Query(ctx, query, limit)
+
+	if err != nil {
+		return 0, err
+	}
+
+	var (
+		events     []models.MembershipEvent
+		ids        []int64
+		namespaces []string
+	)
+
+	for rows.Next() {
+		var (
+			event     models.MembershipEvent
+			operation string
+		)
+
+		err := rows.Scan(
+			&event.ID,
+			&event.Namespace,
+			&event.Segment,
+			&event.UserID,
+			&operation,
+			&event.Variant,
+			&event.Source,
+			&event.Actor,
+			&event.RequestID,
+			&event.OccurredAt,
+		)
+
+		if err != nil {
+			rows.Close()
+			return 0, err
+		}
+
+		switch operation {
+		case "D":
+			event.Type = models.EventUserRemoved
+		case "V":
+			event.Type = models.EventVariantChanged
+		default:
+			event.Type = models.EventUserAdded
+		}
+
+		events = append(events, event)
+		ids = append(ids, event.ID)
+		namespaces = append(namespaces, event.Namespace)
+	}
+
+	rows.Close()
+
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	if len(events) == 0 {
+		return 0, nil
+	}
+
+	type hook struct {
+		id        int64
+		createdAt time.Time
+	}
+
+	hooks := map[string][]hook{}
+
+	rows, err = tx.Query(ctx, `SELECT id, namespace, created_at FROM webhooks WHERE namespace = ANY($1) ORDER BY id`, namespaces)
+
+	if err != nil {
+		return 0, err
+	}
+
+	for rows.Next() {
+		var (
+			h  hook
+			ns string
+		)
+
+		if err := rows.Scan(&h.id, &ns, &h.createdAt); err != nil {
+			rows.Close()
+			return 0, err
+		}
+
+		hooks[ns] = append(hooks[ns], h)
+	}
+
+	rows.Close()
+
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	for _, event := range events {
+		var webhookIds []int64
+
+		// Вебхук получает только события, произошедшие после его регистрации
+		for _, h := range hooks[event.Namespace] {
+			if !h.createdAt.After(event.OccurredAt) {
+				webhookIds = append(webhookIds, h.id)
+			}
+		}
+
+		if len(webhookIds) == 0 {
+			continue
+		}
+
+		if err := fn(event, webhookIds); err != nil {
+			return 0, err
+		}
+	}
+
+	_, err = tx.Exec(ctx, `UPDATE membership_outbox SET dispatched_at = now() WHERE id = ANY($1)`, ids)
+
+	if err != nil {
+		return 0, err
+	}
+
+	return len(events), tx.Commit(ctx)
+}
+
+// DeleteDispatched удаляет события, отправленные раньше before.
+func (r Outbox) DeleteDispatched(ctx context.Context, before time.Time) (int64, error) {
+	ct, err := r.DB.Exec(ctx, `DELETE FROM membership_outbox WHERE dispatched_at < $1`, before)
+
+	if err != nil {
+		return 0, err
+	}
+
+	return ct.RowsAffected(), nil
+}
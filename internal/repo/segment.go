@@ -7,7 +7,11 @@ import (
 	"strings"
 	"time"
 
+	"github.com/dezzerlol/avitotech-test-2023/internal/audit"
 	"github.com/dezzerlol/avitotech-test-2023/internal/db/models"
+	"github.com/dezzerlol/avitotech-test-2023/internal/metrics"
+	"github.com/dezzerlol/avitotech-test-2023/internal/namespace"
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
@@ -22,16 +26,44 @@ func NewSegmentRepo(db *pgxpool.Pool) *Segment {
 
 func (r Segment) Create(ctx context.Context, segment *models.Segment) error {
 	query := `
-		INSERT INTO segments (slug)
-		VALUES ($1)
+		INSERT INTO segments (namespace, slug, rule, layer, bucket_from, bucket_to)
+		VALUES ($1, $2, NULLIF($3, ''), NULLIF($4, ''), $5, $6)
 		RETURNING created_at
 	`
 
+	tx, err := r.DB.Begin(ctx)
+
+	if err != nil {
+		return err
+	}
+
+	defer tx.Rollback(ctx)
+
+	// Раскатка сегмента слоя занимает свободный диапазон бакетов
+	if segment.Layer != "" {
+		segment.Buckets, err = reserveBuckets(ctx, tx, segment.Layer, int(segment.UserPercent))
+
+		if err != nil {
+			return err
+		}
+	}
+
+	var bucketFrom, bucketTo *int
+
+	if segment.Buckets != nil {
+		bucketFrom, bucketTo = &segment.Buckets.From, &segment.Buckets.To
+	}
+
 	args := []any{
+		namespace.FromContext(ctx),
 		segment.Slug,
+		segment.Rule,
+		segment.Layer,
+		bucketFrom,
+		bucketTo,
 	}
 
-	err := r.DB.
+	err = tx.
 		QueryRow(ctx, query, args...).
 		Scan(&segment.CreatedAt)
 
@@ -42,39 +74,123 @@ func (r Segment) Create(ctx context.Context, segment *models.Segment) error {
 			if pgErr.Code == UniqueConstraintViolation {
 				return ErrSegmentAlreadyExists
 			}
+
+			if pgErr.Code == ForeignKeyViolation {
+				return ErrNamespaceNotFound
+			}
 		}
+
+		return err
+	}
+
+	if err := insertVariants(ctx, tx, segment.Slug, segment.Variants, 0); err != nil {
+		return err
 	}
 
-	return err
+	return tx.Commit(ctx)
 }
 
 func (r Segment) DeleteBySlug(ctx context.Context, segment *models.Segment) error {
 	query := `
 		DELETE FROM segments
-		WHERE slug = $1
+		WHERE namespace = $1
+		AND slug = $2
 	`
 
-	args := []any{&segment.Slug}
+	ns := namespace.FromContext(ctx)
+	args := []any{ns, &segment.Slug}
+
+	tx, err := beginWithAudit(ctx, r.DB)
+
+	if err != nil {
+		return err
+	}
+
+	defer tx.Rollback(ctx)
 
-	ct, err := r.DB.Exec(ctx, query, args...)
+	// Пользователи удаляются из сегмента до самого сегмента, а не каскадно, чтобы узнать их число для метрик.
+	// Триггер истории срабатывает так же, как при каскадном удалении.
+	members, err := tx.Exec(ctx, `DELETE FROM user_segments WHERE namespace = $1 AND segment_slug = $2`, args...)
+
+	if err != nil {
+		return err
+	}
+
+	ct, err := tx.Exec(ctx, query, args...)
+
+	if err != nil {
+		return err
+	}
 
 	if ct.RowsAffected() == 0 {
 		return ErrSegmentNotFound
 	}
 
-	return err
+	if err := tx.Commit(ctx); err != nil {
+		return err
+	}
+
+	metrics.AddMembershipChanges(ns, segment.Slug, metrics.OperationDelete, audit.FromContext(ctx).Source, int(members.RowsAffected()))
+
+	return nil
 }
 
 func (r Segment) GetUserSegments(ctx context.Context, userId int64) ([]*models.Segment, error) {
 	query := `
-		SELECT slug
+		SELECT slug, COALESCE(us.variant, '')
 		FROM segments s
 		JOIN user_segments us
-		on s.slug = us.segment_slug
-		WHERE us.user_id = $1
+		on s.namespace = us.namespace
+		AND s.slug = us.segment_slug
+		WHERE us.namespace = $1
+		AND us.user_id = $2
+	`
+
+	args := []any{namespace.FromContext(ctx), userId}
+
+	rows, err := r.DB.Query(ctx, query, args...)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	var segments []*models.Segment
+
+	for rows.Next() {
+		var segment models.Segment
+
+		err := rows.Scan(
+			&segment.Slug,
+			&segment.Variant,
+		)
+
+		if err != nil {
+			return nil, err
+		}
+
+		segments = append(segments, &segment)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return segments, nil
+}
+
+// GetRuleSegments возвращает динамические сегменты пространства имен с их правилами.
+func (r Segment) GetRuleSegments(ctx context.Context) ([]*models.Segment, error) {
+	query := `
+		SELECT slug, rule
+		FROM segments
+		WHERE namespace = $1
+		AND rule IS NOT NULL
+		ORDER BY slug
 	`
 
-	args := []any{userId}
+	args := []any{namespace.FromContext(ctx)}
 
 	rows, err := r.DB.Query(ctx, query, args...)
 
@@ -82,6 +198,8 @@ func (r Segment) GetUserSegments(ctx context.Context, userId int64) ([]*models.S
 		return nil, err
 	}
 
+	defer rows.Close()
+
 	var segments []*models.Segment
 
 	for rows.Next() {
@@ -89,6 +207,7 @@ func (r Segment) GetUserSegments(ctx context.Context, userId int64) ([]*models.S
 
 		err := rows.Scan(
 			&segment.Slug,
+			&segment.Rule,
 		)
 
 		if err != nil {
@@ -105,25 +224,140 @@ func (r Segment) GetUserSegments(ctx context.Context, userId int64) ([]*models.S
 	return segments, nil
 }
 
-func (r Segment) AddUserSegments(ctx context.Context, userId int64, addSegments []string, ttl int64) (int64, error) {
+// GetUsersSegments возвращает сегменты нескольких пользователей одним запросом.
+// В результат попадают только существующие пользователи, у пользователя без сегментов пустой список.
+// Если slugs не пустой, возвращаются только перечисленные сегменты.
+func (r Segment) GetUsersSegments(ctx context.Context, userIds []int64, slugs []string) (map[int64][]*models.Segment, error) {
+	query := `
+		SELECT u.id, us.segment_slug, COALESCE(us.variant, '')
+		FROM users u
+		LEFT JOIN user_segments us
+		ON us.namespace = $1
+		AND us.user_id = u.id
+		AND (cardinality($3::text[]) = 0 OR us.segment_slug = ANY($3))
+		WHERE u.id = ANY($2)
+		ORDER BY u.id, us.segment_slug
+	`
+
+	if slugs == nil {
+		slugs = []string{}
+	}
+
+	args := []any{namespace.FromContext(ctx), userIds, slugs}
+
+	rows, err := r.DB.Query(ctx, query, args...)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	users := make(map[int64][]*models.Segment, len(userIds))
+
+	for rows.Next() {
+		var (
+			userId  int64
+			slug    *string
+			variant string
+		)
+
+		err := rows.Scan(
+			&userId,
+			&slug,
+			&variant,
+		)
+
+		if err != nil {
+			return nil, err
+		}
+
+		if _, ok := users[userId]; !ok {
+			users[userId] = []*models.Segment{}
+		}
+
+		if slug != nil {
+			users[userId] = append(users[userId], &models.Segment{Slug: *slug, Variant: variant})
+		}
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return users, nil
+}
+
+// UpdateUserSegments удаляет и добавляет сегменты пользователя в одной транзакции.
+// Сначала удаляются сегменты, чтобы пользователя можно было перевести в другой сегмент слоя одним запросом.
+// Если добавление не удалось, удаление тоже откатывается.
+func (r Segment) UpdateUserSegments(ctx context.Context, userId int64, addSegments []string, ttl int64, deleteSegments []string) (int64, int64, error) {
+	tx, err := beginWithAudit(ctx, r.DB)
+
+	if err != nil {
+		return 0, 0, err
+	}
+
+	defer tx.Rollback(ctx)
+
+	var added, deleted []string
+
+	if len(deleteSegments) > 0 {
+		query, args := deleteUserSegmentsQuery(ctx, userId, deleteSegments)
+
+		rows, err := tx.Query(ctx, query, args...)
+
+		if err != nil {
+			return 0, 0, err
+		}
+
+		deleted, err = pgx.CollectRows(rows, pgx.RowTo[string])
+
+		if err != nil {
+			return 0, 0, err
+		}
+	}
+
+	if len(addSegments) > 0 {
+		added, err = insertUserSegments(ctx, tx, userId, addSegments, ttl)
+
+		if err != nil {
+			return 0, 0, err
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return 0, 0, err
+	}
+
+	addMembershipChanges(ctx, deleted, metrics.OperationDelete)
+	addMembershipChanges(ctx, added, metrics.OperationAdd)
+
+	return int64(len(added)), int64(len(deleted)), nil
+}
+
+// insertUserSegments добавляет пользователя в сегменты в транзакции tx и возвращает сегменты, в которые он добавлен.
+func insertUserSegments(ctx context.Context, tx pgx.Tx, userId int64, addSegments []string, ttl int64) ([]string, error) {
 	var sb strings.Builder
 
 	// Сначала получаем slug сегментов, которые нужно добавить
 	// Потом вставляем в user_segments
 	sb.WriteString(`
-	INSERT INTO user_segments (segment_slug, user_id, expire_at)
-	SELECT s.slug, $1, $2
+	INSERT INTO user_segments (namespace, segment_slug, user_id, expire_at)
+	SELECT s.namespace, s.slug, $1, $2
 	FROM segments s
-	WHERE s.slug IN (
+	WHERE s.namespace = $3
+	AND s.slug IN (
 	`)
 
-	args := []any{userId, models.NewExpireDate(ttl)}
+	ns := namespace.FromContext(ctx)
+	args := []any{userId, models.NewExpireDate(ttl), ns}
 
 	// Готовим аргументы для запроса
-	// Добавялем 3 потому что первый аргумент это userId
+	// Добавялем 4 потому что первые аргументы это userId, expire_at и namespace
 	for i, slug := range addSegments {
 		args = append(args, slug)
-		sb.WriteString(fmt.Sprintf("$%d", i+3))
+		sb.WriteString(fmt.Sprintf("$%d", i+4))
 
 		if i != len(addSegments)-1 {
 			sb.WriteString(",")
@@ -131,14 +365,85 @@ func (r Segment) AddUserSegments(ctx context.Context, userId int64, addSegments
 	}
 
 	// Закрываем строку запроса
-	// Добавляем пропуск конфликта, если сегмент уже есть у пользователя
-	sb.WriteString(") ON CONFLICT DO NOTHING")
+	// Добавляем пропуск конфликта, если сегмент уже есть у пользователя.
+	// Второй сегмент слоя пропускать нельзя, он нарушает user_segments_layer_idx
+	// Возвращаем добавленные сегменты для метрик
+	sb.WriteString(") ON CONFLICT (namespace, user_id, segment_slug) DO NOTHING RETURNING segment_slug")
 
 	query := sb.String()
 
-	ct, err := r.DB.Exec(ctx, query, args...)
+	if err := checkLayerConflicts(ctx, tx, ns, userId, addSegments); err != nil {
+		return nil, err
+	}
+
+	rows, err := tx.Query(ctx, query, args...)
+
+	if err != nil {
+		return nil, err
+	}
+
+	added, err := pgx.CollectRows(rows, pgx.RowTo[string])
+
+	if err != nil {
+		// Другой запрос успел добавить пользователя в сегмент того же слоя после проверки
+		var pgErr *pgconn.PgError
+
+		if errors.As(err, &pgErr) && pgErr.ConstraintName == "user_segments_layer_idx" {
+			return nil, ErrLayerConflict
+		}
+
+		return nil, err
+	}
+
+	return added, nil
+}
+
+// checkLayerConflicts возвращает *LayerConflictError, если пользователь уже состоит в другом сегменте слоя
+// одного из добавляемых сегментов или добавляемые сегменты относятся к одному слою.
+func checkLayerConflicts(ctx context.Context, tx pgx.Tx, ns string, userId int64, addSegments []string) error {
+	query := `
+		SELECT s.layer, s.slug, COALESCE(us.segment_slug, '')
+		FROM segments s
+		LEFT JOIN user_segments us
+		ON us.namespace = s.namespace
+		AND us.user_id = $2
+		AND us.layer = s.layer
+		WHERE s.namespace = $1
+		AND s.slug = ANY($3)
+		AND s.layer IS NOT NULL
+		ORDER BY s.layer, s.slug
+	`
+
+	rows, err := tx.Query(ctx, query, ns, userId, addSegments)
+
+	if err != nil {
+		return err
+	}
+
+	defer rows.Close()
+
+	// Первый добавляемый сегмент каждого слоя
+	requested := make(map[string]string)
+
+	for rows.Next() {
+		var layer, slug, member string
+
+		if err := rows.Scan(&layer, &slug, &member); err != nil {
+			return err
+		}
+
+		if member != "" && member != slug {
+			return &LayerConflictError{Layer: layer, Segment: slug, Conflicting: member}
+		}
+
+		if first, ok := requested[layer]; ok && first != slug {
+			return &LayerConflictError{Layer: layer, Segment: slug, Conflicting: first}
+		}
+
+		requested[layer] = slug
+	}
 
-	return ct.RowsAffected(), err
+	return rows.Err()
 }
 
 func (r Segment) AddRndUsersSegment(ctx context.Context, slug string, percent int8) error {
@@ -146,42 +451,92 @@ func (r Segment) AddRndUsersSegment(ctx context.Context, slug string, percent in
 	// И создаем записи в user_segments
 	// В случае если запись уже существует пропускаем
 	query := `
-	INSERT INTO user_segments (segment_slug, user_id)
-	SELECT s.slug, u.id 
+	INSERT INTO user_segments (namespace, segment_slug, user_id)
+	SELECT s.namespace, s.slug, u.id 
 	FROM users u
-	JOIN segments s ON s.slug = $1
+	JOIN segments s ON s.namespace = $3 AND s.slug = $1
 	ORDER BY random() 
 	LIMIT (SELECT count(1) FROM users) * ($2/100.0)
 	ON CONFLICT DO NOTHING`
 
-	args := []any{slug, percent}
+	ns := namespace.FromContext(ctx)
+	args := []any{slug, percent, ns}
+
+	ct, err := execWithAudit(ctx, r.DB, query, args...)
+
+	if err != nil {
+		return err
+	}
+
+	metrics.AddMembershipChanges(ns, slug, metrics.OperationAdd, audit.FromContext(ctx).Source, int(ct.RowsAffected()))
+
+	return nil
+}
+
+// AddLayerUsersSegment добавляет сегмент слоя пользователям, бакет которых входит в диапазон сегмента.
+// Пользователи, которые уже состоят в другом сегменте слоя, пропускаются.
+func (r Segment) AddLayerUsersSegment(ctx context.Context, slug string) error {
+	query := `
+	INSERT INTO user_segments (namespace, segment_slug, user_id)
+	SELECT s.namespace, s.slug, u.id
+	FROM segments s
+	JOIN users u ON layer_bucket(s.layer, u.id) >= s.bucket_from AND layer_bucket(s.layer, u.id) < s.bucket_to
+	WHERE s.namespace = $2
+	AND s.slug = $1
+	ON CONFLICT DO NOTHING`
+
+	ns := namespace.FromContext(ctx)
+	args := []any{slug, ns}
+
+	ct, err := execWithAudit(ctx, r.DB, query, args...)
 
-	_, err := r.DB.Exec(ctx, query, args...)
+	if err != nil {
+		return err
+	}
 
-	return err
+	metrics.AddMembershipChanges(ns, slug, metrics.OperationAdd, audit.FromContext(ctx).Source, int(ct.RowsAffected()))
+
+	return nil
 }
 
 func (r Segment) DeleteUserSegments(ctx context.Context, userId int64, deleteSegments []string) (int64, error) {
+	query, args := deleteUserSegmentsQuery(ctx, userId, deleteSegments)
+
+	deleted, err := queryStringsWithAudit(ctx, r.DB, query, args...)
+
+	if err != nil {
+		return 0, err
+	}
+
+	addMembershipChanges(ctx, deleted, metrics.OperationDelete)
+
+	return int64(len(deleted)), nil
+}
+
+// deleteUserSegmentsQuery возвращает запрос удаления пользователя из сегментов, который возвращает сегменты, из которых он удален.
+func deleteUserSegmentsQuery(ctx context.Context, userId int64, deleteSegments []string) (string, []any) {
 	var sb strings.Builder
 
 	// Сначала получаем slug сегментов, которые нужно удалить
 	// Потом удаляем из user_segments
 	sb.WriteString(`
 	DELETE FROM user_segments us
-	WHERE us.user_id = $1
+	WHERE us.namespace = $2
+	AND us.user_id = $1
 	AND us.segment_slug IN (
 		SELECT s.slug
 		FROM segments s
-		WHERE s.slug IN (
+		WHERE s.namespace = $2
+		AND s.slug IN (
 	`)
 
-	args := []any{userId}
+	args := []any{userId, namespace.FromContext(ctx)}
 
 	// Готовим аргументы для запроса
-	// Добавялем 2 потому что первый аргумент это userId
+	// Добавялем 3 потому что первые аргументы это userId и namespace
 	for i, slug := range deleteSegments {
 		args = append(args, slug)
-		sb.WriteString(fmt.Sprintf("$%d", i+2))
+		sb.WriteString(fmt.Sprintf("$%d", i+3))
 
 		if i != len(deleteSegments)-1 {
 			sb.WriteString(",")
@@ -189,55 +544,36 @@ func (r Segment) DeleteUserSegments(ctx context.Context, userId int64, deleteSeg
 	}
 
 	// Закрываем строку запроса
-	sb.WriteString("))")
+	// Возвращаем удаленные сегменты для метрик
+	sb.WriteString(")) RETURNING us.segment_slug")
 
-	query := sb.String()
-
-	ct, err := r.DB.Exec(ctx, query, args...)
-
-	return ct.RowsAffected(), err
+	return sb.String(), args
 }
 
-func (r Segment) GetUserHistory(ctx context.Context, userId int64, date time.Time) ([]*models.UserHistory, error) {
-	query := `
-		SELECT user_id, segment_slug, operation, executed_at
-		FROM user_segment_history
-		WHERE user_id = $1
-		AND date_part('year', executed_at) = $2 
-		AND date_part('month', executed_at) = $3`
-
-	args := []any{
-		userId,
-		date.Year(),
-		date.Month(),
-	}
-
-	rows, err := r.DB.Query(ctx, query, args...)
+// addMembershipChanges учитывает в метриках добавление или удаление пользователя в каждом из сегментов.
+func addMembershipChanges(ctx context.Context, slugs []string, operation string) {
+	ns := namespace.FromContext(ctx)
+	source := audit.FromContext(ctx).Source
 
-	if err != nil {
-		return nil, err
+	for _, slug := range slugs {
+		metrics.AddMembershipChanges(ns, slug, operation, source, 1)
 	}
+}
 
+// GetUserHistory возвращает историю пользователя за месяц date. Границы месяца берутся в часовом поясе date,
+// как и в StreamUserHistory, поэтому файл отчета и выгрузка содержат одни и те же строки.
+func (r Segment) GetUserHistory(ctx context.Context, userId int64, date time.Time) ([]*models.UserHistory, error) {
 	var history []*models.UserHistory
 
-	for rows.Next() {
-		var userHistory models.UserHistory
-
-		err := rows.Scan(
-			&userHistory.UserID,
-			&userHistory.SegmentSlug,
-			&userHistory.Operation,
-			&userHistory.ExecutedAt,
-		)
-
-		if err != nil {
-			return nil, err
-		}
-
+	err := r.StreamUserHistory(ctx, userId, date, func(h *models.UserHistory) error {
+		// StreamUserHistory переиспользует запись между строками
+		userHistory := *h
 		history = append(history, &userHistory)
-	}
 
-	if err := rows.Err(); err != nil {
+		return nil
+	})
+
+	if err != nil {
 		return nil, err
 	}
 
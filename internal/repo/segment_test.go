@@ -2,11 +2,14 @@ package repo
 
 import (
 	"context"
+	"encoding/json"
 	"sort"
 	"testing"
 	"time"
 
+	"github.com/dezzerlol/avitotech-test-2023/internal/audit"
 	"github.com/dezzerlol/avitotech-test-2023/internal/db/models"
+	"github.com/dezzerlol/avitotech-test-2023/internal/namespace"
 	"github.com/dezzerlol/avitotech-test-2023/pkg/testhelper"
 	"github.com/stretchr/testify/require"
 )
@@ -32,7 +35,7 @@ func addUserSegments(t *testing.T, repo *Segment, userId int64) []string {
 		segments[i] = segment.Slug
 	}
 
-	addedSegments, err := repo.AddUserSegments(context.Background(), userId, segments, 0)
+	addedSegments, _, err := repo.UpdateUserSegments(context.Background(), userId, segments, 0, nil)
 	require.NoError(t, err)
 	require.Equal(t, len(segments), int(addedSegments))
 
@@ -54,7 +57,7 @@ func Test_DeleteSegment(t *testing.T) {
 	require.NoError(t, err)
 }
 
-func Test_AddUserSegments(t *testing.T) {
+func Test_UpdateUserSegments(t *testing.T) {
 	repo := NewSegmentRepo(testDbInstance)
 
 	userId := createUser(t, NewUserRepo(testDbInstance))
@@ -80,6 +83,27 @@ func Test_GetUserSegments(t *testing.T) {
 	}
 }
 
+func Test_GetUsersSegments(t *testing.T) {
+	repo := NewSegmentRepo(testDbInstance)
+	userRepo := NewUserRepo(testDbInstance)
+
+	userId := createUser(t, userRepo)
+	segments := addUserSegments(t, repo, userId)
+	emptyUserId := createUser(t, userRepo)
+
+	users, err := repo.GetUsersSegments(context.Background(), []int64{userId, emptyUserId, 1 << 40}, nil)
+	require.NoError(t, err)
+	require.Len(t, users, 2)
+	require.Len(t, users[userId], len(segments))
+	require.Empty(t, users[emptyUserId])
+
+	users, err = repo.GetUsersSegments(context.Background(), []int64{userId, emptyUserId}, segments[:1])
+	require.NoError(t, err)
+	require.Len(t, users[userId], 1)
+	require.Equal(t, segments[0], users[userId][0].Slug)
+	require.Empty(t, users[emptyUserId])
+}
+
 func Test_DeleteUserSegments(t *testing.T) {
 	repo := NewSegmentRepo(testDbInstance)
 
@@ -103,3 +127,339 @@ func Test_GetUserHistory(t *testing.T) {
 	require.NoError(t, err)
 	require.NotEmpty(t, history)
 }
+
+func Test_GetUserHistory_Location(t *testing.T) {
+	repo := NewSegmentRepo(testDbInstance)
+
+	userId := createUser(t, NewUserRepo(testDbInstance))
+
+	moscow, err := time.LoadLocation("Europe/Moscow")
+	require.NoError(t, err)
+
+	// 31 августа 22:30 UTC - это уже 1 сентября по Москве
+	executedAt := time.Date(2023, time.August, 31, 22, 30, 0, 0, time.UTC)
+
+	_, err = testDbInstance.Exec(context.Background(), `
+		INSERT INTO user_segment_history (namespace, segment_slug, user_id, operation, executed_at)
+		VALUES ($1, $2, $3, 'I', $4)`,
+		namespace.Default, testhelper.RandomString(12), userId, executedAt,
+	)
+	require.NoError(t, err)
+
+	september := time.Date(2023, time.September, 1, 0, 0, 0, 0, moscow)
+
+	history, err := repo.GetUserHistory(context.Background(), userId, september)
+	require.NoError(t, err)
+	require.Len(t, history, 1)
+
+	var streamed int
+
+	err = repo.StreamUserHistory(context.Background(), userId, september, func(*models.UserHistory) error {
+		streamed++
+		return nil
+	})
+	require.NoError(t, err)
+	require.Equal(t, len(history), streamed)
+
+	history, err = repo.GetUserHistory(context.Background(), userId, time.Date(2023, time.August, 1, 0, 0, 0, 0, moscow))
+	require.NoError(t, err)
+	require.Empty(t, history)
+}
+
+func Test_StreamUserHistory(t *testing.T) {
+	repo := NewSegmentRepo(testDbInstance)
+
+	userId := createUser(t, NewUserRepo(testDbInstance))
+	segments := addUserSegments(t, repo, userId)
+
+	var streamed []string
+
+	err := repo.StreamUserHistory(context.Background(), userId, time.Now(), func(history *models.UserHistory) error {
+		require.Equal(t, userId, history.UserID)
+		require.Equal(t, "I", history.Operation)
+
+		streamed = append(streamed, history.SegmentSlug)
+		return nil
+	})
+
+	require.NoError(t, err)
+	require.ElementsMatch(t, segments, streamed)
+}
+
+func Test_GetSegmentEventsAfter(t *testing.T) {
+	repo := NewSegmentRepo(testDbInstance)
+
+	userId := createUser(t, NewUserRepo(testDbInstance))
+	segments := addUserSegments(t, repo, userId)
+
+	events, err := repo.GetSegmentEventsAfter(context.Background(), 0, models.SegmentEventFilter{UserID: userId}, 10)
+	require.NoError(t, err)
+	require.Len(t, events, len(segments))
+	require.Equal(t, "default", events[0].Namespace)
+
+	// Продолжение после первого события, только по одному сегменту
+	events, err = repo.GetSegmentEventsAfter(context.Background(), events[0].ID, models.SegmentEventFilter{SegmentSlug: segments[2], UserID: userId}, 10)
+	require.NoError(t, err)
+	require.Len(t, events, 1)
+	require.Equal(t, segments[2], events[0].SegmentSlug)
+	require.Equal(t, "I", events[0].Operation)
+}
+
+func Test_GetChangesAfter(t *testing.T) {
+	repo := NewSegmentRepo(testDbInstance)
+	ctx := context.Background()
+
+	userId := createUser(t, NewUserRepo(testDbInstance))
+	first := createSegment(t, repo)
+	second := createSegment(t, repo)
+
+	_, watermark, err := repo.GetChangesAfter(ctx, 0, 1)
+	require.NoError(t, err)
+
+	// Незавершенная транзакция получает id раньше, а коммитится позже
+	tx, err := testDbInstance.Begin(ctx)
+	require.NoError(t, err)
+	defer tx.Rollback(ctx)
+
+	_, err = tx.Exec(ctx, `INSERT INTO user_segments (namespace, user_id, segment_slug) VALUES ('default', $1, $2)`, userId, first.Slug)
+	require.NoError(t, err)
+
+	_, _, err = repo.UpdateUserSegments(ctx, userId, []string{second.Slug}, 0, nil)
+	require.NoError(t, err)
+
+	changes, blocked, err := repo.GetChangesAfter(ctx, watermark, 1000)
+	require.NoError(t, err)
+
+	for _, change := range changes {
+		require.NotEqual(t, userId, change.UserID)
+	}
+
+	require.NoError(t, tx.Commit(ctx))
+
+	changes, next, err := repo.GetChangesAfter(ctx, watermark, 1000)
+	require.NoError(t, err)
+	require.GreaterOrEqual(t, next, blocked)
+
+	var slugs []string
+
+	for _, change := range changes {
+		if change.UserID == userId {
+			slugs = append(slugs, change.SegmentSlug)
+		}
+	}
+
+	require.Equal(t, []string{first.Slug, second.Slug}, slugs)
+}
+
+func Test_SegmentEventsNotify(t *testing.T) {
+	repo := NewSegmentRepo(testDbInstance)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	conn, err := testDbInstance.Acquire(ctx)
+	require.NoError(t, err)
+	defer conn.Release()
+
+	_, err = conn.Exec(ctx, "LISTEN segment_events")
+	require.NoError(t, err)
+	defer conn.Exec(context.Background(), "UNLISTEN *")
+
+	userId := createUser(t, NewUserRepo(testDbInstance))
+	segment := createSegment(t, repo)
+
+	_, _, err = repo.UpdateUserSegments(audit.WithMeta(ctx, audit.Meta{Source: audit.SourceAPI, RequestID: "req-1"}), userId, []string{segment.Slug}, 0, nil)
+	require.NoError(t, err)
+
+	// Другие тесты работают с той же базой, поэтому ждем уведомление о своем пользователе
+	for {
+		notification, err := conn.Conn().WaitForNotification(ctx)
+		require.NoError(t, err)
+
+		var event models.SegmentEvent
+		require.NoError(t, json.Unmarshal([]byte(notification.Payload), &event))
+
+		if event.UserID != userId {
+			continue
+		}
+
+		require.NotZero(t, event.ID)
+		require.Equal(t, segment.Slug, event.SegmentSlug)
+		require.Equal(t, "I", event.Operation)
+		require.Equal(t, audit.SourceAPI, event.Source)
+		require.Equal(t, "req-1", event.RequestID)
+
+		return
+	}
+}
+
+func Test_GetSegmentHistoryBuckets(t *testing.T) {
+	repo := NewSegmentRepo(testDbInstance)
+
+	segment := createSegment(t, repo)
+
+	for i := 0; i < 3; i++ {
+		userId := createUser(t, NewUserRepo(testDbInstance))
+
+		_, _, err := repo.UpdateUserSegments(context.Background(), userId, []string{segment.Slug}, 0, nil)
+		require.NoError(t, err)
+
+		if i == 0 {
+			_, err = repo.DeleteUserSegments(context.Background(), userId, []string{segment.Slug})
+			require.NoError(t, err)
+		}
+	}
+
+	now := time.Now()
+
+	filter := models.SegmentHistoryFilter{
+		SegmentSlug: segment.Slug,
+		From:        now.Add(-time.Hour),
+		To:          now.Add(time.Hour),
+		Bucket:      models.BucketDay,
+		Location:    time.UTC,
+	}
+
+	buckets, err := repo.GetSegmentHistoryBuckets(context.Background(), filter)
+	require.NoError(t, err)
+	require.NotEmpty(t, buckets)
+
+	var entered, left int64
+	for _, b := range buckets {
+		entered += b.Entered
+		left += b.Left
+	}
+
+	require.Equal(t, int64(3), entered)
+	require.Equal(t, int64(1), left)
+
+	members, err := repo.CountSegmentMembersAt(context.Background(), segment.Slug, filter.To)
+	require.NoError(t, err)
+	require.Equal(t, int64(2), members)
+
+	var events int
+
+	err = repo.StreamSegmentHistory(context.Background(), filter, 2, func(history *models.UserHistory) error {
+		require.Equal(t, segment.Slug, history.SegmentSlug)
+
+		events++
+		return nil
+	})
+
+	require.NoError(t, err)
+	require.Equal(t, 2, events)
+}
+
+func Test_HistorySource(t *testing.T) {
+	repo := NewSegmentRepo(testDbInstance)
+
+	userId := createUser(t, NewUserRepo(testDbInstance))
+	segment := createSegment(t, repo)
+
+	ctx := audit.WithMeta(context.Background(), audit.Meta{
+		Source:    audit.SourceAPI,
+		Actor:     "test",
+		RequestID: "request-1",
+	})
+
+	_, _, err := repo.UpdateUserSegments(ctx, userId, []string{segment.Slug}, 0, nil)
+	require.NoError(t, err)
+
+	// Удаление сегмента каскадно удаляет его у пользователя
+	err = repo.DeleteBySlug(audit.WithSource(ctx, audit.SourceSegmentDelete), segment)
+	require.NoError(t, err)
+
+	history, err := repo.GetUserHistory(context.Background(), userId, time.Now())
+	require.NoError(t, err)
+	require.Len(t, history, 2)
+
+	require.Equal(t, "I", history[0].Operation)
+	require.Equal(t, audit.SourceAPI, history[0].Source)
+	require.Equal(t, "test", history[0].Actor)
+	require.Equal(t, "request-1", history[0].RequestID)
+
+	require.Equal(t, "D", history[1].Operation)
+	require.Equal(t, audit.SourceSegmentDelete, history[1].Source)
+}
+
+func Test_GetUserSegmentsAt(t *testing.T) {
+	repo := NewSegmentRepo(testDbInstance)
+
+	userId := createUser(t, NewUserRepo(testDbInstance))
+	segments := addUserSegments(t, repo, userId)
+
+	at := time.Now()
+
+	// Изменения после at не должны влиять на результат
+	_, err := repo.DeleteUserSegments(context.Background(), userId, segments[:1])
+	require.NoError(t, err)
+
+	err = repo.DeleteBySlug(context.Background(), &models.Segment{Slug: segments[1]})
+	require.NoError(t, err)
+
+	remaining := segments[2]
+
+	userSegments, err := repo.GetUserSegmentsAt(context.Background(), userId, at)
+	require.NoError(t, err)
+	require.Len(t, userSegments, len(segments))
+
+	sort.Strings(segments)
+
+	for i, segment := range userSegments {
+		require.Equal(t, segments[i], segment.Slug)
+	}
+
+	userSegments, err = repo.GetUserSegmentsAt(context.Background(), userId, time.Now())
+	require.NoError(t, err)
+	require.Len(t, userSegments, 1)
+	require.Equal(t, remaining, userSegments[0].Slug)
+}
+
+func Test_GetUserSegmentsAt_RecreatedSegment(t *testing.T) {
+	repo := NewSegmentRepo(testDbInstance)
+	ctx := context.Background()
+
+	userId := createUser(t, NewUserRepo(testDbInstance))
+	segment := createSegment(t, repo)
+
+	_, _, err := repo.UpdateUserSegments(ctx, userId, []string{segment.Slug}, 0, nil)
+	require.NoError(t, err)
+
+	before := time.Now()
+
+	// Сегмент удален и создан заново с тем же slug
+	require.NoError(t, repo.DeleteBySlug(ctx, segment))
+	require.NoError(t, repo.Create(ctx, &models.Segment{Slug: segment.Slug}))
+
+	userSegments, err := repo.GetUserSegmentsAt(ctx, userId, before)
+	require.NoError(t, err)
+	require.Len(t, userSegments, 1)
+	require.True(t, userSegments[0].Deleted)
+
+	// Членство в новом сегменте не помечается удаленным
+	_, _, err = repo.UpdateUserSegments(ctx, userId, []string{segment.Slug}, 0, nil)
+	require.NoError(t, err)
+
+	userSegments, err = repo.GetUserSegmentsAt(ctx, userId, time.Now())
+	require.NoError(t, err)
+	require.Len(t, userSegments, 1)
+	require.False(t, userSegments[0].Deleted)
+}
+
+func Test_GetRuleSegments(t *testing.T) {
+	// Отдельное пространство имен, чтобы не видеть динамические сегменты других тестов
+	ctx := namespace.WithNamespace(context.Background(), createNamespace(t, NewNamespaceRepo(testDbInstance)))
+	repo := NewSegmentRepo(testDbInstance)
+
+	static := &models.Segment{Slug: testhelper.RandomString(12)}
+	require.NoError(t, repo.Create(ctx, static))
+
+	dynamic := &models.Segment{Slug: testhelper.RandomString(12), Rule: `city == "msk"`}
+	require.NoError(t, repo.Create(ctx, dynamic))
+
+	segments, err := repo.GetRuleSegments(ctx)
+	require.NoError(t, err)
+	require.Len(t, segments, 1)
+	require.Equal(t, dynamic.Slug, segments[0].Slug)
+	require.Equal(t, dynamic.Rule, segments[0].Rule)
+}
@@ -2,7 +2,10 @@ package repo
 
 import (
 	"context"
+	"errors"
 
+	"github.com/dezzerlol/avitotech-test-2023/internal/db/models"
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
@@ -48,3 +51,123 @@ func (r User) CheckUserExist(ctx context.Context, userId int64) (bool, error) {
 
 	return exists, err
 }
+
+// GetAttributes возвращает атрибуты пользователя или ErrUserNotFound.
+func (r User) GetAttributes(ctx context.Context, userId int64) (map[string]any, error) {
+	query := `
+		SELECT attributes
+		FROM users
+		WHERE id = $1
+	`
+
+	args := []any{userId}
+
+	var attributes map[string]any
+
+	err := r.DB.
+		QueryRow(ctx, query, args...).
+		Scan(&attributes)
+
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrUserNotFound
+	}
+
+	return attributes, err
+}
+
+// GetUsersAttributes возвращает атрибуты нескольких пользователей. Несуществующих пользователей в результате нет.
+func (r User) GetUsersAttributes(ctx context.Context, userIds []int64) (map[int64]map[string]any, error) {
+	query := `
+		SELECT id, attributes
+		FROM users
+		WHERE id = ANY($1)
+	`
+
+	args := []any{userIds}
+
+	rows, err := r.DB.Query(ctx, query, args...)
+
+	if err != nil {
+		return nil, err
+	}
+
+	users := make(map[int64]map[string]any, len(userIds))
+
+	for rows.Next() {
+		var (
+			userId     int64
+			attributes map[string]any
+		)
+
+		err := rows.Scan(
+			&userId,
+			&attributes,
+		)
+
+		if err != nil {
+			return nil, err
+		}
+
+		users[userId] = attributes
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return users, nil
+}
+
+// UpsertAttributes создает пользователя с заданным id или обновляет его атрибуты.
+// Если merge, то атрибуты дописываются к существующим, иначе заменяют их. Атрибуты со значением null удаляются.
+// Вложенные объекты при merge заменяются целиком.
+func (r User) UpsertAttributes(ctx context.Context, userId int64, attributes map[string]any, merge bool) (*models.User, error) {
+	query := `
+		INSERT INTO users (id, attributes)
+		VALUES ($1, jsonb_strip_nulls($2::jsonb))
+		ON CONFLICT (id) DO UPDATE
+		SET attributes = jsonb_strip_nulls(CASE WHEN $3::boolean THEN users.attributes || $2::jsonb ELSE $2::jsonb END),
+			updated_at = now()
+		RETURNING id, attributes, updated_at
+	`
+
+	if attributes == nil {
+		attributes = map[string]any{}
+	}
+
+	args := []any{userId, attributes, merge}
+
+	tx, err := r.DB.Begin(ctx)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer tx.Rollback(ctx)
+
+	// Последовательность сдвигается до вставки, иначе CreateUser может выдать тот же id.
+	// Блокировка не дает параллельным запросам сдвинуть последовательность назад.
+	_, err = tx.Exec(ctx, `SELECT pg_advisory_xact_lock(hashtext('users_id_seq'))`)
+
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = tx.Exec(ctx, `SELECT setval('users_id_seq', $1) WHERE $1 > (SELECT last_value FROM users_id_seq)`, userId)
+
+	if err != nil {
+		return nil, err
+	}
+
+	var user models.User
+
+	err = tx.
+		QueryRow(ctx, query, args...).
+		Scan(&user.Id, &user.Attributes, &user.UpdatedAt)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &user, tx.Commit(ctx)
+}
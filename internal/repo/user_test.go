@@ -32,3 +32,43 @@ func Test_CheckUserExist(t *testing.T) {
 	require.NoError(t, err)
 	require.True(t, exists)
 }
+
+func Test_UpsertAttributes(t *testing.T) {
+	ctx := context.Background()
+	repo := NewUserRepo(testDbInstance)
+
+	// id больше выданных последовательностью, следующий CreateUser не должен с ним совпасть
+	userId := createUser(t, repo) + 1000
+
+	user, err := repo.UpsertAttributes(ctx, userId, map[string]any{"city": "msk", "age": 27, "beta": nil}, false)
+	require.NoError(t, err)
+	require.Equal(t, int(userId), user.Id)
+	require.Equal(t, map[string]any{"city": "msk", "age": float64(27)}, user.Attributes)
+	require.NotEmpty(t, user.UpdatedAt)
+
+	require.Greater(t, createUser(t, repo), userId)
+
+	user, err = repo.UpsertAttributes(ctx, userId, map[string]any{"platform": "ios", "age": nil}, true)
+	require.NoError(t, err)
+	require.Equal(t, map[string]any{"city": "msk", "platform": "ios"}, user.Attributes)
+
+	user, err = repo.UpsertAttributes(ctx, userId, map[string]any{"platform": "android"}, false)
+	require.NoError(t, err)
+	require.Equal(t, map[string]any{"platform": "android"}, user.Attributes)
+
+	attributes, err := repo.GetAttributes(ctx, userId)
+	require.NoError(t, err)
+	require.Equal(t, user.Attributes, attributes)
+
+	otherId := createUser(t, repo)
+
+	users, err := repo.GetUsersAttributes(ctx, []int64{userId, otherId, -1})
+	require.NoError(t, err)
+	require.Equal(t, map[int64]map[string]any{
+		userId:  {"platform": "android"},
+		otherId: {},
+	}, users)
+
+	_, err = repo.GetAttributes(ctx, -1)
+	require.ErrorIs(t, err, ErrUserNotFound)
+}
@@ -0,0 +1,252 @@
+package repo
+
+import (
+	"context"
+	"errors"
+	"slices"
+
+	"github.com/dezzerlol/avitotech-test-2023/internal/audit"
+	"github.com/dezzerlol/avitotech-test-2023/internal/db/models"
+	"github.com/dezzerlol/avitotech-test-2023/internal/metrics"
+	"github.com/dezzerlol/avitotech-test-2023/internal/namespace"
+	"github.com/jackc/pgx/v5"
+)
+
+// insertVariants добавляет варианты сегмента, начиная с позиции from. Позиция задает порядок диапазонов бакетов вариантов.
+func insertVariants(ctx context.Context, tx pgx.Tx, slug string, variants []*models.SegmentVariant, from int) error {
+	query := `
+		INSERT INTO segment_variants (namespace, segment_slug, name, weight, position)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (namespace, segment_slug, name) DO UPDATE SET weight = EXCLUDED.weight
+	`
+
+	ns := namespace.FromContext(ctx)
+
+	batch := &pgx.Batch{}
+
+	for i, variant := range variants {
+		batch.Queue(query, ns, slug, variant.Name, variant.Weight, from+i)
+	}
+
+	return tx.SendBatch(ctx, batch).Close()
+}
+
+// GetVariants возвращает варианты сегмента в порядке создания с числом участников каждого варианта.
+// У сегмента без вариантов пустой список.
+func (r Segment) GetVariants(ctx context.Context, slug string) ([]*models.SegmentVariant, error) {
+	query := `
+		SELECT v.name, v.weight, count(us.user_id)
+		FROM segments s
+		LEFT JOIN segment_variants v
+		ON v.namespace = s.namespace
+		AND v.segment_slug = s.slug
+		LEFT JOIN user_segments us
+		ON us.namespace = v.namespace
+		AND us.segment_slug = v.segment_slug
+		AND us.variant = v.name
+		WHERE s.namespace = $1
+		AND s.slug = $2
+		GROUP BY v.name, v.weight, v.position
+		ORDER BY v.position
+	`
+
+	args := []any{namespace.FromContext(ctx), slug}
+
+	rows, err := r.DB.Query(ctx, query, args...)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	var (
+		found    bool
+		variants = []*models.SegmentVariant{}
+	)
+
+	for rows.Next() {
+		var (
+			name   *string
+			weight *int
+			users  int64
+		)
+
+		if err := rows.Scan(&name, &weight, &users); err != nil {
+			return nil, err
+		}
+
+		found = true
+
+		// Сегмент без вариантов дает одну строку с NULL
+		if name == nil {
+			continue
+		}
+
+		variants = append(variants, &models.SegmentVariant{Name: *name, Weight: *weight, Users: users})
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if !found {
+		return nil, ErrSegmentNotFound
+	}
+
+	return variants, nil
+}
+
+// UpdateVariants меняет веса вариантов сегмента и добавляет новые варианты в конец.
+// Назначенные пользователям варианты не меняются, новые веса действуют для новых участников.
+// Существующие варианты удалить нельзя, вместо этого им ставится вес 0.
+func (r Segment) UpdateVariants(ctx context.Context, slug string, variants []*models.SegmentVariant) error {
+	ns := namespace.FromContext(ctx)
+
+	tx, err := r.DB.Begin(ctx)
+
+	if err != nil {
+		return err
+	}
+
+	defer tx.Rollback(ctx)
+
+	// Блокировка сегмента не дает параллельным запросам добавить варианты на одни позиции
+	var exists bool
+
+	err = tx.
+		QueryRow(ctx, `SELECT true FROM segments WHERE namespace = $1 AND slug = $2 FOR UPDATE`, ns, slug).
+		Scan(&exists)
+
+	if errors.Is(err, pgx.ErrNoRows) {
+		return ErrSegmentNotFound
+	}
+
+	if err != nil {
+		return err
+	}
+
+	rows, err := tx.Query(ctx, `SELECT name FROM segment_variants WHERE namespace = $1 AND segment_slug = $2`, ns, slug)
+
+	if err != nil {
+		return err
+	}
+
+	current, err := pgx.CollectRows(rows, pgx.RowTo[string])
+
+	if err != nil {
+		return err
+	}
+
+	// Участники сегмента без вариантов остались бы без варианта
+	if len(current) == 0 {
+		return ErrSegmentHasNoVariants
+	}
+
+	var added, updated []*models.SegmentVariant
+
+	for _, variant := range variants {
+		if slices.Contains(current, variant.Name) {
+			updated = append(updated, variant)
+		} else {
+			added = append(added, variant)
+		}
+	}
+
+	if len(updated) != len(current) {
+		return ErrVariantRemoved
+	}
+
+	if err := insertVariants(ctx, tx, slug, updated, 0); err != nil {
+		return err
+	}
+
+	if err := insertVariants(ctx, tx, slug, added, len(current)); err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+// SetUserVariant меняет вариант участника сегмента, смена записывается в историю операцией V.
+func (r Segment) SetUserVariant(ctx context.Context, slug string, userId int64, variant string) error {
+	ns := namespace.FromContext(ctx)
+
+	tx, err := beginWithAudit(ctx, r.DB)
+
+	if err != nil {
+		return err
+	}
+
+	defer tx.Rollback(ctx)
+
+	var segmentExists, variantExists bool
+
+	err = tx.
+		QueryRow(ctx, `
+			SELECT
+				EXISTS (SELECT 1 FROM segments WHERE namespace = $1 AND slug = $2),
+				EXISTS (SELECT 1 FROM segment_variants WHERE namespace = $1 AND segment_slug = $2 AND name = $3)`,
+			ns, slug, variant,
+		).
+		Scan(&segmentExists, &variantExists)
+
+	if err != nil {
+		return err
+	}
+
+	if !segmentExists {
+		return ErrSegmentNotFound
+	}
+
+	if !variantExists {
+		return ErrVariantNotFound
+	}
+
+	var current *string
+
+	err = tx.
+		QueryRow(ctx, `
+			SELECT variant
+			FROM user_segments
+			WHERE namespace = $1
+			AND segment_slug = $2
+			AND user_id = $3
+			FOR UPDATE`,
+			ns, slug, userId,
+		).
+		Scan(&current)
+
+	if errors.Is(err, pgx.ErrNoRows) {
+		return ErrUserNotInSegment
+	}
+
+	if err != nil {
+		return err
+	}
+
+	// Вариант не меняется, в историю ничего не пишется
+	if current != nil && *current == variant {
+		return nil
+	}
+
+	query := `
+		UPDATE user_segments
+		SET variant = $4
+		WHERE namespace = $1
+		AND segment_slug = $2
+		AND user_id = $3
+	`
+
+	if _, err := tx.Exec(ctx, query, ns, slug, userId, variant); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return err
+	}
+
+	metrics.AddMembershipChanges(ns, slug, metrics.OperationVariant, audit.FromContext(ctx).Source, 1)
+
+	return nil
+}
@@ -0,0 +1,202 @@
+package repo
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/dezzerlol/avitotech-test-2023/internal/db/models"
+	"github.com/dezzerlol/avitotech-test-2023/internal/namespace"
+	"github.com/dezzerlol/avitotech-test-2023/pkg/testhelper"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_Variants(t *testing.T) {
+	ctx := namespace.WithNamespace(context.Background(), createNamespace(t, NewNamespaceRepo(testDbInstance)))
+	userRepo := NewUserRepo(testDbInstance)
+	segmentRepo := NewSegmentRepo(testDbInstance)
+
+	segment := &models.Segment{
+		Slug: testhelper.RandomString(12),
+		Variants: []*models.SegmentVariant{
+			{Name: "control", Weight: 50},
+			{Name: "treatment_a", Weight: 25},
+			{Name: "treatment_b", Weight: 25},
+		},
+	}
+	require.NoError(t, segmentRepo.Create(ctx, segment))
+
+	users := make([]int64, 0, 40)
+
+	for i := 0; i < cap(users); i++ {
+		userId := createUser(t, userRepo)
+		users = append(users, userId)
+
+		_, _, err := segmentRepo.UpdateUserSegments(ctx, userId, []string{segment.Slug}, 0, nil)
+		require.NoError(t, err)
+	}
+
+	assigned := make(map[int64]string, len(users))
+
+	lookup, err := segmentRepo.GetUsersSegments(ctx, users, nil)
+	require.NoError(t, err)
+
+	for _, userId := range users {
+		require.Len(t, lookup[userId], 1)
+		require.Contains(t, []string{"control", "treatment_a", "treatment_b"}, lookup[userId][0].Variant)
+
+		assigned[userId] = lookup[userId][0].Variant
+	}
+
+	t.Run("Should assign same variant after re-adding user", func(t *testing.T) {
+		userId := users[0]
+
+		_, err := segmentRepo.DeleteUserSegments(ctx, userId, []string{segment.Slug})
+		require.NoError(t, err)
+
+		_, _, err = segmentRepo.UpdateUserSegments(ctx, userId, []string{segment.Slug}, 0, nil)
+		require.NoError(t, err)
+
+		segments, err := segmentRepo.GetUserSegments(ctx, userId)
+		require.NoError(t, err)
+		require.Equal(t, assigned[userId], segments[0].Variant)
+	})
+
+	t.Run("Should keep assigned variants after weights change", func(t *testing.T) {
+		err := segmentRepo.UpdateVariants(ctx, segment.Slug, []*models.SegmentVariant{
+			{Name: "control", Weight: 0},
+			{Name: "treatment_a", Weight: 0},
+			{Name: "treatment_b", Weight: 0},
+			{Name: "treatment_c", Weight: 100},
+		})
+		require.NoError(t, err)
+
+		lookup, err := segmentRepo.GetUsersSegments(ctx, users, nil)
+		require.NoError(t, err)
+
+		for _, userId := range users {
+			require.Equal(t, assigned[userId], lookup[userId][0].Variant)
+		}
+
+		// Новые участники получают варианты по новым весам
+		userId := createUser(t, userRepo)
+
+		_, _, err = segmentRepo.UpdateUserSegments(ctx, userId, []string{segment.Slug}, 0, nil)
+		require.NoError(t, err)
+
+		segments, err := segmentRepo.GetUserSegments(ctx, userId)
+		require.NoError(t, err)
+		require.Equal(t, "treatment_c", segments[0].Variant)
+
+		variants, err := segmentRepo.GetVariants(ctx, segment.Slug)
+		require.NoError(t, err)
+		require.Len(t, variants, 4)
+		require.Equal(t, "treatment_c", variants[3].Name)
+		require.Equal(t, int64(1), variants[3].Users)
+
+		var members int64
+
+		for _, v := range variants {
+			members += v.Users
+		}
+
+		require.Equal(t, int64(len(users)+1), members)
+	})
+
+	t.Run("Should reject removing variants", func(t *testing.T) {
+		err := segmentRepo.UpdateVariants(ctx, segment.Slug, []*models.SegmentVariant{
+			{Name: "control", Weight: 50},
+			{Name: "treatment_a", Weight: 50},
+		})
+		require.ErrorIs(t, err, ErrVariantRemoved)
+	})
+
+	t.Run("Should record variant change in history", func(t *testing.T) {
+		userId := users[1]
+		variant := "treatment_b"
+
+		if assigned[userId] == variant {
+			variant = "control"
+		}
+
+		require.NoError(t, segmentRepo.SetUserVariant(ctx, segment.Slug, userId, variant))
+
+		segments, err := segmentRepo.GetUserSegments(ctx, userId)
+		require.NoError(t, err)
+		require.Equal(t, variant, segments[0].Variant)
+
+		history, err := segmentRepo.GetUserHistory(ctx, userId, time.Now())
+		require.NoError(t, err)
+		require.Len(t, history, 2)
+		require.Equal(t, "I", history[0].Operation)
+		require.Equal(t, assigned[userId], history[0].Variant)
+		require.Equal(t, "V", history[1].Operation)
+		require.Equal(t, variant, history[1].Variant)
+
+		segments, err = segmentRepo.GetUserSegmentsAt(ctx, userId, time.Now())
+		require.NoError(t, err)
+		require.Len(t, segments, 1)
+		require.Equal(t, variant, segments[0].Variant)
+	})
+
+	t.Run("Should return errors for unknown variant, user and segment", func(t *testing.T) {
+		err := segmentRepo.SetUserVariant(ctx, segment.Slug, users[0], "missing")
+		require.ErrorIs(t, err, ErrVariantNotFound)
+
+		err = segmentRepo.SetUserVariant(ctx, segment.Slug, createUser(t, userRepo), "control")
+		require.ErrorIs(t, err, ErrUserNotInSegment)
+
+		err = segmentRepo.SetUserVariant(ctx, "missing", users[0], "control")
+		require.ErrorIs(t, err, ErrSegmentNotFound)
+
+		_, err = segmentRepo.GetVariants(ctx, "missing")
+		require.ErrorIs(t, err, ErrSegmentNotFound)
+	})
+
+	t.Run("Should not change weights of segment without variants", func(t *testing.T) {
+		plain := &models.Segment{Slug: testhelper.RandomString(12)}
+		require.NoError(t, segmentRepo.Create(ctx, plain))
+
+		variants, err := segmentRepo.GetVariants(ctx, plain.Slug)
+		require.NoError(t, err)
+		require.Empty(t, variants)
+
+		err = segmentRepo.UpdateVariants(ctx, plain.Slug, []*models.SegmentVariant{{Name: "a", Weight: 50}, {Name: "b", Weight: 50}})
+		require.ErrorIs(t, err, ErrSegmentHasNoVariants)
+	})
+}
+
+func Test_VariantsZeroWeight(t *testing.T) {
+	ctx := namespace.WithNamespace(context.Background(), createNamespace(t, NewNamespaceRepo(testDbInstance)))
+	userRepo := NewUserRepo(testDbInstance)
+	segmentRepo := NewSegmentRepo(testDbInstance)
+
+	// Граница retired совпадает с границей control, вариант с весом 0 не должен получать пользователей
+	segment := &models.Segment{
+		Slug: testhelper.RandomString(12),
+		Variants: []*models.SegmentVariant{
+			{Name: "control", Weight: 50},
+			{Name: "retired", Weight: 0},
+			{Name: "treatment", Weight: 50},
+		},
+	}
+	require.NoError(t, segmentRepo.Create(ctx, segment))
+
+	users := make([]int64, 0, 100)
+
+	for i := 0; i < cap(users); i++ {
+		userId := createUser(t, userRepo)
+		users = append(users, userId)
+
+		_, _, err := segmentRepo.UpdateUserSegments(ctx, userId, []string{segment.Slug}, 0, nil)
+		require.NoError(t, err)
+	}
+
+	lookup, err := segmentRepo.GetUsersSegments(ctx, users, nil)
+	require.NoError(t, err)
+
+	for _, userId := range users {
+		require.Len(t, lookup[userId], 1)
+		require.Contains(t, []string{"control", "treatment"}, lookup[userId][0].Variant)
+	}
+}
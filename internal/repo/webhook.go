@@ -0,0 +1,234 @@
+package repo
+
+import (
+	"context"
+	"errors"
+
+	"github.com/dezzerlol/avitotech-test-2023/internal/db/models"
+	"github.com/dezzerlol/avitotech-test-2023/internal/namespace"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type Webhook struct {
+	DB *pgxpool.Pool
+}
+
+func NewWebhookRepo(db *pgxpool.Pool) *Webhook {
+	return &Webhook{DB: db}
+}
+
+// Create регистрирует вебхук в пространстве имен запроса.
+func (r Webhook) Create(ctx context.Context, hook *models.Webhook) error {
+	query := `
+		INSERT INTO webhooks (namespace, url, description, secret)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id, namespace, created_at
+	`
+
+	args := []any{namespace.FromContext(ctx), hook.URL, hook.Description, hook.Secret}
+
+	err := r.DB.
+		QueryRow(ctx, query, args...).
+		Scan(&hook.ID, &hook.Namespace, &hook.CreatedAt)
+
+	if err != nil {
+		var pgErr *pgconn.PgError
+
+		if ok := errors.As(err, &pgErr); ok {
+			if pgErr.Code == ForeignKeyViolation {
+				return ErrNamespaceNotFound
+			}
+		}
+	}
+
+	return err
+}
+
+// Get возвращает вебхук пространства имен запроса вместе с ключом подписи.
+func (r Webhook) Get(ctx context.Context, id int64) (*models.Webhook, error) {
+	query := `
+		SELECT id, namespace, url, description, secret, created_at
+		FROM webhooks
+		WHERE namespace = $1
+		AND id = $2
+	`
+
+	args := []any{namespace.FromContext(ctx), id}
+
+	var hook models.Webhook
+
+	err := r.DB.
+		QueryRow(ctx, query, args...).
+		Scan(&hook.ID, &hook.Namespace, &hook.URL, &hook.Description, &hook.Secret, &hook.CreatedAt)
+
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrWebhookNotFound
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &hook, nil
+}
+
+func (r Webhook) List(ctx context.Context) ([]*models.Webhook, error) {
+	query := `
+		SELECT id, namespace, url, description, created_at
+		FROM webhooks
+		WHERE namespace = $1
+		ORDER BY id
+	`
+
+	args := []any{namespace.FromContext(ctx)}
+
+	rows, err := r.DB.Query(ctx, query, args...)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	hooks := []*models.Webhook{}
+
+	for rows.Next() {
+		var hook models.Webhook
+
+		err := rows.Scan(
+			&hook.ID,
+			&hook.Namespace,
+			&hook.URL,
+			&hook.Description,
+			&hook.CreatedAt,
+		)
+
+		if err != nil {
+			return nil, err
+		}
+
+		hooks = append(hooks, &hook)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return hooks, nil
+}
+
+// Delete удаляет вебхук вместе с журналом доставки. Поставленные доставки пропускаются воркером.
+func (r Webhook) Delete(ctx context.Context, id int64) error {
+	query := `
+		DELETE FROM webhooks
+		WHERE namespace = $1
+		AND id = $2
+	`
+
+	args := []any{namespace.FromContext(ctx), id}
+
+	ct, err := r.DB.Exec(ctx, query, args...)
+
+	if err != nil {
+		return err
+	}
+
+	if ct.RowsAffected() == 0 {
+		return ErrWebhookNotFound
+	}
+
+	return nil
+}
+
+func (r Webhook) AddDelivery(ctx context.Context, delivery *models.WebhookDelivery) error {
+	query := `
+		INSERT INTO webhook_deliveries (webhook_id, event_id, event_type, attempt, status_code, error, duration_ms, success)
+		VALUES ($1, $2, $3, $4, $5, NULLIF($6, ''), $7, $8)
+		RETURNING id, created_at
+	`
+
+	args := []any{
+		delivery.WebhookID,
+		delivery.EventID,
+		delivery.EventType,
+		delivery.Attempt,
+		delivery.StatusCode,
+		delivery.Error,
+		delivery.DurationMs,
+		delivery.Success,
+	}
+
+	err := r.DB.
+		QueryRow(ctx, query, args...).
+		Scan(&delivery.ID, &delivery.CreatedAt)
+
+	// Вебхук удалили, пока шла доставка
+	if err != nil {
+		var pgErr *pgconn.PgError
+
+		if ok := errors.As(err, &pgErr); ok {
+			if pgErr.Code == ForeignKeyViolation {
+				return ErrWebhookNotFound
+			}
+		}
+	}
+
+	return err
+}
+
+// ListDeliveries возвращает последние попытки доставки вебхука, новые первыми.
+func (r Webhook) ListDeliveries(ctx context.Context, webhookId int64, limit int64) ([]*models.WebhookDelivery, error) {
+	query := `
+		SELECT d.id, d.webhook_id, d.event_id, d.event_type, d.attempt, d.status_code, COALESCE(d.error, ''), d.duration_ms, d.success, d.created_at
+		FROM webhook_deliveries d
+		JOIN webhooks w
+		ON w.id = d.webhook_id
+		WHERE w.namespace = $1
+		AND d.webhook_id = $2
+		ORDER BY d.id DESC
+		LIMIT $3
+	`
+
+	args := []any{namespace.FromContext(ctx), webhookId, limit}
+
+	rows, err := r.DB.Query(ctx, query, args...)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	deliveries := []*models.WebhookDelivery{}
+
+	for rows.Next() {
+		var delivery models.WebhookDelivery
+
+		err := rows.Scan(
+			&delivery.ID,
+			&delivery.WebhookID,
+			&delivery.EventID,
+			&delivery.EventType,
+			&delivery.Attempt,
+			&delivery.StatusCode,
+			&delivery.Error,
+			&delivery.DurationMs,
+			&delivery.Success,
+			&delivery.CreatedAt,
+		)
+
+		if err != nil {
+			return nil, err
+		}
+
+		deliveries = append(deliveries, &delivery)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return deliveries, nil
+}
@@ -0,0 +1,142 @@
+package repo
+
+import (
+	"context"
+	"testing"
+
+	"github.com/dezzerlol/avitotech-test-2023/internal/audit"
+	"github.com/dezzerlol/avitotech-test-2023/internal/db/models"
+	"github.com/dezzerlol/avitotech-test-2023/internal/namespace"
+	"github.com/dezzerlol/avitotech-test-2023/pkg/testhelper"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_Webhooks(t *testing.T) {
+	repo := NewWebhookRepo(testDbInstance)
+	ctx := namespace.WithNamespace(context.Background(), createNamespace(t, NewNamespaceRepo(testDbInstance)))
+
+	hook := &models.Webhook{URL: "https://example.com/hook", Secret: "whsec_test"}
+	require.NoError(t, repo.Create(ctx, hook))
+	require.NotZero(t, hook.ID)
+
+	hooks, err := repo.List(ctx)
+	require.NoError(t, err)
+	require.Len(t, hooks, 1)
+	require.Empty(t, hooks[0].Secret)
+
+	// Вебхук не виден из другого пространства имен
+	_, err = repo.Get(context.Background(), hook.ID)
+	require.ErrorIs(t, err, ErrWebhookNotFound)
+
+	got, err := repo.Get(ctx, hook.ID)
+	require.NoError(t, err)
+	require.Equal(t, "whsec_test", got.Secret)
+
+	statusCode := 500
+	require.NoError(t, repo.AddDelivery(ctx, &models.WebhookDelivery{WebhookID: hook.ID, EventType: models.EventWebhookTest, Attempt: 1, StatusCode: &statusCode, Error: "unexpected response status: 500"}))
+	require.NoError(t, repo.AddDelivery(ctx, &models.WebhookDelivery{WebhookID: hook.ID, EventType: models.EventWebhookTest, Attempt: 1, Success: true}))
+
+	deliveries, err := repo.ListDeliveries(ctx, hook.ID, 10)
+	require.NoError(t, err)
+	require.Len(t, deliveries, 2)
+	require.True(t, deliveries[0].Success)
+	require.Equal(t, 500, *deliveries[1].StatusCode)
+
+	require.NoError(t, repo.Delete(ctx, hook.ID))
+	require.ErrorIs(t, repo.Delete(ctx, hook.ID), ErrWebhookNotFound)
+	require.ErrorIs(t, repo.AddDelivery(ctx, &models.WebhookDelivery{WebhookID: hook.ID, EventType: models.EventWebhookTest, Attempt: 1}), ErrWebhookNotFound)
+}
+
+func Test_OutboxDispatch(t *testing.T) {
+	webhookRepo := NewWebhookRepo(testDbInstance)
+	segmentRepo := NewSegmentRepo(testDbInstance)
+	outboxRepo := NewOutboxRepo(testDbInstance)
+
+	ctx := namespace.WithNamespace(context.Background(), createNamespace(t, NewNamespaceRepo(testDbInstance)))
+	ctx = audit.WithMeta(ctx, audit.Meta{Source: audit.SourceAPI, Actor: "api_key:test", RequestID: "req-1"})
+
+	hook := &models.Webhook{URL: "https://example.com/hook", Secret: "whsec_test"}
+	require.NoError(t, webhookRepo.Create(ctx, hook))
+
+	userId := createUser(t, NewUserRepo(testDbInstance))
+	slug := testhelper.RandomString(12)
+
+	require.NoError(t, segmentRepo.Create(ctx, &models.Segment{Slug: slug}))
+
+	_, _, err := segmentRepo.UpdateUserSegments(ctx, userId, []string{slug}, 0, nil)
+	require.NoError(t, err)
+
+	// Удаление сегмента удаляет пользователей каскадно, событие пишется и для него
+	require.NoError(t, segmentRepo.DeleteBySlug(audit.WithSource(ctx, audit.SourceSegmentDelete), &models.Segment{Slug: slug}))
+
+	var events []models.MembershipEvent
+
+	n, err := outboxRepo.Dispatch(context.Background(), 100, func(event models.MembershipEvent, webhookIds []int64) error {
+		require.Equal(t, []int64{hook.ID}, webhookIds)
+		events = append(events, event)
+
+		return nil
+	})
+
+	require.NoError(t, err)
+	require.Equal(t, 2, n)
+	require.Len(t, events, 2)
+
+	require.Equal(t, models.EventUserAdded, events[0].Type)
+	require.Equal(t, slug, events[0].Segment)
+	require.Equal(t, userId, events[0].UserID)
+	require.Equal(t, audit.SourceAPI, events[0].Source)
+	require.Equal(t, "req-1", events[0].RequestID)
+
+	require.Equal(t, models.EventUserRemoved, events[1].Type)
+	require.Equal(t, audit.SourceSegmentDelete, events[1].Source)
+
+	// Отправленные события больше не выдаются
+	n, err = outboxRepo.Dispatch(context.Background(), 100, func(event models.MembershipEvent, webhookIds []int64) error {
+		return nil
+	})
+
+	require.NoError(t, err)
+	require.Zero(t, n)
+}
+
+func Test_OutboxDispatchVariantChanged(t *testing.T) {
+	webhookRepo := NewWebhookRepo(testDbInstance)
+	segmentRepo := NewSegmentRepo(testDbInstance)
+	outboxRepo := NewOutboxRepo(testDbInstance)
+
+	ctx := namespace.WithNamespace(context.Background(), createNamespace(t, NewNamespaceRepo(testDbInstance)))
+
+	hook := &models.Webhook{URL: "https://example.com/hook", Secret: "whsec_test"}
+	require.NoError(t, webhookRepo.Create(ctx, hook))
+
+	userId := createUser(t, NewUserRepo(testDbInstance))
+	segment := &models.Segment{
+		Slug:     testhelper.RandomString(12),
+		Variants: []*models.SegmentVariant{{Name: "control", Weight: 100}, {Name: "treatment", Weight: 0}},
+	}
+
+	require.NoError(t, segmentRepo.Create(ctx, segment))
+
+	_, _, err := segmentRepo.UpdateUserSegments(ctx, userId, []string{segment.Slug}, 0, nil)
+	require.NoError(t, err)
+
+	require.NoError(t, segmentRepo.SetUserVariant(ctx, segment.Slug, userId, "treatment"))
+
+	var events []models.MembershipEvent
+
+	_, err = outboxRepo.Dispatch(context.Background(), 100, func(event models.MembershipEvent, webhookIds []int64) error {
+		events = append(events, event)
+
+		return nil
+	})
+
+	require.NoError(t, err)
+	require.Len(t, events, 2)
+
+	require.Equal(t, models.EventUserAdded, events[0].Type)
+	require.Equal(t, "control", events[0].Variant)
+
+	require.Equal(t, models.EventVariantChanged, events[1].Type)
+	require.Equal(t, "treatment", events[1].Variant)
+}
@@ -0,0 +1,186 @@
+package report
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+	_ "time/tzdata" // таймзоны нужны и в образах без zoneinfo
+	"unicode/utf8"
+)
+
+// Папка, в которой сохраняются отчеты, внутри нее отдельная папка на каждое пространство имен
+const Dir = "reports"
+
+type Format string
+
+const (
+	FormatCSV  Format = "csv"
+	FormatXLSX Format = "xlsx"
+	FormatJSON Format = "json"
+)
+
+func (f Format) Ext() string {
+	return string(f)
+}
+
+func (f Format) ContentType() string {
+	switch f {
+	case FormatXLSX:
+		return "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+	case FormatJSON:
+		return "application/json"
+	default:
+		return "text/csv"
+	}
+}
+
+// FormatByExt возвращает формат отчета по расширению файла.
+func FormatByExt(fileName string) Format {
+	i := strings.LastIndexByte(fileName, '.')
+
+	if i >= 0 {
+		switch f := Format(fileName[i+1:]); f {
+		case FormatCSV, FormatXLSX, FormatJSON:
+			return f
+		}
+	}
+
+	return FormatCSV
+}
+
+// Язык подписей операций в отчете
+const (
+	LangCode = "code" // I / D / V
+	LangEN   = "en"   // added / removed / variant changed
+	LangRU   = "ru"   // добавление / удаление / смена варианта
+)
+
+var operationLabels = map[string]map[string]string{
+	LangEN: {"I": "added", "D": "removed", "V": "variant changed"},
+	LangRU: {"I": "добавление", "D": "удаление", "V": "смена варианта"},
+}
+
+// Именованные форматы времени, помимо них можно передать layout в формате Go
+var timeFormats = map[string]string{
+	"datetime": "2006-01-02 15:04:05",
+	"rfc3339":  time.RFC3339,
+	"date":     "2006-01-02",
+	"unix":     "unix",
+}
+
+type Options struct {
+	Format     Format
+	Delimiter  rune
+	Lang       string
+	TimeFormat string
+	Location   *time.Location
+}
+
+// DefaultOptions совпадают с форматом отчетов до появления настроек.
+func DefaultOptions() Options {
+	return Options{
+		Format:     FormatCSV,
+		Delimiter:  ',',
+		Lang:       LangCode,
+		TimeFormat: timeFormats["datetime"],
+		Location:   time.UTC,
+	}
+}
+
+// ParseOptions читает настройки отчета из query параметров:
+// format, delimiter, lang, time_format и tz.
+func ParseOptions(query url.Values) (Options, error) {
+	opts := DefaultOptions()
+
+	if v := query.Get("format"); v != "" {
+		switch f := Format(strings.ToLower(v)); f {
+		case FormatCSV, FormatXLSX, FormatJSON:
+			opts.Format = f
+		default:
+			return opts, fmt.Errorf("unknown report format %q", v)
+		}
+	}
+
+	if v := query.Get("delimiter"); v != "" {
+		if v == "tab" || v == `\t` {
+			v = "\t"
+		}
+
+		r, size := utf8.DecodeRuneInString(v)
+
+		if size != len(v) || r == '"' || r == '\r' || r == '\n' || r == utf8.RuneError {
+			return opts, errors.New("delimiter must be a single character")
+		}
+
+		opts.Delimiter = r
+	}
+
+	if v := query.Get("lang"); v != "" {
+		switch v = strings.ToLower(v); v {
+		case LangCode, LangEN, LangRU:
+			opts.Lang = v
+		default:
+			return opts, fmt.Errorf("unknown report language %q", v)
+		}
+	}
+
+	if v := query.Get("time_format"); v != "" {
+		if layout, ok := timeFormats[strings.ToLower(v)]; ok {
+			opts.TimeFormat = layout
+		} else {
+			opts.TimeFormat = v
+		}
+	}
+
+	if v := query.Get("tz"); v != "" {
+		loc, err := time.LoadLocation(v)
+
+		if err != nil {
+			return opts, fmt.Errorf("unknown timezone %q", v)
+		}
+
+		opts.Location = loc
+	}
+
+	return opts, nil
+}
+
+// OperationLabel возвращает подпись операции на выбранном языке.
+func (o Options) OperationLabel(op string) string {
+	if label, ok := operationLabels[o.Lang][op]; ok {
+		return label
+	}
+
+	return op
+}
+
+// FormatTime переводит время в выбранную таймзону и формат.
+func (o Options) FormatTime(t time.Time) string {
+	if o.TimeFormat == "unix" {
+		return fmt.Sprint(t.Unix())
+	}
+
+	loc := o.Location
+	if loc == nil {
+		loc = time.UTC
+	}
+
+	return t.In(loc).Format(o.TimeFormat)
+}
+
+// Describe возвращает настройки отчета в виде полей для заголовка.
+func (o Options) Describe() []Field {
+	loc := o.Location
+	if loc == nil {
+		loc = time.UTC
+	}
+
+	return []Field{
+		{"format", string(o.Format)},
+		{"lang", o.Lang},
+		{"time_format", o.TimeFormat},
+		{"timezone", loc.String()},
+	}
+}
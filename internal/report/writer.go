@@ -0,0 +1,269 @@
+package report
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/xuri/excelize/v2"
+)
+
+var (
+	ErrTooManyValues = errors.New("more values than report columns")
+	ErrTooManyRows   = errors.New("report exceeds xlsx row limit")
+)
+
+// Field описывает один параметр запроса, по которому построен отчет.
+type Field struct {
+	Key   string
+	Value string
+}
+
+// Operation - код операции из истории сегментов (I / D / V),
+// при записи заменяется подписью на выбранном языке.
+type Operation string
+
+// Writer пишет строки отчета в выбранном формате.
+// Первой строкой (или отдельным листом для xlsx) идет описание запроса.
+type Writer interface {
+	Write(values ...any) error
+	Close() error
+}
+
+func NewWriter(w io.Writer, opts Options, query []Field, columns []string) (Writer, error) {
+	query = append(query, Field{"generated_at", opts.FormatTime(time.Now())})
+	query = append(query, opts.Describe()...)
+
+	switch opts.Format {
+	case FormatJSON:
+		return newJSONWriter(w, opts, query, columns)
+	case FormatXLSX:
+		return newXLSXWriter(w, opts, query, columns)
+	default:
+		return newCSVWriter(w, opts, query, columns)
+	}
+}
+
+func (o Options) value(v any) any {
+	switch v := v.(type) {
+	case time.Time:
+		return o.FormatTime(v)
+	case Operation:
+		return o.OperationLabel(string(v))
+	default:
+		return v
+	}
+}
+
+// =============================== CSV ===============================
+
+type csvWriter struct {
+	w    *csv.Writer
+	opts Options
+	row  []string
+}
+
+func newCSVWriter(w io.Writer, opts Options, query []Field, columns []string) (*csvWriter, error) {
+	cw := csv.NewWriter(w)
+	cw.Comma = opts.Delimiter
+
+	// Строка с описанием запроса в виде query;key=value;key=value
+	header := []string{"query"}
+	for _, f := range query {
+		header = append(header, f.Key+"="+f.Value)
+	}
+
+	if err := cw.Write(header); err != nil {
+		return nil, err
+	}
+
+	if err := cw.Write(columns); err != nil {
+		return nil, err
+	}
+
+	return &csvWriter{w: cw, opts: opts, row: make([]string, len(columns))}, nil
+}
+
+func (c *csvWriter) Write(values ...any) error {
+	if len(values) > len(c.row) {
+		return fmt.Errorf("%w: %d > %d", ErrTooManyValues, len(values), len(c.row))
+	}
+
+	for i, v := range values {
+		c.row[i] = fmt.Sprint(c.opts.value(v))
+	}
+
+	return c.w.Write(c.row[:len(values)])
+}
+
+func (c *csvWriter) Close() error {
+	c.w.Flush()
+
+	return c.w.Error()
+}
+
+// =============================== JSON ==============================
+
+type jsonWriter struct {
+	w       *bufio.Writer
+	opts    Options
+	columns [][]byte
+	rows    int
+}
+
+// Отчет в формате {"query": {...}, "rows": [{...}, ...]},
+// строки пишутся по одной, без сборки всего документа в памяти.
+func newJSONWriter(w io.Writer, opts Options, query []Field, columns []string) (*jsonWriter, error) {
+	jw := &jsonWriter{w: bufio.NewWriter(w), opts: opts}
+
+	for _, c := range columns {
+		key, _ := json.Marshal(c)
+		jw.columns = append(jw.columns, key)
+	}
+
+	jw.w.WriteString(`{"query":{`)
+
+	for i, f := range query {
+		if i > 0 {
+			jw.w.WriteByte(',')
+		}
+
+		key, _ := json.Marshal(f.Key)
+		value, _ := json.Marshal(f.Value)
+
+		jw.w.Write(key)
+		jw.w.WriteByte(':')
+		jw.w.Write(value)
+	}
+
+	_, err := jw.w.WriteString(`},"rows":[`)
+
+	return jw, err
+}
+
+func (j *jsonWriter) Write(values ...any) error {
+	// Значение без колонки не во что записать, а строка в документе должна остаться целой
+	if len(values) > len(j.columns) {
+		return fmt.Errorf("%w: %d > %d", ErrTooManyValues, len(values), len(j.columns))
+	}
+
+	if j.rows > 0 {
+		j.w.WriteByte(',')
+	}
+
+	j.rows++
+
+	j.w.WriteByte('{')
+
+	for i, v := range values {
+		value, err := json.Marshal(j.opts.value(v))
+
+		if err != nil {
+			return err
+		}
+
+		if i > 0 {
+			j.w.WriteByte(',')
+		}
+
+		j.w.Write(j.columns[i])
+		j.w.WriteByte(':')
+		j.w.Write(value)
+	}
+
+	return j.w.WriteByte('}')
+}
+
+func (j *jsonWriter) Close() error {
+	j.w.WriteString("]}")
+
+	return j.w.Flush()
+}
+
+// =============================== XLSX ==============================
+
+const (
+	xlsxQuerySheet  = "query"
+	xlsxReportSheet = "report"
+)
+
+type xlsxWriter struct {
+	out    io.Writer
+	file   *excelize.File
+	stream *excelize.StreamWriter
+	opts   Options
+	row    int
+}
+
+// Описание запроса пишется на лист query, сами данные на лист report.
+// StreamWriter сбрасывает большие листы во временный файл, а не держит их в памяти.
+// Лист вмещает не больше excelize.TotalRows (1 048 576) строк вместе с заголовком, дальше Write возвращает ErrTooManyRows.
+func newXLSXWriter(w io.Writer, opts Options, query []Field, columns []string) (*xlsxWriter, error) {
+	file := excelize.NewFile()
+
+	if err := file.SetSheetName("Sheet1", xlsxQuerySheet); err != nil {
+		return nil, err
+	}
+
+	for i, f := range query {
+		cell, _ := excelize.CoordinatesToCellName(1, i+1)
+
+		if err := file.SetSheetRow(xlsxQuerySheet, cell, &[]any{f.Key, f.Value}); err != nil {
+			return nil, err
+		}
+	}
+
+	idx, err := file.NewSheet(xlsxReportSheet)
+
+	if err != nil {
+		return nil, err
+	}
+
+	file.SetActiveSheet(idx)
+
+	stream, err := file.NewStreamWriter(xlsxReportSheet)
+
+	if err != nil {
+		return nil, err
+	}
+
+	xw := &xlsxWriter{out: w, file: file, stream: stream, opts: opts}
+
+	header := make([]any, len(columns))
+	for i, c := range columns {
+		header[i] = c
+	}
+
+	return xw, xw.Write(header...)
+}
+
+func (x *xlsxWriter) Write(values ...any) error {
+	if x.row == excelize.TotalRows {
+		return ErrTooManyRows
+	}
+
+	x.row++
+
+	row := make([]any, len(values))
+	for i, v := range values {
+		row[i] = x.opts.value(v)
+	}
+
+	cell, _ := excelize.CoordinatesToCellName(1, x.row)
+
+	return x.stream.SetRow(cell, row)
+}
+
+func (x *xlsxWriter) Close() error {
+	defer x.file.Close()
+
+	if err := x.stream.Flush(); err != nil {
+		return err
+	}
+
+	return x.file.Write(x.out)
+}
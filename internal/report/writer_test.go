@@ -0,0 +1,155 @@
+package report
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/xuri/excelize/v2"
+)
+
+var (
+	testQuery   = []Field{{Key: "report", Value: "user_history"}, {Key: "user_id", Value: "1"}}
+	testColumns = []string{"user_id", "segment_slug", "operation", "executed_at"}
+	testTime    = time.Date(2023, 8, 28, 7, 25, 25, 0, time.UTC)
+)
+
+func Test_ParseOptions(t *testing.T) {
+	t.Run("Should return defaults for empty query", func(t *testing.T) {
+		opts, err := ParseOptions(url.Values{})
+		require.NoError(t, err)
+		require.Equal(t, DefaultOptions(), opts)
+	})
+
+	t.Run("Should parse all options", func(t *testing.T) {
+		query, _ := url.ParseQuery("format=json&delimiter=%3B&lang=ru&time_format=rfc3339&tz=Europe/Moscow")
+
+		opts, err := ParseOptions(query)
+		require.NoError(t, err)
+		require.Equal(t, FormatJSON, opts.Format)
+		require.Equal(t, ';', opts.Delimiter)
+		require.Equal(t, LangRU, opts.Lang)
+		require.Equal(t, time.RFC3339, opts.TimeFormat)
+		require.Equal(t, "2023-08-28T10:25:25+03:00", opts.FormatTime(testTime))
+	})
+
+	t.Run("Should return error for invalid options", func(t *testing.T) {
+		for _, q := range []string{"format=pdf", "delimiter=%3B%3B", "lang=de", "tz=Mars/Olympus"} {
+			query, _ := url.ParseQuery(q)
+
+			_, err := ParseOptions(query)
+			require.Error(t, err, q)
+		}
+	})
+}
+
+func Test_CSVWriter(t *testing.T) {
+	opts := DefaultOptions()
+	opts.Delimiter = ';'
+	opts.Lang = LangEN
+
+	var buf bytes.Buffer
+
+	w, err := NewWriter(&buf, opts, testQuery, testColumns)
+	require.NoError(t, err)
+
+	require.NoError(t, w.Write(int64(1), "AVITO_VOICE_MESSAGES", Operation("I"), testTime))
+	require.NoError(t, w.Write(int64(1), "AVITO_VOICE_MESSAGES", Operation("D"), testTime))
+	require.NoError(t, w.Close())
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	require.Len(t, lines, 4)
+	require.True(t, strings.HasPrefix(lines[0], "query;report=user_history;user_id=1;generated_at="))
+	require.Equal(t, "user_id;segment_slug;operation;executed_at", lines[1])
+	require.Equal(t, "1;AVITO_VOICE_MESSAGES;added;2023-08-28 07:25:25", lines[2])
+	require.Equal(t, "1;AVITO_VOICE_MESSAGES;removed;2023-08-28 07:25:25", lines[3])
+}
+
+func Test_JSONWriter(t *testing.T) {
+	opts := DefaultOptions()
+	opts.Format = FormatJSON
+	opts.TimeFormat = "unix"
+
+	var buf bytes.Buffer
+
+	w, err := NewWriter(&buf, opts, testQuery, testColumns)
+	require.NoError(t, err)
+
+	require.NoError(t, w.Write(int64(1), "AVITO_VOICE_MESSAGES", Operation("I"), testTime))
+	require.NoError(t, w.Close())
+
+	var out struct {
+		Query map[string]string `json:"query"`
+		Rows  []map[string]any  `json:"rows"`
+	}
+
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &out))
+	require.Equal(t, "user_history", out.Query["report"])
+	require.Equal(t, "json", out.Query["format"])
+	require.Len(t, out.Rows, 1)
+	require.Equal(t, float64(1), out.Rows[0]["user_id"])
+	require.Equal(t, "I", out.Rows[0]["operation"])
+	require.Equal(t, "1693207525", out.Rows[0]["executed_at"])
+}
+
+func Test_XLSXWriter(t *testing.T) {
+	opts := DefaultOptions()
+	opts.Format = FormatXLSX
+	opts.Lang = LangRU
+
+	var buf bytes.Buffer
+
+	w, err := NewWriter(&buf, opts, testQuery, testColumns)
+	require.NoError(t, err)
+
+	require.NoError(t, w.Write(int64(1), "AVITO_VOICE_MESSAGES", Operation("I"), testTime))
+	require.NoError(t, w.Close())
+
+	f, err := excelize.OpenReader(&buf)
+	require.NoError(t, err)
+	defer f.Close()
+
+	require.Equal(t, []string{xlsxQuerySheet, xlsxReportSheet}, f.GetSheetList())
+
+	query, err := f.GetRows(xlsxQuerySheet)
+	require.NoError(t, err)
+	require.Equal(t, []string{"report", "user_history"}, query[0])
+
+	rows, err := f.GetRows(xlsxReportSheet)
+	require.NoError(t, err)
+	require.Equal(t, testColumns, rows[0])
+	require.Equal(t, []string{"1", "AVITO_VOICE_MESSAGES", "добавление", "2023-08-28 07:25:25"}, rows[1])
+}
+
+func Test_WriterTooManyValues(t *testing.T) {
+	for _, format := range []Format{FormatCSV, FormatJSON} {
+		opts := DefaultOptions()
+		opts.Format = format
+
+		w, err := NewWriter(io.Discard, opts, testQuery, testColumns)
+		require.NoError(t, err)
+
+		err = w.Write(int64(1), "AVITO_VOICE_MESSAGES", Operation("I"), testTime, "extra")
+		require.ErrorIs(t, err, ErrTooManyValues, format)
+	}
+}
+
+func Test_XLSXWriterRowLimit(t *testing.T) {
+	opts := DefaultOptions()
+	opts.Format = FormatXLSX
+
+	w, err := NewWriter(io.Discard, opts, testQuery, testColumns)
+	require.NoError(t, err)
+	defer w.Close()
+
+	// Строки до лимита пишутся через StreamWriter, поэтому лимит проверяется по счетчику
+	w.(*xlsxWriter).row = excelize.TotalRows - 1
+
+	require.NoError(t, w.Write(int64(1), "AVITO_VOICE_MESSAGES", Operation("I"), testTime))
+	require.ErrorIs(t, w.Write(int64(1), "AVITO_VOICE_MESSAGES", Operation("I"), testTime), ErrTooManyRows)
+}
@@ -0,0 +1,174 @@
+package rules
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+type tokenKind int
+
+const (
+	tokenEOF tokenKind = iota
+	tokenIdent
+	tokenString
+	tokenNumber
+	tokenTrue
+	tokenFalse
+	tokenNull
+	tokenIn
+	tokenNot
+	tokenAnd
+	tokenOr
+	tokenBang
+	tokenEq
+	tokenNe
+	tokenLt
+	tokenLe
+	tokenGt
+	tokenGe
+	tokenLParen
+	tokenRParen
+	tokenLBracket
+	tokenRBracket
+	tokenComma
+)
+
+type token struct {
+	kind tokenKind
+	// Исходный текст, для строк - значение без кавычек
+	text string
+	num  float64
+	// Позиция в правиле, начиная с 1
+	pos int
+}
+
+var keywords = map[string]tokenKind{
+	"true":  tokenTrue,
+	"false": tokenFalse,
+	"null":  tokenNull,
+	"in":    tokenIn,
+	"not":   tokenNot,
+}
+
+// Операторы из двух символов проверяются раньше односимвольных
+var operators = []struct {
+	text string
+	kind tokenKind
+}{
+	{"&&", tokenAnd},
+	{"||", tokenOr},
+	{"==", tokenEq},
+	{"!=", tokenNe},
+	{"<=", tokenLe},
+	{">=", tokenGe},
+	{"<", tokenLt},
+	{">", tokenGt},
+	{"!", tokenBang},
+	{"(", tokenLParen},
+	{")", tokenRParen},
+	{"[", tokenLBracket},
+	{"]", tokenRBracket},
+	{",", tokenComma},
+}
+
+// tokenize разбивает правило на токены. Позиции считаются в символах, а не в байтах.
+func tokenize(expr string) ([]token, error) {
+	src := []rune(expr)
+
+	var tokens []token
+
+	for i := 0; i < len(src); {
+		r := src[i]
+		pos := i + 1
+
+		switch {
+		case unicode.IsSpace(r):
+			i++
+
+		case r == '"':
+			var sb strings.Builder
+
+			i++
+
+			for {
+				if i >= len(src) {
+					return nil, &Error{Pos: pos, Msg: "unterminated string"}
+				}
+
+				if src[i] == '"' {
+					i++
+					break
+				}
+
+				if src[i] == '\\' && i+1 < len(src) {
+					i++
+				}
+
+				sb.WriteRune(src[i])
+				i++
+			}
+
+			tokens = append(tokens, token{kind: tokenString, text: sb.String(), pos: pos})
+
+		case unicode.IsDigit(r) || (r == '-' && i+1 < len(src) && unicode.IsDigit(src[i+1])):
+			start := i
+			i++
+
+			for i < len(src) && (unicode.IsDigit(src[i]) || src[i] == '.') {
+				i++
+			}
+
+			text := string(src[start:i])
+			num, err := strconv.ParseFloat(text, 64)
+
+			if err != nil {
+				return nil, &Error{Pos: pos, Msg: fmt.Sprintf("invalid number %q", text)}
+			}
+
+			tokens = append(tokens, token{kind: tokenNumber, text: text, num: num, pos: pos})
+
+		case unicode.IsLetter(r) || r == '_':
+			start := i
+
+			// Точка разделяет вложенные атрибуты: device.os
+			for i < len(src) && (unicode.IsLetter(src[i]) || unicode.IsDigit(src[i]) || src[i] == '_' || src[i] == '.') {
+				i++
+			}
+
+			text := string(src[start:i])
+
+			if strings.HasSuffix(text, ".") || strings.Contains(text, "..") {
+				return nil, &Error{Pos: pos, Msg: fmt.Sprintf("invalid attribute name %q", text)}
+			}
+
+			kind, ok := keywords[text]
+
+			if !ok {
+				kind = tokenIdent
+			}
+
+			tokens = append(tokens, token{kind: kind, text: text, pos: pos})
+
+		default:
+			matched := false
+
+			for _, op := range operators {
+				if strings.HasPrefix(string(src[i:min(i+2, len(src))]), op.text) {
+					tokens = append(tokens, token{kind: op.kind, text: op.text, pos: pos})
+					i += len([]rune(op.text))
+					matched = true
+
+					break
+				}
+			}
+
+			if !matched {
+				return nil, &Error{Pos: pos, Msg: fmt.Sprintf("unexpected character %q", r)}
+			}
+		}
+	}
+
+	return append(tokens, token{kind: tokenEOF, pos: len(src) + 1}), nil
+}
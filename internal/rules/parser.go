@@ -0,0 +1,313 @@
+package rules
+
+import (
+	"fmt"
+	"strings"
+)
+
+// parser - рекурсивный спуск по грамматике:
+//
+//	or      = and { "||" and }
+//	and     = unary { "&&" unary }
+//	unary   = "!" unary | compare
+//	compare = operand [ ("==" | "!=" | "<" | "<=" | ">" | ">=") operand | ["not"] "in" (list | ident) ]
+//	operand = ident | string | number | true | false | null | "(" or ")"
+//	list    = "[" [ value { "," value } ] "]"
+type parser struct {
+	tokens []token
+	i      int
+}
+
+// Позиция узла нужна для сообщений проверки типов
+type positioned struct {
+	node
+	pos int
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.i]
+}
+
+func (p *parser) next() token {
+	tok := p.tokens[p.i]
+
+	if tok.kind != tokenEOF {
+		p.i++
+	}
+
+	return tok
+}
+
+func (p *parser) parseOr() (node, error) {
+	left, err := p.parseAnd()
+
+	if err != nil {
+		return nil, err
+	}
+
+	for p.peek().kind == tokenOr {
+		op := p.next()
+
+		right, err := p.parseAnd()
+
+		if err != nil {
+			return nil, err
+		}
+
+		left = positioned{logical{op: tokenOr, left: left, right: right}, op.pos}
+	}
+
+	return left, nil
+}
+
+func (p *parser) parseAnd() (node, error) {
+	left, err := p.parseUnary()
+
+	if err != nil {
+		return nil, err
+	}
+
+	for p.peek().kind == tokenAnd {
+		op := p.next()
+
+		right, err := p.parseUnary()
+
+		if err != nil {
+			return nil, err
+		}
+
+		left = positioned{logical{op: tokenAnd, left: left, right: right}, op.pos}
+	}
+
+	return left, nil
+}
+
+func (p *parser) parseUnary() (node, error) {
+	if p.peek().kind == tokenBang {
+		op := p.next()
+
+		x, err := p.parseUnary()
+
+		if err != nil {
+			return nil, err
+		}
+
+		return positioned{not{x: x}, op.pos}, nil
+	}
+
+	return p.parseCompare()
+}
+
+func (p *parser) parseCompare() (node, error) {
+	left, err := p.parseOperand()
+
+	if err != nil {
+		return nil, err
+	}
+
+	op := p.peek()
+
+	switch op.kind {
+	case tokenEq, tokenNe, tokenLt, tokenLe, tokenGt, tokenGe:
+		p.next()
+
+		right, err := p.parseOperand()
+
+		if err != nil {
+			return nil, err
+		}
+
+		if err := checkValue(left); err != nil {
+			return nil, err
+		}
+
+		if err := checkValue(right); err != nil {
+			return nil, err
+		}
+
+		if op.kind != tokenEq && op.kind != tokenNe {
+			if err := checkOrdered(left); err != nil {
+				return nil, err
+			}
+
+			if err := checkOrdered(right); err != nil {
+				return nil, err
+			}
+		}
+
+		return positioned{compare{op: op.kind, left: left, right: right}, op.pos}, nil
+
+	case tokenIn, tokenNot:
+		p.next()
+
+		negate := op.kind == tokenNot
+
+		if negate {
+			if tok := p.next(); tok.kind != tokenIn {
+				return nil, &Error{Pos: tok.pos, Msg: fmt.Sprintf("expected \"in\" after \"not\", got %s", describe(tok))}
+			}
+		}
+
+		if err := checkValue(left); err != nil {
+			return nil, err
+		}
+
+		var values node
+
+		switch tok := p.peek(); tok.kind {
+		case tokenLBracket:
+			values, err = p.parseList()
+
+			if err != nil {
+				return nil, err
+			}
+		case tokenIdent:
+			p.next()
+			values = newAttribute(tok)
+		default:
+			return nil, &Error{Pos: tok.pos, Msg: fmt.Sprintf("expected list or attribute after \"in\", got %s", describe(tok))}
+		}
+
+		return positioned{in{x: left, list: values, negate: negate}, op.pos}, nil
+	}
+
+	return left, nil
+}
+
+func (p *parser) parseOperand() (node, error) {
+	tok := p.next()
+
+	switch tok.kind {
+	case tokenIdent:
+		return positioned{newAttribute(tok), tok.pos}, nil
+	case tokenString, tokenNumber, tokenTrue, tokenFalse, tokenNull:
+		return positioned{literal{value: literalValue(tok)}, tok.pos}, nil
+	case tokenLParen:
+		x, err := p.parseOr()
+
+		if err != nil {
+			return nil, err
+		}
+
+		if closing := p.next(); closing.kind != tokenRParen {
+			return nil, &Error{Pos: closing.pos, Msg: fmt.Sprintf("expected \")\", got %s", describe(closing))}
+		}
+
+		return x, nil
+	case tokenLBracket:
+		return nil, &Error{Pos: tok.pos, Msg: "list is allowed only after \"in\""}
+	}
+
+	return nil, &Error{Pos: tok.pos, Msg: fmt.Sprintf("expected attribute or value, got %s", describe(tok))}
+}
+
+func (p *parser) parseList() (node, error) {
+	p.next()
+
+	values := []any{}
+
+	if p.peek().kind == tokenRBracket {
+		p.next()
+		return list{values: values}, nil
+	}
+
+	for {
+		tok := p.next()
+
+		switch tok.kind {
+		case tokenString, tokenNumber, tokenTrue, tokenFalse, tokenNull:
+			values = append(values, literalValue(tok))
+		default:
+			return nil, &Error{Pos: tok.pos, Msg: fmt.Sprintf("expected value in list, got %s", describe(tok))}
+		}
+
+		switch tok := p.next(); tok.kind {
+		case tokenComma:
+		case tokenRBracket:
+			return list{values: values}, nil
+		default:
+			return nil, &Error{Pos: tok.pos, Msg: fmt.Sprintf("expected \",\" or \"]\", got %s", describe(tok))}
+		}
+	}
+}
+
+func newAttribute(tok token) attribute {
+	return attribute{name: tok.text, path: strings.Split(tok.text, ".")}
+}
+
+func literalValue(tok token) any {
+	switch tok.kind {
+	case tokenString:
+		return tok.text
+	case tokenNumber:
+		return tok.num
+	case tokenTrue:
+		return true
+	case tokenFalse:
+		return false
+	}
+
+	return nil
+}
+
+func describe(tok token) string {
+	if tok.kind == tokenEOF {
+		return "end of rule"
+	}
+
+	return fmt.Sprintf("%q", tok.text)
+}
+
+// checkValue проверяет, что операнд сравнения - атрибут или литерал, а не условие.
+func checkValue(n node) error {
+	p := n.(positioned)
+
+	switch p.node.(type) {
+	case attribute, literal:
+		return nil
+	}
+
+	return &Error{Pos: p.pos, Msg: "expected attribute or value, got condition"}
+}
+
+// checkOrdered запрещает <, <=, >, >= с true, false и null.
+func checkOrdered(n node) error {
+	p := n.(positioned)
+
+	if lit, ok := p.node.(literal); ok {
+		switch lit.value.(type) {
+		case string, float64:
+		default:
+			return &Error{Pos: p.pos, Msg: "only strings and numbers can be ordered"}
+		}
+	}
+
+	return nil
+}
+
+// checkBool проверяет, что условие не сводится к литералу, кроме true и false.
+func checkBool(n node, pos int) error {
+	p, ok := n.(positioned)
+
+	if ok {
+		pos = p.pos
+		n = p.node
+	}
+
+	switch n := n.(type) {
+	case literal:
+		if _, ok := n.value.(bool); !ok {
+			return &Error{Pos: pos, Msg: "expected condition, got value"}
+		}
+	case not:
+		return checkBool(n.x, pos)
+	case logical:
+		if err := checkBool(n.left, pos); err != nil {
+			return err
+		}
+
+		return checkBool(n.right, pos)
+	}
+
+	return nil
+}
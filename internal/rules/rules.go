@@ -0,0 +1,292 @@
+// Package rules разбирает и вычисляет правила динамических сегментов по атрибутам пользователя,
+// например: city in ["msk", "spb"] && platform == "ios".
+//
+// Поддерживаются строки, числа, true, false, null, списки литералов, атрибуты (вложенные - через точку),
+// операторы ==, !=, <, <=, >, >=, in, not in, &&, ||, ! и скобки. Атрибут без оператора истинен, если равен true.
+// Отсутствующий атрибут равен null. Сравнение значений разных типов ложно, поэтому вычисление правила
+// не возвращает ошибок. Даты сравниваются как строки, поэтому хранятся в формате 2006-01-02.
+package rules
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// Ограничение длины правила в символах
+const MaxLength = 1000
+
+// Error - ошибка разбора правила с позицией (в символах, начиная с 1).
+type Error struct {
+	Pos int    `json:"position"`
+	Msg string `json:"message"`
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("rule error at position %d: %s", e.Pos, e.Msg)
+}
+
+// Rule - разобранное правило. Безопасно для использования из нескольких горутин.
+type Rule struct {
+	expr       string
+	root       node
+	attributes []string
+}
+
+// Parse разбирает правило.
+func Parse(expr string) (*Rule, error) {
+	if len([]rune(expr)) > MaxLength {
+		return nil, &Error{Pos: MaxLength + 1, Msg: fmt.Sprintf("rule is longer than %d characters", MaxLength)}
+	}
+
+	tokens, err := tokenize(expr)
+
+	if err != nil {
+		return nil, err
+	}
+
+	p := &parser{tokens: tokens}
+
+	root, err := p.parseOr()
+
+	if err != nil {
+		return nil, err
+	}
+
+	if tok := p.peek(); tok.kind != tokenEOF {
+		return nil, &Error{Pos: tok.pos, Msg: fmt.Sprintf("unexpected %q", tok.text)}
+	}
+
+	if err := checkBool(root, 1); err != nil {
+		return nil, err
+	}
+
+	rule := &Rule{expr: expr, root: root}
+	collectAttributes(root, &rule.attributes)
+	slices.Sort(rule.attributes)
+	rule.attributes = slices.Compact(rule.attributes)
+
+	return rule, nil
+}
+
+// Разобранные правила сегментов. Правило сегмента не меняется, поэтому записи не устаревают.
+var compiled sync.Map
+
+// ParseCached разбирает правило один раз и переиспользует результат.
+// Используется для правил из базы, правила из запросов разбираются через Parse.
+func ParseCached(expr string) (*Rule, error) {
+	if rule, ok := compiled.Load(expr); ok {
+		return rule.(*Rule), nil
+	}
+
+	rule, err := Parse(expr)
+
+	if err != nil {
+		return nil, err
+	}
+
+	compiled.Store(expr, rule)
+
+	return rule, nil
+}
+
+func (r *Rule) String() string {
+	return r.expr
+}
+
+// Attributes возвращает имена атрибутов, которые использует правило.
+func (r *Rule) Attributes() []string {
+	return r.attributes
+}
+
+// Match вычисляет правило для атрибутов пользователя.
+func (r *Rule) Match(attributes map[string]any) bool {
+	return r.root.eval(attributes) == true
+}
+
+type node interface {
+	eval(attributes map[string]any) any
+}
+
+type literal struct {
+	value any
+}
+
+func (n literal) eval(map[string]any) any {
+	return n.value
+}
+
+type attribute struct {
+	name string
+	path []string
+}
+
+func (n attribute) eval(attributes map[string]any) any {
+	var value any = attributes
+
+	for _, key := range n.path {
+		obj, ok := value.(map[string]any)
+
+		if !ok {
+			return nil
+		}
+
+		value = obj[key]
+	}
+
+	return value
+}
+
+type list struct {
+	values []any
+}
+
+func (n list) eval(map[string]any) any {
+	return n.values
+}
+
+type not struct {
+	x node
+}
+
+func (n not) eval(attributes map[string]any) any {
+	return n.x.eval(attributes) != true
+}
+
+type logical struct {
+	op          tokenKind
+	left, right node
+}
+
+func (n logical) eval(attributes map[string]any) any {
+	left := n.left.eval(attributes) == true
+
+	if n.op == tokenAnd {
+		return left && n.right.eval(attributes) == true
+	}
+
+	return left || n.right.eval(attributes) == true
+}
+
+type compare struct {
+	op          tokenKind
+	left, right node
+}
+
+func (n compare) eval(attributes map[string]any) any {
+	left, right := n.left.eval(attributes), n.right.eval(attributes)
+
+	switch n.op {
+	case tokenEq:
+		return equal(left, right)
+	case tokenNe:
+		return !equal(left, right)
+	}
+
+	var cmp int
+
+	switch l := left.(type) {
+	case float64:
+		r, ok := right.(float64)
+
+		if !ok {
+			return false
+		}
+
+		cmp = compareOrdered(l, r)
+	case string:
+		r, ok := right.(string)
+
+		if !ok {
+			return false
+		}
+
+		cmp = strings.Compare(l, r)
+	default:
+		return false
+	}
+
+	switch n.op {
+	case tokenLt:
+		return cmp < 0
+	case tokenLe:
+		return cmp <= 0
+	case tokenGt:
+		return cmp > 0
+	}
+
+	return cmp >= 0
+}
+
+type in struct {
+	x      node
+	list   node
+	negate bool
+}
+
+func (n in) eval(attributes map[string]any) any {
+	values, ok := n.list.eval(attributes).([]any)
+
+	// Атрибут не список - условие ложно и для not in
+	if !ok {
+		return false
+	}
+
+	x := n.x.eval(attributes)
+
+	found := slices.ContainsFunc(values, func(v any) bool {
+		return equal(x, v)
+	})
+
+	return found != n.negate
+}
+
+func equal(a, b any) bool {
+	switch a := a.(type) {
+	case nil:
+		return b == nil
+	case string:
+		b, ok := b.(string)
+		return ok && a == b
+	case float64:
+		b, ok := b.(float64)
+		return ok && a == b
+	case bool:
+		b, ok := b.(bool)
+		return ok && a == b
+	}
+
+	return false
+}
+
+func compareOrdered(a, b float64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+
+	return 0
+}
+
+func collectAttributes(n node, names *[]string) {
+	switch n := n.(type) {
+	case positioned:
+		collectAttributes(n.node, names)
+	case attribute:
+		*names = append(*names, n.name)
+	case not:
+		collectAttributes(n.x, names)
+	case logical:
+		collectAttributes(n.left, names)
+		collectAttributes(n.right, names)
+	case compare:
+		collectAttributes(n.left, names)
+		collectAttributes(n.right, names)
+	case in:
+		collectAttributes(n.x, names)
+		collectAttributes(n.list, names)
+	}
+}
@@ -0,0 +1,105 @@
+package rules
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRule_Match(t *testing.T) {
+	var attributes map[string]any
+
+	require.NoError(t, json.Unmarshal([]byte(`{
+		"city": "msk",
+		"platform": "ios",
+		"age": 27,
+		"premium": true,
+		"registered_at": "2023-05-01",
+		"tags": ["beta", "staff"],
+		"device": {"os": "ios", "version": 17}
+	}`), &attributes))
+
+	tests := []struct {
+		rule string
+		want bool
+	}{
+		{`city in ["msk", "spb"] && platform == "ios"`, true},
+		{`city in ["msk", "spb"] && platform == "android"`, false},
+		{`city not in ["msk", "spb"]`, false},
+		{`city == "spb" || age >= 18`, true},
+		{`age > 27`, false},
+		{`age <= 27 && age != 30`, true},
+		{`premium`, true},
+		{`!premium`, false},
+		{`!(city == "spb")`, true},
+		{`registered_at >= "2023-01-01" && registered_at < "2024-01-01"`, true},
+		{`"beta" in tags`, true},
+		{`"admin" not in tags`, true},
+		{`device.os == "ios" && device.version >= 17`, true},
+		{`device.model == null`, true},
+		{`missing == null`, true},
+		{`missing != "msk"`, true},
+		{`missing`, false},
+		{`missing > 1`, false},
+		// Сравнение разных типов ложно
+		{`age == "27"`, false},
+		{`city > 1`, false},
+		// Не список справа от in - ложно и для not in
+		{`"msk" not in city`, false},
+		{`true`, true},
+		{`false || city == "msk"`, true},
+		{`city == "msk" && platform == "ios" || age < 18`, true},
+		{`city == "spb" && (platform == "ios" || age > 18)`, false},
+		{`age == 27.0 && -1 < age`, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.rule, func(t *testing.T) {
+			rule, err := Parse(tt.rule)
+			require.NoError(t, err)
+			require.Equal(t, tt.want, rule.Match(attributes))
+		})
+	}
+}
+
+func TestParse_Errors(t *testing.T) {
+	tests := []struct {
+		rule string
+		pos  int
+	}{
+		{``, 1},
+		{`city ==`, 8},
+		{`city == "msk`, 9},
+		{`city = "msk"`, 6},
+		{`city in "msk"`, 9},
+		{`city in [platform]`, 10},
+		{`city in ["msk" "spb"]`, 16},
+		{`city not ["msk"]`, 10},
+		{`(city == "msk"`, 15},
+		{`city == "msk")`, 14},
+		{`"msk"`, 1},
+		{`city == "msk" && 1`, 18},
+		{`premium > true`, 11},
+		{`(a == 1) == true`, 4},
+		{`city.`, 1},
+		{`city # 1`, 6},
+		{`город == "москва" &&`, 21},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.rule, func(t *testing.T) {
+			_, err := Parse(tt.rule)
+
+			var ruleErr *Error
+			require.ErrorAs(t, err, &ruleErr)
+			require.Equal(t, tt.pos, ruleErr.Pos, ruleErr.Msg)
+		})
+	}
+}
+
+func TestRule_Attributes(t *testing.T) {
+	rule, err := Parse(`city in ["msk"] && (platform == "ios" || city == "spb") && "beta" in tags`)
+	require.NoError(t, err)
+	require.Equal(t, []string{"city", "platform", "tags"}, rule.Attributes())
+}
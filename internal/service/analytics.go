@@ -0,0 +1,98 @@
+package service
+
+import (
+	"context"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/dezzerlol/avitotech-test-2023/internal/db/models"
+	"github.com/dezzerlol/avitotech-test-2023/internal/report"
+)
+
+type AnalyticsRepo interface {
+	GetRollupRefreshedAt(ctx context.Context) (*time.Time, error)
+	StreamMembership(ctx context.Context, filter models.MembershipFilter, fn func(*models.MembershipPoint) error) error
+}
+
+type Analytics struct {
+	analyticsRepo AnalyticsRepo
+}
+
+func NewAnalyticsSvc(analyticsRepo AnalyticsRepo) *Analytics {
+	return &Analytics{
+		analyticsRepo: analyticsRepo,
+	}
+}
+
+var membershipColumns = []string{"segment_slug", "day", "entered", "left", "members"}
+
+// GetMembership возвращает размер сегментов на конец каждого дня периода.
+// Данные берутся из посуточной сводки, которую пересчитывает воркер.
+func (a *Analytics) GetMembership(ctx context.Context, filter models.MembershipFilter) (*models.Membership, error) {
+	refreshedAt, err := a.analyticsRepo.GetRollupRefreshedAt(ctx)
+
+	if err != nil {
+		return nil, err
+	}
+
+	membership := &models.Membership{
+		From:        filter.From,
+		To:          filter.To,
+		Series:      []*models.MembershipSeries{},
+		RefreshedAt: refreshedAt,
+	}
+
+	var series *models.MembershipSeries
+
+	// Точки приходят отсортированными по сегменту, поэтому новая серия начинается при смене сегмента
+	err = a.analyticsRepo.StreamMembership(ctx, filter, func(point *models.MembershipPoint) error {
+		if series == nil || series.SegmentSlug != point.SegmentSlug {
+			series = &models.MembershipSeries{SegmentSlug: point.SegmentSlug}
+			membership.Series = append(membership.Series, series)
+		}
+
+		p := *point
+		series.Points = append(series.Points, &p)
+
+		return nil
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return membership, nil
+}
+
+// ExportMembership пишет размер сегментов по дням в w в выбранном формате.
+func (a *Analytics) ExportMembership(ctx context.Context, filter models.MembershipFilter, opts report.Options, w io.Writer) error {
+	query := []report.Field{
+		{Key: "report", Value: "segment_membership"},
+		{Key: "from", Value: filter.From.Format("2006-01-02")},
+		{Key: "to", Value: filter.To.Format("2006-01-02")},
+		{Key: "segments", Value: strings.Join(filter.Segments, ",")},
+	}
+
+	writer, err := report.NewWriter(w, opts, query, membershipColumns)
+
+	if err != nil {
+		return err
+	}
+
+	err = a.analyticsRepo.StreamMembership(ctx, filter, func(point *models.MembershipPoint) error {
+		return writer.Write(
+			point.SegmentSlug,
+			point.Day.Format("2006-01-02"),
+			point.Entered,
+			point.Left,
+			point.Members,
+		)
+	})
+
+	if err != nil {
+		return err
+	}
+
+	return writer.Close()
+}
@@ -0,0 +1,96 @@
+package service
+
+import (
+	"context"
+	"crypto/subtle"
+
+	"github.com/dezzerlol/avitotech-test-2023/internal/auth"
+	"github.com/dezzerlol/avitotech-test-2023/internal/db/models"
+)
+
+type APIKeyRepo interface {
+	Create(ctx context.Context, key *models.APIKey, keyHash string) error
+	GetByHash(ctx context.Context, keyHash string) (*models.APIKey, error)
+	List(ctx context.Context) ([]*models.APIKey, error)
+	Rotate(ctx context.Context, id int64, keyHash string) (*models.APIKey, error)
+	Revoke(ctx context.Context, id int64) error
+}
+
+// Имя, под которым в истории записываются изменения, сделанные ключом из конфига
+const adminKeyName = "admin"
+
+type APIKey struct {
+	apiKeyRepo APIKeyRepo
+	// Ключ администратора из конфига, нужен чтобы выпустить первые ключи
+	adminKey string
+}
+
+func NewAPIKeySvc(apiKeyRepo APIKeyRepo, adminKey string) *APIKey {
+	return &APIKey{
+		apiKeyRepo: apiKeyRepo,
+		adminKey:   adminKey,
+	}
+}
+
+// Authenticate возвращает владельца ключа. Для неизвестного или отозванного ключа возвращается repo.ErrAPIKeyNotFound.
+func (s *APIKey) Authenticate(ctx context.Context, key string) (auth.Identity, error) {
+	if s.adminKey != "" && subtle.ConstantTimeCompare([]byte(key), []byte(s.adminKey)) == 1 {
+		return auth.Identity{Method: auth.MethodAPIKey, Name: adminKeyName, Scopes: auth.Scopes}, nil
+	}
+
+	apiKey, err := s.apiKeyRepo.GetByHash(ctx, auth.HashKey(key))
+
+	if err != nil {
+		return auth.Identity{}, err
+	}
+
+	return auth.Identity{
+		Method:     auth.MethodAPIKey,
+		KeyID:      apiKey.ID,
+		Name:       apiKey.Name,
+		Scopes:     apiKey.Scopes,
+		Namespaces: apiKey.Namespaces,
+	}, nil
+}
+
+// Issue выпускает новый ключ. Сам ключ возвращается только здесь, в базе хранится его хэш.
+func (s *APIKey) Issue(ctx context.Context, apiKey *models.APIKey) (string, error) {
+	key, err := auth.GenerateKey()
+
+	if err != nil {
+		return "", err
+	}
+
+	err = s.apiKeyRepo.Create(ctx, apiKey, auth.HashKey(key))
+
+	if err != nil {
+		return "", err
+	}
+
+	return key, nil
+}
+
+func (s *APIKey) List(ctx context.Context) ([]*models.APIKey, error) {
+	return s.apiKeyRepo.List(ctx)
+}
+
+// Rotate выпускает новый ключ с теми же правами, старый ключ перестает работать.
+func (s *APIKey) Rotate(ctx context.Context, id int64) (*models.APIKey, string, error) {
+	key, err := auth.GenerateKey()
+
+	if err != nil {
+		return nil, "", err
+	}
+
+	apiKey, err := s.apiKeyRepo.Rotate(ctx, id, auth.HashKey(key))
+
+	if err != nil {
+		return nil, "", err
+	}
+
+	return apiKey, key, nil
+}
+
+func (s *APIKey) Revoke(ctx context.Context, id int64) error {
+	return s.apiKeyRepo.Revoke(ctx, id)
+}
@@ -0,0 +1,188 @@
+package service
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/dezzerlol/avitotech-test-2023/internal/db/models"
+	"github.com/dezzerlol/avitotech-test-2023/internal/events"
+	"github.com/dezzerlol/avitotech-test-2023/internal/namespace"
+)
+
+// Сколько пропущенных событий можно догнать по Last-Event-ID. Дальше клиент получает reset.
+const maxReplayEvents = 10000
+
+const replayPageSize = 1000
+
+// Интервал heartbeat, пока событий нет
+const heartbeatInterval = 15 * time.Second
+
+type SegmentEventsRepo interface {
+	GetSegmentEventsAfter(ctx context.Context, afterId int64, filter models.SegmentEventFilter, limit int64) ([]*models.SegmentEvent, error)
+	GetChangesAfter(ctx context.Context, after, limit int64) ([]*models.SegmentEvent, int64, error)
+	GetHistoryWatermark(ctx context.Context) (int64, error)
+}
+
+type EventHub interface {
+	Subscribe(ns string, filter models.SegmentEventFilter) (*events.Subscription, error)
+}
+
+type Events struct {
+	hub         EventHub
+	segmentRepo SegmentEventsRepo
+}
+
+func NewEventsSvc(hub EventHub, segmentRepo SegmentEventsRepo) *Events {
+	return &Events{
+		hub:         hub,
+		segmentRepo: segmentRepo,
+	}
+}
+
+// Stream отправляет в sink изменения членства в пространстве имен запроса, пока не отменен ctx.
+// Если afterId больше нуля, сначала отправляются события из истории с id больше afterId.
+// События приходят в порядке коммита, поэтому вместе с событием отправляется не его id, а курсор:
+// все события до курсора уже отправлены. Клиент продолжает с курсора и может повторно получить события после него.
+// Потеря подписки возвращается как events.ErrSubscriptionLost: клиент продолжает с последнего полученного курсора.
+func (s *Events) Stream(ctx context.Context, afterId int64, filter models.SegmentEventFilter, sink events.Sink) error {
+	// Подписка оформляется до чтения истории, чтобы не пропустить события между ними
+	sub, err := s.hub.Subscribe(namespace.FromContext(ctx), filter)
+
+	if err != nil {
+		return err
+	}
+
+	defer sub.Close()
+
+	// Записи до границы завершены до чтения истории, более поздние придут в подписку или попадут в историю
+	watermark, err := s.segmentRepo.GetHistoryWatermark(ctx)
+
+	if err != nil {
+		return err
+	}
+
+	if err := sink.Open(); err != nil {
+		return err
+	}
+
+	cursor := max(afterId, watermark)
+
+	replayed, err := s.replay(ctx, afterId, watermark, filter, sink)
+
+	if err != nil {
+		return err
+	}
+
+	heartbeat := time.NewTicker(heartbeatInterval)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-sub.Lost():
+			return events.ErrSubscriptionLost
+		case <-heartbeat.C:
+			if err := sink.Heartbeat(); err != nil {
+				return err
+			}
+		case msg := <-sub.Messages():
+			if msg.Event == nil {
+				if msg.Checkpoint <= cursor {
+					continue
+				}
+
+				cursor = msg.Checkpoint
+
+				if err := sink.Checkpoint(cursor); err != nil {
+					return err
+				}
+
+				heartbeat.Reset(heartbeatInterval)
+				continue
+			}
+
+			// Событие уже отправлено из истории. Сравнивать только с последним id нельзя:
+			// транзакция с меньшим id могла закоммититься позже чтения истории.
+			if _, ok := replayed[msg.Event.ID]; ok {
+				continue
+			}
+
+			if err := sink.Send(msg.Event, cursor); err != nil {
+				return err
+			}
+
+			heartbeat.Reset(heartbeatInterval)
+		}
+	}
+}
+
+// replay отправляет события из истории после afterId и возвращает их id.
+// Записи до границы watermark завершены, поэтому курсор события до нее - его id, а после нее - сама граница.
+func (s *Events) replay(ctx context.Context, afterId, watermark int64, filter models.SegmentEventFilter, sink events.Sink) (map[int64]struct{}, error) {
+	replayed := make(map[int64]struct{})
+
+	if afterId <= 0 {
+		return replayed, nil
+	}
+
+	for after := afterId; ; {
+		page, err := s.segmentRepo.GetSegmentEventsAfter(ctx, after, filter, replayPageSize)
+
+		if err != nil {
+			return nil, err
+		}
+
+		for _, event := range page {
+			if len(replayed) == maxReplayEvents {
+				if err := sink.Reset(); err != nil {
+					return nil, err
+				}
+
+				// Клиент перечитывает состояние целиком, пропущенные события при переподключении не нужны
+				return replayed, sink.Checkpoint(max(afterId, watermark))
+			}
+
+			if err := sink.Send(event, max(afterId, min(event.ID, watermark))); err != nil {
+				return nil, err
+			}
+
+			replayed[event.ID] = struct{}{}
+			after = event.ID
+		}
+
+		if len(page) < replayPageSize {
+			return replayed, nil
+		}
+	}
+}
+
+// Changes возвращает до limit изменений членства в пространстве имен запроса после курсора after (id записи истории).
+func (s *Events) Changes(ctx context.Context, after, limit int64) (*models.ChangesPage, error) {
+	changes, watermark, err := s.segmentRepo.GetChangesAfter(ctx, after, limit)
+
+	if err != nil {
+		return nil, err
+	}
+
+	next := after
+
+	if len(changes) > 0 {
+		next = changes[len(changes)-1].ID
+	}
+
+	hasMore := int64(len(changes)) == limit
+
+	// До границы изменений пространства имен больше нет, курсор можно передвинуть к ней,
+	// чтобы следующий запрос не перечитывал изменения других пространств имен
+	if !hasMore && watermark > next {
+		next = watermark
+	}
+
+	return &models.ChangesPage{
+		Changes:    changes,
+		NextCursor: strconv.FormatInt(next, 10),
+		HasMore:    hasMore,
+	}, nil
+}
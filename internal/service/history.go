@@ -0,0 +1,285 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/dezzerlol/avitotech-test-2023/cfg"
+	"github.com/dezzerlol/avitotech-test-2023/internal/db/models"
+	"github.com/dezzerlol/avitotech-test-2023/internal/namespace"
+	"github.com/dezzerlol/avitotech-test-2023/internal/report"
+	"github.com/dezzerlol/avitotech-test-2023/internal/tracing"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var userHistoryColumns = []string{"user_id", "segment_slug", "operation", "executed_at", "source", "actor", "request_id", "variant"}
+
+func (s *Segment) GetUserHistory(ctx context.Context, userId, month, year int64, opts report.Options) (_ string, err error) {
+	ctx, span := tracer.Start(ctx, "service.Segment.GetUserHistory", trace.WithAttributes(attribute.Int64("user.id", userId)))
+	defer func() { tracing.End(span, err) }()
+
+	date := time.Date(int(year), time.Month(month), 1, 0, 0, 0, 0, opts.Location)
+
+	userHistory, err := s.segmentRepo.GetUserHistory(ctx, userId, date)
+
+	if err != nil {
+		return "", err
+	}
+
+	ns := namespace.FromContext(ctx)
+
+	fileName, err := s.generateReport(ns, userId, date, userHistory, opts)
+
+	if err != nil {
+		return "", err
+	}
+
+	// Ссылка для скачивания файла в формате addr:port/api/v1/ns/namespace/segment/reports/file_name.csv
+	addr := fmt.Sprintf("%s:%s", cfg.Get().REPORTS_HOST, cfg.Get().API_PORT)
+	downloadLink := addr + "/api/v1/ns/" + ns + "/segment/reports/" + fileName
+
+	return downloadLink, err
+}
+
+// generateReport сохраняет отчет в папку пространства имен и возвращает имя файла.
+func (s *Segment) generateReport(ns string, userId int64, date time.Time, userHistory []*models.UserHistory, opts report.Options) (string, error) {
+	dir := reportsDir(ns)
+
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return "", err
+	}
+
+	fileName := fmt.Sprintf("%d-%d.%s", userId, time.Now().Unix(), opts.Format.Ext())
+
+	// Создаем файл
+	file, err := os.Create(filepath.Join(dir, fileName))
+
+	if err != nil {
+		return "", err
+	}
+
+	defer file.Close()
+
+	writer, err := report.NewWriter(file, opts, userHistoryQuery(userId, date), userHistoryColumns)
+
+	if err != nil {
+		return "", err
+	}
+
+	// Записываем слайс значения в файл
+	for _, history := range userHistory {
+		if err := writeUserHistory(writer, history); err != nil {
+			return "", err
+		}
+	}
+
+	if err := writer.Close(); err != nil {
+		return "", err
+	}
+
+	return fileName, nil
+}
+
+// reportsDir возвращает папку с отчетами пространства имен.
+func reportsDir(ns string) string {
+	return filepath.Join(report.Dir, ns)
+}
+
+// ExportUserHistory пишет отчет по истории пользователя напрямую в w,
+// читая строки из базы по мере записи.
+func (s *Segment) ExportUserHistory(ctx context.Context, userId, month, year int64, opts report.Options, w io.Writer) (err error) {
+	ctx, span := tracer.Start(ctx, "service.Segment.ExportUserHistory", trace.WithAttributes(attribute.Int64("user.id", userId)))
+	defer func() { tracing.End(span, err) }()
+
+	date := time.Date(int(year), time.Month(month), 1, 0, 0, 0, 0, opts.Location)
+
+	// Writer буферизирует вывод, поэтому при ошибке запроса
+	// до первой строки в w ничего не будет записано
+	writer, err := report.NewWriter(w, opts, userHistoryQuery(userId, date), userHistoryColumns)
+
+	if err != nil {
+		return err
+	}
+
+	err = s.segmentRepo.StreamUserHistory(ctx, userId, date, func(history *models.UserHistory) error {
+		return writeUserHistory(writer, history)
+	})
+
+	if err != nil {
+		return err
+	}
+
+	return writer.Close()
+}
+
+func userHistoryQuery(userId int64, date time.Time) []report.Field {
+	return []report.Field{
+		{Key: "report", Value: "user_history"},
+		{Key: "user_id", Value: fmt.Sprint(userId)},
+		{Key: "period", Value: date.Format("2006-01")},
+	}
+}
+
+func writeUserHistory(w report.Writer, history *models.UserHistory) error {
+	return w.Write(
+		history.UserID,
+		history.SegmentSlug,
+		report.Operation(history.Operation),
+		history.ExecutedAt,
+		history.Source,
+		history.Actor,
+		history.RequestID,
+		history.Variant,
+	)
+}
+
+// Сколько событий отдается вместе с агрегатами в GetSegmentHistory,
+// полный список доступен через выгрузку
+const segmentHistoryEventsLimit = 1000
+
+var segmentBucketColumns = []string{"bucket_start", "entered", "left", "net", "members"}
+
+// GetSegmentHistory возвращает число вошедших и вышедших из сегмента пользователей
+// по интервалам, размер сегмента на конец каждого интервала и первые события за период.
+func (s *Segment) GetSegmentHistory(ctx context.Context, filter models.SegmentHistoryFilter) (_ *models.SegmentHistory, err error) {
+	ctx, span := tracer.Start(ctx, "service.Segment.GetSegmentHistory", trace.WithAttributes(attribute.String("segment.slug", filter.SegmentSlug)))
+	defer func() { tracing.End(span, err) }()
+
+	filter.From = filter.BucketStart(filter.From)
+
+	buckets, err := s.getSegmentBuckets(ctx, filter)
+
+	if err != nil {
+		return nil, err
+	}
+
+	history := &models.SegmentHistory{
+		SegmentSlug: filter.SegmentSlug,
+		From:        filter.From,
+		To:          filter.To,
+		Bucket:      filter.Bucket,
+		Buckets:     buckets,
+		Events:      []*models.UserHistory{},
+	}
+
+	// Запрашиваем на одно событие больше, чтобы понять, что список обрезан
+	err = s.segmentRepo.StreamSegmentHistory(ctx, filter, segmentHistoryEventsLimit+1, func(event *models.UserHistory) error {
+		if len(history.Events) == segmentHistoryEventsLimit {
+			history.EventsTruncated = true
+			return nil
+		}
+
+		e := *event
+		history.Events = append(history.Events, &e)
+
+		return nil
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return history, nil
+}
+
+// ExportSegmentHistory пишет в w агрегаты по интервалам или все события сегмента за период.
+func (s *Segment) ExportSegmentHistory(ctx context.Context, filter models.SegmentHistoryFilter, view string, opts report.Options, w io.Writer) (err error) {
+	ctx, span := tracer.Start(ctx, "service.Segment.ExportSegmentHistory", trace.WithAttributes(attribute.String("segment.slug", filter.SegmentSlug)))
+	defer func() { tracing.End(span, err) }()
+
+	filter.From = filter.BucketStart(filter.From)
+
+	query := []report.Field{
+		{Key: "report", Value: "segment_history"},
+		{Key: "segment_slug", Value: filter.SegmentSlug},
+		{Key: "from", Value: opts.FormatTime(filter.From)},
+		{Key: "to", Value: opts.FormatTime(filter.To)},
+		{Key: "view", Value: view},
+	}
+
+	if view == models.SegmentHistoryViewEvents {
+		writer, err := report.NewWriter(w, opts, query, userHistoryColumns)
+
+		if err != nil {
+			return err
+		}
+
+		err = s.segmentRepo.StreamSegmentHistory(ctx, filter, 0, func(event *models.UserHistory) error {
+			return writeUserHistory(writer, event)
+		})
+
+		if err != nil {
+			return err
+		}
+
+		return writer.Close()
+	}
+
+	// Число интервалов ограничено, поэтому агрегаты собираются в памяти
+	buckets, err := s.getSegmentBuckets(ctx, filter)
+
+	if err != nil {
+		return err
+	}
+
+	query = append(query, report.Field{Key: "bucket", Value: filter.Bucket})
+
+	writer, err := report.NewWriter(w, opts, query, segmentBucketColumns)
+
+	if err != nil {
+		return err
+	}
+
+	for _, b := range buckets {
+		if err := writer.Write(b.Start, b.Entered, b.Left, b.Net, b.Members); err != nil {
+			return err
+		}
+	}
+
+	return writer.Close()
+}
+
+// getSegmentBuckets дополняет агрегаты из базы пустыми интервалами
+// и считает размер сегмента на конец каждого интервала.
+func (s *Segment) getSegmentBuckets(ctx context.Context, filter models.SegmentHistoryFilter) ([]*models.SegmentHistoryBucket, error) {
+	members, err := s.segmentRepo.CountSegmentMembersAt(ctx, filter.SegmentSlug, filter.From)
+
+	if err != nil {
+		return nil, err
+	}
+
+	stored, err := s.segmentRepo.GetSegmentHistoryBuckets(ctx, filter)
+
+	if err != nil {
+		return nil, err
+	}
+
+	byStart := make(map[int64]*models.SegmentHistoryBucket, len(stored))
+	for _, b := range stored {
+		byStart[b.Start.Unix()] = b
+	}
+
+	var buckets []*models.SegmentHistoryBucket
+
+	for start := filter.From; start.Before(filter.To); start = filter.NextBucket(start) {
+		bucket := &models.SegmentHistoryBucket{Start: start}
+
+		if b, ok := byStart[start.Unix()]; ok {
+			bucket.Entered = b.Entered
+			bucket.Left = b.Left
+		}
+
+		bucket.Net = bucket.Entered - bucket.Left
+		members += bucket.Net
+		bucket.Members = members
+
+		buckets = append(buckets, bucket)
+	}
+
+	return buckets, nil
+}
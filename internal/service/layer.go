@@ -0,0 +1,30 @@
+package service
+
+import (
+	"context"
+
+	"github.com/dezzerlol/avitotech-test-2023/internal/db/models"
+)
+
+type LayerRepo interface {
+	Create(ctx context.Context, layer *models.Layer) error
+	List(ctx context.Context) ([]*models.Layer, error)
+}
+
+type Layer struct {
+	layerRepo LayerRepo
+}
+
+func NewLayerSvc(layerRepo LayerRepo) *Layer {
+	return &Layer{
+		layerRepo: layerRepo,
+	}
+}
+
+func (s *Layer) Create(ctx context.Context, layer *models.Layer) error {
+	return s.layerRepo.Create(ctx, layer)
+}
+
+func (s *Layer) List(ctx context.Context) ([]*models.Layer, error) {
+	return s.layerRepo.List(ctx)
+}
@@ -0,0 +1,30 @@
+package service
+
+import (
+	"context"
+
+	"github.com/dezzerlol/avitotech-test-2023/internal/db/models"
+)
+
+type NamespaceRepo interface {
+	Create(ctx context.Context, ns *models.Namespace) error
+	List(ctx context.Context) ([]*models.Namespace, error)
+}
+
+type Namespace struct {
+	namespaceRepo NamespaceRepo
+}
+
+func NewNamespaceSvc(namespaceRepo NamespaceRepo) *Namespace {
+	return &Namespace{
+		namespaceRepo: namespaceRepo,
+	}
+}
+
+func (s *Namespace) Create(ctx context.Context, ns *models.Namespace) error {
+	return s.namespaceRepo.Create(ctx, ns)
+}
+
+func (s *Namespace) List(ctx context.Context) ([]*models.Namespace, error) {
+	return s.namespaceRepo.List(ctx)
+}
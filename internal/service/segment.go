@@ -2,63 +2,120 @@ package service
 
 import (
 	"context"
-	"encoding/csv"
-	"fmt"
-	"os"
+	"slices"
 	"time"
 
-	"github.com/dezzerlol/avitotech-test-2023/cfg"
+	"github.com/dezzerlol/avitotech-test-2023/internal/audit"
+	"github.com/dezzerlol/avitotech-test-2023/internal/cache"
 	"github.com/dezzerlol/avitotech-test-2023/internal/db/models"
+	"github.com/dezzerlol/avitotech-test-2023/internal/namespace"
+	"github.com/dezzerlol/avitotech-test-2023/internal/repo"
+	"github.com/dezzerlol/avitotech-test-2023/internal/rules"
+	"github.com/dezzerlol/avitotech-test-2023/internal/tracing"
 	"github.com/dezzerlol/avitotech-test-2023/internal/worker"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
+var tracer = tracing.Tracer("internal/service")
+
 type SegmentRepo interface {
 	Create(ctx context.Context, segment *models.Segment) error
 	DeleteBySlug(ctx context.Context, segment *models.Segment) error
 
-	AddUserSegments(ctx context.Context, userId int64, addSegments []string, ttl int64) (int64, error)
+	UpdateUserSegments(ctx context.Context, userId int64, addSegments []string, ttl int64, deleteSegments []string) (int64, int64, error)
 	AddRndUsersSegment(ctx context.Context, slug string, percent int8) error
-	DeleteUserSegments(ctx context.Context, userId int64, deleteSegments []string) (int64, error)
+	AddLayerUsersSegment(ctx context.Context, slug string) error
 	GetUserSegments(ctx context.Context, userId int64) ([]*models.Segment, error)
+	GetUserSegmentsAt(ctx context.Context, userId int64, at time.Time) ([]*models.Segment, error)
+	GetUsersSegments(ctx context.Context, userIds []int64, slugs []string) (map[int64][]*models.Segment, error)
+	GetRuleSegments(ctx context.Context) ([]*models.Segment, error)
+
+	GetVariants(ctx context.Context, slug string) ([]*models.SegmentVariant, error)
+	UpdateVariants(ctx context.Context, slug string, variants []*models.SegmentVariant) error
+	SetUserVariant(ctx context.Context, slug string, userId int64, variant string) error
 
 	GetUserHistory(ctx context.Context, userId int64, date time.Time) ([]*models.UserHistory, error)
+	StreamUserHistory(ctx context.Context, userId int64, date time.Time, fn func(*models.UserHistory) error) error
+
+	CountSegmentMembersAt(ctx context.Context, slug string, at time.Time) (int64, error)
+	GetSegmentHistoryBuckets(ctx context.Context, filter models.SegmentHistoryFilter) ([]*models.SegmentHistoryBucket, error)
+	StreamSegmentHistory(ctx context.Context, filter models.SegmentHistoryFilter, limit int64, fn func(*models.UserHistory) error) error
 }
 
 type UserRepo interface {
 	CreateUser(ctx context.Context) (int64, error)
 	CheckUserExist(ctx context.Context, userId int64) (bool, error)
+	GetAttributes(ctx context.Context, userId int64) (map[string]any, error)
+	GetUsersAttributes(ctx context.Context, userIds []int64) (map[int64]map[string]any, error)
+	UpsertAttributes(ctx context.Context, userId int64, attributes map[string]any, merge bool) (*models.User, error)
 }
 
 type Segment struct {
 	segmentRepo SegmentRepo
 	userRepo    UserRepo
 	worker      worker.TaskDistributor
+	// nil, если кеш выключен
+	cache *cache.UserSegments
 }
 
-func NewSegmentSvc(worker worker.TaskDistributor, segmentRepo SegmentRepo, userRepo UserRepo) *Segment {
+func NewSegmentSvc(worker worker.TaskDistributor, segmentRepo SegmentRepo, userRepo UserRepo, cache *cache.UserSegments) *Segment {
 	return &Segment{
 		segmentRepo: segmentRepo,
 		userRepo:    userRepo,
 		worker:      worker,
+		cache:       cache,
 	}
 }
 
-func (s *Segment) Create(ctx context.Context, segment *models.Segment) error {
-	err := s.segmentRepo.Create(ctx, segment)
+func (s *Segment) Create(ctx context.Context, segment *models.Segment) (err error) {
+	ctx, span := tracer.Start(ctx, "service.Segment.Create", trace.WithAttributes(attribute.String("segment.slug", segment.Slug)))
+	defer func() { tracing.End(span, err) }()
+
+	err = s.segmentRepo.Create(ctx, segment)
 
 	if err != nil {
 		return err
 	}
 
 	if segment.UserPercent > 0 {
-		err = s.segmentRepo.AddRndUsersSegment(ctx, segment.Slug, segment.UserPercent)
+		ctx = audit.WithSource(ctx, audit.SourceRollout)
+
+		// В слое раскатка берет пользователей из бакетов сегмента, чтобы не пересекаться с другими сегментами слоя
+		if segment.Layer != "" {
+			err = s.segmentRepo.AddLayerUsersSegment(ctx, segment.Slug)
+		} else {
+			err = s.segmentRepo.AddRndUsersSegment(ctx, segment.Slug, segment.UserPercent)
+		}
+
+		// Случайные пользователи заранее неизвестны, сбрасываем кеш всего пространства имен
+		s.cache.InvalidateNamespace(ctx, namespace.FromContext(ctx), cache.ReasonRollout)
+	}
+
+	// Динамический сегмент может появиться у любого пользователя пространства имен
+	if segment.Rule != "" {
+		s.cache.InvalidateNamespace(ctx, namespace.FromContext(ctx), cache.ReasonRuleSegment)
 	}
 
 	return err
 }
 
-func (s *Segment) DeleteBySlug(ctx context.Context, segment *models.Segment) error {
-	return s.segmentRepo.DeleteBySlug(ctx, segment)
+func (s *Segment) DeleteBySlug(ctx context.Context, segment *models.Segment) (err error) {
+	ctx, span := tracer.Start(ctx, "service.Segment.DeleteBySlug", trace.WithAttributes(attribute.String("segment.slug", segment.Slug)))
+	defer func() { tracing.End(span, err) }()
+
+	// Пользователи удаляются из сегмента каскадно, в истории это отражается как segment_delete
+	ctx = audit.WithSource(ctx, audit.SourceSegmentDelete)
+
+	err = s.segmentRepo.DeleteBySlug(ctx, segment)
+
+	if err != nil {
+		return err
+	}
+
+	s.cache.InvalidateNamespace(ctx, namespace.FromContext(ctx), cache.ReasonSegmentDelete)
+
+	return nil
 }
 
 func (s *Segment) UpdateUserSegments(
@@ -72,6 +129,22 @@ func (s *Segment) UpdateUserSegments(
 	segmentsDeleted int64,
 	err error,
 ) {
+	ctx, span := tracer.Start(ctx, "service.Segment.UpdateUserSegments", trace.WithAttributes(
+		attribute.Int64("user.id", userId),
+		attribute.StringSlice("segments.add", addSegments),
+		attribute.StringSlice("segments.delete", deleteSegments),
+		attribute.Int64("ttl", ttl),
+	))
+	defer func() {
+		span.SetAttributes(
+			attribute.Int64("segments.added", segmentsAdded),
+			attribute.Int64("segments.deleted", segmentsDeleted),
+		)
+		tracing.End(span, err)
+	}()
+
+	ctx = audit.WithSource(ctx, audit.SourceAPI)
+
 	// Проверяем, существует ли пользователь
 	// Если нет, то создаем новую запись в таблице users
 	isExists, err := s.userRepo.CheckUserExist(ctx, userId)
@@ -88,94 +161,226 @@ func (s *Segment) UpdateUserSegments(
 		}
 	}
 
-	// Если заданы сегменты на добавление, то добавляем их
-	if len(addSegments) > 0 {
-		segmentsAdded, err = s.segmentRepo.AddUserSegments(ctx, userId, addSegments, ttl)
-		if err != nil {
-			return segmentsAdded, segmentsDeleted, err
-		}
+	// Сегмент из обоих списков остается удаленным
+	addSegments = slices.DeleteFunc(slices.Clone(addSegments), func(slug string) bool {
+		return slices.Contains(deleteSegments, slug)
+	})
 
-		// Если задан TTL, то добавляем таски на удаление сегментов
-		if ttl > 0 {
-			for _, v := range addSegments {
-				payload := worker.SegmentExpirePayload{
-					UserID:      userId,
-					SegmentSlug: v,
-					ExpireAt:    ttl,
-				}
+	// Удаление и добавление выполняются в одной транзакции: при ошибке добавления пользователь остается в прежних сегментах
+	segmentsAdded, segmentsDeleted, err = s.segmentRepo.UpdateUserSegments(ctx, userId, addSegments, ttl, deleteSegments)
+
+	if err != nil {
+		return segmentsAdded, segmentsDeleted, err
+	}
 
-				s.worker.ScheduleSegmentExpireTask(ctx, payload)
+	s.cache.InvalidateUser(ctx, namespace.FromContext(ctx), userId, cache.ReasonUserUpdate)
+
+	// Если задан TTL, то добавляем таски на удаление сегментов
+	if ttl > 0 {
+		for _, v := range addSegments {
+			payload := worker.SegmentExpirePayload{
+				Namespace:   namespace.FromContext(ctx),
+				UserID:      userId,
+				SegmentSlug: v,
+				ExpireAt:    ttl,
+				RequestID:   audit.FromContext(ctx).RequestID,
 			}
+
+			s.worker.ScheduleSegmentExpireTask(ctx, payload)
 		}
 	}
 
-	// Если заданы сегменты на удаление, то удаляем их
-	if len(deleteSegments) > 0 {
-		segmentsDeleted, err = s.segmentRepo.DeleteUserSegments(ctx, userId, deleteSegments)
-		if err != nil {
-			return segmentsAdded, segmentsDeleted, err
+	return segmentsAdded, segmentsDeleted, nil
+}
+
+func (s *Segment) GetUserSegments(ctx context.Context, userId int64) (segments []*models.Segment, err error) {
+	ctx, span := tracer.Start(ctx, "service.Segment.GetUserSegments", trace.WithAttributes(attribute.Int64("user.id", userId)))
+	defer func() { tracing.End(span, err) }()
+
+	ns := namespace.FromContext(ctx)
+
+	if segments, ok := s.cache.Get(ctx, ns, userId); ok {
+		span.SetAttributes(attribute.Bool("cache.hit", true))
+		return segments, nil
+	}
+
+	segments, err = s.getUserSegments(ctx, userId)
+
+	// Несуществующих пользователей не кешируем, иначе созданный пользователь не будет найден до истечения TTL
+	if err == nil {
+		s.cache.Set(ctx, ns, userId, segments)
+	}
+
+	return segments, err
+}
+
+// getUserSegments возвращает сегменты, в которые пользователь добавлен, и динамические сегменты, правила которых ему подходят.
+func (s *Segment) getUserSegments(ctx context.Context, userId int64) ([]*models.Segment, error) {
+	segments, err := s.segmentRepo.GetUserSegments(ctx, userId)
+
+	if err != nil {
+		return nil, err
+	}
+
+	dynamic, err := s.ruleSegments(ctx, nil)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if len(dynamic) == 0 {
+		if len(segments) == 0 {
+			return segments, s.checkUserExist(ctx, userId)
 		}
+
+		return segments, nil
 	}
 
-	return segmentsAdded, segmentsDeleted, nil
+	// Атрибуты заодно проверяют, что пользователь существует
+	attributes, err := s.userRepo.GetAttributes(ctx, userId)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return appendMatched(segments, dynamic, attributes), nil
 }
 
-func (s *Segment) GetUserSegments(ctx context.Context, userId int64) ([]*models.Segment, error) {
-	return s.segmentRepo.GetUserSegments(ctx, userId)
+// GetUserSegmentsAt возвращает сегменты, в которых пользователь состоял на момент at.
+// Динамические сегменты не учитываются: история атрибутов не хранится.
+func (s *Segment) GetUserSegmentsAt(ctx context.Context, userId int64, at time.Time) (segments []*models.Segment, err error) {
+	ctx, span := tracer.Start(ctx, "service.Segment.GetUserSegmentsAt", trace.WithAttributes(attribute.Int64("user.id", userId)))
+	defer func() { tracing.End(span, err) }()
+
+	segments, err = s.segmentRepo.GetUserSegmentsAt(ctx, userId, at)
+
+	if err != nil || len(segments) > 0 {
+		return segments, err
+	}
+
+	return segments, s.checkUserExist(ctx, userId)
 }
 
-func (s *Segment) GetUserHistory(ctx context.Context, userId, month, year int64) (string, error) {
-	date := time.Date(int(year), time.Month(month), 1, 0, 0, 0, 0, time.UTC)
+// LookupUserSegments возвращает сегменты нескольких пользователей одним запросом к базе.
+// Если slugs не пустой, возвращаются только перечисленные сегменты.
+func (s *Segment) LookupUserSegments(ctx context.Context, userIds []int64, slugs []string) (lookup *models.UserSegmentsLookup, err error) {
+	ctx, span := tracer.Start(ctx, "service.Segment.LookupUserSegments", trace.WithAttributes(
+		attribute.Int("users.count", len(userIds)),
+		attribute.StringSlice("segments.filter", slugs),
+	))
+	defer func() { tracing.End(span, err) }()
 
-	userHistory, err := s.segmentRepo.GetUserHistory(ctx, userId, date)
+	users, err := s.segmentRepo.GetUsersSegments(ctx, userIds, slugs)
 
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 
-	filePath, err := s.generateCSVReport(userId, userHistory)
+	dynamic, err := s.ruleSegments(ctx, slugs)
 
 	if err != nil {
-		return "", err
+		return nil, err
+	}
+
+	if len(dynamic) > 0 && len(users) > 0 {
+		found := make([]int64, 0, len(users))
+
+		for userId := range users {
+			found = append(found, userId)
+		}
+
+		attributes, err := s.userRepo.GetUsersAttributes(ctx, found)
+
+		if err != nil {
+			return nil, err
+		}
+
+		for userId, segments := range users {
+			users[userId] = appendMatched(segments, dynamic, attributes[userId])
+		}
+	}
+
+	lookup = &models.UserSegmentsLookup{
+		Users:    users,
+		NotFound: []int64{},
 	}
 
-	// Ссылка для скачивания файла в формате addr:port/segment/reports/file_name.csv
-	addr := fmt.Sprintf("%s:%s", cfg.Get().REPORTS_HOST, cfg.Get().API_PORT)
-	downloadLink := addr + "/segment" + filePath
+	for _, userId := range userIds {
+		if _, ok := users[userId]; !ok && !slices.Contains(lookup.NotFound, userId) {
+			lookup.NotFound = append(lookup.NotFound, userId)
+		}
+	}
 
-	return downloadLink, err
+	return lookup, nil
 }
 
-func (s *Segment) generateCSVReport(userId int64, userHistory []*models.UserHistory) (string, error) {
-	fileName := fmt.Sprintf("./reports/%d-%d.csv", userId, time.Now().Unix())
+// checkUserExist возвращает repo.ErrUserNotFound, если пользователя нет.
+// Пустой список сегментов сам по себе не отличает пользователя без сегментов от несуществующего.
+func (s *Segment) checkUserExist(ctx context.Context, userId int64) error {
+	exists, err := s.userRepo.CheckUserExist(ctx, userId)
 
-	// Создаем файл
-	csvFile, err := os.Create(fileName)
+	if err != nil {
+		return err
+	}
+
+	if !exists {
+		return repo.ErrUserNotFound
+	}
+
+	return nil
+}
+
+type ruleSegment struct {
+	slug string
+	rule *rules.Rule
+}
+
+// ruleSegments возвращает разобранные правила динамических сегментов пространства имен.
+// Если slugs не пустой, возвращаются только перечисленные сегменты.
+func (s *Segment) ruleSegments(ctx context.Context, slugs []string) ([]ruleSegment, error) {
+	segments, err := s.segmentRepo.GetRuleSegments(ctx)
 
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 
-	defer csvFile.Close()
+	var dynamic []ruleSegment
 
-	csvWriter := csv.NewWriter(csvFile)
+	for _, segment := range segments {
+		if len(slugs) > 0 && !slices.Contains(slugs, segment.Slug) {
+			continue
+		}
 
-	// Записываем headers
-	csvWriter.Write([]string{"user_id", "segment_slug", "operation", "executed_at"})
+		rule, err := rules.ParseCached(segment.Rule)
 
-	// Записываем слайс значения в файл
-	for _, history := range userHistory {
-		csvWriter.Write([]string{
-			fmt.Sprintf("%d", history.UserID),
-			history.SegmentSlug,
-			history.Operation,
-			history.ExecutedAt.Format("2006-01-02 15:04:05"),
-		})
+		// Правила проверяются при создании сегмента, сюда может попасть только правило,
+		// записанное в базу в обход API. Такой сегмент не подходит никому.
+		if err != nil {
+			continue
+		}
+
+		dynamic = append(dynamic, ruleSegment{slug: segment.Slug, rule: rule})
 	}
 
-	csvWriter.Flush()
+	return dynamic, nil
+}
+
+// appendMatched добавляет к сегментам пользователя динамические сегменты, правила которых подходят его атрибутам.
+func appendMatched(segments []*models.Segment, dynamic []ruleSegment, attributes map[string]any) []*models.Segment {
+	for _, d := range dynamic {
+		if !d.rule.Match(attributes) {
+			continue
+		}
+
+		// Пользователь может быть добавлен в динамический сегмент и вручную
+		added := slices.ContainsFunc(segments, func(segment *models.Segment) bool {
+			return segment.Slug == d.slug
+		})
+
+		if !added {
+			segments = append(segments, &models.Segment{Slug: d.slug})
+		}
+	}
 
-	// убираем точку в начале пути
-	// возвращаем ссылку в формате /reports/file_name
-	return fileName[1:], nil
+	return segments
 }
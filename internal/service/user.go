@@ -1,17 +1,63 @@
 package service
 
-import "context"
+import (
+	"context"
+
+	"github.com/dezzerlol/avitotech-test-2023/internal/cache"
+	"github.com/dezzerlol/avitotech-test-2023/internal/db/models"
+)
 
 type User struct {
-	userRepo UserRepo
+	userRepo      UserRepo
+	namespaceRepo NamespaceRepo
+	// nil, если кеш выключен
+	cache *cache.UserSegments
 }
 
-func NewUserSvc(userRepo UserRepo) *User {
+func NewUserSvc(userRepo UserRepo, namespaceRepo NamespaceRepo, cache *cache.UserSegments) *User {
 	return &User{
-		userRepo: userRepo,
+		userRepo:      userRepo,
+		namespaceRepo: namespaceRepo,
+		cache:         cache,
 	}
 }
 
 func (u *User) Create(ctx context.Context) (int64, error) {
 	return u.userRepo.CreateUser(ctx)
 }
+
+// Get возвращает пользователя с атрибутами или repo.ErrUserNotFound.
+func (u *User) Get(ctx context.Context, userId int64) (*models.User, error) {
+	attributes, err := u.userRepo.GetAttributes(ctx, userId)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.User{Id: int(userId), Attributes: attributes}, nil
+}
+
+// Upsert создает пользователя с заданным id или обновляет его атрибуты.
+// Если merge, то атрибуты дописываются к существующим, иначе заменяют их.
+func (u *User) Upsert(ctx context.Context, userId int64, attributes map[string]any, merge bool) (*models.User, error) {
+	user, err := u.userRepo.UpsertAttributes(ctx, userId, attributes, merge)
+
+	if err != nil {
+		return nil, err
+	}
+
+	// Пользователи общие для всех пространств имен, поэтому динамические сегменты могли измениться в каждом
+	if u.cache != nil {
+		namespaces, err := u.namespaceRepo.List(ctx)
+
+		if err != nil {
+			return nil, err
+		}
+
+		for _, ns := range namespaces {
+			u.cache.InvalidateUser(ctx, ns.Slug, userId, cache.ReasonUserAttributes)
+		}
+	}
+
+	return user, nil
+}
@@ -0,0 +1,53 @@
+package service
+
+import (
+	"context"
+
+	"github.com/dezzerlol/avitotech-test-2023/internal/audit"
+	"github.com/dezzerlol/avitotech-test-2023/internal/cache"
+	"github.com/dezzerlol/avitotech-test-2023/internal/db/models"
+	"github.com/dezzerlol/avitotech-test-2023/internal/namespace"
+	"github.com/dezzerlol/avitotech-test-2023/internal/tracing"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func (s *Segment) GetVariants(ctx context.Context, slug string) (variants []*models.SegmentVariant, err error) {
+	ctx, span := tracer.Start(ctx, "service.Segment.GetVariants", trace.WithAttributes(attribute.String("segment.slug", slug)))
+	defer func() { tracing.End(span, err) }()
+
+	return s.segmentRepo.GetVariants(ctx, slug)
+}
+
+// UpdateVariants меняет веса вариантов и возвращает варианты сегмента после изменения.
+// Кеш не сбрасывается: варианты участников сегмента не меняются.
+func (s *Segment) UpdateVariants(ctx context.Context, slug string, variants []*models.SegmentVariant) (_ []*models.SegmentVariant, err error) {
+	ctx, span := tracer.Start(ctx, "service.Segment.UpdateVariants", trace.WithAttributes(attribute.String("segment.slug", slug)))
+	defer func() { tracing.End(span, err) }()
+
+	if err = s.segmentRepo.UpdateVariants(ctx, slug, variants); err != nil {
+		return nil, err
+	}
+
+	return s.segmentRepo.GetVariants(ctx, slug)
+}
+
+// SetUserVariant вручную переводит участника сегмента в другой вариант.
+func (s *Segment) SetUserVariant(ctx context.Context, slug string, userId int64, variant string) (err error) {
+	ctx, span := tracer.Start(ctx, "service.Segment.SetUserVariant", trace.WithAttributes(
+		attribute.String("segment.slug", slug),
+		attribute.Int64("user.id", userId),
+		attribute.String("segment.variant", variant),
+	))
+	defer func() { tracing.End(span, err) }()
+
+	ctx = audit.WithSource(ctx, audit.SourceAPI)
+
+	if err = s.segmentRepo.SetUserVariant(ctx, slug, userId, variant); err != nil {
+		return err
+	}
+
+	s.cache.InvalidateUser(ctx, namespace.FromContext(ctx), userId, cache.ReasonUserUpdate)
+
+	return nil
+}
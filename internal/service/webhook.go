@@ -0,0 +1,104 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"github.com/dezzerlol/avitotech-test-2023/internal/db/models"
+	"github.com/dezzerlol/avitotech-test-2023/internal/namespace"
+	"github.com/dezzerlol/avitotech-test-2023/internal/webhook"
+)
+
+type WebhookRepo interface {
+	Create(ctx context.Context, hook *models.Webhook) error
+	Get(ctx context.Context, id int64) (*models.Webhook, error)
+	List(ctx context.Context) ([]*models.Webhook, error)
+	Delete(ctx context.Context, id int64) error
+	AddDelivery(ctx context.Context, delivery *models.WebhookDelivery) error
+	ListDeliveries(ctx context.Context, webhookId int64, limit int64) ([]*models.WebhookDelivery, error)
+}
+
+type Webhook struct {
+	webhookRepo  WebhookRepo
+	sender       *webhook.Sender
+	allowPrivate bool
+}
+
+// timeout - время ожидания ответа на тестовое событие, allowPrivate разрешает вебхуки на непубличные адреса
+func NewWebhookSvc(webhookRepo WebhookRepo, timeout time.Duration, allowPrivate bool) *Webhook {
+	return &Webhook{
+		webhookRepo:  webhookRepo,
+		sender:       webhook.NewSender(timeout, allowPrivate),
+		allowPrivate: allowPrivate,
+	}
+}
+
+// Create регистрирует вебхук в пространстве имен запроса и возвращает ключ подписи.
+// Ключ возвращается только здесь. Адрес, который разрешается не в публичные адреса, возвращает webhook.ErrForbiddenAddress.
+func (s *Webhook) Create(ctx context.Context, hook *models.Webhook) (string, error) {
+	if !s.allowPrivate {
+		if err := webhook.CheckURL(ctx, hook.URL); err != nil {
+			return "", err
+		}
+	}
+
+	secret, err := webhook.GenerateSecret()
+
+	if err != nil {
+		return "", err
+	}
+
+	hook.Secret = secret
+
+	err = s.webhookRepo.Create(ctx, hook)
+
+	if err != nil {
+		return "", err
+	}
+
+	return secret, nil
+}
+
+func (s *Webhook) List(ctx context.Context) ([]*models.Webhook, error) {
+	return s.webhookRepo.List(ctx)
+}
+
+func (s *Webhook) Delete(ctx context.Context, id int64) error {
+	return s.webhookRepo.Delete(ctx, id)
+}
+
+// SendTest сразу отправляет вебхуку тестовое событие и возвращает результат попытки.
+// Неудачная попытка не повторяется, но, как и остальные, попадает в журнал доставки.
+func (s *Webhook) SendTest(ctx context.Context, id int64) (*models.WebhookDelivery, error) {
+	hook, err := s.webhookRepo.Get(ctx, id)
+
+	if err != nil {
+		return nil, err
+	}
+
+	event := models.MembershipEvent{
+		Type:       models.EventWebhookTest,
+		Namespace:  namespace.FromContext(ctx),
+		OccurredAt: time.Now().UTC(),
+	}
+
+	delivery := s.sender.Send(ctx, hook, event).Delivery(hook.ID, event, 1)
+
+	err = s.webhookRepo.AddDelivery(ctx, delivery)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return delivery, nil
+}
+
+// ListDeliveries возвращает последние limit попыток доставки вебхука.
+func (s *Webhook) ListDeliveries(ctx context.Context, id int64, limit int64) ([]*models.WebhookDelivery, error) {
+	// Для несуществующего вебхука возвращаем repo.ErrWebhookNotFound, а не пустой список
+	if _, err := s.webhookRepo.Get(ctx, id); err != nil {
+		return nil, err
+	}
+
+	return s.webhookRepo.ListDeliveries(ctx, id, limit)
+}
@@ -0,0 +1,90 @@
+package webhook
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"syscall"
+)
+
+var ErrForbiddenAddress = errors.New("webhook url must resolve to a public address")
+
+// Диапазоны, которых нет среди проверок net.IP
+var reservedNets = []*net.IPNet{
+	mustParseCIDR("0.0.0.0/8"),
+	// Shared address space (RFC 6598)
+	mustParseCIDR("100.64.0.0/10"),
+}
+
+func mustParseCIDR(s string) *net.IPNet {
+	_, ipNet, err := net.ParseCIDR(s)
+
+	if err != nil {
+		panic(err)
+	}
+
+	return ipNet
+}
+
+// PublicIP сообщает, можно ли отправлять вебхук на адрес ip. Loopback, частные (RFC 1918, fc00::/7),
+// link-local (в том числе адрес метаданных облака 169.254.169.254) и служебные адреса запрещены.
+func PublicIP(ip net.IP) bool {
+	if ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() || ip.IsMulticast() ||
+		ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() || ip.IsInterfaceLocalMulticast() {
+		return false
+	}
+
+	for _, ipNet := range reservedNets {
+		if ipNet.Contains(ip) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// CheckURL проверяет, что все адреса хоста rawURL публичные. Проверка при регистрации не защищает
+// от смены DNS записи, поэтому Sender дополнительно проверяет адрес при каждом соединении.
+func CheckURL(ctx context.Context, rawURL string) error {
+	u, err := url.Parse(rawURL)
+
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrForbiddenAddress, err)
+	}
+
+	addrs, err := net.DefaultResolver.LookupIPAddr(ctx, u.Hostname())
+
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrForbiddenAddress, err)
+	}
+
+	for _, addr := range addrs {
+		if !PublicIP(addr.IP) {
+			return fmt.Errorf("%w: %s resolves to %s", ErrForbiddenAddress, u.Hostname(), addr.IP)
+		}
+	}
+
+	return nil
+}
+
+// dialControl запрещает соединение с адресом, для которого allow возвращает false.
+// Вызывается после разрешения имени, поэтому проверяется адрес, с которым действительно устанавливается соединение.
+func dialControl(allow func(ip net.IP) bool) func(network, address string, c syscall.RawConn) error {
+	return func(network, address string, c syscall.RawConn) error {
+		host, _, err := net.SplitHostPort(address)
+
+		if err != nil {
+			return err
+		}
+
+		ip := net.ParseIP(host)
+
+		if ip == nil || !allow(ip) {
+			return fmt.Errorf("%w: %s", ErrForbiddenAddress, host)
+		}
+
+		return nil
+	}
+}
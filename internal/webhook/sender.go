@@ -0,0 +1,149 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/dezzerlol/avitotech-test-2023/internal/db/models"
+)
+
+const userAgent = "avitotech-segments-webhooks/1.0"
+
+var ErrUnexpectedStatus = errors.New("unexpected response status")
+
+// Сколько байт ответа читается, остальное отбрасывается
+const maxResponseBody = 4 << 10
+
+// Result - результат попытки доставки.
+type Result struct {
+	// 0, если ответ не получен
+	StatusCode int
+	Duration   time.Duration
+	// Ответ не получен или статус не 2xx
+	Err error
+}
+
+func (r Result) Success() bool {
+	return r.Err == nil
+}
+
+// Delivery возвращает запись журнала доставки для попытки attempt.
+func (r Result) Delivery(webhookId int64, event models.MembershipEvent, attempt int) *models.WebhookDelivery {
+	delivery := &models.WebhookDelivery{
+		WebhookID:  webhookId,
+		EventType:  event.Type,
+		Attempt:    attempt,
+		DurationMs: r.Duration.Milliseconds(),
+		Success:    r.Success(),
+	}
+
+	if event.ID != 0 {
+		delivery.EventID = &event.ID
+	}
+
+	if r.StatusCode != 0 {
+		delivery.StatusCode = &r.StatusCode
+	}
+
+	if r.Err != nil {
+		delivery.Error = r.Err.Error()
+	}
+
+	return delivery
+}
+
+// Sender отправляет события на адреса вебхуков.
+type Sender struct {
+	client *http.Client
+	now    func() time.Time
+}
+
+// NewSender возвращает Sender, который соединяется только с публичными адресами (PublicIP).
+// allowPrivate снимает проверку, например для получателей во внутренней сети при локальной разработке.
+func NewSender(timeout time.Duration, allowPrivate bool) *Sender {
+	allowIP := PublicIP
+
+	if allowPrivate {
+		allowIP = func(ip net.IP) bool { return true }
+	}
+
+	dialer := &net.Dialer{
+		Timeout: timeout,
+		Control: dialControl(allowIP),
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	// Через прокси проверялся бы адрес прокси, а не вебхука
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+
+	return &Sender{
+		client: &http.Client{
+			Timeout:   timeout,
+			Transport: transport,
+			// Редирект считается ошибкой: подписанное тело не должно уходить на другой адрес
+			CheckRedirect: func(req *http.Request, via []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		},
+		now: time.Now,
+	}
+}
+
+// Send отправляет событие POST запросом с подписью. Успешной считается доставка с ответом 2xx.
+func (s *Sender) Send(ctx context.Context, hook *models.Webhook, event models.MembershipEvent) Result {
+	start := time.Now()
+
+	statusCode, err := s.send(ctx, hook, event)
+
+	return Result{StatusCode: statusCode, Duration: time.Since(start), Err: err}
+}
+
+func (s *Sender) send(ctx context.Context, hook *models.Webhook, event models.MembershipEvent) (int, error) {
+	body, err := json.Marshal(event)
+
+	if err != nil {
+		return 0, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, hook.URL, bytes.NewReader(body))
+
+	if err != nil {
+		return 0, err
+	}
+
+	timestamp := s.now().Unix()
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", userAgent)
+	req.Header.Set(HeaderID, strconv.FormatInt(hook.ID, 10))
+	req.Header.Set(HeaderEventID, strconv.FormatInt(event.ID, 10))
+	req.Header.Set(HeaderEventType, event.Type)
+	req.Header.Set(HeaderTimestamp, strconv.FormatInt(timestamp, 10))
+	req.Header.Set(HeaderSignature, Sign(hook.Secret, timestamp, body))
+
+	res, err := s.client.Do(req)
+
+	if err != nil {
+		return 0, err
+	}
+
+	defer res.Body.Close()
+
+	// Дочитываем ответ, чтобы соединение вернулось в пул
+	io.Copy(io.Discard, io.LimitReader(res.Body, maxResponseBody))
+
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		return res.StatusCode, fmt.Errorf("%w: %d", ErrUnexpectedStatus, res.StatusCode)
+	}
+
+	return res.StatusCode, nil
+}
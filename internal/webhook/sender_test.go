@@ -0,0 +1,147 @@
+package webhook
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dezzerlol/avitotech-test-2023/internal/db/models"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_SenderSend(t *testing.T) {
+	event := models.MembershipEvent{ID: 42, Type: models.EventUserAdded, Namespace: "default", Segment: "AVITO_TEST", UserID: 1000}
+
+	t.Run("Should send signed event", func(t *testing.T) {
+		hook := &models.Webhook{ID: 7, Secret: "whsec_test"}
+
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			body, err := io.ReadAll(r.Body)
+			require.NoError(t, err)
+
+			timestamp, err := strconv.ParseInt(r.Header.Get(HeaderTimestamp), 10, 64)
+			require.NoError(t, err)
+
+			require.True(t, Verify(hook.Secret, timestamp, body, r.Header.Get(HeaderSignature)))
+			require.Equal(t, "7", r.Header.Get(HeaderID))
+			require.Equal(t, "42", r.Header.Get(HeaderEventID))
+			require.Equal(t, models.EventUserAdded, r.Header.Get(HeaderEventType))
+
+			w.WriteHeader(http.StatusNoContent)
+		}))
+		defer srv.Close()
+
+		hook.URL = srv.URL
+
+		res := NewSender(time.Second, true).Send(context.Background(), hook, event)
+
+		require.True(t, res.Success())
+		require.Equal(t, http.StatusNoContent, res.StatusCode)
+
+		delivery := res.Delivery(hook.ID, event, 1)
+		require.True(t, delivery.Success)
+		require.Equal(t, int64(42), *delivery.EventID)
+	})
+
+	t.Run("Should fail on non 2xx status and redirect", func(t *testing.T) {
+		for _, status := range []int{http.StatusInternalServerError, http.StatusFound} {
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if status == http.StatusFound {
+					w.Header().Set("Location", "https://example.com")
+				}
+
+				w.WriteHeader(status)
+			}))
+
+			res := NewSender(time.Second, true).Send(context.Background(), &models.Webhook{URL: srv.URL, Secret: "whsec_test"}, event)
+			srv.Close()
+
+			require.ErrorIs(t, res.Err, ErrUnexpectedStatus)
+			require.Equal(t, status, res.StatusCode)
+			require.Equal(t, status, *res.Delivery(1, event, 1).StatusCode)
+		}
+	})
+
+	t.Run("Should fail if receiver is unavailable", func(t *testing.T) {
+		srv := httptest.NewServer(http.NotFoundHandler())
+		srv.Close()
+
+		res := NewSender(time.Second, true).Send(context.Background(), &models.Webhook{URL: srv.URL, Secret: "whsec_test"}, event)
+
+		require.Error(t, res.Err)
+		require.Nil(t, res.Delivery(1, event, 1).StatusCode)
+	})
+}
+
+func Test_SenderForbiddenAddress(t *testing.T) {
+	var received bool
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received = true
+	}))
+	defer srv.Close()
+
+	event := models.MembershipEvent{ID: 42, Type: models.EventUserAdded}
+
+	// Адрес проверяется при соединении, поэтому запрещен и хост, который после регистрации стал указывать на loopback
+	for _, url := range []string{srv.URL, strings.Replace(srv.URL, "127.0.0.1", "localhost", 1)} {
+		res := NewSender(time.Second, false).Send(context.Background(), &models.Webhook{URL: url, Secret: "whsec_test"}, event)
+
+		require.ErrorIs(t, res.Err, ErrForbiddenAddress, url)
+		require.Zero(t, res.StatusCode)
+	}
+
+	require.False(t, received)
+}
+
+func Test_PublicIP(t *testing.T) {
+	tests := []struct {
+		ip     string
+		public bool
+	}{
+		{"93.184.216.34", true},
+		{"2606:2800:220:1:248:1893:25c8:1946", true},
+		{"127.0.0.1", false},
+		{"::1", false},
+		{"10.0.0.1", false},
+		{"172.16.5.4", false},
+		{"192.168.1.1", false},
+		{"169.254.169.254", false},
+		{"fe80::1", false},
+		{"fd00::1", false},
+		{"0.0.0.0", false},
+		{"100.64.0.1", false},
+		{"::ffff:127.0.0.1", false},
+	}
+
+	for _, tc := range tests {
+		require.Equal(t, tc.public, PublicIP(net.ParseIP(tc.ip)), tc.ip)
+	}
+}
+
+func Test_CheckURL(t *testing.T) {
+	for _, url := range []string{"http://127.0.0.1:8080/hook", "http://localhost/hook", "http://169.254.169.254/latest/meta-data", "https://10.1.2.3/hook", "http://[::1]/hook"} {
+		require.ErrorIs(t, CheckURL(context.Background(), url), ErrForbiddenAddress, url)
+	}
+
+	require.NoError(t, CheckURL(context.Background(), "https://93.184.216.34/hook"))
+}
+
+func Test_Signature(t *testing.T) {
+	secret, err := GenerateSecret()
+	require.NoError(t, err)
+	require.Contains(t, secret, secretPrefix)
+
+	body := []byte(`{"id":1}`)
+	signature := Sign(secret, 1700000000, body)
+
+	require.True(t, Verify(secret, 1700000000, body, signature))
+	require.False(t, Verify(secret, 1700000001, body, signature))
+	require.False(t, Verify("whsec_other", 1700000000, body, signature))
+}
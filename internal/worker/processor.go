@@ -2,29 +2,75 @@ package worker
 
 import (
 	"context"
+	"encoding/json"
+	"time"
 
+	"github.com/dezzerlol/avitotech-test-2023/internal/cache"
+	"github.com/dezzerlol/avitotech-test-2023/internal/db/models"
+	"github.com/dezzerlol/avitotech-test-2023/internal/metrics"
 	"github.com/dezzerlol/avitotech-test-2023/internal/repo"
+	"github.com/dezzerlol/avitotech-test-2023/internal/tracing"
+	"github.com/dezzerlol/avitotech-test-2023/internal/webhook"
 	"github.com/hibiken/asynq"
 	"github.com/jackc/pgx/v5/pgxpool"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 	"go.uber.org/zap"
 )
 
+var tracer = tracing.Tracer("internal/worker")
+
 type TaskProcessor interface {
 	Start() error
 	ProcessSegmentExpireTask(ctx context.Context, task *asynq.Task) error
+	ProcessRefreshMembershipTask(ctx context.Context, task *asynq.Task) error
+	ProcessOutboxDispatchTask(ctx context.Context, task *asynq.Task) error
+	ProcessWebhookDeliverTask(ctx context.Context, task *asynq.Task) error
 }
 
 type SegmentRepo interface {
 	DeleteUserSegments(ctx context.Context, userId int64, deleteSegments []string) (int64, error)
 }
 
+type AnalyticsRepo interface {
+	RefreshMembershipRollup(ctx context.Context, now time.Time) error
+}
+
+type OutboxRepo interface {
+	Dispatch(ctx context.Context, limit int, fn func(event models.MembershipEvent, webhookIds []int64) error) (int, error)
+	DeleteDispatched(ctx context.Context, before time.Time) (int64, error)
+}
+
+type WebhookRepo interface {
+	Get(ctx context.Context, id int64) (*models.Webhook, error)
+	AddDelivery(ctx context.Context, delivery *models.WebhookDelivery) error
+}
+
+// WebhookConfig задает доставку вебхуков.
+type WebhookConfig struct {
+	// Сколько раз повторять неудачную доставку
+	MaxRetries int
+	// Время ожидания ответа получателя
+	Timeout time.Duration
+	// Разрешить доставку на loopback, частные и link-local адреса
+	AllowPrivate bool
+}
+
 type RedisTaskProcessor struct {
-	server      *asynq.Server
-	logger      *zap.SugaredLogger
-	segmentRepo SegmentRepo
+	server        *asynq.Server
+	client        *asynq.Client
+	logger        *zap.SugaredLogger
+	segmentRepo   SegmentRepo
+	analyticsRepo AnalyticsRepo
+	outboxRepo    OutboxRepo
+	webhookRepo   WebhookRepo
+	sender        *webhook.Sender
+	webhookCfg    WebhookConfig
+	// nil, если кеш выключен
+	cache *cache.UserSegments
 }
 
-func NewTaskProcessor(r asynq.RedisClientOpt, logger *zap.SugaredLogger, db *pgxpool.Pool) TaskProcessor {
+func NewTaskProcessor(r asynq.RedisClientOpt, logger *zap.SugaredLogger, db *pgxpool.Pool, cache *cache.UserSegments, webhookCfg WebhookConfig) TaskProcessor {
 	server := asynq.NewServer(r, asynq.Config{
 		ErrorHandler: asynq.ErrorHandlerFunc(func(ctx context.Context, task *asynq.Task, err error) {
 			logger.Errorw(
@@ -33,22 +79,84 @@ func NewTaskProcessor(r asynq.RedisClientOpt, logger *zap.SugaredLogger, db *pgx
 				"err: ", err,
 			)
 		}),
-		Logger: logger,
+		RetryDelayFunc: retryDelay,
+		Logger:         logger,
 	})
 
 	segmentRepo := repo.NewSegmentRepo(db)
+	analyticsRepo := repo.NewAnalyticsRepo(db)
+	outboxRepo := repo.NewOutboxRepo(db)
+	webhookRepo := repo.NewWebhookRepo(db)
 
 	return &RedisTaskProcessor{
-		server:      server,
-		logger:      logger,
-		segmentRepo: segmentRepo,
+		server:        server,
+		client:        asynq.NewClient(r),
+		logger:        logger,
+		segmentRepo:   segmentRepo,
+		analyticsRepo: analyticsRepo,
+		outboxRepo:    outboxRepo,
+		webhookRepo:   webhookRepo,
+		sender:        webhook.NewSender(webhookCfg.Timeout, webhookCfg.AllowPrivate),
+		webhookCfg:    webhookCfg,
+		cache:         cache,
+	}
+}
+
+// retryDelay задает задержку перед повтором задачи. У доставки вебхуков своя задержка, у остальных задач - по умолчанию asynq.
+func retryDelay(n int, err error, task *asynq.Task) time.Duration {
+	if task.Type() == WebhookDeliverTaskType {
+		return webhookRetryDelay(n)
 	}
+
+	return asynq.DefaultRetryDelayFunc(n, err, task)
 }
 
 func (p *RedisTaskProcessor) Start() error {
 	mux := asynq.NewServeMux()
+	mux.Use(observeTask)
+	mux.Use(traceTask)
 
 	mux.HandleFunc(SegmentExpireTaskType, p.ProcessSegmentExpireTask)
+	mux.HandleFunc(RefreshMembershipTaskType, p.ProcessRefreshMembershipTask)
+	mux.HandleFunc(OutboxDispatchTaskType, p.ProcessOutboxDispatchTask)
+	mux.HandleFunc(WebhookDeliverTaskType, p.ProcessWebhookDeliverTask)
 
 	return p.server.Run(mux)
 }
+
+// observeTask учитывает результат и время обработки задачи в метриках.
+func observeTask(next asynq.Handler) asynq.Handler {
+	return asynq.HandlerFunc(func(ctx context.Context, task *asynq.Task) error {
+		start := time.Now()
+
+		err := next.ProcessTask(ctx, task)
+		metrics.ObserveTask(task.Type(), err, time.Since(start))
+
+		return err
+	})
+}
+
+// traceTask начинает спан обработки задачи, продолжая трейс из поля TraceContext в payload.
+// У задач без него, например периодических, начинается новый трейс.
+func traceTask(next asynq.Handler) asynq.Handler {
+	return asynq.HandlerFunc(func(ctx context.Context, task *asynq.Task) (err error) {
+		var payload struct {
+			TraceContext map[string]string
+		}
+
+		// Ошибку разбора payload вернет сам обработчик задачи
+		_ = json.Unmarshal(task.Payload(), &payload)
+
+		ctx = tracing.Extract(ctx, payload.TraceContext)
+
+		taskId, _ := asynq.GetTaskID(ctx)
+
+		ctx, span := tracer.Start(ctx, "asynq.process "+task.Type(),
+			trace.WithSpanKind(trace.SpanKindConsumer),
+			trace.WithAttributes(attribute.String("asynq.task_id", taskId)),
+		)
+		defer func() { tracing.End(span, err) }()
+
+		return next.ProcessTask(ctx, task)
+	})
+}
@@ -0,0 +1,35 @@
+package worker
+
+import (
+	"github.com/hibiken/asynq"
+	"go.uber.org/zap"
+)
+
+// NewScheduler создает планировщик периодических задач.
+// Задачи ставятся в ту же очередь, что и остальные, и выполняются TaskProcessor.
+func NewScheduler(redis asynq.RedisClientOpt, logger *zap.SugaredLogger, refreshMembershipCron, outboxDispatchCron string) (*asynq.Scheduler, error) {
+	scheduler := asynq.NewScheduler(redis, &asynq.SchedulerOpts{
+		Logger: logger,
+		EnqueueErrorHandler: func(task *asynq.Task, opts []asynq.Option, err error) {
+			logger.Errorw(
+				"error scheduling task",
+				"task_type", task.Type(),
+				"err", err,
+			)
+		},
+	})
+
+	_, err := scheduler.Register(refreshMembershipCron, NewRefreshMembershipTask())
+
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = scheduler.Register(outboxDispatchCron, NewOutboxDispatchTask())
+
+	if err != nil {
+		return nil, err
+	}
+
+	return scheduler, nil
+}
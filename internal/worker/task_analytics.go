@@ -0,0 +1,36 @@
+package worker
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/hibiken/asynq"
+)
+
+const (
+	RefreshMembershipTaskType = "analytics:refresh_membership"
+)
+
+func NewRefreshMembershipTask() *asynq.Task {
+	// Unique не дает поставить новый пересчет, пока предыдущий еще в очереди
+	return asynq.NewTask(RefreshMembershipTaskType, nil, asynq.Unique(time.Minute), asynq.MaxRetry(3))
+}
+
+func (p *RedisTaskProcessor) ProcessRefreshMembershipTask(ctx context.Context, task *asynq.Task) error {
+	start := time.Now()
+
+	err := p.analyticsRepo.RefreshMembershipRollup(ctx, start)
+
+	if err != nil {
+		return fmt.Errorf("analyticsRepo.RefreshMembershipRollup failed: %w", err)
+	}
+
+	p.logger.Infow(
+		"task processed",
+		"task_type", task.Type(),
+		"duration", time.Since(start),
+	)
+
+	return nil
+}
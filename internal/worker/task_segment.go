@@ -5,21 +5,37 @@ import (
 	"encoding/json"
 	"fmt"
 
+	"github.com/dezzerlol/avitotech-test-2023/internal/audit"
+	"github.com/dezzerlol/avitotech-test-2023/internal/cache"
 	"github.com/dezzerlol/avitotech-test-2023/internal/db/models"
+	"github.com/dezzerlol/avitotech-test-2023/internal/namespace"
+	"github.com/dezzerlol/avitotech-test-2023/internal/tracing"
 	"github.com/hibiken/asynq"
+	"go.opentelemetry.io/otel/trace"
 )
 
 type SegmentExpirePayload struct {
+	// Пустое у задач, поставленных до появления пространств имен, для них используется default
+	Namespace   string
 	UserID      int64
 	SegmentSlug string
 	ExpireAt    int64 // seconds
+	// id запроса, который добавил сегмент с ttl. Пустой у задач, поставленных до его появления
+	RequestID string
+	// Контекст трейса запроса, обработка задачи продолжает его
+	TraceContext map[string]string `json:",omitempty"`
 }
 
 const (
 	SegmentExpireTaskType = "segment:expire"
 )
 
-func (d *RedisTaskDistributor) ScheduleSegmentExpireTask(ctx context.Context, payload SegmentExpirePayload, opts ...asynq.Option) error {
+func (d *RedisTaskDistributor) ScheduleSegmentExpireTask(ctx context.Context, payload SegmentExpirePayload, opts ...asynq.Option) (err error) {
+	ctx, span := tracer.Start(ctx, "asynq.enqueue "+SegmentExpireTaskType, trace.WithSpanKind(trace.SpanKindProducer))
+	defer func() { tracing.End(span, err) }()
+
+	payload.TraceContext = tracing.Inject(ctx)
+
 	jsonPayload, err := json.Marshal(payload)
 
 	if err != nil {
@@ -32,14 +48,19 @@ func (d *RedisTaskDistributor) ScheduleSegmentExpireTask(ctx context.Context, pa
 
 	info, err := d.client.EnqueueContext(ctx, task, asynq.ProcessAt(processAt.Time))
 
+	if err != nil {
+		return err
+	}
+
 	d.logger.Infow(
 		"task enqueue",
 		"task_type", info.Type,
 		"task_id", info.ID,
 		"queue", info.Queue,
+		"request_id", payload.RequestID,
 	)
 
-	return err
+	return nil
 }
 
 func (p *RedisTaskProcessor) ProcessSegmentExpireTask(ctx context.Context, task *asynq.Task) error {
@@ -48,15 +69,35 @@ func (p *RedisTaskProcessor) ProcessSegmentExpireTask(ctx context.Context, task
 		return fmt.Errorf("json.Unmarshal failed: %v: %w", err, asynq.SkipRetry)
 	}
 
+	taskId, _ := asynq.GetTaskID(ctx)
+
+	// В историю пишется id исходного запроса, чтобы удаление по ttl можно было связать с ним
+	requestId := payload.RequestID
+
+	if requestId == "" {
+		requestId = taskId
+	}
+
+	ctx = audit.WithMeta(ctx, audit.Meta{
+		Source:    audit.SourceTTL,
+		Actor:     "worker",
+		RequestID: requestId,
+	})
+	ctx = namespace.WithNamespace(ctx, payload.Namespace)
+
 	_, err := p.segmentRepo.DeleteUserSegments(ctx, payload.UserID, []string{payload.SegmentSlug})
 
 	if err != nil {
 		return fmt.Errorf("segmentService.UpdateUserSegments failed: %v: %w", err, asynq.SkipRetry)
 	}
 
+	p.cache.InvalidateUser(ctx, namespace.FromContext(ctx), payload.UserID, cache.ReasonSegmentExpire)
+
 	p.logger.Infow(
 		"task processed",
 		"task_type", task.Type(),
+		"task_id", taskId,
+		"request_id", requestId,
 	)
 
 	return nil
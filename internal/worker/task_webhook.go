@@ -0,0 +1,168 @@
+package worker
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/rand"
+	"time"
+
+	"github.com/dezzerlol/avitotech-test-2023/internal/db/models"
+	"github.com/dezzerlol/avitotech-test-2023/internal/metrics"
+	"github.com/dezzerlol/avitotech-test-2023/internal/namespace"
+	"github.com/dezzerlol/avitotech-test-2023/internal/repo"
+	"github.com/hibiken/asynq"
+)
+
+const (
+	OutboxDispatchTaskType = "outbox:dispatch"
+	WebhookDeliverTaskType = "webhook:deliver"
+)
+
+const (
+	// Сколько событий outbox отмечается отправленными в одной транзакции
+	outboxBatchSize = 500
+	// Сколько хранятся отправленные события
+	outboxRetention = 24 * time.Hour
+
+	webhookRetryBaseDelay = 10 * time.Second
+	webhookRetryMaxDelay  = time.Hour
+)
+
+type WebhookDeliverPayload struct {
+	WebhookID int64
+	Event     models.MembershipEvent
+}
+
+func NewOutboxDispatchTask() *asynq.Task {
+	// Следующий запуск по расписанию и есть повтор, поэтому задача не повторяется сама
+	return asynq.NewTask(OutboxDispatchTaskType, nil, asynq.Unique(time.Minute), asynq.MaxRetry(0))
+}
+
+// webhookRetryDelay растет экспоненциально от 10 секунд до часа, разброс до 20% не дает повторам
+// к одному получателю приходить одновременно.
+func webhookRetryDelay(n int) time.Duration {
+	delay := webhookRetryMaxDelay
+
+	if n < 16 {
+		delay = min(webhookRetryBaseDelay<<n, webhookRetryMaxDelay)
+	}
+
+	return delay + time.Duration(rand.Int63n(int64(delay)/5+1))
+}
+
+// ProcessOutboxDispatchTask ставит задачи доставки для новых событий outbox и удаляет старые отправленные события.
+func (p *RedisTaskProcessor) ProcessOutboxDispatchTask(ctx context.Context, task *asynq.Task) error {
+	total := 0
+
+	for {
+		n, err := p.outboxRepo.Dispatch(ctx, outboxBatchSize, func(event models.MembershipEvent, webhookIds []int64) error {
+			for _, webhookId := range webhookIds {
+				if err := p.enqueueWebhookDelivery(ctx, webhookId, event); err != nil {
+					return err
+				}
+			}
+
+			return nil
+		})
+
+		if err != nil {
+			return fmt.Errorf("outboxRepo.Dispatch failed: %w", err)
+		}
+
+		total += n
+
+		if n < outboxBatchSize {
+			break
+		}
+	}
+
+	deleted, err := p.outboxRepo.DeleteDispatched(ctx, time.Now().Add(-outboxRetention))
+
+	if err != nil {
+		return fmt.Errorf("outboxRepo.DeleteDispatched failed: %w", err)
+	}
+
+	if total > 0 || deleted > 0 {
+		p.logger.Infow(
+			"task processed",
+			"task_type", task.Type(),
+			"events_dispatched", total,
+			"events_deleted", deleted,
+		)
+	}
+
+	return nil
+}
+
+func (p *RedisTaskProcessor) enqueueWebhookDelivery(ctx context.Context, webhookId int64, event models.MembershipEvent) error {
+	jsonPayload, err := json.Marshal(WebhookDeliverPayload{WebhookID: webhookId, Event: event})
+
+	if err != nil {
+		return err
+	}
+
+	task := asynq.NewTask(WebhookDeliverTaskType, jsonPayload,
+		// Если транзакция outbox не зафиксировалась, событие будет взято снова, а задача с тем же id не поставится второй раз
+		asynq.TaskID(fmt.Sprintf("webhook:%d:%d", webhookId, event.ID)),
+		asynq.MaxRetry(p.webhookCfg.MaxRetries),
+	)
+
+	_, err = p.client.EnqueueContext(ctx, task)
+
+	if errors.Is(err, asynq.ErrTaskIDConflict) {
+		return nil
+	}
+
+	return err
+}
+
+// ProcessWebhookDeliverTask отправляет событие на адрес вебхука и пишет попытку в журнал доставки.
+// Ошибка доставки возвращается, и asynq повторяет задачу с задержкой webhookRetryDelay.
+func (p *RedisTaskProcessor) ProcessWebhookDeliverTask(ctx context.Context, task *asynq.Task) error {
+	var payload WebhookDeliverPayload
+	if err := json.Unmarshal(task.Payload(), &payload); err != nil {
+		return fmt.Errorf("json.Unmarshal failed: %v: %w", err, asynq.SkipRetry)
+	}
+
+	taskId, _ := asynq.GetTaskID(ctx)
+	retry, _ := asynq.GetRetryCount(ctx)
+
+	ctx = namespace.WithNamespace(ctx, payload.Event.Namespace)
+
+	hook, err := p.webhookRepo.Get(ctx, payload.WebhookID)
+
+	// Вебхук удалили после постановки задачи
+	if errors.Is(err, repo.ErrWebhookNotFound) {
+		return nil
+	}
+
+	if err != nil {
+		return fmt.Errorf("webhookRepo.Get failed: %w", err)
+	}
+
+	res := p.sender.Send(ctx, hook, payload.Event)
+	metrics.ObserveWebhookDelivery(payload.Event.Type, res.Err)
+
+	err = p.webhookRepo.AddDelivery(ctx, res.Delivery(hook.ID, payload.Event, retry+1))
+
+	if err != nil && !errors.Is(err, repo.ErrWebhookNotFound) {
+		p.logger.Errorw("error saving webhook delivery", "task_id", taskId, "err", err)
+	}
+
+	if res.Err != nil {
+		return fmt.Errorf("webhook delivery failed: %w", res.Err)
+	}
+
+	p.logger.Infow(
+		"task processed",
+		"task_type", task.Type(),
+		"task_id", taskId,
+		"webhook_id", hook.ID,
+		"event_id", payload.Event.ID,
+		"attempt", retry+1,
+	)
+
+	return nil
+}
@@ -0,0 +1,77 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+)
+
+// IssueAPIKey выпускает API ключ. Нужно право keys:admin.
+func (c *Client) IssueAPIKey(ctx context.Context, req IssueAPIKeyRequest) (*IssuedAPIKey, error) {
+	res := &IssuedAPIKey{}
+
+	if err := c.do(ctx, request{method: http.MethodPost, path: "/admin/keys", body: req}, res); err != nil {
+		return nil, err
+	}
+
+	return res, nil
+}
+
+// ListAPIKeys возвращает все API ключи, включая отозванные.
+func (c *Client) ListAPIKeys(ctx context.Context) ([]*APIKey, error) {
+	var res struct {
+		APIKeys []*APIKey `json:"api_keys"`
+	}
+
+	if err := c.do(ctx, request{method: http.MethodGet, path: "/admin/keys"}, &res); err != nil {
+		return nil, err
+	}
+
+	return res.APIKeys, nil
+}
+
+// RotateAPIKey выпускает новое значение ключа, старое перестает работать.
+func (c *Client) RotateAPIKey(ctx context.Context, id int64) (*IssuedAPIKey, error) {
+	res := &IssuedAPIKey{}
+
+	req := request{method: http.MethodPost, path: "/admin/keys/" + strconv.FormatInt(id, 10) + "/rotate"}
+
+	if err := c.do(ctx, req, res); err != nil {
+		return nil, err
+	}
+
+	return res, nil
+}
+
+// RevokeAPIKey отзывает ключ.
+func (c *Client) RevokeAPIKey(ctx context.Context, id int64) error {
+	return c.do(ctx, request{method: http.MethodDelete, path: "/admin/keys/" + strconv.FormatInt(id, 10)}, nil)
+}
+
+// CreateNamespace создает пространство имен.
+func (c *Client) CreateNamespace(ctx context.Context, slug string) (*Namespace, error) {
+	var res struct {
+		Namespace *Namespace `json:"namespace"`
+	}
+
+	body := map[string]string{"slug": slug}
+
+	if err := c.do(ctx, request{method: http.MethodPost, path: "/admin/namespaces", body: body}, &res); err != nil {
+		return nil, err
+	}
+
+	return res.Namespace, nil
+}
+
+// ListNamespaces возвращает все пространства имен.
+func (c *Client) ListNamespaces(ctx context.Context) ([]*Namespace, error) {
+	var res struct {
+		Namespaces []*Namespace `json:"namespaces"`
+	}
+
+	if err := c.do(ctx, request{method: http.MethodGet, path: "/admin/namespaces"}, &res); err != nil {
+		return nil, err
+	}
+
+	return res.Namespaces, nil
+}
@@ -0,0 +1,54 @@
+package client
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+const dateLayout = "2006-01-02"
+
+// GetMembership возвращает размер сегментов по дням.
+func (c *Client) GetMembership(ctx context.Context, q MembershipQuery) (*Membership, error) {
+	var res struct {
+		Membership *Membership `json:"membership"`
+	}
+
+	req := request{
+		method:     http.MethodGet,
+		path:       "/analytics/membership",
+		namespaced: true,
+		query:      q.values(url.Values{}),
+	}
+
+	if err := c.do(ctx, req, &res); err != nil {
+		return nil, err
+	}
+
+	return res.Membership, nil
+}
+
+// ExportMembership выгружает размер сегментов по дням в формате opts.Format. Тело нужно закрыть.
+func (c *Client) ExportMembership(ctx context.Context, q MembershipQuery, opts *ReportOptions) (io.ReadCloser, error) {
+	req := request{
+		method:     http.MethodGet,
+		path:       "/analytics/membership/export." + string(opts.format()),
+		namespaced: true,
+		query:      q.values(opts.values()),
+	}
+
+	return c.stream(ctx, req)
+}
+
+func (q MembershipQuery) values(v url.Values) url.Values {
+	v.Set("from", q.From.Format(dateLayout))
+	v.Set("to", q.To.Format(dateLayout))
+
+	if len(q.Segments) > 0 {
+		v.Set("segments", strings.Join(q.Segments, ","))
+	}
+
+	return v
+}
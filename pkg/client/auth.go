@@ -0,0 +1,49 @@
+package client
+
+import (
+	"context"
+	"net/http"
+)
+
+// Auth добавляет к запросу данные авторизации. Вызывается перед каждой попыткой запроса,
+// поэтому может обновлять истекший токен.
+type Auth interface {
+	Apply(ctx context.Context, r *http.Request) error
+}
+
+// AuthFunc позволяет использовать функцию как Auth.
+type AuthFunc func(ctx context.Context, r *http.Request) error
+
+func (f AuthFunc) Apply(ctx context.Context, r *http.Request) error {
+	return f(ctx, r)
+}
+
+// APIKeyAuth передает ключ в заголовке X-Api-Key.
+func APIKeyAuth(key string) Auth {
+	return AuthFunc(func(ctx context.Context, r *http.Request) error {
+		r.Header.Set("X-Api-Key", key)
+		return nil
+	})
+}
+
+// BearerAuth передает JWT в заголовке Authorization.
+func BearerAuth(token string) Auth {
+	return TokenSourceAuth(func(ctx context.Context) (string, error) {
+		return token, nil
+	})
+}
+
+// TokenSourceAuth получает JWT перед каждым запросом, например из кеша с обновлением.
+func TokenSourceAuth(token func(ctx context.Context) (string, error)) Auth {
+	return AuthFunc(func(ctx context.Context, r *http.Request) error {
+		t, err := token(ctx)
+
+		if err != nil {
+			return err
+		}
+
+		r.Header.Set("Authorization", "Bearer "+t)
+
+		return nil
+	})
+}
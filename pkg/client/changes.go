@@ -0,0 +1,30 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strconv"
+)
+
+// Changes возвращает изменения членства в пространстве имен клиента после курсора after.
+// Пустой after - с начала ленты, limit 0 - значение сервиса по умолчанию.
+func (c *Client) Changes(ctx context.Context, after string, limit int) (*ChangesPage, error) {
+	query := url.Values{}
+
+	if after != "" {
+		query.Set("after", after)
+	}
+
+	if limit > 0 {
+		query.Set("limit", strconv.Itoa(limit))
+	}
+
+	res := &ChangesPage{}
+
+	if err := c.do(ctx, request{method: http.MethodGet, path: "/changes", namespaced: true, query: query}, res); err != nil {
+		return nil, err
+	}
+
+	return res, nil
+}
@@ -0,0 +1,330 @@
+// Package client - Go клиент HTTP API сервиса сегментов /api/v1.
+//
+//	c, err := client.New("http://localhost:8080", client.WithAuth(client.APIKeyAuth(key)))
+//	segments, err := c.GetUserSegments(ctx, 1)
+//	if errors.Is(err, client.ErrUserNotFound) { ... }
+//
+// Ошибки API возвращаются как *Error и сравниваются через errors.Is с ErrSegmentNotFound и другими
+// ошибками пакета. Идемпотентные запросы (GET, PUT, DELETE) повторяются при сетевых ошибках,
+// 429, 502, 503 и 504 с экспоненциальной задержкой.
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/rand"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const apiPrefix = "/api/v1"
+
+// RetryPolicy задает повторы идемпотентных запросов. Задержка перед n-ым повтором
+// выбирается случайно от 0 до MinBackoff*2^n, но не больше MaxBackoff.
+// Если сервер вернул Retry-After больше MaxBackoff, запрос не повторяется.
+type RetryPolicy struct {
+	// Сколько всего раз выполнить запрос, 1 - без повторов
+	MaxAttempts int
+	MinBackoff  time.Duration
+	MaxBackoff  time.Duration
+}
+
+var DefaultRetryPolicy = RetryPolicy{
+	MaxAttempts: 3,
+	MinBackoff:  100 * time.Millisecond,
+	MaxBackoff:  2 * time.Second,
+}
+
+type Client struct {
+	baseURL    *url.URL
+	httpClient *http.Client
+	auth       Auth
+	namespace  string
+	retry      RetryPolicy
+	userAgent  string
+}
+
+type Option func(*Client)
+
+// WithHTTPClient задает http.Client, например с таймаутом или своим транспортом.
+func WithHTTPClient(httpClient *http.Client) Option {
+	return func(c *Client) {
+		c.httpClient = httpClient
+	}
+}
+
+// WithAuth задает способ авторизации запросов.
+func WithAuth(auth Auth) Option {
+	return func(c *Client) {
+		c.auth = auth
+	}
+}
+
+// WithNamespace задает пространство имен для методов сегментов, пользователей, отчетов и аналитики.
+func WithNamespace(ns string) Option {
+	return func(c *Client) {
+		c.namespace = ns
+	}
+}
+
+// WithRetry задает повторы идемпотентных запросов.
+func WithRetry(policy RetryPolicy) Option {
+	return func(c *Client) {
+		c.retry = policy
+	}
+}
+
+func WithUserAgent(userAgent string) Option {
+	return func(c *Client) {
+		c.userAgent = userAgent
+	}
+}
+
+// New создает клиент для сервиса по адресу baseURL, например http://localhost:8080.
+func New(baseURL string, opts ...Option) (*Client, error) {
+	u, err := url.Parse(baseURL)
+
+	if err != nil {
+		return nil, fmt.Errorf("parse base url: %w", err)
+	}
+
+	if u.Scheme == "" || u.Host == "" {
+		return nil, fmt.Errorf("base url must be absolute: %q", baseURL)
+	}
+
+	u.Path = strings.TrimSuffix(u.Path, "/")
+
+	c := &Client{
+		baseURL:    u,
+		httpClient: http.DefaultClient,
+		retry:      DefaultRetryPolicy,
+		userAgent:  "avitotech-segments-go",
+	}
+
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	return c, nil
+}
+
+// InNamespace возвращает копию клиента, работающую в пространстве имен ns.
+func (c *Client) InNamespace(ns string) *Client {
+	cp := *c
+	cp.namespace = ns
+
+	return &cp
+}
+
+type request struct {
+	method string
+	// Путь после /api/v1
+	path string
+	// Путь начинается с /ns/{namespace}, если у клиента задано пространство имен
+	namespaced bool
+	// Путь без префикса /api/v1, например /readyz
+	unversioned bool
+	query       url.Values
+	body        any
+}
+
+// do выполняет запрос и разбирает JSON ответа в out.
+func (c *Client) do(ctx context.Context, req request, out any) error {
+	res, err := c.send(ctx, req)
+
+	if err != nil {
+		return err
+	}
+
+	defer res.Body.Close()
+
+	if out == nil {
+		_, _ = io.Copy(io.Discard, res.Body)
+		return nil
+	}
+
+	if err := json.NewDecoder(res.Body).Decode(out); err != nil {
+		return fmt.Errorf("decode response: %w", err)
+	}
+
+	return nil
+}
+
+// stream выполняет запрос и возвращает тело ответа, которое нужно закрыть.
+func (c *Client) stream(ctx context.Context, req request) (io.ReadCloser, error) {
+	res, err := c.send(ctx, req)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return res.Body, nil
+}
+
+// send выполняет запрос с повторами и возвращает успешный ответ. Ответ с ошибкой разбирается в *Error.
+func (c *Client) send(ctx context.Context, req request) (*http.Response, error) {
+	var body []byte
+
+	if req.body != nil {
+		var err error
+
+		body, err = json.Marshal(req.body)
+
+		if err != nil {
+			return nil, fmt.Errorf("encode request: %w", err)
+		}
+	}
+
+	attempts := 1
+
+	if idempotent(req.method) && c.retry.MaxAttempts > 1 {
+		attempts = c.retry.MaxAttempts
+	}
+
+	for attempt := 0; ; attempt++ {
+		httpReq, err := c.newRequest(ctx, req, body)
+
+		if err != nil {
+			return nil, err
+		}
+
+		res, err := c.httpClient.Do(httpReq)
+
+		var retryAfter time.Duration
+
+		if err == nil {
+			if res.StatusCode < http.StatusBadRequest {
+				return res, nil
+			}
+
+			err = decodeError(res)
+
+			if !retryableStatus(res.StatusCode) {
+				return nil, err
+			}
+
+			retryAfter = parseRetryAfter(res.Header.Get("Retry-After"))
+		} else if ctx.Err() != nil {
+			return nil, err
+		}
+
+		if attempt+1 >= attempts {
+			return nil, err
+		}
+
+		wait, ok := c.retry.backoff(attempt, retryAfter)
+
+		if !ok {
+			return nil, err
+		}
+
+		timer := time.NewTimer(wait)
+
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+func (c *Client) newRequest(ctx context.Context, req request, body []byte) (*http.Request, error) {
+	u := *c.baseURL
+
+	if !req.unversioned {
+		u.Path += apiPrefix
+	}
+
+	if req.namespaced && c.namespace != "" {
+		u.Path += "/ns/" + url.PathEscape(c.namespace)
+	}
+
+	u.Path += req.path
+	u.RawQuery = req.query.Encode()
+
+	var reader io.Reader
+
+	if body != nil {
+		reader = bytes.NewReader(body)
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, req.method, u.String(), reader)
+
+	if err != nil {
+		return nil, err
+	}
+
+	httpReq.Header.Set("Accept", "application/json")
+	httpReq.Header.Set("User-Agent", c.userAgent)
+
+	if body != nil {
+		httpReq.Header.Set("Content-Type", "application/json")
+	}
+
+	if c.auth != nil {
+		if err := c.auth.Apply(ctx, httpReq); err != nil {
+			return nil, fmt.Errorf("auth: %w", err)
+		}
+	}
+
+	return httpReq, nil
+}
+
+func (p RetryPolicy) backoff(attempt int, retryAfter time.Duration) (time.Duration, bool) {
+	if retryAfter > 0 {
+		return retryAfter, retryAfter <= p.MaxBackoff
+	}
+
+	limit := p.MinBackoff << attempt
+
+	if limit <= 0 || limit > p.MaxBackoff {
+		limit = p.MaxBackoff
+	}
+
+	if limit <= 0 {
+		return 0, true
+	}
+
+	return time.Duration(rand.Int63n(int64(limit) + 1)), true
+}
+
+func idempotent(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodPut, http.MethodDelete:
+		return true
+	}
+
+	return false
+}
+
+func retryableStatus(status int) bool {
+	switch status {
+	case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+
+	return false
+}
+
+func parseRetryAfter(v string) time.Duration {
+	if v == "" {
+		return 0
+	}
+
+	if seconds, err := strconv.Atoi(v); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second
+	}
+
+	if t, err := http.ParseTime(v); err == nil {
+		return time.Until(t)
+	}
+
+	return 0
+}
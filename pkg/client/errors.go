@@ -0,0 +1,185 @@
+package client
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// Code - машиночитаемый код ошибки API.
+type Code string
+
+const (
+	CodeInvalidRequest         Code = "INVALID_REQUEST"
+	CodeValidationFailed       Code = "VALIDATION_FAILED"
+	CodeRouteNotFound          Code = "ROUTE_NOT_FOUND"
+	CodeMethodNotAllowed       Code = "METHOD_NOT_ALLOWED"
+	CodeUnauthorized           Code = "UNAUTHORIZED"
+	CodeInvalidAPIKey          Code = "INVALID_API_KEY"
+	CodeInvalidToken           Code = "INVALID_TOKEN"
+	CodeForbidden              Code = "FORBIDDEN"
+	CodeNamespaceForbidden     Code = "NAMESPACE_FORBIDDEN"
+	CodeRateLimitExceeded      Code = "RATE_LIMIT_EXCEEDED"
+	CodeSegmentNotFound        Code = "SEGMENT_NOT_FOUND"
+	CodeSegmentAlreadyExists   Code = "SEGMENT_ALREADY_EXISTS"
+	CodeUserNotFound           Code = "USER_NOT_FOUND"
+	CodeNamespaceNotFound      Code = "NAMESPACE_NOT_FOUND"
+	CodeNamespaceAlreadyExists Code = "NAMESPACE_ALREADY_EXISTS"
+	CodeAPIKeyNotFound         Code = "API_KEY_NOT_FOUND"
+	CodeWebhookNotFound        Code = "WEBHOOK_NOT_FOUND"
+	CodeWebhookURLForbidden    Code = "WEBHOOK_URL_FORBIDDEN"
+	CodeLayerNotFound          Code = "LAYER_NOT_FOUND"
+	CodeLayerAlreadyExists     Code = "LAYER_ALREADY_EXISTS"
+	CodeLayerFull              Code = "LAYER_FULL"
+	CodeLayerConflict          Code = "LAYER_CONFLICT"
+	CodeVariantNotFound        Code = "VARIANT_NOT_FOUND"
+	CodeVariantRemoved         Code = "VARIANT_REMOVED"
+	CodeSegmentHasNoVariants   Code = "SEGMENT_HAS_NO_VARIANTS"
+	CodeUserNotInSegment       Code = "USER_NOT_IN_SEGMENT"
+	CodeReportNotFound         Code = "REPORT_NOT_FOUND"
+	CodeNotFound               Code = "NOT_FOUND"
+	CodeAlreadyExists          Code = "ALREADY_EXISTS"
+	CodeTimeout                Code = "TIMEOUT"
+	CodeCanceled               Code = "CANCELED"
+	CodeUnavailable            Code = "UNAVAILABLE"
+	CodeInternal               Code = "INTERNAL"
+)
+
+// Ошибки для errors.Is, по одной на код API
+var (
+	ErrInvalidRequest         = errors.New("invalid request")
+	ErrValidationFailed       = errors.New("request validation failed")
+	ErrRouteNotFound          = errors.New("route not found")
+	ErrMethodNotAllowed       = errors.New("method not allowed")
+	ErrUnauthorized           = errors.New("unauthorized")
+	ErrInvalidAPIKey          = errors.New("invalid api key")
+	ErrInvalidToken           = errors.New("invalid token")
+	ErrForbidden              = errors.New("forbidden")
+	ErrNamespaceForbidden     = errors.New("no access to namespace")
+	ErrRateLimitExceeded      = errors.New("rate limit exceeded")
+	ErrSegmentNotFound        = errors.New("segment not found")
+	ErrSegmentAlreadyExists   = errors.New("segment already exists")
+	ErrUserNotFound           = errors.New("user not found")
+	ErrNamespaceNotFound      = errors.New("namespace not found")
+	ErrNamespaceAlreadyExists = errors.New("namespace already exists")
+	ErrAPIKeyNotFound         = errors.New("api key not found")
+	ErrWebhookNotFound        = errors.New("webhook not found")
+	ErrWebhookURLForbidden    = errors.New("webhook url must resolve to a public address")
+	ErrLayerNotFound          = errors.New("layer not found")
+	ErrLayerAlreadyExists     = errors.New("layer already exists")
+	ErrLayerFull              = errors.New("not enough free buckets in layer")
+	ErrLayerConflict          = errors.New("user is already in another segment of the layer")
+	ErrVariantNotFound        = errors.New("variant not found")
+	ErrVariantRemoved         = errors.New("variants cannot be removed, set weight to 0 instead")
+	ErrSegmentHasNoVariants   = errors.New("segment has no variants")
+	ErrUserNotInSegment       = errors.New("user is not in segment")
+	ErrReportNotFound         = errors.New("report not found")
+	ErrNotFound               = errors.New("not found")
+	ErrAlreadyExists          = errors.New("already exists")
+	ErrTimeout                = errors.New("timeout")
+	ErrCanceled               = errors.New("request canceled")
+	ErrUnavailable            = errors.New("service unavailable")
+	ErrInternal               = errors.New("internal server error")
+)
+
+var codeErrors = map[Code]error{
+	CodeInvalidRequest:         ErrInvalidRequest,
+	CodeValidationFailed:       ErrValidationFailed,
+	CodeRouteNotFound:          ErrRouteNotFound,
+	CodeMethodNotAllowed:       ErrMethodNotAllowed,
+	CodeUnauthorized:           ErrUnauthorized,
+	CodeInvalidAPIKey:          ErrInvalidAPIKey,
+	CodeInvalidToken:           ErrInvalidToken,
+	CodeForbidden:              ErrForbidden,
+	CodeNamespaceForbidden:     ErrNamespaceForbidden,
+	CodeRateLimitExceeded:      ErrRateLimitExceeded,
+	CodeSegmentNotFound:        ErrSegmentNotFound,
+	CodeSegmentAlreadyExists:   ErrSegmentAlreadyExists,
+	CodeUserNotFound:           ErrUserNotFound,
+	CodeNamespaceNotFound:      ErrNamespaceNotFound,
+	CodeNamespaceAlreadyExists: ErrNamespaceAlreadyExists,
+	CodeAPIKeyNotFound:         ErrAPIKeyNotFound,
+	CodeWebhookNotFound:        ErrWebhookNotFound,
+	CodeWebhookURLForbidden:    ErrWebhookURLForbidden,
+	CodeLayerNotFound:          ErrLayerNotFound,
+	CodeLayerAlreadyExists:     ErrLayerAlreadyExists,
+	CodeLayerFull:              ErrLayerFull,
+	CodeLayerConflict:          ErrLayerConflict,
+	CodeVariantNotFound:        ErrVariantNotFound,
+	CodeVariantRemoved:         ErrVariantRemoved,
+	CodeSegmentHasNoVariants:   ErrSegmentHasNoVariants,
+	CodeUserNotInSegment:       ErrUserNotInSegment,
+	CodeReportNotFound:         ErrReportNotFound,
+	CodeNotFound:               ErrNotFound,
+	CodeAlreadyExists:          ErrAlreadyExists,
+	CodeTimeout:                ErrTimeout,
+	CodeCanceled:               ErrCanceled,
+	CodeUnavailable:            ErrUnavailable,
+	CodeInternal:               ErrInternal,
+}
+
+type ValidationError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// Error - ошибка, которую вернул API. Для ответов без тела ошибки (например от балансировщика)
+// Code пустой, а Message - текст статуса.
+type Error struct {
+	StatusCode int
+	Code       Code
+	Message    string
+	Details    []ValidationError
+	RequestID  string
+}
+
+func (e *Error) Error() string {
+	if e.Code == "" {
+		return fmt.Sprintf("segments api: %d %s", e.StatusCode, e.Message)
+	}
+
+	return fmt.Sprintf("segments api: %s: %s", e.Code, e.Message)
+}
+
+// Unwrap возвращает ошибку пакета для кода, поэтому работает errors.Is(err, ErrSegmentNotFound).
+func (e *Error) Unwrap() error {
+	return codeErrors[e.Code]
+}
+
+// decodeError читает и закрывает тело ответа с ошибкой.
+func decodeError(res *http.Response) error {
+	defer res.Body.Close()
+
+	apiErr := &Error{
+		StatusCode: res.StatusCode,
+		Message:    http.StatusText(res.StatusCode),
+		RequestID:  res.Header.Get("X-Request-Id"),
+	}
+
+	var body struct {
+		Error struct {
+			Code      Code              `json:"code"`
+			Message   string            `json:"message"`
+			Details   []ValidationError `json:"details"`
+			RequestID string            `json:"request_id"`
+		} `json:"error"`
+	}
+
+	data, err := io.ReadAll(io.LimitReader(res.Body, 1<<20))
+
+	if err != nil || json.Unmarshal(data, &body) != nil || body.Error.Code == "" {
+		return apiErr
+	}
+
+	apiErr.Code = body.Error.Code
+	apiErr.Message = body.Error.Message
+	apiErr.Details = body.Error.Details
+
+	if body.Error.RequestID != "" {
+		apiErr.RequestID = body.Error.RequestID
+	}
+
+	return apiErr
+}
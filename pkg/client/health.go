@@ -0,0 +1,38 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+)
+
+// Ready проверяет готовность сервиса. Для неготового сервиса (503) возвращается отчет со Status, отличным от ok,
+// поэтому запрос не повторяется.
+func (c *Client) Ready(ctx context.Context) (*HealthReport, error) {
+	httpReq, err := c.newRequest(ctx, request{method: http.MethodGet, path: "/readyz", unversioned: true}, nil)
+
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := c.httpClient.Do(httpReq)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if res.StatusCode != http.StatusOK && res.StatusCode != http.StatusServiceUnavailable {
+		return nil, decodeError(res)
+	}
+
+	defer res.Body.Close()
+
+	report := &HealthReport{}
+
+	if err := json.NewDecoder(res.Body).Decode(report); err != nil {
+		return nil, fmt.Errorf("decode response: %w", err)
+	}
+
+	return report, nil
+}
@@ -0,0 +1,30 @@
+package client
+
+import (
+	"context"
+	"net/http"
+)
+
+// CreateLayer создает слой взаимоисключающих сегментов в пространстве имен клиента.
+func (c *Client) CreateLayer(ctx context.Context, slug, description string) (*Layer, error) {
+	body := map[string]string{"slug": slug, "description": description}
+
+	var res struct {
+		Layer *Layer `json:"layer"`
+	}
+
+	err := c.do(ctx, request{method: http.MethodPost, path: "/layers", namespaced: true, body: body}, &res)
+
+	return res.Layer, err
+}
+
+// ListLayers возвращает слои пространства имен клиента с их сегментами.
+func (c *Client) ListLayers(ctx context.Context) ([]*Layer, error) {
+	var res struct {
+		Layers []*Layer `json:"layers"`
+	}
+
+	err := c.do(ctx, request{method: http.MethodGet, path: "/layers", namespaced: true}, &res)
+
+	return res.Layers, err
+}
@@ -0,0 +1,115 @@
+package client
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/url"
+	"path"
+	"strconv"
+	"time"
+)
+
+// GetUserHistoryReport создает отчет по сегментам пользователя за месяц и возвращает ссылку на него.
+// Отчет скачивается через DownloadReport.
+func (c *Client) GetUserHistoryReport(ctx context.Context, userID int64, year int, month time.Month, opts *ReportOptions) (string, error) {
+	var res struct {
+		ReportLink string `json:"report_link"`
+	}
+
+	query := opts.values()
+	query.Set("year", strconv.Itoa(year))
+	query.Set("month", strconv.Itoa(int(month)))
+
+	if opts != nil && opts.Format != "" {
+		query.Set("format", string(opts.Format))
+	}
+
+	req := request{
+		method:     http.MethodGet,
+		path:       "/segment/history/" + strconv.FormatInt(userID, 10),
+		namespaced: true,
+		query:      query,
+	}
+
+	err := c.do(ctx, req, &res)
+
+	return res.ReportLink, err
+}
+
+// DownloadReport скачивает отчет. fileName - имя файла или ссылка из GetUserHistoryReport.
+// Тело нужно закрыть.
+func (c *Client) DownloadReport(ctx context.Context, fileName string) (io.ReadCloser, error) {
+	req := request{
+		method:     http.MethodGet,
+		path:       "/segment/reports/" + url.PathEscape(path.Base(fileName)),
+		namespaced: true,
+	}
+
+	return c.stream(ctx, req)
+}
+
+// ExportUserHistory выгружает историю сегментов пользователя за месяц в формате opts.Format.
+// Тело нужно закрыть.
+func (c *Client) ExportUserHistory(ctx context.Context, userID int64, year int, month time.Month, opts *ReportOptions) (io.ReadCloser, error) {
+	query := opts.values()
+	query.Set("year", strconv.Itoa(year))
+	query.Set("month", strconv.Itoa(int(month)))
+
+	req := request{
+		method:     http.MethodGet,
+		path:       "/segment/history/" + strconv.FormatInt(userID, 10) + "/export." + string(opts.format()),
+		namespaced: true,
+		query:      query,
+	}
+
+	return c.stream(ctx, req)
+}
+
+// ExportSegmentHistory выгружает агрегаты или события сегмента за период в формате opts.Format.
+// Тело нужно закрыть.
+func (c *Client) ExportSegmentHistory(ctx context.Context, slug string, q SegmentHistoryQuery, opts *ReportOptions) (io.ReadCloser, error) {
+	req := request{
+		method:     http.MethodGet,
+		path:       "/segment/" + url.PathEscape(slug) + "/history/export." + string(opts.format()),
+		namespaced: true,
+		query:      q.values(opts.values()),
+	}
+
+	return c.stream(ctx, req)
+}
+
+// values возвращает настройки отчета без формата, формат передается в пути или отдельно.
+func (o *ReportOptions) values() url.Values {
+	v := url.Values{}
+
+	if o == nil {
+		return v
+	}
+
+	if o.Delimiter != "" {
+		v.Set("delimiter", o.Delimiter)
+	}
+
+	if o.Lang != "" {
+		v.Set("lang", o.Lang)
+	}
+
+	if o.TimeFormat != "" {
+		v.Set("time_format", o.TimeFormat)
+	}
+
+	if o.TZ != "" {
+		v.Set("tz", o.TZ)
+	}
+
+	return v
+}
+
+func (o *ReportOptions) format() Format {
+	if o == nil || o.Format == "" {
+		return FormatCSV
+	}
+
+	return o.Format
+}
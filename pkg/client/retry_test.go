@@ -0,0 +1,145 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+var fastRetry = RetryPolicy{MaxAttempts: 3, MinBackoff: time.Millisecond, MaxBackoff: 10 * time.Millisecond}
+
+func newFlakyServer(t *testing.T, failures int32, status int, header http.Header) (*httptest.Server, *atomic.Int32) {
+	var calls atomic.Int32
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if calls.Add(1) <= failures {
+			for k, v := range header {
+				w.Header()[k] = v
+			}
+
+			w.WriteHeader(status)
+			w.Write([]byte(`{"error":{"code":"RATE_LIMIT_EXCEEDED","message":"rate limit exceeded"}}`))
+
+			return
+		}
+
+		w.Write([]byte(`{"segments":[{"slug":"AVITO_TEST"}],"user_id":1}`))
+	}))
+
+	t.Cleanup(srv.Close)
+
+	return srv, &calls
+}
+
+func Test_Retry(t *testing.T) {
+	t.Run("Should retry idempotent request", func(t *testing.T) {
+		srv, calls := newFlakyServer(t, 2, http.StatusServiceUnavailable, nil)
+
+		c, err := New(srv.URL, WithRetry(fastRetry))
+		require.NoError(t, err)
+
+		segments, err := c.GetUserSegments(context.Background(), 1)
+
+		require.NoError(t, err)
+		require.Equal(t, "AVITO_TEST", segments[0].Slug)
+		require.Equal(t, int32(3), calls.Load())
+	})
+
+	t.Run("Should stop after max attempts", func(t *testing.T) {
+		srv, calls := newFlakyServer(t, 5, http.StatusTooManyRequests, nil)
+
+		c, err := New(srv.URL, WithRetry(fastRetry))
+		require.NoError(t, err)
+
+		_, err = c.GetUserSegments(context.Background(), 1)
+
+		require.ErrorIs(t, err, ErrRateLimitExceeded)
+		require.Equal(t, int32(3), calls.Load())
+	})
+
+	t.Run("Should not retry POST", func(t *testing.T) {
+		srv, calls := newFlakyServer(t, 1, http.StatusServiceUnavailable, nil)
+
+		c, err := New(srv.URL, WithRetry(fastRetry))
+		require.NoError(t, err)
+
+		_, err = c.CreateUser(context.Background())
+
+		require.Error(t, err)
+		require.Equal(t, int32(1), calls.Load())
+	})
+
+	t.Run("Should not retry if Retry-After is longer than max backoff", func(t *testing.T) {
+		srv, calls := newFlakyServer(t, 1, http.StatusTooManyRequests, http.Header{"Retry-After": {"30"}})
+
+		c, err := New(srv.URL, WithRetry(fastRetry))
+		require.NoError(t, err)
+
+		_, err = c.GetUserSegments(context.Background(), 1)
+
+		require.ErrorIs(t, err, ErrRateLimitExceeded)
+		require.Equal(t, int32(1), calls.Load())
+	})
+
+	t.Run("Should stop retrying when context is canceled", func(t *testing.T) {
+		srv, _ := newFlakyServer(t, 5, http.StatusServiceUnavailable, nil)
+
+		c, err := New(srv.URL, WithRetry(RetryPolicy{MaxAttempts: 5, MinBackoff: time.Second, MaxBackoff: time.Second}))
+		require.NoError(t, err)
+
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+
+		_, err = c.GetUserSegments(ctx, 1)
+
+		require.True(t, errors.Is(err, context.DeadlineExceeded))
+	})
+}
+
+func Test_DecodeError(t *testing.T) {
+	t.Run("Should decode error envelope", func(t *testing.T) {
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"error":{"code":"VALIDATION_FAILED","message":"request validation failed","details":[{"field":"Slug","message":"min"}],"request_id":"req-1"}}`))
+		}))
+		defer srv.Close()
+
+		c, err := New(srv.URL)
+		require.NoError(t, err)
+
+		_, err = c.CreateSegment(context.Background(), CreateSegmentRequest{Slug: "AV"})
+
+		require.ErrorIs(t, err, ErrValidationFailed)
+
+		var apiErr *Error
+		require.ErrorAs(t, err, &apiErr)
+		require.Equal(t, http.StatusBadRequest, apiErr.StatusCode)
+		require.Equal(t, "req-1", apiErr.RequestID)
+		require.Equal(t, "Slug", apiErr.Details[0].Field)
+	})
+
+	t.Run("Should keep status of response without envelope", func(t *testing.T) {
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusBadGateway)
+			w.Write([]byte(`<html>bad gateway</html>`))
+		}))
+		defer srv.Close()
+
+		c, err := New(srv.URL, WithRetry(RetryPolicy{MaxAttempts: 1}))
+		require.NoError(t, err)
+
+		_, err = c.GetUserSegments(context.Background(), 1)
+
+		var apiErr *Error
+		require.ErrorAs(t, err, &apiErr)
+		require.Equal(t, http.StatusBadGateway, apiErr.StatusCode)
+		require.Empty(t, apiErr.Code)
+		require.Nil(t, errors.Unwrap(err))
+	})
+}
@@ -0,0 +1,421 @@
+package client
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/dezzerlol/avitotech-test-2023/cfg"
+	"github.com/dezzerlol/avitotech-test-2023/internal/auth"
+	"github.com/dezzerlol/avitotech-test-2023/internal/health"
+	internalhttp "github.com/dezzerlol/avitotech-test-2023/internal/http"
+	"github.com/dezzerlol/avitotech-test-2023/internal/worker"
+	"github.com/dezzerlol/avitotech-test-2023/pkg/testhelper"
+	"github.com/hibiken/asynq"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+const testAdminKey = "seg_test_admin_key"
+
+var (
+	setupOnce  sync.Once
+	testDB     *testhelper.TestDatabase
+	testServer *httptest.Server
+)
+
+func TestMain(m *testing.M) {
+	code := m.Run()
+
+	if testServer != nil {
+		testServer.Close()
+		testDB.TearDown()
+	}
+
+	os.Exit(code)
+}
+
+type noopDistributor struct{}
+
+func (noopDistributor) ScheduleSegmentExpireTask(ctx context.Context, payload worker.SegmentExpirePayload, opts ...asynq.Option) error {
+	return nil
+}
+
+// newTestClient возвращает клиент с ключом администратора к серверу с настоящим роутером.
+// База и сервер поднимаются при первом вызове, поэтому тестам без них docker не нужен.
+func newTestClient(t *testing.T, opts ...Option) *Client {
+	setupOnce.Do(func() {
+		testDB = testhelper.SetupTestDatabase()
+		cfg.Get().ADMIN_API_KEY = testAdminKey
+		// Получатель вебхуков в тестах слушает loopback
+		cfg.Get().WEBHOOK_ALLOW_PRIVATE = true
+
+		server := internalhttp.New(zap.NewNop().Sugar(), testDB.DbInstance, noopDistributor{}, nil, nil, nil, nil, health.NewChecker())
+		testServer = httptest.NewServer(server.Handler())
+	})
+
+	c, err := New(testServer.URL, append([]Option{WithAuth(APIKeyAuth(testAdminKey))}, opts...)...)
+	require.NoError(t, err)
+
+	return c
+}
+
+func randomSlug() string {
+	return "AVITO_" + strings.ToUpper(testhelper.RandomString(10))
+}
+
+func Test_Client_Segments(t *testing.T) {
+	c := newTestClient(t)
+	ctx := context.Background()
+
+	slug := randomSlug()
+
+	createdAt, err := c.CreateSegment(ctx, CreateSegmentRequest{Slug: slug})
+	require.NoError(t, err)
+	require.False(t, createdAt.IsZero())
+
+	_, err = c.CreateSegment(ctx, CreateSegmentRequest{Slug: slug})
+	require.ErrorIs(t, err, ErrSegmentAlreadyExists)
+
+	_, err = c.CreateSegment(ctx, CreateSegmentRequest{Slug: "AV"})
+	require.ErrorIs(t, err, ErrValidationFailed)
+
+	userID, err := c.CreateUser(ctx)
+	require.NoError(t, err)
+
+	res, err := c.UpdateUserSegments(ctx, UpdateUserSegmentsRequest{UserID: userID, AddSegments: []string{slug}, TTL: time.Hour})
+	require.NoError(t, err)
+	require.Equal(t, int64(1), res.SegmentsAdded)
+
+	segments, err := c.GetUserSegments(ctx, userID)
+	require.NoError(t, err)
+	require.Len(t, segments, 1)
+	require.Equal(t, slug, segments[0].Slug)
+
+	lookup, err := c.LookupUserSegments(ctx, LookupUserSegmentsRequest{UserIDs: []int64{userID, 1 << 40}, Segments: []string{slug}})
+	require.NoError(t, err)
+	require.Equal(t, slug, lookup.Users[userID][0].Slug)
+	require.Equal(t, []int64{1 << 40}, lookup.NotFound)
+
+	history, err := c.GetSegmentHistory(ctx, slug, SegmentHistoryQuery{From: time.Now().Add(-time.Hour), To: time.Now().Add(time.Hour)}, nil)
+	require.NoError(t, err)
+	require.Equal(t, slug, history.SegmentSlug)
+	require.NotEmpty(t, history.Events)
+
+	body, err := c.ExportUserHistory(ctx, userID, time.Now().Year(), time.Now().Month(), &ReportOptions{Format: FormatCSV})
+	require.NoError(t, err)
+
+	data, err := io.ReadAll(body)
+	body.Close()
+	require.NoError(t, err)
+	require.Contains(t, string(data), slug)
+
+	require.NoError(t, c.DeleteSegment(ctx, slug))
+	require.ErrorIs(t, c.DeleteSegment(ctx, slug), ErrSegmentNotFound)
+
+	_, err = c.GetUserSegments(ctx, 1<<40)
+	require.ErrorIs(t, err, ErrUserNotFound)
+}
+
+func Test_Client_Admin(t *testing.T) {
+	c := newTestClient(t)
+	ctx := context.Background()
+
+	ns := strings.ToLower(testhelper.RandomString(10))
+
+	_, err := c.CreateNamespace(ctx, ns)
+	require.NoError(t, err)
+
+	_, err = c.CreateNamespace(ctx, ns)
+	require.ErrorIs(t, err, ErrNamespaceAlreadyExists)
+
+	issued, err := c.IssueAPIKey(ctx, IssueAPIKeyRequest{
+		Name:       "client-test",
+		Scopes:     []string{auth.ScopeSegmentsRead, auth.ScopeSegmentsWrite},
+		Namespaces: []string{ns},
+	})
+	require.NoError(t, err)
+	require.NotEmpty(t, issued.Key)
+
+	keyClient := newTestClient(t, WithAuth(APIKeyAuth(issued.Key)), WithNamespace(ns))
+
+	_, err = keyClient.CreateSegment(ctx, CreateSegmentRequest{Slug: randomSlug()})
+	require.NoError(t, err)
+
+	_, err = keyClient.InNamespace("default").CreateSegment(ctx, CreateSegmentRequest{Slug: randomSlug()})
+	require.ErrorIs(t, err, ErrNamespaceForbidden)
+
+	_, err = keyClient.CreateUser(ctx)
+	require.ErrorIs(t, err, ErrForbidden)
+
+	require.NoError(t, c.RevokeAPIKey(ctx, issued.APIKey.ID))
+
+	_, err = keyClient.GetUserSegments(ctx, 1)
+	require.ErrorIs(t, err, ErrInvalidAPIKey)
+
+	require.ErrorIs(t, c.RevokeAPIKey(ctx, 1<<40), ErrAPIKeyNotFound)
+}
+
+func Test_Client_Ready(t *testing.T) {
+	c := newTestClient(t)
+
+	report, err := c.Ready(context.Background())
+
+	require.NoError(t, err)
+	require.Equal(t, health.StatusOK, report.Status)
+}
+
+func Test_Client_Webhooks(t *testing.T) {
+	c := newTestClient(t)
+	ctx := context.Background()
+
+	var received atomic.Int32
+
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received.Add(1)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer receiver.Close()
+
+	created, err := c.CreateWebhook(ctx, CreateWebhookRequest{URL: receiver.URL, Description: "client-test"})
+	require.NoError(t, err)
+	require.NotEmpty(t, created.Secret)
+
+	delivery, err := c.SendTestWebhook(ctx, created.Webhook.ID)
+	require.NoError(t, err)
+	require.True(t, delivery.Success)
+	require.Equal(t, int32(1), received.Load())
+
+	deliveries, err := c.ListWebhookDeliveries(ctx, created.Webhook.ID, 10)
+	require.NoError(t, err)
+	require.Len(t, deliveries, 1)
+
+	require.NoError(t, c.DeleteWebhook(ctx, created.Webhook.ID))
+	require.ErrorIs(t, c.DeleteWebhook(ctx, created.Webhook.ID), ErrWebhookNotFound)
+}
+
+func Test_Client_Changes(t *testing.T) {
+	c := newTestClient(t)
+	ctx := context.Background()
+
+	slug := randomSlug()
+
+	_, err := c.CreateSegment(ctx, CreateSegmentRequest{Slug: slug})
+	require.NoError(t, err)
+
+	userID, err := c.CreateUser(ctx)
+	require.NoError(t, err)
+
+	_, err = c.UpdateUserSegments(ctx, UpdateUserSegmentsRequest{UserID: userID, AddSegments: []string{slug}})
+	require.NoError(t, err)
+
+	_, err = c.UpdateUserSegments(ctx, UpdateUserSegmentsRequest{UserID: userID, DeleteSegments: []string{slug}})
+	require.NoError(t, err)
+
+	var operations []string
+
+	page := &ChangesPage{HasMore: true}
+
+	for page.HasMore {
+		page, err = c.Changes(ctx, page.NextCursor, 100)
+		require.NoError(t, err)
+
+		for _, change := range page.Changes {
+			if change.UserID == userID {
+				operations = append(operations, change.Operation)
+			}
+		}
+	}
+
+	require.Equal(t, []string{"I", "D"}, operations)
+
+	// Курсор после последнего изменения больше ничего не возвращает
+	page, err = c.Changes(ctx, page.NextCursor, 100)
+	require.NoError(t, err)
+	require.Empty(t, page.Changes)
+
+	_, err = c.Changes(ctx, "abc", 0)
+	require.ErrorIs(t, err, ErrInvalidRequest)
+}
+
+func Test_Client_Rules(t *testing.T) {
+	c := newTestClient(t)
+	ctx := context.Background()
+
+	slug := randomSlug()
+	// Атрибут с именем сегмента, чтобы правило не задело пользователей других тестов
+	rule := slug + ` == true && city in ["msk", "spb"]`
+
+	validation, err := c.ValidateRule(ctx, rule, map[string]any{slug: true, "city": "msk"})
+	require.NoError(t, err)
+	require.True(t, validation.Valid)
+	require.NotNil(t, validation.Matches)
+	require.True(t, *validation.Matches)
+
+	validation, err = c.ValidateRule(ctx, "city ==", nil)
+	require.NoError(t, err)
+	require.False(t, validation.Valid)
+	require.Equal(t, 8, validation.Error.Position)
+
+	_, err = c.CreateSegment(ctx, CreateSegmentRequest{Slug: slug, Rule: "city =="})
+	require.ErrorIs(t, err, ErrInvalidRequest)
+
+	_, err = c.CreateSegment(ctx, CreateSegmentRequest{Slug: slug, Rule: rule})
+	require.NoError(t, err)
+
+	userID := testhelper.RandomInt(1<<30, 1<<39)
+
+	user, err := c.UpsertUser(ctx, userID, map[string]any{slug: true, "city": "spb"})
+	require.NoError(t, err)
+	require.Equal(t, userID, user.ID)
+
+	segments, err := c.GetUserSegments(ctx, userID)
+	require.NoError(t, err)
+	require.Len(t, segments, 1)
+	require.Equal(t, slug, segments[0].Slug)
+
+	user, err = c.PatchUser(ctx, userID, map[string]any{"city": "kzn", "platform": "ios"})
+	require.NoError(t, err)
+	require.Equal(t, map[string]any{slug: true, "city": "kzn", "platform": "ios"}, user.Attributes)
+
+	segments, err = c.GetUserSegments(ctx, userID)
+	require.NoError(t, err)
+	require.Empty(t, segments)
+
+	user, err = c.GetUser(ctx, userID)
+	require.NoError(t, err)
+	require.Equal(t, "kzn", user.Attributes["city"])
+
+	_, err = c.GetUser(ctx, 1<<40)
+	require.ErrorIs(t, err, ErrUserNotFound)
+}
+
+func Test_Client_Layers(t *testing.T) {
+	c := newTestClient(t)
+	ctx := context.Background()
+
+	layerSlug := randomSlug()
+
+	layer, err := c.CreateLayer(ctx, layerSlug, "checkout")
+	require.NoError(t, err)
+	require.Equal(t, 100, layer.FreeBuckets)
+
+	_, err = c.CreateLayer(ctx, layerSlug, "")
+	require.ErrorIs(t, err, ErrLayerAlreadyExists)
+
+	first, second := randomSlug(), randomSlug()
+
+	_, err = c.CreateSegment(ctx, CreateSegmentRequest{Slug: first, Layer: layerSlug})
+	require.NoError(t, err)
+
+	_, err = c.CreateSegment(ctx, CreateSegmentRequest{Slug: second, Layer: layerSlug, UserPercent: 70})
+	require.NoError(t, err)
+
+	_, err = c.CreateSegment(ctx, CreateSegmentRequest{Slug: randomSlug(), Layer: layerSlug, UserPercent: 40})
+	require.ErrorIs(t, err, ErrLayerFull)
+
+	_, err = c.CreateSegment(ctx, CreateSegmentRequest{Slug: randomSlug(), Layer: randomSlug()})
+	require.ErrorIs(t, err, ErrLayerNotFound)
+
+	userID, err := c.CreateUser(ctx)
+	require.NoError(t, err)
+
+	// Новый пользователь не попал в раскатку, созданную до него
+	_, err = c.UpdateUserSegments(ctx, UpdateUserSegmentsRequest{UserID: userID, AddSegments: []string{first}})
+	require.NoError(t, err)
+
+	_, err = c.UpdateUserSegments(ctx, UpdateUserSegmentsRequest{UserID: userID, AddSegments: []string{second}})
+	require.ErrorIs(t, err, ErrLayerConflict)
+
+	// Перевод в другой сегмент слоя одним запросом
+	res, err := c.UpdateUserSegments(ctx, UpdateUserSegmentsRequest{UserID: userID, AddSegments: []string{second}, DeleteSegments: []string{first}})
+	require.NoError(t, err)
+	require.Equal(t, int64(1), res.SegmentsAdded)
+	require.Equal(t, int64(1), res.SegmentsDeleted)
+
+	layers, err := c.ListLayers(ctx)
+	require.NoError(t, err)
+
+	var found *Layer
+
+	for _, l := range layers {
+		if l.Slug == layerSlug {
+			found = l
+		}
+	}
+
+	require.NotNil(t, found)
+	require.Equal(t, 30, found.FreeBuckets)
+	require.Equal(t, second, found.Segments[0].Slug)
+	require.Equal(t, &BucketRange{From: 0, To: 70}, found.Segments[0].Buckets)
+}
+
+func Test_Client_Variants(t *testing.T) {
+	c := newTestClient(t)
+	ctx := context.Background()
+
+	slug := randomSlug()
+
+	_, err := c.CreateSegment(ctx, CreateSegmentRequest{
+		Slug:     slug,
+		Variants: []VariantWeight{{Name: "control", Weight: 50}, {Name: "treatment_a", Weight: 25}, {Name: "treatment_b", Weight: 25}},
+	})
+	require.NoError(t, err)
+
+	userID, err := c.CreateUser(ctx)
+	require.NoError(t, err)
+
+	_, err = c.UpdateUserSegments(ctx, UpdateUserSegmentsRequest{UserID: userID, AddSegments: []string{slug}})
+	require.NoError(t, err)
+
+	segments, err := c.GetUserSegments(ctx, userID)
+	require.NoError(t, err)
+	require.Len(t, segments, 1)
+
+	assigned := segments[0].Variant
+	require.Contains(t, []string{"control", "treatment_a", "treatment_b"}, assigned)
+
+	// Изменение весов не меняет назначенный вариант
+	variants, err := c.UpdateVariants(ctx, slug, []VariantWeight{{Name: "control", Weight: 100}, {Name: "treatment_a"}, {Name: "treatment_b"}})
+	require.NoError(t, err)
+	require.Len(t, variants, 3)
+	require.Equal(t, 100, variants[0].Weight)
+
+	segments, err = c.GetUserSegments(ctx, userID)
+	require.NoError(t, err)
+	require.Equal(t, assigned, segments[0].Variant)
+
+	_, err = c.UpdateVariants(ctx, slug, []VariantWeight{{Name: "control", Weight: 50}, {Name: "treatment_c", Weight: 50}})
+	require.ErrorIs(t, err, ErrVariantRemoved)
+
+	other := "treatment_b"
+
+	if assigned == other {
+		other = "treatment_a"
+	}
+
+	require.NoError(t, c.SetUserVariant(ctx, slug, userID, other))
+
+	segments, err = c.GetUserSegments(ctx, userID)
+	require.NoError(t, err)
+	require.Equal(t, other, segments[0].Variant)
+
+	err = c.SetUserVariant(ctx, slug, userID, "missing")
+	require.ErrorIs(t, err, ErrVariantNotFound)
+
+	variants, err = c.GetVariants(ctx, slug)
+	require.NoError(t, err)
+
+	for _, v := range variants {
+		if v.Name == other {
+			require.Equal(t, int64(1), v.Users)
+		}
+	}
+}
@@ -0,0 +1,148 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// CreateSegment создает сегмент и возвращает время создания.
+func (c *Client) CreateSegment(ctx context.Context, req CreateSegmentRequest) (time.Time, error) {
+	var res struct {
+		CreatedAt time.Time `json:"created_at"`
+	}
+
+	err := c.do(ctx, request{method: http.MethodPost, path: "/segment", namespaced: true, body: req}, &res)
+
+	return res.CreatedAt, err
+}
+
+// DeleteSegment удаляет сегмент у всех пользователей.
+func (c *Client) DeleteSegment(ctx context.Context, slug string) error {
+	body := map[string]string{"slug": slug}
+
+	return c.do(ctx, request{method: http.MethodDelete, path: "/segment", namespaced: true, body: body}, nil)
+}
+
+// UpdateUserSegments добавляет и удаляет сегменты пользователя. Несуществующий пользователь создается.
+func (c *Client) UpdateUserSegments(ctx context.Context, req UpdateUserSegmentsRequest) (*UpdateUserSegmentsResult, error) {
+	body := struct {
+		UserID         int64    `json:"user_id"`
+		AddSegments    []string `json:"add_segments,omitempty"`
+		TTL            int64    `json:"ttl,omitempty"`
+		DeleteSegments []string `json:"delete_segments,omitempty"`
+	}{
+		UserID:         req.UserID,
+		AddSegments:    req.AddSegments,
+		TTL:            int64(req.TTL.Round(time.Second) / time.Second),
+		DeleteSegments: req.DeleteSegments,
+	}
+
+	res := &UpdateUserSegmentsResult{}
+
+	err := c.do(ctx, request{method: http.MethodPost, path: "/segment/user", namespaced: true, body: body}, res)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return res, nil
+}
+
+// GetUserSegments возвращает активные сегменты пользователя.
+func (c *Client) GetUserSegments(ctx context.Context, userID int64) ([]*Segment, error) {
+	return c.getUserSegments(ctx, userID, nil)
+}
+
+// LookupUserSegments возвращает сегменты нескольких пользователей одним запросом.
+func (c *Client) LookupUserSegments(ctx context.Context, req LookupUserSegmentsRequest) (*LookupUserSegmentsResult, error) {
+	res := &LookupUserSegmentsResult{}
+
+	err := c.do(ctx, request{method: http.MethodPost, path: "/segment/users/lookup", namespaced: true, body: req}, res)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return res, nil
+}
+
+// GetUserSegmentsAt возвращает сегменты, в которых пользователь состоял в момент at,
+// включая сегменты, удаленные позже.
+func (c *Client) GetUserSegmentsAt(ctx context.Context, userID int64, at time.Time) ([]*Segment, error) {
+	return c.getUserSegments(ctx, userID, url.Values{"at": {at.Format(time.RFC3339Nano)}})
+}
+
+func (c *Client) getUserSegments(ctx context.Context, userID int64, query url.Values) ([]*Segment, error) {
+	var res struct {
+		Segments []*Segment `json:"segments"`
+	}
+
+	req := request{
+		method:     http.MethodGet,
+		path:       "/segment/user/" + strconv.FormatInt(userID, 10),
+		namespaced: true,
+		query:      query,
+	}
+
+	if err := c.do(ctx, req, &res); err != nil {
+		return nil, err
+	}
+
+	return res.Segments, nil
+}
+
+// GetSegmentHistory возвращает агрегаты по интервалам и первые события сегмента за период.
+func (c *Client) GetSegmentHistory(ctx context.Context, slug string, q SegmentHistoryQuery, opts *ReportOptions) (*SegmentHistory, error) {
+	var res struct {
+		History *SegmentHistory `json:"history"`
+	}
+
+	req := request{
+		method:     http.MethodGet,
+		path:       "/segment/" + url.PathEscape(slug) + "/history",
+		namespaced: true,
+		query:      q.values(opts.values()),
+	}
+
+	if err := c.do(ctx, req, &res); err != nil {
+		return nil, err
+	}
+
+	return res.History, nil
+}
+
+func (q SegmentHistoryQuery) values(v url.Values) url.Values {
+	v.Set("from", q.From.Format(time.RFC3339Nano))
+	v.Set("to", q.To.Format(time.RFC3339Nano))
+
+	if q.Bucket != "" {
+		v.Set("bucket", q.Bucket)
+	}
+
+	if q.View != "" {
+		v.Set("view", q.View)
+	}
+
+	return v
+}
+
+// ValidateRule проверяет правило динамического сегмента. Некорректное правило - не ошибка запроса,
+// а результат с Valid = false. Если attributes не nil, правило вычисляется для них.
+func (c *Client) ValidateRule(ctx context.Context, rule string, attributes map[string]any) (*RuleValidation, error) {
+	body := map[string]any{"rule": rule}
+
+	if attributes != nil {
+		body["attributes"] = attributes
+	}
+
+	res := &RuleValidation{}
+
+	if err := c.do(ctx, request{method: http.MethodPost, path: "/segment/rules/validate", namespaced: true, body: body}, res); err != nil {
+		return nil, err
+	}
+
+	return res, nil
+}
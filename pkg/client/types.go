@@ -0,0 +1,295 @@
+package client
+
+import "time"
+
+type Segment struct {
+	Slug        string `json:"slug"`
+	UserPercent int    `json:"user_percent,omitempty"`
+	// Сегмент удален, заполняется только в GetUserSegmentsAt
+	Deleted bool `json:"deleted,omitempty"`
+	// Вариант эксперимента пользователя, пустой у сегментов без вариантов
+	Variant string `json:"variant,omitempty"`
+}
+
+type CreateSegmentRequest struct {
+	Slug string `json:"slug"`
+	// Процент пользователей, которые сразу будут добавлены в сегмент, от 1 до 100. 0 - никто
+	UserPercent int `json:"user_percent,omitempty"`
+	// Правило динамического сегмента по атрибутам пользователя, не указывается вместе с UserPercent
+	Rule string `json:"rule,omitempty"`
+	// Слой взаимоисключающих сегментов. Раскатка на UserPercent занимает свободные бакеты слоя
+	Layer string `json:"layer,omitempty"`
+	// Варианты эксперимента, сумма весов - 100
+	Variants []VariantWeight `json:"variants,omitempty"`
+}
+
+type UpdateUserSegmentsRequest struct {
+	UserID      int64
+	AddSegments []string
+	// Через сколько удалить добавленные сегменты, округляется до секунд. 0 - не удалять
+	TTL            time.Duration
+	DeleteSegments []string
+}
+
+type UpdateUserSegmentsResult struct {
+	SegmentsAdded   int64 `json:"segments_added"`
+	SegmentsDeleted int64 `json:"segments_deleted"`
+}
+
+type LookupUserSegmentsRequest struct {
+	// Не больше 500 пользователей
+	UserIDs []int64 `json:"user_ids"`
+	// Если задан, возвращаются только перечисленные сегменты
+	Segments []string `json:"segments,omitempty"`
+}
+
+type LookupUserSegmentsResult struct {
+	// Сегменты по id пользователя, у пользователя без сегментов пустой список
+	Users map[int64][]*Segment `json:"users"`
+	// id пользователей, которых нет в сервисе
+	NotFound []int64 `json:"not_found"`
+}
+
+// Format - формат отчета.
+type Format string
+
+const (
+	FormatCSV  Format = "csv"
+	FormatXLSX Format = "xlsx"
+	FormatJSON Format = "json"
+)
+
+// ReportOptions - настройки отчетов и выгрузок. Пустые поля не передаются, и сервис использует значения по умолчанию.
+type ReportOptions struct {
+	// По умолчанию csv
+	Format Format
+	// Разделитель csv: один символ или tab
+	Delimiter string
+	// Подписи операций: code, en или ru
+	Lang string
+	// Формат времени: datetime, date, rfc3339, unix или layout Go
+	TimeFormat string
+	// Таймзона IANA
+	TZ string
+}
+
+// SegmentHistoryQuery задает период [From, To) истории сегмента.
+type SegmentHistoryQuery struct {
+	From time.Time
+	To   time.Time
+	// Интервал агрегации: day (по умолчанию) или hour
+	Bucket string
+	// Только для выгрузки: buckets (по умолчанию) или events
+	View string
+}
+
+type SegmentHistoryBucket struct {
+	Start   time.Time `json:"start"`
+	Entered int64     `json:"entered"`
+	Left    int64     `json:"left"`
+	Net     int64     `json:"net"`
+	Members int64     `json:"members"`
+}
+
+type HistoryEvent struct {
+	SegmentSlug string    `json:"segment_slug"`
+	UserID      int64     `json:"user_id"`
+	Operation   string    `json:"operation"`
+	Variant     string    `json:"variant,omitempty"`
+	ExecutedAt  time.Time `json:"executed_at"`
+	Source      string    `json:"source"`
+	Actor       string    `json:"actor"`
+	RequestID   string    `json:"request_id"`
+}
+
+type SegmentHistory struct {
+	SegmentSlug     string                  `json:"segment_slug"`
+	From            time.Time               `json:"from"`
+	To              time.Time               `json:"to"`
+	Bucket          string                  `json:"bucket"`
+	Buckets         []*SegmentHistoryBucket `json:"buckets"`
+	Events          []*HistoryEvent         `json:"events"`
+	EventsTruncated bool                    `json:"events_truncated"`
+}
+
+// MembershipQuery задает период по дням (включительно) и сегменты. Без сегментов возвращаются все.
+type MembershipQuery struct {
+	From     time.Time
+	To       time.Time
+	Segments []string
+}
+
+type MembershipPoint struct {
+	Day     time.Time `json:"day"`
+	Entered int64     `json:"entered"`
+	Left    int64     `json:"left"`
+	Members int64     `json:"members"`
+}
+
+type MembershipSeries struct {
+	SegmentSlug string             `json:"segment_slug"`
+	Points      []*MembershipPoint `json:"points"`
+}
+
+type Membership struct {
+	From   time.Time           `json:"from"`
+	To     time.Time           `json:"to"`
+	Series []*MembershipSeries `json:"series"`
+	// Время последнего пересчета сводки
+	RefreshedAt *time.Time `json:"refreshed_at"`
+}
+
+type APIKey struct {
+	ID     int64    `json:"id"`
+	Name   string   `json:"name"`
+	Scopes []string `json:"scopes"`
+	// Пустой список - доступ ко всем пространствам имен
+	Namespaces []string   `json:"namespaces"`
+	CreatedAt  time.Time  `json:"created_at"`
+	RotatedAt  *time.Time `json:"rotated_at,omitempty"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty"`
+}
+
+type IssueAPIKeyRequest struct {
+	Name       string   `json:"name"`
+	Scopes     []string `json:"scopes"`
+	Namespaces []string `json:"namespaces,omitempty"`
+}
+
+// IssuedAPIKey - новый ключ. Key возвращается только при выпуске и ротации.
+type IssuedAPIKey struct {
+	APIKey *APIKey `json:"api_key"`
+	Key    string  `json:"key"`
+}
+
+type Namespace struct {
+	Slug      string    `json:"slug"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type Webhook struct {
+	ID          int64     `json:"id"`
+	Namespace   string    `json:"namespace"`
+	URL         string    `json:"url"`
+	Description string    `json:"description"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+type CreateWebhookRequest struct {
+	URL         string `json:"url"`
+	Description string `json:"description,omitempty"`
+}
+
+// CreatedWebhook - новый вебхук. Secret возвращается только при регистрации.
+type CreatedWebhook struct {
+	Webhook *Webhook `json:"webhook"`
+	Secret  string   `json:"secret"`
+}
+
+// WebhookDelivery - попытка доставки события вебхуку.
+type WebhookDelivery struct {
+	ID        int64 `json:"id"`
+	WebhookID int64 `json:"webhook_id"`
+	// Пустой у тестовых событий
+	EventID   *int64 `json:"event_id"`
+	EventType string `json:"event_type"`
+	Attempt   int    `json:"attempt"`
+	// Пустой, если ответ не получен
+	StatusCode *int      `json:"status_code"`
+	Error      string    `json:"error,omitempty"`
+	DurationMs int64     `json:"duration_ms"`
+	Success    bool      `json:"success"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+type HealthCheck struct {
+	Status    string  `json:"status"`
+	LatencyMs float64 `json:"latency_ms"`
+	Error     string  `json:"error,omitempty"`
+}
+
+type HealthReport struct {
+	Status string                  `json:"status"`
+	Checks map[string]*HealthCheck `json:"checks"`
+}
+
+// Change - изменение членства из ленты изменений.
+type Change struct {
+	ID          int64  `json:"id"`
+	Namespace   string `json:"namespace"`
+	SegmentSlug string `json:"segment_slug"`
+	UserID      int64  `json:"user_id"`
+	// I - добавление, D - удаление, V - смена варианта
+	Operation string `json:"operation"`
+	// Вариант эксперимента: назначенный при добавлении, новый при смене, последний при удалении
+	Variant    string    `json:"variant,omitempty"`
+	ExecutedAt time.Time `json:"executed_at"`
+	Source     string    `json:"source"`
+	Actor      string    `json:"actor"`
+	RequestID  string    `json:"request_id"`
+}
+
+// ChangesPage - страница ленты изменений.
+type ChangesPage struct {
+	Changes []*Change `json:"changes"`
+	// Курсор следующего запроса, сохраняется вместе с обработанными изменениями
+	NextCursor string `json:"next_cursor"`
+	HasMore    bool   `json:"has_more"`
+}
+
+// User - пользователь с атрибутами для правил динамических сегментов.
+type User struct {
+	ID         int64          `json:"id"`
+	Attributes map[string]any `json:"attributes"`
+	UpdatedAt  *time.Time     `json:"updated_at,omitempty"`
+}
+
+// RuleError - ошибка разбора правила, Position считается в символах с 1.
+type RuleError struct {
+	Position int    `json:"position"`
+	Message  string `json:"message"`
+}
+
+// RuleValidation - результат проверки правила.
+type RuleValidation struct {
+	Valid bool       `json:"valid"`
+	Error *RuleError `json:"error,omitempty"`
+	// Атрибуты, которые использует правило
+	Attributes []string `json:"attributes,omitempty"`
+	// Подходят ли переданные атрибуты под правило, nil если атрибуты не переданы
+	Matches *bool `json:"matches,omitempty"`
+}
+
+// Layer - слой взаимоисключающих сегментов.
+type Layer struct {
+	Slug        string          `json:"slug"`
+	Description string          `json:"description,omitempty"`
+	CreatedAt   time.Time       `json:"created_at"`
+	Segments    []*LayerSegment `json:"segments"`
+	FreeBuckets int             `json:"free_buckets"`
+}
+
+type LayerSegment struct {
+	Slug string `json:"slug"`
+	// Бакеты слоя [From, To), занятые раскаткой сегмента, nil у сегментов без раскатки
+	Buckets *BucketRange `json:"buckets,omitempty"`
+}
+
+type BucketRange struct {
+	From int `json:"from"`
+	To   int `json:"to"`
+}
+
+// VariantWeight - вариант эксперимента в запросе.
+type VariantWeight struct {
+	Name string `json:"name"`
+	// Доля новых участников сегмента в процентах
+	Weight int `json:"weight"`
+}
+
+// Variant - вариант эксперимента с числом участников.
+type Variant struct {
+	Name   string `json:"name"`
+	Weight int    `json:"weight"`
+	Users  int64  `json:"users"`
+}
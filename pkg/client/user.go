@@ -0,0 +1,57 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+)
+
+// CreateUser создает пользователя и возвращает его id.
+func (c *Client) CreateUser(ctx context.Context) (int64, error) {
+	var res struct {
+		UserID int64 `json:"user_id"`
+	}
+
+	err := c.do(ctx, request{method: http.MethodPost, path: "/user", namespaced: true}, &res)
+
+	return res.UserID, err
+}
+
+// UpsertUser создает пользователя с заданным id или заменяет его атрибуты.
+// Атрибуты со значением nil не сохраняются.
+func (c *Client) UpsertUser(ctx context.Context, userID int64, attributes map[string]any) (*User, error) {
+	return c.upsertUser(ctx, http.MethodPut, userID, attributes)
+}
+
+// PatchUser дописывает атрибуты к существующим, создавая пользователя при необходимости.
+// Атрибут со значением nil удаляется.
+func (c *Client) PatchUser(ctx context.Context, userID int64, attributes map[string]any) (*User, error) {
+	return c.upsertUser(ctx, http.MethodPatch, userID, attributes)
+}
+
+func (c *Client) upsertUser(ctx context.Context, method string, userID int64, attributes map[string]any) (*User, error) {
+	if attributes == nil {
+		attributes = map[string]any{}
+	}
+
+	body := map[string]any{"attributes": attributes}
+
+	var res struct {
+		User *User `json:"user"`
+	}
+
+	err := c.do(ctx, request{method: method, path: "/user/" + strconv.FormatInt(userID, 10), namespaced: true, body: body}, &res)
+
+	return res.User, err
+}
+
+// GetUser возвращает пользователя с атрибутами.
+func (c *Client) GetUser(ctx context.Context, userID int64) (*User, error) {
+	var res struct {
+		User *User `json:"user"`
+	}
+
+	err := c.do(ctx, request{method: http.MethodGet, path: "/user/" + strconv.FormatInt(userID, 10), namespaced: true}, &res)
+
+	return res.User, err
+}
@@ -0,0 +1,50 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+)
+
+// GetVariants возвращает варианты эксперимента с числом участников. У сегмента без вариантов пустой список.
+func (c *Client) GetVariants(ctx context.Context, slug string) ([]*Variant, error) {
+	var res struct {
+		Variants []*Variant `json:"variants"`
+	}
+
+	req := request{method: http.MethodGet, path: "/segment/" + url.PathEscape(slug) + "/variants", namespaced: true}
+
+	if err := c.do(ctx, req, &res); err != nil {
+		return nil, err
+	}
+
+	return res.Variants, nil
+}
+
+// UpdateVariants меняет веса вариантов эксперимента. Передаются все варианты сегмента, сумма весов - 100.
+// Назначенные участникам варианты не меняются, новые веса действуют для новых участников.
+func (c *Client) UpdateVariants(ctx context.Context, slug string, variants []VariantWeight) ([]*Variant, error) {
+	body := map[string]any{"variants": variants}
+
+	var res struct {
+		Variants []*Variant `json:"variants"`
+	}
+
+	req := request{method: http.MethodPut, path: "/segment/" + url.PathEscape(slug) + "/variants", namespaced: true, body: body}
+
+	if err := c.do(ctx, req, &res); err != nil {
+		return nil, err
+	}
+
+	return res.Variants, nil
+}
+
+// SetUserVariant переводит участника сегмента в другой вариант эксперимента.
+func (c *Client) SetUserVariant(ctx context.Context, slug string, userID int64, variant string) error {
+	body := map[string]string{"variant": variant}
+
+	path := fmt.Sprintf("/segment/%s/user/%d/variant", url.PathEscape(slug), userID)
+
+	return c.do(ctx, request{method: http.MethodPut, path: path, namespaced: true, body: body}, nil)
+}
@@ -0,0 +1,71 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strconv"
+)
+
+// CreateWebhook регистрирует вебхук в пространстве имен клиента. Нужно право webhooks:admin.
+func (c *Client) CreateWebhook(ctx context.Context, req CreateWebhookRequest) (*CreatedWebhook, error) {
+	res := &CreatedWebhook{}
+
+	if err := c.do(ctx, request{method: http.MethodPost, path: "/webhooks", namespaced: true, body: req}, res); err != nil {
+		return nil, err
+	}
+
+	return res, nil
+}
+
+// ListWebhooks возвращает вебхуки пространства имен клиента.
+func (c *Client) ListWebhooks(ctx context.Context) ([]*Webhook, error) {
+	var res struct {
+		Webhooks []*Webhook `json:"webhooks"`
+	}
+
+	if err := c.do(ctx, request{method: http.MethodGet, path: "/webhooks", namespaced: true}, &res); err != nil {
+		return nil, err
+	}
+
+	return res.Webhooks, nil
+}
+
+// DeleteWebhook удаляет вебхук.
+func (c *Client) DeleteWebhook(ctx context.Context, id int64) error {
+	return c.do(ctx, request{method: http.MethodDelete, path: "/webhooks/" + strconv.FormatInt(id, 10), namespaced: true}, nil)
+}
+
+// SendTestWebhook отправляет вебхуку тестовое событие. Недоступность адреса не ошибка, а Success = false.
+func (c *Client) SendTestWebhook(ctx context.Context, id int64) (*WebhookDelivery, error) {
+	var res struct {
+		Delivery *WebhookDelivery `json:"delivery"`
+	}
+
+	req := request{method: http.MethodPost, path: "/webhooks/" + strconv.FormatInt(id, 10) + "/test", namespaced: true}
+
+	if err := c.do(ctx, req, &res); err != nil {
+		return nil, err
+	}
+
+	return res.Delivery, nil
+}
+
+// ListWebhookDeliveries возвращает последние попытки доставки, новые первыми. limit 0 - значение сервиса по умолчанию.
+func (c *Client) ListWebhookDeliveries(ctx context.Context, id int64, limit int) ([]*WebhookDelivery, error) {
+	var res struct {
+		Deliveries []*WebhookDelivery `json:"deliveries"`
+	}
+
+	req := request{method: http.MethodGet, path: "/webhooks/" + strconv.FormatInt(id, 10) + "/deliveries", namespaced: true}
+
+	if limit > 0 {
+		req.query = url.Values{"limit": {strconv.Itoa(limit)}}
+	}
+
+	if err := c.do(ctx, req, &res); err != nil {
+		return nil, err
+	}
+
+	return res.Deliveries, nil
+}
@@ -8,6 +8,7 @@ import (
 	"net/http"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/go-playground/validator/v10"
@@ -111,6 +112,8 @@ func msgForTag(fe validator.FieldError) string {
 		return fmt.Sprintf("This field must be at least %s", fe.Param())
 	case "max":
 		return fmt.Sprintf("This field must be at most %s", fe.Param())
+	case "oneof":
+		return fmt.Sprintf("This field must be one of: %s", fe.Param())
 	}
 
 	return fe.Error()
@@ -132,6 +135,28 @@ func QueryInt(r *http.Request, key string) (int64, error) {
 	return query, nil
 }
 
+// QueryTime читает время из query параметра в формате RFC3339 или дату 2006-01-02.
+// Для даты возвращает начало дня в таймзоне loc и dateOnly = true.
+func QueryTime(r *http.Request, key string, loc *time.Location) (t time.Time, dateOnly bool, err error) {
+	value := r.URL.Query().Get(key)
+
+	if value == "" {
+		return t, false, errors.New("empty query param")
+	}
+
+	if t, err := time.ParseInLocation("2006-01-02", value, loc); err == nil {
+		return t, true, nil
+	}
+
+	t, err = time.Parse(time.RFC3339, value)
+
+	if err != nil {
+		return t, false, errors.New("invalid query param")
+	}
+
+	return t, false, nil
+}
+
 func ParamInt(r *http.Request, key string) (int64, error) {
 	paramStr := chi.URLParam(r, key)
 
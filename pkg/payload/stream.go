@@ -0,0 +1,90 @@
+package payload
+
+import (
+	"compress/gzip"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// FileStream отдает файл клиенту по мере записи.
+// Заголовки ответа отправляются только при первой записи, поэтому пока
+// ничего не записано, вместо файла можно вернуть ошибку через WriteJSON.
+type FileStream struct {
+	w           http.ResponseWriter
+	out         io.Writer
+	gzip        *gzip.Writer
+	useGzip     bool
+	contentType string
+	fileName    string
+	started     bool
+}
+
+// NewFileStream создает поток для скачивания файла.
+// Если клиент принимает gzip, ответ сжимается.
+func NewFileStream(w http.ResponseWriter, r *http.Request, contentType, fileName string) *FileStream {
+	return &FileStream{
+		w:           w,
+		useGzip:     AcceptsGzip(r),
+		contentType: contentType,
+		fileName:    fileName,
+	}
+}
+
+func (s *FileStream) Write(p []byte) (int, error) {
+	if !s.started {
+		s.start()
+	}
+
+	return s.out.Write(p)
+}
+
+// Started сообщает, были ли уже отправлены заголовки ответа.
+func (s *FileStream) Started() bool {
+	return s.started
+}
+
+// Close дописывает сжатые данные. Если в поток ничего не писали,
+// отправляет пустой файл.
+func (s *FileStream) Close() error {
+	if !s.started {
+		s.start()
+	}
+
+	if s.gzip != nil {
+		return s.gzip.Close()
+	}
+
+	return nil
+}
+
+func (s *FileStream) start() {
+	s.started = true
+
+	// Устанавливаем заголовки позволяющие браузеру скачать файл
+	s.w.Header().Set("Content-Type", s.contentType)
+	s.w.Header().Set("Content-Disposition", "attachment;filename="+s.fileName)
+	s.w.Header().Add("Vary", "Accept-Encoding")
+
+	if s.useGzip {
+		s.w.Header().Set("Content-Encoding", "gzip")
+		s.gzip = gzip.NewWriter(s.w)
+		s.out = s.gzip
+	} else {
+		s.out = s.w
+	}
+
+	s.w.WriteHeader(http.StatusOK)
+}
+
+func AcceptsGzip(r *http.Request) bool {
+	for _, enc := range strings.Split(r.Header.Get("Accept-Encoding"), ",") {
+		name, params, _ := strings.Cut(strings.TrimSpace(enc), ";")
+
+		if strings.EqualFold(strings.TrimSpace(name), "gzip") {
+			return strings.ReplaceAll(params, " ", "") != "q=0"
+		}
+	}
+
+	return false
+}
@@ -5,6 +5,8 @@ import (
 	"errors"
 	"fmt"
 	"log"
+	"path/filepath"
+	"runtime"
 	"time"
 
 	"github.com/golang-migrate/migrate/v4"
@@ -97,7 +99,7 @@ func createContainer(ctx context.Context) (testcontainers.Container, *pgxpool.Po
 
 func migrateDb(dbAddr string) error {
 	databaseURL := fmt.Sprintf("postgres://%s:%s@%s/%s?sslmode=disable", DB_USER, DB_PASS, dbAddr, DB_NAME)
-	m, err := migrate.New("file://../db/migrations", databaseURL)
+	m, err := migrate.New("file://"+migrationsDir(), databaseURL)
 	if err != nil {
 		return err
 	}
@@ -112,3 +114,10 @@ func migrateDb(dbAddr string) error {
 
 	return nil
 }
+
+// migrationsDir возвращает путь к миграциям относительно этого файла,
+// чтобы базу можно было поднять из тестов любого пакета.
+func migrationsDir() string {
+	_, file, _, _ := runtime.Caller(0)
+	return filepath.Join(filepath.Dir(file), "..", "..", "internal", "db", "migrations")
+}